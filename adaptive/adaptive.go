@@ -0,0 +1,139 @@
+// Package adaptive implements a concurrency limiter that adapts
+// to the rate of blocked requests using AIMD (additive increase,
+// multiplicative decrease).
+package adaptive
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/metrics"
+)
+
+const (
+	decreaseFactor = 0.5
+	// cooldown is the minimum time between two decreases so that a burst of
+	// blocks caused by the same limit doesn't collapse the concurrency
+	cooldown = 10 * time.Second
+)
+
+// Limiter limits the number of concurrent requests. Every successful
+// request increases the limit by 1/limit, so by one after a full window of
+// successes, and a blocked request halves it. The limit stays in [min, max].
+type Limiter struct {
+	minLimit float64
+	maxLimit float64
+
+	mu           sync.Mutex
+	limit        float64
+	inflight     int
+	lastDecrease time.Time
+	waitc        chan struct{}
+}
+
+// New returns a Limiter that starts at max
+func New(minLimit, maxLimit int) *Limiter {
+	minLimit = max(1, minLimit)
+	maxLimit = max(minLimit, maxLimit)
+
+	l := Limiter{
+		minLimit: float64(minLimit),
+		maxLimit: float64(maxLimit),
+		limit:    float64(maxLimit),
+		waitc:    make(chan struct{}),
+	}
+
+	metrics.AdaptiveConcurrency.Set(float64(maxLimit))
+
+	return &l
+}
+
+// Acquire blocks until a slot is available or ctx is done
+func (l *Limiter) Acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+
+		if l.inflight < l.currentLimit() {
+			l.inflight++
+			l.mu.Unlock()
+
+			return nil
+		}
+
+		waitc := l.waitc
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-waitc:
+		}
+	}
+}
+
+// Release frees the slot and adapts the limit to the outcome of the request
+func (l *Limiter) Release(blocked bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+
+	prev := l.currentLimit()
+
+	switch {
+	case blocked:
+		if time.Since(l.lastDecrease) >= cooldown {
+			l.limit = math.Max(l.minLimit, l.limit*decreaseFactor)
+			l.lastDecrease = time.Now()
+		}
+	default:
+		l.limit = math.Min(l.maxLimit, l.limit+1/l.limit)
+	}
+
+	if next := l.currentLimit(); next != prev {
+		metrics.AdaptiveConcurrency.Set(float64(next))
+		log.Printf("adaptive concurrency changed from %d to %d (blocked: %v)", prev, next, blocked)
+	}
+
+	// wake up the waiting requests
+	close(l.waitc)
+	l.waitc = make(chan struct{})
+}
+
+// SetBounds changes the range of the limit, the current limit is moved into it
+func (l *Limiter) SetBounds(minLimit, maxLimit int) {
+	minLimit = max(1, minLimit)
+	maxLimit = max(minLimit, maxLimit)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prev := l.currentLimit()
+
+	l.minLimit = float64(minLimit)
+	l.maxLimit = float64(maxLimit)
+	l.limit = math.Min(l.maxLimit, math.Max(l.minLimit, l.limit))
+
+	if next := l.currentLimit(); next != prev {
+		metrics.AdaptiveConcurrency.Set(float64(next))
+		log.Printf("adaptive concurrency changed from %d to %d (bounds: %d-%d)", prev, next, minLimit, maxLimit)
+	}
+
+	close(l.waitc)
+	l.waitc = make(chan struct{})
+}
+
+// Limit returns the current concurrency limit
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.currentLimit()
+}
+
+func (l *Limiter) currentLimit() int {
+	return int(l.limit)
+}
@@ -0,0 +1,16 @@
+// Package apilimits holds the default limits of the API server. It has no
+// dependencies so that the flags can be declared without the web packages.
+package apilimits
+
+import "time"
+
+// default timeouts of the server
+const (
+	ReadTimeout = 30 * time.Second
+	// refreshing a place scrapes it while the client waits
+	WriteTimeout = 3 * time.Minute
+	IdleTimeout  = 120 * time.Second
+)
+
+// MaxBodySize is the default maximum size in bytes of the request bodies
+const MaxBodySize = 1 << 20
@@ -0,0 +1,181 @@
+package checkpoint
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Checkpoint keeps track of the input queries that have been fully processed
+// so an interrupted run can skip them when it is restarted.
+type Checkpoint interface {
+	IsDone(query string) bool
+	Track(jobID, query string)
+	SetPlacesFound(jobID string, val int)
+	IncrPlacesCompleted(jobID string, val int)
+	// PlacesPending returns the number of places of the job that are not
+	// completed yet, it's false when the job is not tracked or already done
+	PlacesPending(jobID string) (int, bool)
+	Close() error
+}
+
+type checkpoint struct {
+	mu      *sync.Mutex
+	f       *os.File
+	done    map[string]struct{}
+	queries map[string]string
+	pending map[string]int
+}
+
+// New opens (or creates) the checkpoint file at path and loads the queries
+// that were completed in previous runs.
+func New(path string) (Checkpoint, error) {
+	ans := checkpoint{
+		mu:      &sync.Mutex{},
+		done:    make(map[string]struct{}),
+		queries: make(map[string]string),
+		pending: make(map[string]int),
+	}
+
+	if err := ans.load(path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	ans.f = f
+
+	return &ans, nil
+}
+
+// Path returns the checkpoint file path that belongs to resultsFile.
+func Path(resultsFile string) string {
+	return resultsFile + ".checkpoint"
+}
+
+func (c *checkpoint) IsDone(query string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.done[query]
+
+	return ok
+}
+
+func (c *checkpoint) Track(jobID, query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queries[jobID] = query
+}
+
+func (c *checkpoint) SetPlacesFound(jobID string, val int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[jobID] += val
+
+	if c.pending[jobID] <= 0 {
+		c.markDone(jobID)
+	}
+}
+
+func (c *checkpoint) IncrPlacesCompleted(jobID string, val int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.pending[jobID]; !ok {
+		return
+	}
+
+	c.pending[jobID] -= val
+
+	if c.pending[jobID] <= 0 {
+		c.markDone(jobID)
+	}
+}
+
+func (c *checkpoint) PlacesPending(jobID string) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n, ok := c.pending[jobID]
+
+	return n, ok
+}
+
+func (c *checkpoint) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.f == nil {
+		return nil
+	}
+
+	err := c.f.Close()
+	c.f = nil
+
+	return err
+}
+
+// markDone must be called with the lock held
+func (c *checkpoint) markDone(jobID string) {
+	delete(c.pending, jobID)
+
+	query, ok := c.queries[jobID]
+	if !ok {
+		return
+	}
+
+	delete(c.queries, jobID)
+
+	if _, ok := c.done[query]; ok {
+		return
+	}
+
+	c.done[query] = struct{}{}
+
+	if c.f == nil {
+		return
+	}
+
+	if _, err := c.f.WriteString(query + "\n"); err == nil {
+		_ = c.f.Sync()
+	}
+}
+
+// load reads the completed queries of path. A crash can leave the last
+// query half written, it's dropped from the file and run again.
+func (c *checkpoint) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	complete := data[:bytes.LastIndexByte(data, '\n')+1]
+
+	if len(complete) < len(data) {
+		if err := os.Truncate(path, int64(len(complete))); err != nil {
+			return err
+		}
+	}
+
+	for _, line := range strings.Split(string(complete), "\n") {
+		query := strings.TrimSpace(line)
+		if query == "" {
+			continue
+		}
+
+		c.done[query] = struct{}{}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+// Package compactcsv writes the main fields of the places as a CSV file,
+// for the tools and spreadsheets that don't need the full export.
+package compactcsv
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// Headers are the columns of the file, in order
+var Headers = []string{
+	"title",
+	"address",
+	"phone",
+	"phone_e164",
+	"website",
+	"review_rating",
+	"review_count",
+	"latitude",
+	"longitude",
+	"category",
+	"cid",
+	"place_id",
+	"maps_url",
+}
+
+type writer struct {
+	w *csv.Writer
+}
+
+// New returns a result writer that writes one row per place to w,
+// the header is written before the first place
+func New(w io.Writer) scrapemate.ResultWriter {
+	return &writer{w: csv.NewWriter(w)}
+}
+
+func (c *writer) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	headerWritten := false
+
+	for result := range in {
+		var entries []*gmaps.Entry
+
+		switch v := result.Data.(type) {
+		case *gmaps.Entry:
+			entries = append(entries, v)
+		case []any:
+			for i := range v {
+				entry, ok := v[i].(*gmaps.Entry)
+				if !ok {
+					return fmt.Errorf("cannot cast %T to *gmaps.Entry", v[i])
+				}
+
+				entries = append(entries, entry)
+			}
+		default:
+			return fmt.Errorf("cannot cast %T to *gmaps.Entry", result.Data)
+		}
+
+		if !headerWritten {
+			if err := c.w.Write(Headers); err != nil {
+				return err
+			}
+
+			headerWritten = true
+		}
+
+		for _, entry := range entries {
+			if err := c.w.Write(Row(entry)); err != nil {
+				return err
+			}
+		}
+
+		c.w.Flush()
+
+		if err := c.w.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Row returns the cells of entry in the order of Headers. The rating of
+// the places without reviews and the coordinates of the places without
+// a location are left blank.
+func Row(entry *gmaps.Entry) []string {
+	var rating, lat, lon string
+
+	if entry.ReviewCount > 0 {
+		rating = strconv.FormatFloat(entry.ReviewRating, 'f', -1, 64)
+	}
+
+	if entry.Latitude != 0 || entry.Longtitude != 0 {
+		lat = strconv.FormatFloat(entry.Latitude, 'f', -1, 64)
+		lon = strconv.FormatFloat(entry.Longtitude, 'f', -1, 64)
+	}
+
+	return []string{
+		entry.Title,
+		entry.Address,
+		entry.Phone,
+		entry.PhoneE164,
+		entry.WebSite,
+		rating,
+		strconv.Itoa(entry.ReviewCount),
+		lat,
+		lon,
+		entry.Category,
+		entry.Cid,
+		entry.PlaceID,
+		entry.MapsURL,
+	}
+}
@@ -0,0 +1,91 @@
+package deduper
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+var _ Deduper = (*bloom)(nil)
+
+// exactThreshold is the number of keys kept in an exact hashmap before
+// switching to the bloom filter. Small jobs never pay the false positive cost.
+const exactThreshold = 10_000
+
+// bloom is a deduper that starts as an exact hashmap and switches to a bloom
+// filter once more than exactThreshold keys have been seen.
+// The bloom filter uses a fixed amount of memory at the cost of a small
+// false positive rate: a new key may be reported as already seen.
+type bloom struct {
+	mux      *sync.Mutex
+	exact    map[uint64]struct{}
+	bits     []uint64
+	m        uint64
+	k        uint64
+	expected uint64
+	fpRate   float64
+}
+
+func (d *bloom) AddIfNotExists(_ context.Context, key string) bool {
+	h := hashKey(key)
+
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	if d.exact != nil {
+		if _, ok := d.exact[h]; ok {
+			return false
+		}
+
+		d.exact[h] = struct{}{}
+
+		if len(d.exact) > exactThreshold {
+			d.switchToBloom()
+		}
+
+		return true
+	}
+
+	return d.add(h)
+}
+
+func (d *bloom) switchToBloom() {
+	d.m, d.k = bloomParams(d.expected, d.fpRate)
+	d.bits = make([]uint64, (d.m+63)/64)
+
+	for h := range d.exact {
+		d.add(h)
+	}
+
+	d.exact = nil
+}
+
+// add sets the bits of h and reports whether any of them was unset
+func (d *bloom) add(h uint64) bool {
+	h1, h2 := h&0xffffffff, h>>32|1
+	added := false
+
+	for i := uint64(0); i < d.k; i++ {
+		pos := (h1 + i*h2) % d.m
+		word, mask := pos/64, uint64(1)<<(pos%64)
+
+		if d.bits[word]&mask == 0 {
+			d.bits[word] |= mask
+			added = true
+		}
+	}
+
+	return added
+}
+
+// bloomParams returns the optimal number of bits and hash functions
+// for n items and false positive rate p
+func bloomParams(n uint64, p float64) (m, k uint64) {
+	mf := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	kf := math.Round(mf / float64(n) * math.Ln2)
+
+	m = max(uint64(mf), 64)
+	k = max(uint64(kf), 1)
+
+	return m, k
+}
@@ -0,0 +1,34 @@
+package deduper
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+var _ Deduper = (*Counter)(nil)
+
+// Counter is a deduper counting the keys that were already seen
+type Counter struct {
+	Deduper
+	duplicates atomic.Int64
+}
+
+// NewCounter returns a deduper counting the duplicate keys of d
+func NewCounter(d Deduper) *Counter {
+	return &Counter{Deduper: d}
+}
+
+func (c *Counter) AddIfNotExists(ctx context.Context, key string) bool {
+	if c.Deduper.AddIfNotExists(ctx, key) {
+		return true
+	}
+
+	c.duplicates.Add(1)
+
+	return false
+}
+
+// Duplicates returns the number of keys that were already seen
+func (c *Counter) Duplicates() int64 {
+	return c.duplicates.Load()
+}
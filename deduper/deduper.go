@@ -15,3 +15,23 @@ func New() Deduper {
 		mux:  &sync.RWMutex{},
 	}
 }
+
+// NewBloom returns a deduper that bounds its memory usage using a bloom filter
+// sized for expected keys with the false positive rate fpRate.
+// Up to a few thousand keys the deduplication is exact.
+func NewBloom(expected int, fpRate float64) Deduper {
+	if expected < exactThreshold {
+		expected = exactThreshold
+	}
+
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.001
+	}
+
+	return &bloom{
+		mux:      &sync.Mutex{},
+		exact:    make(map[uint64]struct{}),
+		expected: uint64(expected),
+		fpRate:   fpRate,
+	}
+}
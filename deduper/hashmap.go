@@ -35,6 +35,10 @@ func (d *hashmap) AddIfNotExists(_ context.Context, key string) bool {
 }
 
 func (d *hashmap) hash(key string) uint64 {
+	return hashKey(key)
+}
+
+func hashKey(key string) uint64 {
 	h := fnv.New64()
 	h.Write([]byte(key))
 
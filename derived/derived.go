@@ -0,0 +1,278 @@
+package derived
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// Field is a derived field computed from the scraped data of a place
+type Field struct {
+	Name string
+	eval func(*gmaps.Entry) any
+}
+
+// Fields are the derived fields of the jobs, they are computed by the jobs
+// once per place before the results are handed to the writers
+type Fields []Field
+
+// Derive computes the fields and attaches them to entry
+func (f Fields) Derive(entry *gmaps.Entry) {
+	Apply(entry, f)
+}
+
+// Parse parses a semicolon separated list of derived field definitions
+// of the form name=function(args). The supported functions are:
+//
+//	not_empty(field)           true when the field has a value
+//	contains(field, text)      true when the field contains text (case insensitive)
+//	gt(field, number)          true when the numeric field is greater than number
+//	lt(field, number)          true when the numeric field is less than number
+//	count(field)               the number of items of a list field
+//	distance(lat, lon)         the distance in km of the place from lat, lon
+//
+// Example: has_website=not_empty(website);distance_km=distance(34.67,33.04)
+func Parse(spec string) (Fields, error) {
+	var fields Fields
+
+	seen := map[string]bool{}
+
+	for _, def := range strings.Split(spec, ";") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+
+		name, expr, ok := strings.Cut(def, "=")
+		name = strings.TrimSpace(name)
+
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid derived field %q: expected name=function(args)", def)
+		}
+
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate derived field %q", name)
+		}
+
+		seen[name] = true
+
+		eval, err := compile(strings.TrimSpace(expr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid derived field %q: %w", name, err)
+		}
+
+		fields = append(fields, Field{Name: name, eval: eval})
+	}
+
+	return fields, nil
+}
+
+// Apply computes the fields and attaches them to entry
+func Apply(entry *gmaps.Entry, fields []Field) {
+	if len(fields) == 0 {
+		return
+	}
+
+	if entry.Derived == nil {
+		entry.Derived = make(map[string]any, len(fields))
+	}
+
+	for i := range fields {
+		entry.Derived[fields[i].Name] = fields[i].eval(entry)
+	}
+}
+
+func compile(expr string) (func(*gmaps.Entry) any, error) {
+	open := strings.Index(expr, "(")
+	if open <= 0 || !strings.HasSuffix(expr, ")") {
+		return nil, fmt.Errorf("expected function(args) got %q", expr)
+	}
+
+	fn := strings.TrimSpace(expr[:open])
+
+	var args []string
+
+	for _, a := range strings.Split(expr[open+1:len(expr)-1], ",") {
+		args = append(args, strings.Trim(strings.TrimSpace(a), `"'`))
+	}
+
+	switch fn {
+	case "not_empty":
+		get, err := fieldArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(e *gmaps.Entry) any {
+			return !isEmpty(get(e))
+		}, nil
+	case "contains":
+		get, err := fieldArg(args, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		text := strings.ToLower(args[1])
+
+		return func(e *gmaps.Entry) any {
+			return strings.Contains(strings.ToLower(fmt.Sprint(get(e))), text)
+		}, nil
+	case "gt", "lt":
+		get, err := fieldArg(args, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		num, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid number %q", fn, args[1])
+		}
+
+		return func(e *gmaps.Entry) any {
+			v, ok := toFloat(get(e))
+			if !ok {
+				return false
+			}
+
+			if fn == "gt" {
+				return v > num
+			}
+
+			return v < num
+		}, nil
+	case "count":
+		get, err := fieldArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(e *gmaps.Entry) any {
+			return count(get(e))
+		}, nil
+	case "distance":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("distance expects 2 arguments")
+		}
+
+		lat, err1 := strconv.ParseFloat(args[0], 64)
+		lon, err2 := strconv.ParseFloat(args[1], 64)
+
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("distance: invalid coordinates %s,%s", args[0], args[1])
+		}
+
+		return func(e *gmaps.Entry) any {
+			return math.Round(haversine(lat, lon, e.Latitude, e.Longtitude)*1000) / 1000
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown function %q", fn)
+	}
+}
+
+var entryFields = map[string]func(*gmaps.Entry) any{
+	"title":        func(e *gmaps.Entry) any { return e.Title },
+	"category":     func(e *gmaps.Entry) any { return e.Category },
+	"categories":   func(e *gmaps.Entry) any { return e.Categories },
+	"address":      func(e *gmaps.Entry) any { return e.Address },
+	"website":      func(e *gmaps.Entry) any { return e.WebSite },
+	"phone":        func(e *gmaps.Entry) any { return e.Phone },
+	"phone_e164":   func(e *gmaps.Entry) any { return e.PhoneE164 },
+	"plus_code":    func(e *gmaps.Entry) any { return e.PlusCode },
+	"review_count": func(e *gmaps.Entry) any { return e.ReviewCount },
+	"review_rating": func(e *gmaps.Entry) any {
+		return e.ReviewRating
+	},
+	"latitude":     func(e *gmaps.Entry) any { return e.Latitude },
+	"longitude":    func(e *gmaps.Entry) any { return e.Longtitude },
+	"status":       func(e *gmaps.Entry) any { return e.Status },
+	"description":  func(e *gmaps.Entry) any { return e.Description },
+	"price_range":  func(e *gmaps.Entry) any { return e.PriceRange },
+	"images":       func(e *gmaps.Entry) any { return len(e.Images) },
+	"photos":       func(e *gmaps.Entry) any { return len(e.Photos) },
+	"emails":       func(e *gmaps.Entry) any { return e.Emails },
+	"user_reviews": func(e *gmaps.Entry) any { return len(e.UserReviews) },
+	"open_hours":   func(e *gmaps.Entry) any { return len(e.OpenHours) },
+	"opened_year":  func(e *gmaps.Entry) any { return e.OpenedYear },
+	"sparse":       func(e *gmaps.Entry) any { return e.Sparse },
+	"completeness": func(e *gmaps.Entry) any { return e.Completeness() },
+	"spam_score":   func(e *gmaps.Entry) any { return e.SpamScore },
+}
+
+func fieldArg(args []string, n int) (func(*gmaps.Entry) any, error) {
+	if len(args) != n {
+		return nil, fmt.Errorf("expected %d arguments got %d", n, len(args))
+	}
+
+	get, ok := entryFields[args[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", args[0])
+	}
+
+	return get, nil
+}
+
+func isEmpty(v any) bool {
+	switch val := v.(type) {
+	case string:
+		return strings.TrimSpace(val) == ""
+	case []string:
+		return len(val) == 0
+	case bool:
+		return !val
+	case int:
+		return val == 0
+	case float64:
+		return val == 0
+	default:
+		return v == nil
+	}
+}
+
+func count(v any) int {
+	switch val := v.(type) {
+	case []string:
+		return len(val)
+	case int:
+		return val
+	case string:
+		if val == "" {
+			return 0
+		}
+
+		return 1
+	default:
+		return 0
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch val := v.(type) {
+	case int:
+		return float64(val), true
+	case float64:
+		return val, true
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
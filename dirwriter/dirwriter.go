@@ -0,0 +1,180 @@
+package dirwriter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const manifestName = "manifest.json"
+
+// Uploader uploads a file to an S3 bucket
+type Uploader interface {
+	Upload(ctx context.Context, bucketName, key string, body io.Reader) error
+}
+
+// Manifest lists the files written by the writer
+type Manifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	Count     int       `json:"count"`
+	Files     []string  `json:"files"`
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+type dirWriter struct {
+	dir      string
+	bucket   string
+	uploader Uploader
+	seen     map[string]int
+	files    []string
+}
+
+// New returns a result writer that writes every place as a separate json file
+// in dir, named after the place id. When dir has the form s3://bucket/prefix
+// the files are uploaded to the bucket using uploader instead.
+// A manifest.json listing all the written files is written when the results end.
+func New(dir string, uploader Uploader) (scrapemate.ResultWriter, error) {
+	ans := dirWriter{
+		seen: make(map[string]int),
+	}
+
+	if rest, ok := strings.CutPrefix(dir, "s3://"); ok {
+		if uploader == nil {
+			return nil, errors.New("s3 output requires aws credentials")
+		}
+
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("invalid s3 location: %s", dir)
+		}
+
+		ans.bucket = bucket
+		ans.dir = strings.Trim(prefix, "/")
+		ans.uploader = uploader
+
+		return &ans, nil
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	ans.dir = dir
+
+	return &ans, nil
+}
+
+func (w *dirWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		entries, err := asEntries(result.Data)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+
+			if err := w.write(ctx, w.fileName(entry), data); err != nil {
+				return err
+			}
+		}
+	}
+
+	manifest := Manifest{
+		CreatedAt: time.Now().UTC(),
+		Count:     len(w.files),
+		Files:     w.files,
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return w.save(ctx, manifestName, data)
+}
+
+func (w *dirWriter) write(ctx context.Context, name string, data []byte) error {
+	if err := w.save(ctx, name, data); err != nil {
+		return err
+	}
+
+	w.files = append(w.files, name)
+
+	return nil
+}
+
+func (w *dirWriter) save(ctx context.Context, name string, data []byte) error {
+	if w.uploader != nil {
+		return w.uploader.Upload(ctx, w.bucket, path.Join(w.dir, name), bytes.NewReader(data))
+	}
+
+	return os.WriteFile(filepath.Join(w.dir, name), data, 0o600)
+}
+
+// fileName returns a filesystem safe and unique file name for the entry
+func (w *dirWriter) fileName(entry *gmaps.Entry) string {
+	id := entry.DataID
+	if id == "" {
+		id = entry.Cid
+	}
+
+	if id == "" {
+		sum := sha256.Sum256([]byte(entry.Link))
+		id = hex.EncodeToString(sum[:8])
+	}
+
+	name := strings.Trim(unsafeChars.ReplaceAllString(id, "_"), "._")
+	if name == "" {
+		name = "place"
+	}
+
+	w.seen[name]++
+
+	if n := w.seen[name]; n > 1 {
+		name = fmt.Sprintf("%s-%d", name, n)
+	}
+
+	return name + ".json"
+}
+
+func asEntries(data any) ([]*gmaps.Entry, error) {
+	switch v := data.(type) {
+	case *gmaps.Entry:
+		return []*gmaps.Entry{v}, nil
+	case []any:
+		ans := make([]*gmaps.Entry, 0, len(v))
+
+		for i := range v {
+			entry, ok := v[i].(*gmaps.Entry)
+			if !ok {
+				return nil, fmt.Errorf("cannot cast %T to *gmaps.Entry", v[i])
+			}
+
+			ans = append(ans, entry)
+		}
+
+		return ans, nil
+	default:
+		return nil, fmt.Errorf("cannot cast %T to *gmaps.Entry", data)
+	}
+}
@@ -12,9 +12,18 @@ type Exiter interface {
 	IncrSeedCompleted(int)
 	IncrPlacesFound(int)
 	IncrPlacesCompleted(int)
+	Progress() Progress
 	Run(context.Context)
 }
 
+// Progress is the estimated completion of a job
+type Progress struct {
+	// Percent is between 0 and 100, it's 0 when Indeterminate
+	Percent int `json:"percent"`
+	// Indeterminate is true until a search page gave an estimate of the places
+	Indeterminate bool `json:"indeterminate"`
+}
+
 type exiter struct {
 	seedCount       int
 	seedCompleted   int
@@ -66,6 +75,29 @@ func (e *exiter) IncrPlacesCompleted(val int) {
 	e.placesCompleted += val
 }
 
+// Progress estimates the completion from the places scraped and the places
+// the total is expected to be. The total is extrapolated from the search pages
+// completed so far, so it's indeterminate until the first one completes.
+func (e *exiter) Progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.seedCount > 0 && e.seedCompleted >= e.seedCount && e.placesCompleted >= e.placesFound {
+		return Progress{Percent: 100}
+	}
+
+	if e.seedCompleted == 0 || e.placesFound == 0 {
+		return Progress{Indeterminate: true}
+	}
+
+	estimated := float64(e.placesFound) * float64(max(e.seedCount, e.seedCompleted)) / float64(e.seedCompleted)
+
+	// 100 is reported only when everything completed
+	percent := min(int(float64(e.placesCompleted)*100/estimated), 99)
+
+	return Progress{Percent: percent}
+}
+
 func (e *exiter) Run(ctx context.Context) {
 	ticker := time.NewTicker(time.Second * 5)
 	defer ticker.Stop()
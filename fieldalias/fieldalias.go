@@ -0,0 +1,186 @@
+// Package fieldalias renames the output fields of the places
+// in the csv headers and the json keys.
+package fieldalias
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// Parse parses a comma separated list of field=alias pairs and
+// validates that every field is an output field. A field can be given
+// either by its csv header or by its json key (e.g. website or web_site).
+func Parse(spec string) (map[string]string, error) {
+	known := knownFields()
+	aliases := map[string]string{}
+	targets := map[string]string{}
+
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		field, alias, ok := strings.Cut(pair, "=")
+		field, alias = strings.TrimSpace(field), strings.TrimSpace(alias)
+
+		if !ok || field == "" || alias == "" {
+			return nil, fmt.Errorf("invalid alias %q: expected field=alias", pair)
+		}
+
+		if !known[field] {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+
+		if _, ok := aliases[field]; ok {
+			return nil, fmt.Errorf("duplicate alias for field %q", field)
+		}
+
+		if other, ok := targets[alias]; ok && other != equivalents[field] {
+			return nil, fmt.Errorf("fields %q and %q have the same alias %q", other, field, alias)
+		}
+
+		aliases[field] = alias
+		targets[alias] = field
+	}
+
+	// apply the alias to both the csv header and the json key
+	for field, alias := range aliases {
+		if other, ok := equivalents[field]; ok {
+			if _, exists := aliases[other]; !exists {
+				aliases[other] = alias
+			}
+		}
+	}
+
+	return aliases, nil
+}
+
+// equivalents maps the csv headers to the json keys that differ and vice versa
+var equivalents = map[string]string{
+	"website":      "web_site",
+	"web_site":     "website",
+	"longitude":    "longtitude",
+	"longtitude":   "longitude",
+	"descriptions": "description",
+	"description":  "descriptions",
+}
+
+func knownFields() map[string]bool {
+	ans := map[string]bool{}
+
+	var e gmaps.Entry
+
+	for _, h := range e.CsvHeaders() {
+		ans[h] = true
+	}
+
+	t := reflect.TypeOf(e)
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); name != "" && name != "-" {
+			ans[name] = true
+		}
+	}
+
+	return ans
+}
+
+// WrapWriter returns a writer that renames the fields of the places
+// before handing them to w. It's meant for the csv and json writers.
+func WrapWriter(w scrapemate.ResultWriter, aliases map[string]string) scrapemate.ResultWriter {
+	if len(aliases) == 0 {
+		return w
+	}
+
+	return &writer{inner: w, aliases: aliases}
+}
+
+type writer struct {
+	inner   scrapemate.ResultWriter
+	aliases map[string]string
+}
+
+func (w *writer) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	out := make(chan scrapemate.Result)
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- w.inner.Run(ctx, out)
+	}()
+
+	for result := range in {
+		switch data := result.Data.(type) {
+		case *gmaps.Entry:
+			result.Data = &Entry{Entry: data, aliases: w.aliases}
+		case []any:
+			items := make([]any, len(data))
+
+			for i := range data {
+				if entry, ok := data[i].(*gmaps.Entry); ok {
+					items[i] = &Entry{Entry: entry, aliases: w.aliases}
+				} else {
+					items[i] = data[i]
+				}
+			}
+
+			result.Data = items
+		}
+
+		select {
+		case out <- result:
+		case err := <-errc:
+			return err
+		}
+	}
+
+	close(out)
+
+	return <-errc
+}
+
+// Entry is a place with renamed output fields
+type Entry struct {
+	*gmaps.Entry
+	aliases map[string]string
+}
+
+func (e *Entry) CsvHeaders() []string {
+	headers := e.Entry.CsvHeaders()
+
+	for i := range headers {
+		if alias, ok := e.aliases[headers[i]]; ok {
+			headers[i] = alias
+		}
+	}
+
+	return headers
+}
+
+func (e *Entry) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(e.Entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for name, alias := range e.aliases {
+		if v, ok := fields[name]; ok {
+			delete(fields, name)
+			fields[alias] = v
+		}
+	}
+
+	return json.Marshal(fields)
+}
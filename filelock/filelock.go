@@ -0,0 +1,44 @@
+// Package filelock prevents several runs from writing to the same results file.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrLocked is returned when the file is already written by another run
+var ErrLocked = errors.New("file is in use by another run")
+
+// OpenFile opens the file like os.OpenFile and takes an exclusive lock on it
+// that is released when the file is closed. It returns ErrLocked without
+// changing the file when another run holds the lock.
+//
+// The file is truncated only after the lock is taken, so a second run
+// targeting the same file cannot wipe the results of the first one.
+func OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	f, err := os.OpenFile(name, flag&^os.O_TRUNC, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lock(f); err != nil {
+		_ = f.Close()
+
+		if errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("%s: %w", name, ErrLocked)
+		}
+
+		return nil, err
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		if err := f.Truncate(0); err != nil {
+			_ = f.Close()
+
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,135 @@
+package filelock
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestOpenFileExcludesConcurrentRuns(t *testing.T) {
+	const (
+		runs  = 2
+		lines = 1000
+	)
+
+	fname := filepath.Join(t.TempDir(), "results.csv")
+
+	var (
+		wg     sync.WaitGroup
+		opened sync.WaitGroup
+		errs   = make([]error, runs)
+	)
+
+	wg.Add(runs)
+	opened.Add(runs)
+
+	for i := range runs {
+		go func() {
+			defer wg.Done()
+
+			f, err := OpenFile(fname, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
+
+			// every run holds the file while the others try to open it
+			opened.Done()
+			opened.Wait()
+
+			if err != nil {
+				errs[i] = err
+
+				return
+			}
+
+			defer f.Close()
+
+			w := bufio.NewWriter(f)
+
+			for j := range lines {
+				fmt.Fprintf(w, "run-%d,%d\n", i, j)
+			}
+
+			errs[i] = w.Flush()
+		}()
+	}
+
+	wg.Wait()
+
+	winner := -1
+
+	for i, err := range errs {
+		switch {
+		case err == nil && winner == -1:
+			winner = i
+		case err == nil:
+			t.Fatalf("runs %d and %d both wrote the file", winner, i)
+		case !errors.Is(err, ErrLocked):
+			t.Fatalf("run %d: expected ErrLocked got %v", i, err)
+		}
+	}
+
+	if winner == -1 {
+		t.Fatal("no run wrote the file")
+	}
+
+	f, err := os.Open(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	n := 0
+
+	for scanner.Scan() {
+		if want := fmt.Sprintf("run-%d,%d", winner, n); scanner.Text() != want {
+			t.Fatalf("line %d: expected %q got %q", n, want, scanner.Text())
+		}
+
+		n++
+	}
+
+	if n != lines {
+		t.Fatalf("expected %d lines got %d", lines, n)
+	}
+}
+
+func TestOpenFileKeepsLockedFile(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "results.csv")
+
+	first, err := OpenFile(fname, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := first.WriteString("title\nplace\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenFile(fname, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked got %v", err)
+	}
+
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "title\nplace\n" {
+		t.Fatalf("the locked file was changed: %q", data)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := OpenFile(fname, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
+	if err != nil {
+		t.Fatalf("expected the lock to be released on close got %v", err)
+	}
+
+	defer second.Close()
+}
@@ -0,0 +1,440 @@
+// Package geocode resolves location names like "Berlin, Germany"
+// into the coordinates and zoom used by the searches, and coordinates
+// into their country.
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProviderNominatim = "nominatim"
+	ProviderGoogle    = "google"
+
+	defaultNominatimURL = "https://nominatim.openstreetmap.org/search"
+	defaultGoogleURL    = "https://maps.googleapis.com/maps/api/geocode/json"
+
+	requestTimeout = 15 * time.Second
+	// the usage policy of the public nominatim allows one request per second
+	nominatimInterval = time.Second
+	userAgent         = "google-maps-scraper (+https://github.com/gosom/google-maps-scraper)"
+)
+
+var ErrNotFound = errors.New("location not found")
+
+// Result is a geocoded location
+type Result struct {
+	Lat  float64
+	Lon  float64
+	Zoom int
+	// Country is the ISO 3166-1 alpha-2 code of the country in upper case,
+	// empty when the provider doesn't know it
+	Country string
+}
+
+// Coordinates returns the coordinates in the format of the -geo flag
+func (r Result) Coordinates() string {
+	return strconv.FormatFloat(r.Lat, 'f', 6, 64) + "," + strconv.FormatFloat(r.Lon, 'f', 6, 64)
+}
+
+// Geocoder resolves a location name
+type Geocoder interface {
+	Geocode(ctx context.Context, location string) (Result, error)
+	// Reverse resolves the country of the coordinates
+	Reverse(ctx context.Context, lat, lon float64) (Result, error)
+}
+
+// New returns a cached Geocoder of provider. baseURL overrides the url of
+// the provider, e.g. for a self hosted nominatim. The google provider requires apiKey.
+func New(provider, apiKey, baseURL string) (Geocoder, error) {
+	client := &http.Client{Timeout: requestTimeout}
+
+	var g Geocoder
+
+	switch provider {
+	case "", ProviderNominatim:
+		n := &nominatim{client: client, baseURL: baseURL}
+
+		if baseURL == "" {
+			n.baseURL = defaultNominatimURL
+			n.throttle = &throttle{interval: nominatimInterval}
+		}
+
+		g = n
+	case ProviderGoogle:
+		if apiKey == "" {
+			return nil, errors.New("the google geocoder requires an api key")
+		}
+
+		if baseURL == "" {
+			baseURL = defaultGoogleURL
+		}
+
+		g = &google{client: client, baseURL: baseURL, apiKey: apiKey}
+	default:
+		return nil, fmt.Errorf("unknown geocoding provider %q", provider)
+	}
+
+	return newCache(g), nil
+}
+
+// cache keeps the results for the lifetime of the process,
+// locations don't move and the providers are rate limited
+type cache struct {
+	g Geocoder
+
+	mu       sync.Mutex
+	entries  map[string]Result
+	reversed map[string]Result
+}
+
+func newCache(g Geocoder) *cache {
+	return &cache{
+		g:        g,
+		entries:  make(map[string]Result),
+		reversed: make(map[string]Result),
+	}
+}
+
+func (c *cache) Geocode(ctx context.Context, location string) (Result, error) {
+	key := strings.ToLower(strings.Join(strings.Fields(location), " "))
+	if key == "" {
+		return Result{}, fmt.Errorf("%w: empty location", ErrNotFound)
+	}
+
+	c.mu.Lock()
+	res, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok {
+		return res, nil
+	}
+
+	res, err := c.g.Geocode(ctx, location)
+	if err != nil {
+		return Result{}, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = res
+	c.mu.Unlock()
+
+	return res, nil
+}
+
+func (c *cache) Reverse(ctx context.Context, lat, lon float64) (Result, error) {
+	// about 100m, the country doesn't change in between
+	key := strconv.FormatFloat(lat, 'f', 3, 64) + "," + strconv.FormatFloat(lon, 'f', 3, 64)
+
+	c.mu.Lock()
+	res, ok := c.reversed[key]
+	c.mu.Unlock()
+
+	if ok {
+		return res, nil
+	}
+
+	res, err := c.g.Reverse(ctx, lat, lon)
+	if err != nil {
+		return Result{}, err
+	}
+
+	c.mu.Lock()
+	c.reversed[key] = res
+	c.mu.Unlock()
+
+	return res, nil
+}
+
+// throttle spaces the requests to a provider by at least interval
+type throttle struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until the next request is allowed or ctx is done
+func (t *throttle) wait(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	now := time.Now()
+
+	at := t.next
+	if at.Before(now) {
+		at = now
+	}
+
+	t.next = at.Add(t.interval)
+	t.mu.Unlock()
+
+	if at.Equal(now) {
+		return nil
+	}
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type nominatim struct {
+	client  *http.Client
+	baseURL string
+	// throttle is set for the public instance, nil for a self hosted one
+	throttle *throttle
+}
+
+func (n *nominatim) Geocode(ctx context.Context, location string) (Result, error) {
+	params := url.Values{
+		"q":              {location},
+		"format":         {"json"},
+		"limit":          {"1"},
+		"addressdetails": {"1"},
+	}
+
+	var items []struct {
+		Lat         string   `json:"lat"`
+		Lon         string   `json:"lon"`
+		BoundingBox []string `json:"boundingbox"` // south, north, west, east
+		Address     struct {
+			CountryCode string `json:"country_code"`
+		} `json:"address"`
+	}
+
+	if err := n.throttle.wait(ctx); err != nil {
+		return Result{}, err
+	}
+
+	if err := getJSON(ctx, n.client, n.baseURL+"?"+params.Encode(), &items); err != nil {
+		return Result{}, err
+	}
+
+	if len(items) == 0 {
+		return Result{}, fmt.Errorf("%w: %s", ErrNotFound, location)
+	}
+
+	var (
+		res Result
+		err error
+	)
+
+	if res.Lat, err = strconv.ParseFloat(items[0].Lat, 64); err != nil {
+		return Result{}, fmt.Errorf("invalid latitude: %w", err)
+	}
+
+	if res.Lon, err = strconv.ParseFloat(items[0].Lon, 64); err != nil {
+		return Result{}, fmt.Errorf("invalid longitude: %w", err)
+	}
+
+	var box [4]float64
+
+	if len(items[0].BoundingBox) == len(box) {
+		for i := range box {
+			box[i], _ = strconv.ParseFloat(items[0].BoundingBox[i], 64)
+		}
+	}
+
+	res.Zoom = zoomForSpan(box[1]-box[0], box[3]-box[2])
+	res.Country = strings.ToUpper(items[0].Address.CountryCode)
+
+	return res, nil
+}
+
+func (n *nominatim) Reverse(ctx context.Context, lat, lon float64) (Result, error) {
+	params := url.Values{
+		"lat":    {strconv.FormatFloat(lat, 'f', 6, 64)},
+		"lon":    {strconv.FormatFloat(lon, 'f', 6, 64)},
+		"format": {"json"},
+		"zoom":   {"3"}, // the country level
+	}
+
+	var item struct {
+		Error   string `json:"error"`
+		Address struct {
+			CountryCode string `json:"country_code"`
+		} `json:"address"`
+	}
+
+	// the reverse endpoint is next to the search one
+	u := strings.TrimSuffix(n.baseURL, "/search") + "/reverse?" + params.Encode()
+
+	if err := n.throttle.wait(ctx); err != nil {
+		return Result{}, err
+	}
+
+	if err := getJSON(ctx, n.client, u, &item); err != nil {
+		return Result{}, err
+	}
+
+	// the coordinates in the sea have no address
+	if item.Error != "" || item.Address.CountryCode == "" {
+		return Result{}, fmt.Errorf("%w: %f,%f", ErrNotFound, lat, lon)
+	}
+
+	return Result{Lat: lat, Lon: lon, Country: strings.ToUpper(item.Address.CountryCode)}, nil
+}
+
+type google struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+}
+
+func (g *google) Geocode(ctx context.Context, location string) (Result, error) {
+	params := url.Values{
+		"address": {location},
+		"key":     {g.apiKey},
+	}
+
+	type latLng struct {
+		Lat float64 `json:"lat"`
+		Lng float64 `json:"lng"`
+	}
+
+	var body struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+		Results      []struct {
+			AddressComponents addressComponents `json:"address_components"`
+			Geometry          struct {
+				Location latLng `json:"location"`
+				Viewport struct {
+					Northeast latLng `json:"northeast"`
+					Southwest latLng `json:"southwest"`
+				} `json:"viewport"`
+			} `json:"geometry"`
+		} `json:"results"`
+	}
+
+	if err := getJSON(ctx, g.client, g.baseURL+"?"+params.Encode(), &body); err != nil {
+		return Result{}, err
+	}
+
+	switch body.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return Result{}, fmt.Errorf("%w: %s", ErrNotFound, location)
+	default:
+		return Result{}, fmt.Errorf("google geocoding failed: %s %s", body.Status, body.ErrorMessage)
+	}
+
+	geom := body.Results[0].Geometry
+
+	return Result{
+		Lat: geom.Location.Lat,
+		Lon: geom.Location.Lng,
+		Zoom: zoomForSpan(
+			geom.Viewport.Northeast.Lat-geom.Viewport.Southwest.Lat,
+			geom.Viewport.Northeast.Lng-geom.Viewport.Southwest.Lng,
+		),
+		Country: body.Results[0].AddressComponents.country(),
+	}, nil
+}
+
+func (g *google) Reverse(ctx context.Context, lat, lon float64) (Result, error) {
+	params := url.Values{
+		"latlng":      {strconv.FormatFloat(lat, 'f', 6, 64) + "," + strconv.FormatFloat(lon, 'f', 6, 64)},
+		"result_type": {"country"},
+		"key":         {g.apiKey},
+	}
+
+	var body struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"error_message"`
+		Results      []struct {
+			AddressComponents addressComponents `json:"address_components"`
+		} `json:"results"`
+	}
+
+	if err := getJSON(ctx, g.client, g.baseURL+"?"+params.Encode(), &body); err != nil {
+		return Result{}, err
+	}
+
+	switch body.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return Result{}, fmt.Errorf("%w: %f,%f", ErrNotFound, lat, lon)
+	default:
+		return Result{}, fmt.Errorf("google geocoding failed: %s %s", body.Status, body.ErrorMessage)
+	}
+
+	return Result{Lat: lat, Lon: lon, Country: body.Results[0].AddressComponents.country()}, nil
+}
+
+// addressComponents are the parts of the address of a google geocoding result
+type addressComponents []struct {
+	ShortName string   `json:"short_name"`
+	Types     []string `json:"types"`
+}
+
+// country returns the code of the country component
+func (a addressComponents) country() string {
+	for i := range a {
+		if slices.Contains(a[i].Types, "country") {
+			return strings.ToUpper(a[i].ShortName)
+		}
+	}
+
+	return ""
+}
+
+func getJSON(ctx context.Context, client *http.Client, u string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("geocoding request failed with status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+// zoomForSpan returns the zoom that fits an area spanning the given degrees.
+// Points and unknown areas get a city level zoom.
+func zoomForSpan(latSpan, lonSpan float64) int {
+	const (
+		defaultZoom = 12
+		minZoom     = 3
+		maxZoom     = 18
+	)
+
+	span := math.Max(math.Abs(latSpan), math.Abs(lonSpan))
+	if span <= 0 {
+		return defaultZoom
+	}
+
+	zoom := int(math.Round(math.Log2(360/span))) + 1
+
+	return min(max(zoom, minZoom), maxZoom)
+}
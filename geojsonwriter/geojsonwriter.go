@@ -0,0 +1,137 @@
+// Package geojsonwriter writes places as a GeoJSON FeatureCollection that
+// can be loaded in QGIS, Leaflet and other mapping tools.
+package geojsonwriter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// ContentType is the media type of GeoJSON documents
+const ContentType = "application/geo+json"
+
+type feature struct {
+	Type       string         `json:"type"`
+	Geometry   point          `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type point struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type geojsonWriter struct {
+	w io.Writer
+}
+
+// New returns a result writer that writes the places to w as the features
+// of a FeatureCollection. Places without valid coordinates are skipped.
+func New(w io.Writer) scrapemate.ResultWriter {
+	return &geojsonWriter{w: w}
+}
+
+func (g *geojsonWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	// the collection is opened before the first result so that it is
+	// valid even when no place is written
+	if _, err := io.WriteString(g.w, `{"type":"FeatureCollection","features":[`); err != nil {
+		return err
+	}
+
+	written := 0
+
+	for result := range in {
+		var entries []*gmaps.Entry
+
+		switch v := result.Data.(type) {
+		case *gmaps.Entry:
+			entries = append(entries, v)
+		case []any:
+			for i := range v {
+				entry, ok := v[i].(*gmaps.Entry)
+				if !ok {
+					return fmt.Errorf("cannot cast %T to *gmaps.Entry", v[i])
+				}
+
+				entries = append(entries, entry)
+			}
+		default:
+			return fmt.Errorf("cannot cast %T to *gmaps.Entry", result.Data)
+		}
+
+		for _, entry := range entries {
+			if !validCoordinates(entry) {
+				continue
+			}
+
+			f, err := Feature(entry)
+			if err != nil {
+				return err
+			}
+
+			if written > 0 {
+				if _, err := io.WriteString(g.w, ","); err != nil {
+					return err
+				}
+			}
+
+			if _, err := g.w.Write(f); err != nil {
+				return err
+			}
+
+			written++
+		}
+	}
+
+	_, err := io.WriteString(g.w, "]}\n")
+
+	return err
+}
+
+// Feature returns entry encoded as a GeoJSON Feature with a Point geometry,
+// the other fields of the entry are the properties
+func Feature(entry *gmaps.Entry) ([]byte, error) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	var properties map[string]any
+	if err := json.Unmarshal(raw, &properties); err != nil {
+		return nil, err
+	}
+
+	delete(properties, "latitude")
+	delete(properties, "longtitude")
+
+	return json.Marshal(feature{
+		Type: "Feature",
+		Geometry: point{
+			Type: "Point",
+			// GeoJSON uses longitude,latitude order
+			Coordinates: [2]float64{entry.Longtitude, entry.Latitude},
+		},
+		Properties: properties,
+	})
+}
+
+func validCoordinates(entry *gmaps.Entry) bool {
+	lat, lon := entry.Latitude, entry.Longtitude
+
+	if math.IsNaN(lat) || math.IsNaN(lon) {
+		return false
+	}
+
+	if lat == 0 && lon == 0 {
+		return false
+	}
+
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
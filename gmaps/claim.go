@@ -0,0 +1,58 @@
+package gmaps
+
+import (
+	"net/url"
+	"strings"
+)
+
+// claimHost is the host of the google business profile links
+const claimHost = "business.google.com"
+
+// getClaimURL returns the url to claim the place when it's unclaimed. The
+// listings managed by an owner show the owner's profile, the others show an
+// "Own this business?" link which is taken from the place data when present
+// and built from the Cid otherwise. It returns an empty string for the
+// claimed places and when the url is not valid.
+func getClaimURL(entry *Entry, darray []any) string {
+	if entry.Owner.ID != "" {
+		return ""
+	}
+
+	link := findClaimLink(darray)
+	if link == "" && cidPattern.MatchString(entry.Cid) {
+		link = "https://" + claimHost + "/create?fp=" + entry.Cid
+	}
+
+	if !validClaimURL(link) {
+		return ""
+	}
+
+	return link
+}
+
+// findClaimLink returns the first business profile link of the place data
+func findClaimLink(v any) string {
+	switch val := v.(type) {
+	case string:
+		if strings.HasPrefix(val, "https://"+claimHost+"/") {
+			return val
+		}
+	case []any:
+		for i := range val {
+			if link := findClaimLink(val[i]); link != "" {
+				return link
+			}
+		}
+	}
+
+	return ""
+}
+
+func validClaimURL(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	return u.Scheme == "https" && u.Host == claimHost && u.Path != "" && u.Path != "/"
+}
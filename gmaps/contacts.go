@@ -0,0 +1,277 @@
+package gmaps
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ContactValidationFlag keeps the scraped contacts and lists the invalid ones
+	ContactValidationFlag = "flag"
+	// ContactValidationDrop replaces the scraped contacts with the valid ones
+	ContactValidationDrop = "drop"
+)
+
+// ContactRules configures the validation of the emails and the website
+type ContactRules struct {
+	// Mode is ContactValidationFlag or ContactValidationDrop
+	Mode string
+	// CheckWebsite sends a HEAD request to the website to check it's reachable
+	CheckWebsite bool
+}
+
+// ParseContactValidationMode validates the mode of the -validate-contacts flag
+func ParseContactValidationMode(s string) (string, error) {
+	switch s = strings.ToLower(strings.TrimSpace(s)); s {
+	case ContactValidationFlag, ContactValidationDrop:
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid contact validation mode %q, expected %s or %s", s, ContactValidationFlag, ContactValidationDrop)
+	}
+}
+
+// ContactValidation is the outcome of the validation of the emails and the website
+type ContactValidation struct {
+	// Emails are the valid emails, normalized
+	Emails []string `json:"emails"`
+	// WebSite is the normalized website, empty when invalid
+	WebSite string `json:"web_site"`
+	// RawEmails and RawWebSite are the values as scraped
+	RawEmails  []string `json:"raw_emails"`
+	RawWebSite string   `json:"raw_web_site"`
+	// Issues describe the dropped contacts, e.g. "disposable email domain: a@mailinator.com"
+	Issues []string `json:"issues"`
+}
+
+// disposableDomains are the domains of throwaway mailboxes, extended with LoadDisposableDomains
+var disposableDomains = map[string]bool{
+	"mailinator.com": true, "guerrillamail.com": true, "guerrillamail.net": true,
+	"10minutemail.com": true, "tempmail.com": true, "temp-mail.org": true,
+	"throwawaymail.com": true, "yopmail.com": true, "trashmail.com": true,
+	"getnada.com": true, "sharklasers.com": true, "maildrop.cc": true,
+	"dispostable.com": true, "fakeinbox.com": true, "mintemail.com": true,
+	"mohmal.com": true, "emailondeck.com": true, "spamgourmet.com": true,
+	"mailnesia.com": true, "tempr.email": true,
+}
+
+// placeholderDomains are the domains of the sample addresses left in website templates
+var placeholderDomains = map[string]bool{
+	"example.com": true, "example.org": true, "example.net": true,
+	"domain.com": true, "yourdomain.com": true, "email.com": true,
+	"sentry.io": true, "wixpress.com": true, "sentry-next.wixpress.com": true,
+}
+
+// fileExtensions are matched by the email regex in asset names like "logo@2x.png"
+var fileExtensions = map[string]bool{
+	"png": true, "jpg": true, "jpeg": true, "gif": true, "webp": true,
+	"svg": true, "css": true, "js": true, "ico": true, "avif": true,
+}
+
+// LoadDisposableDomains adds the domains of a file, one per line, to the disposable domains
+func LoadDisposableDomains(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			disposableDomains[line] = true
+		}
+	}
+
+	return scanner.Err()
+}
+
+// validateContacts validates the emails and the website of the entry
+func (r *ContactRules) validateContacts(ctx context.Context, e *Entry) {
+	ans := ContactValidation{
+		RawEmails:  e.Emails,
+		RawWebSite: e.WebSite,
+	}
+
+	seen := make(map[string]bool)
+
+	for _, raw := range e.Emails {
+		email, err := normalizeEmail(raw)
+		if err != nil {
+			ans.Issues = append(ans.Issues, err.Error())
+
+			continue
+		}
+
+		if !seen[email] {
+			seen[email] = true
+
+			ans.Emails = append(ans.Emails, email)
+		}
+	}
+
+	if e.WebSite != "" {
+		website, err := normalizeWebsite(e.WebSite)
+		if err == nil && r.CheckWebsite {
+			err = checkReachable(ctx, website)
+		}
+
+		if err != nil {
+			ans.Issues = append(ans.Issues, err.Error())
+		} else {
+			ans.WebSite = website
+		}
+	}
+
+	if r.Mode == ContactValidationDrop {
+		e.Emails = ans.Emails
+		e.WebSite = ans.WebSite
+	}
+
+	e.ContactValidation = &ans
+}
+
+// normalizeEmail lowercases the address and rejects the ones that
+// can't receive mail or are not meant to be contacted
+func normalizeEmail(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "mailto:"), "MAILTO:")
+
+	// mailto links carry the subject and the body as parameters
+	if i := strings.IndexByte(s, '?'); i != -1 {
+		s = s[:i]
+	}
+
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		s = unescaped
+	}
+
+	s = strings.ToLower(strings.Trim(s, " .,;:<>\"'"))
+
+	email, err := getValidEmail(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid email: %s", raw)
+	}
+
+	_, domain, _ := strings.Cut(email, "@")
+
+	tld := domain[strings.LastIndexByte(domain, '.')+1:]
+
+	switch {
+	case !strings.Contains(domain, ".") || fileExtensions[tld]:
+		return "", fmt.Errorf("invalid email: %s", raw)
+	case disposableDomains[domain]:
+		return "", fmt.Errorf("disposable email domain: %s", email)
+	case placeholderDomains[domain]:
+		return "", fmt.Errorf("placeholder email: %s", email)
+	}
+
+	return email, nil
+}
+
+// normalizeWebsite returns the website as an absolute url with a lowercase
+// host and without the fragment, the default port and the tracking parameters
+func normalizeWebsite(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+
+	if !strings.Contains(s, "://") {
+		s = "https://" + strings.TrimPrefix(s, "//")
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid website: %s", raw)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if !strings.Contains(host, ".") || strings.ContainsAny(host, " _") {
+		return "", fmt.Errorf("invalid website: %s", raw)
+	}
+
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = host
+	u.Fragment = ""
+
+	if u.RawQuery != "" {
+		q := u.Query()
+
+		for k := range q {
+			if strings.HasPrefix(strings.ToLower(k), "utm_") {
+				q.Del(k)
+			}
+		}
+
+		u.RawQuery = q.Encode()
+	}
+
+	if u.Path == "/" && u.RawQuery == "" {
+		u.Path = ""
+	}
+
+	return u.String(), nil
+}
+
+var (
+	reachClient = &http.Client{Timeout: 10 * time.Second}
+	// reachCache keeps the result of the checks, chains share their websites
+	reachCache sync.Map
+)
+
+// checkReachable checks that the website answers, some servers reject
+// HEAD requests so those are retried with GET
+func checkReachable(ctx context.Context, website string) error {
+	if v, ok := reachCache.Load(website); ok {
+		err, _ := v.(error)
+
+		return err
+	}
+
+	code, err := statusCode(ctx, http.MethodHead, website)
+	if err == nil && (code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented) {
+		code, err = statusCode(ctx, http.MethodGet, website)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// don't cache the checks of a cancelled job
+		return nil
+	case err != nil:
+		err = fmt.Errorf("unreachable website: %s", website)
+	case code == http.StatusNotFound || code == http.StatusGone || code >= http.StatusInternalServerError:
+		err = fmt.Errorf("unreachable website (%d): %s", code, website)
+	}
+
+	reachCache.Store(website, err)
+
+	return err
+}
+
+func statusCode(ctx context.Context, method, u string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, http.NoBody)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := reachClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,147 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EntryFromCsvRow is the reverse of CsvRow. It builds an Entry from a csv row
+// written by this scraper. Columns that are not known are ignored, so rows
+// written by older versions can be read too.
+func EntryFromCsvRow(headers, row []string) (Entry, error) {
+	var entry Entry
+
+	if len(headers) != len(row) {
+		return entry, fmt.Errorf("expected %d columns got %d", len(headers), len(row))
+	}
+
+	for i, header := range headers {
+		value := row[i]
+		if value == "" {
+			continue
+		}
+
+		var err error
+
+		switch header {
+		case "input_id":
+			entry.ID = value
+		case "link":
+			entry.Link = value
+		case "title":
+			entry.Title = value
+		case "category":
+			entry.Category = value
+		case "address":
+			entry.Address = value
+		case "website":
+			entry.WebSite = value
+		case "phone":
+			entry.Phone = value
+		case "phone_e164":
+			entry.PhoneE164 = value
+		case "plus_code":
+			entry.PlusCode = value
+		case "cid":
+			entry.Cid = value
+		case "status":
+			entry.Status = value
+		case "descriptions":
+			entry.Description = value
+		case "reviews_link":
+			entry.ReviewsLink = value
+		case "thumbnail":
+			entry.Thumbnail = value
+		case "timezone":
+			entry.Timezone = value
+		case "price_range":
+			entry.PriceRange = value
+		case "data_id":
+			entry.DataID = value
+		case "booking_provider":
+			entry.BookingProvider = value
+		case "request_id":
+			entry.RequestID = value
+		case "claim_url":
+			entry.ClaimURL = value
+		case "review_count":
+			entry.ReviewCount, err = strconv.Atoi(value)
+		case "opened_year":
+			entry.OpenedYear, err = strconv.Atoi(value)
+		case "review_rating":
+			entry.ReviewRating, err = strconv.ParseFloat(value, 64)
+		case "latitude":
+			entry.Latitude, err = strconv.ParseFloat(value, 64)
+		case "longitude":
+			entry.Longtitude, err = strconv.ParseFloat(value, 64)
+		case "booking_available":
+			entry.BookingAvailable, err = strconv.ParseBool(value)
+		case "sparse":
+			entry.Sparse, err = strconv.ParseBool(value)
+		case "spam_score":
+			entry.SpamScore, err = strconv.ParseFloat(value, 64)
+		case "emails":
+			entry.Emails = strings.Split(value, ", ")
+		case "photos":
+			entry.Photos = strings.Split(value, ";")
+		case "dietary_options":
+			entry.DietaryOptions = strings.Split(value, ", ")
+		case "open_hours":
+			err = json.Unmarshal([]byte(value), &entry.OpenHours)
+		case "popular_times":
+			err = json.Unmarshal([]byte(value), &entry.PopularTimes)
+		case "reviews_per_rating":
+			err = json.Unmarshal([]byte(value), &entry.ReviewsPerRating)
+		case "images":
+			err = json.Unmarshal([]byte(value), &entry.Images)
+		case "reservations":
+			err = json.Unmarshal([]byte(value), &entry.Reservations)
+		case "order_online":
+			err = json.Unmarshal([]byte(value), &entry.OrderOnline)
+		case "menu":
+			err = json.Unmarshal([]byte(value), &entry.Menu)
+		case "owner":
+			err = json.Unmarshal([]byte(value), &entry.Owner)
+		case "complete_address":
+			err = json.Unmarshal([]byte(value), &entry.CompleteAddress)
+		case "about":
+			err = json.Unmarshal([]byte(value), &entry.About)
+		case "user_reviews":
+			err = json.Unmarshal([]byte(value), &entry.UserReviews)
+		case "custom_fields":
+			err = json.Unmarshal([]byte(value), &entry.CustomFields)
+		case "derived":
+			err = json.Unmarshal([]byte(value), &entry.Derived)
+		case "ticket_links":
+			err = json.Unmarshal([]byte(value), &entry.TicketLinks)
+		case "charging":
+			err = json.Unmarshal([]byte(value), &entry.Charging)
+		case "fuel":
+			err = json.Unmarshal([]byte(value), &entry.Fuel)
+		case "price_per_person":
+			err = json.Unmarshal([]byte(value), &entry.PricePerPerson)
+		case "third_party_ratings":
+			err = json.Unmarshal([]byte(value), &entry.ThirdPartyRatings)
+		case "menu_highlights":
+			err = json.Unmarshal([]byte(value), &entry.MenuHighlights)
+		case "contact_validation":
+			err = json.Unmarshal([]byte(value), &entry.ContactValidation)
+		case "weekly_hours":
+			err = json.Unmarshal([]byte(value), &entry.WeeklyHours)
+		case "social_links":
+			err = json.Unmarshal([]byte(value), &entry.SocialLinks)
+		case "place_id":
+			entry.PlaceID = value
+		case "maps_url":
+			entry.MapsURL = value
+		}
+
+		if err != nil {
+			return entry, fmt.Errorf("invalid %s: %w", header, err)
+		}
+	}
+
+	return entry, nil
+}
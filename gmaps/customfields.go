@@ -0,0 +1,83 @@
+package gmaps
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/xpath"
+	"github.com/playwright-community/playwright-go"
+)
+
+const maxCustomFields = 20
+
+// ValidateCustomFields checks that the custom fields map contains valid
+// field names and selectors.
+// A selector is either a CSS selector or an XPath expression
+// (prefixed with xpath= or starting with // or ..).
+func ValidateCustomFields(fields map[string]string) error {
+	if len(fields) > maxCustomFields {
+		return fmt.Errorf("too many custom fields: max %d allowed", maxCustomFields)
+	}
+
+	for name, sel := range fields {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("custom field name cannot be empty")
+		}
+
+		sel = strings.TrimSpace(sel)
+		if sel == "" {
+			return fmt.Errorf("custom field %s: selector cannot be empty", name)
+		}
+
+		if isXPathSelector(sel) {
+			// playwright evaluates the expression in the browser, it's compiled
+			// here so that an invalid one fails at startup instead of on every place
+			if _, err := xpath.Compile(strings.TrimPrefix(sel, "xpath=")); err != nil {
+				return fmt.Errorf("custom field %s: invalid xpath: %w", name, err)
+			}
+
+			continue
+		}
+
+		if _, err := cascadia.Compile(strings.TrimPrefix(sel, "css=")); err != nil {
+			return fmt.Errorf("custom field %s: invalid selector: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func isXPathSelector(sel string) bool {
+	return strings.HasPrefix(sel, "xpath=") || strings.HasPrefix(sel, "//") || strings.HasPrefix(sel, "..")
+}
+
+// extractCustomFields evaluates the selectors on the page and returns the text
+// of the first matching element for each field. Missing elements result in empty values.
+func extractCustomFields(page playwright.Page, fields map[string]string) map[string]string {
+	const timeout = 1000
+
+	ans := make(map[string]string, len(fields))
+
+	for name, sel := range fields {
+		ans[name] = ""
+
+		loc := page.Locator(strings.TrimSpace(sel)).First()
+
+		cnt, err := loc.Count()
+		if err != nil || cnt == 0 {
+			continue
+		}
+
+		text, err := loc.TextContent(playwright.LocatorTextContentOptions{
+			Timeout: playwright.Float(timeout),
+		})
+		if err != nil {
+			continue
+		}
+
+		ans[name] = strings.TrimSpace(text)
+	}
+
+	return ans
+}
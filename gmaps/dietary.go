@@ -0,0 +1,120 @@
+package gmaps
+
+import (
+	"strings"
+)
+
+// dietaryAttributes maps the google attribute ids to the dietary tags
+var dietaryAttributes = map[string]string{
+	"serves_vegan":               "vegan",
+	"serves_vegetarian":          "vegetarian",
+	"serves_gluten_free":         "gluten_free",
+	"has_gluten_free_options":    "gluten_free",
+	"serves_halal":               "halal",
+	"serves_halal_food":          "halal",
+	"serves_kosher":              "kosher",
+	"serves_organic":             "organic",
+	"serves_organic_dishes":      "organic",
+	"serves_healthy_options":     "healthy",
+	"serves_dairy_free":          "dairy_free",
+	"serves_vegetarian_only":     "vegetarian",
+	"serves_vegan_only":          "vegan",
+	"has_allergy_friendly_menus": "allergy_friendly",
+}
+
+// dietaryNames holds per language the (lowercase) words google uses
+// in the attribute names. They are used when the attribute id is unknown.
+var dietaryNames = map[string]map[string]string{
+	"en": {
+		"vegan":       "vegan",
+		"vegetarian":  "vegetarian",
+		"gluten-free": "gluten_free",
+		"gluten free": "gluten_free",
+		"halal":       "halal",
+		"kosher":      "kosher",
+		"organic":     "organic",
+	},
+	"de": {
+		"vegan":       "vegan",
+		"vegetarisch": "vegetarian",
+		"glutenfrei":  "gluten_free",
+		"halal":       "halal",
+		"koscher":     "kosher",
+		"bio-":        "organic",
+	},
+	"fr": {
+		"végan":       "vegan",
+		"végétalien":  "vegan",
+		"végétarien":  "vegetarian",
+		"sans gluten": "gluten_free",
+		"halal":       "halal",
+		"casher":      "kosher",
+		"biologique":  "organic",
+	},
+	"es": {
+		"vegan":      "vegan",
+		"vegetarian": "vegetarian",
+		"sin gluten": "gluten_free",
+		"halal":      "halal",
+		"kosher":     "kosher",
+		"orgánic":    "organic",
+	},
+	"it": {
+		"vegan":         "vegan",
+		"vegetarian":    "vegetarian",
+		"senza glutine": "gluten_free",
+		"halal":         "halal",
+		"kosher":        "kosher",
+		"biologic":      "organic",
+	},
+	"el": {
+		"vegan":          "vegan",
+		"χορτοφαγ":       "vegetarian",
+		"χωρίς γλουτένη": "gluten_free",
+		"χαλάλ":          "halal",
+		"κοσέρ":          "kosher",
+		"βιολογικ":       "organic",
+	},
+}
+
+// dietaryTag returns the dietary tag of an attribute or an empty string
+// if the attribute is not a dietary one.
+func dietaryTag(langCode, attrID, name string) string {
+	if idx := strings.LastIndex(attrID, "/"); idx >= 0 {
+		if tag, ok := dietaryAttributes[attrID[idx+1:]]; ok {
+			return tag
+		}
+	}
+
+	// only attributes about serving food are considered by name
+	if attrID != "" && !strings.Contains(attrID, "serves_") {
+		return ""
+	}
+
+	name = strings.ToLower(name)
+
+	langs := []string{strings.ToLower(langCode)}
+	if langs[0] != "en" {
+		langs = append(langs, "en")
+	}
+
+	for _, lang := range langs {
+		for word, tag := range dietaryNames[lang] {
+			if strings.Contains(name, word) {
+				return tag
+			}
+		}
+	}
+
+	return ""
+}
+
+func appendUnique(items []string, item string) []string {
+	for i := range items {
+		if items[i] == item {
+			return items
+		}
+	}
+
+	return append(items, item)
+}
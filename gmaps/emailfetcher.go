@@ -0,0 +1,423 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+// EmailFetcher fetches the websites of the places for the email extraction.
+// When it's not set the websites are fetched by the browser.
+type EmailFetcher interface {
+	// Fetch fetches u, jobID is the id of the job the place belongs to.
+	// The fetch is bounded by the deadline of ctx.
+	Fetch(ctx context.Context, jobID, u string) scrapemate.Response
+}
+
+const (
+	emailFetchTimeout = 15 * time.Second
+	emailMaxBodySize  = 5 << 20
+)
+
+var (
+	errNoAddresses   = errors.New("no addresses found")
+	errJobBytesLimit = errors.New("website crawl byte cap of the job reached")
+	errSiteTimeout   = errors.New("website timeout")
+)
+
+type emailFetcher struct {
+	client       *http.Client
+	hosts        *hostLimiter
+	maxSiteBytes int64
+	jobBytes     *byteBudget
+	proxies      func() []string
+	next         atomic.Uint64
+}
+
+// EmailFetcherOption configures the EmailFetcher
+type EmailFetcherOption func(*emailFetcher)
+
+// WithEmailFetcherProxies fetches the websites through the proxies returned
+// by proxies, taken in turn, so that they don't come from the address of the
+// scraper. The websites are fetched directly when it returns none.
+func WithEmailFetcherProxies(proxies func() []string) EmailFetcherOption {
+	return func(f *emailFetcher) {
+		f.proxies = proxies
+	}
+}
+
+// NewEmailFetcher returns an EmailFetcher that caches the DNS lookups
+// for dnsTTL and crawls at most maxHosts distinct hosts concurrently.
+// A maxHosts <= 0 means no limit.
+// Every website is read up to maxSiteBytes and the websites of a job up
+// to maxJobBytes in total. A cap <= 0 means the default of 5MB per website
+// and no limit per job respectively.
+func NewEmailFetcher(dnsTTL time.Duration, maxHosts int, maxSiteBytes, maxJobBytes int64, opts ...EmailFetcherOption) EmailFetcher {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	resolver := newDNSCache(net.DefaultResolver, dnsTTL)
+
+	if maxSiteBytes <= 0 {
+		maxSiteBytes = emailMaxBodySize
+	}
+
+	f := emailFetcher{
+		hosts:        newHostLimiter(maxHosts),
+		maxSiteBytes: maxSiteBytes,
+		jobBytes:     newByteBudget(maxJobBytes),
+	}
+
+	for _, opt := range opts {
+		opt(&f)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = f.proxy
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := resolver.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+
+			lastErr = err
+		}
+
+		return nil, lastErr
+	}
+
+	// the email jobs set the deadline of every fetch
+	f.client = &http.Client{Transport: transport}
+
+	return &f
+}
+
+// proxy returns the next proxy of the pool for req, nil without proxies
+func (f *emailFetcher) proxy(*http.Request) (*url.URL, error) {
+	if f.proxies == nil {
+		return nil, nil
+	}
+
+	proxies := f.proxies()
+	if len(proxies) == 0 {
+		return nil, nil
+	}
+
+	i := (f.next.Add(1) - 1) % uint64(len(proxies))
+
+	return url.Parse(proxies[i])
+}
+
+func (f *emailFetcher) Fetch(ctx context.Context, jobID, u string) scrapemate.Response {
+	var resp scrapemate.Response
+
+	limit := f.jobBytes.limit(jobID, f.maxSiteBytes)
+	if limit == 0 {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Info(fmt.Sprintf("skipping website %s: %v", u, errJobBytesLimit), "job", jobID)
+
+		resp.Error = errJobBytesLimit
+
+		return resp
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
+	req.Header.Set("User-Agent", emailUserAgent)
+
+	host := req.URL.Hostname()
+
+	if err := f.hosts.acquire(ctx, host); err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
+	defer f.hosts.release(host)
+
+	httpResp, err := f.client.Do(req)
+	if err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
+	defer httpResp.Body.Close()
+
+	resp.URL = httpResp.Request.URL.String()
+	resp.StatusCode = httpResp.StatusCode
+	resp.Headers = httpResp.Header
+
+	// one byte more than the limit tells apart the truncated pages
+	resp.Body, err = io.ReadAll(io.LimitReader(httpResp.Body, limit+1))
+	if err != nil {
+		resp.Error = err
+	}
+
+	if int64(len(resp.Body)) > limit {
+		resp.Body = resp.Body[:limit]
+
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Info(fmt.Sprintf("website crawl of %s truncated at %d bytes", u, limit), "job", jobID)
+	}
+
+	f.jobBytes.consume(jobID, int64(len(resp.Body)))
+
+	return resp
+}
+
+// byteBudget tracks the bytes crawled per job. The jobs whose websites
+// were not fetched for budgetIdle are forgotten, so that the long running
+// workers don't keep the budget of every job they ever ran.
+type byteBudget struct {
+	max int64
+
+	mu        sync.Mutex
+	used      map[string]*jobBytes
+	lastSweep time.Time
+}
+
+// jobBytes are the bytes crawled for a job and the time of the last fetch
+type jobBytes struct {
+	n    int64
+	last time.Time
+}
+
+const (
+	budgetIdle          = 30 * time.Minute
+	budgetSweepInterval = time.Minute
+)
+
+func newByteBudget(maxBytes int64) *byteBudget {
+	return &byteBudget{
+		max:       maxBytes,
+		used:      make(map[string]*jobBytes),
+		lastSweep: time.Now(),
+	}
+}
+
+// limit returns the bytes that can be read for jobID, at most siteLimit
+func (b *byteBudget) limit(jobID string, siteLimit int64) int64 {
+	if b.max <= 0 {
+		return siteLimit
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var used int64
+	if j, ok := b.used[jobID]; ok {
+		used = j.n
+	}
+
+	return min(siteLimit, max(b.max-used, 0))
+}
+
+func (b *byteBudget) consume(jobID string, n int64) {
+	if b.max <= 0 {
+		return
+	}
+
+	now := time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sweep(now)
+
+	j, ok := b.used[jobID]
+	if !ok {
+		j = &jobBytes{}
+		b.used[jobID] = j
+	}
+
+	j.n += n
+	j.last = now
+}
+
+// sweep drops the idle jobs, it must be called with the lock held
+func (b *byteBudget) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < budgetSweepInterval {
+		return
+	}
+
+	b.lastSweep = now
+
+	for jobID, j := range b.used {
+		if now.Sub(j.last) > budgetIdle {
+			delete(b.used, jobID)
+		}
+	}
+}
+
+const emailUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+type dnsEntry struct {
+	ips     []string
+	expires time.Time
+}
+
+type dnsCache struct {
+	resolver *net.Resolver
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+func newDNSCache(resolver *net.Resolver, ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		resolver: resolver,
+		ttl:      ttl,
+		entries:  make(map[string]dnsEntry),
+	}
+}
+
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+
+	if ok && now.Before(entry.expires) {
+		return shuffled(entry.ips), nil
+	}
+
+	addrs, err := c.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s: %w", host, errNoAddresses)
+	}
+
+	ips := make([]string, len(addrs))
+	for i := range addrs {
+		ips[i] = addrs[i].IP.String()
+	}
+
+	if c.ttl > 0 {
+		c.mu.Lock()
+		c.entries[host] = dnsEntry{ips: ips, expires: now.Add(c.ttl)}
+		c.mu.Unlock()
+	}
+
+	return ips, nil
+}
+
+func shuffled(ips []string) []string {
+	if len(ips) < 2 {
+		return ips
+	}
+
+	ans := make([]string, len(ips))
+	copy(ans, ips)
+
+	//nolint:gosec // no need for crypto rand here
+	rand.Shuffle(len(ans), func(i, j int) { ans[i], ans[j] = ans[j], ans[i] })
+
+	return ans
+}
+
+// hostLimiter limits the number of distinct hosts crawled concurrently.
+// Requests to a host that is already being crawled share its slot.
+type hostLimiter struct {
+	sem chan struct{}
+
+	mu     sync.Mutex
+	active map[string]int
+}
+
+func newHostLimiter(maxHosts int) *hostLimiter {
+	l := hostLimiter{
+		active: make(map[string]int),
+	}
+
+	if maxHosts > 0 {
+		l.sem = make(chan struct{}, maxHosts)
+	}
+
+	return &l
+}
+
+func (l *hostLimiter) acquire(ctx context.Context, host string) error {
+	if l.sem == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+
+		if l.active[host] > 0 {
+			l.active[host]++
+			l.mu.Unlock()
+
+			return nil
+		}
+
+		select {
+		case l.sem <- struct{}{}:
+			l.active[host] = 1
+			l.mu.Unlock()
+
+			return nil
+		default:
+		}
+
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+func (l *hostLimiter) release(host string) {
+	if l.sem == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active[host]--
+
+	if l.active[host] <= 0 {
+		delete(l.active, host)
+		<-l.sem
+	}
+}
@@ -2,12 +2,17 @@ package gmaps
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/metrics"
 	"github.com/gosom/scrapemate"
 	"github.com/mcnijman/go-emailaddress"
+	"github.com/playwright-community/playwright-go"
 )
 
 type EmailExtractJobOptions func(*EmailExtractJob)
@@ -17,6 +22,14 @@ type EmailExtractJob struct {
 
 	Entry       *Entry
 	ExitMonitor exiter.Exiter
+	Fetcher     EmailFetcher
+	// ContactRules validates the emails found and the website when set
+	ContactRules *ContactRules
+	Tracker      JobTracker
+	// Timeout bounds every attempt to fetch the website, 0 means 15s
+	Timeout time.Duration
+	// Deriver adds the derived fields to the entry when set
+	Deriver Deriver
 }
 
 func NewEmailJob(parentID string, entry *Entry, opts ...EmailExtractJobOptions) *EmailExtractJob {
@@ -50,6 +63,76 @@ func WithEmailJobExitMonitor(exitMonitor exiter.Exiter) EmailExtractJobOptions {
 	}
 }
 
+func WithEmailJobTracker(t JobTracker) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.Tracker = t
+	}
+}
+
+func WithEmailJobDeriver(d Deriver) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.Deriver = d
+	}
+}
+
+func WithEmailJobFetcher(f EmailFetcher) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.Fetcher = f
+	}
+}
+
+func WithEmailJobContactRules(r *ContactRules) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.ContactRules = r
+	}
+}
+
+// WithEmailJobTimeout sets the timeout of every attempt to fetch the website
+func WithEmailJobTimeout(d time.Duration) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.Timeout = d
+	}
+}
+
+// WithEmailJobRetries sets how many times a failed fetch of the website is
+// retried, scrapemate caps it to 5
+func WithEmailJobRetries(n int) EmailExtractJobOptions {
+	return func(j *EmailExtractJob) {
+		j.MaxRetries = n
+	}
+}
+
+// BrowserActions fetches the website using the Fetcher when it's set
+// and falls back to the browser otherwise
+func (j *EmailExtractJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
+	timeout := j.Timeout
+	if timeout <= 0 {
+		timeout = emailFetchTimeout
+	}
+
+	var resp scrapemate.Response
+
+	if j.Fetcher == nil {
+		resp = scrapeWithTimeout(ctx, page, timeout, errSiteTimeout, j.Job.BrowserActions)
+	} else {
+		fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+		resp = j.Fetcher.Fetch(fetchCtx, j.Entry.ID, j.GetFullURL())
+
+		if resp.Error != nil && errors.Is(fetchCtx.Err(), context.DeadlineExceeded) {
+			resp.Error = fmt.Errorf("%w: not done within %s", errSiteTimeout, timeout)
+		}
+
+		cancel()
+	}
+
+	if resp.Error != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Info(fmt.Sprintf("failed to fetch website %s: %v", j.GetFullURL(), resp.Error))
+	}
+
+	return resp
+}
+
 func (j *EmailExtractJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	defer func() {
 		resp.Document = nil
@@ -60,8 +143,26 @@ func (j *EmailExtractJob) Process(ctx context.Context, resp *scrapemate.Response
 		if j.ExitMonitor != nil {
 			j.ExitMonitor.IncrPlacesCompleted(1)
 		}
+
+		if j.Tracker != nil {
+			j.Tracker.PlaceDone(ctx, j.Entry.ID, true)
+		}
+
+		metrics.PlacesScraped.Inc()
 	}()
 
+	// the derived fields are computed once the entry is final, after the
+	// validation of the contacts
+	if j.Deriver != nil {
+		defer j.Deriver.Derive(j.Entry)
+	}
+
+	// the entry is returned on every path, the website is validated
+	// even when it could not be fetched
+	if j.ContactRules != nil {
+		defer j.ContactRules.validateContacts(ctx, j.Entry)
+	}
+
 	log := scrapemate.GetLoggerFromContext(ctx)
 
 	log.Info("Processing email job", "url", j.URL)
@@ -76,12 +177,8 @@ func (j *EmailExtractJob) Process(ctx context.Context, resp *scrapemate.Response
 		return j.Entry, nil, nil
 	}
 
-	emails := docEmailExtractor(doc)
-	if len(emails) == 0 {
-		emails = regexEmailExtractor(resp.Body)
-	}
-
-	j.Entry.Emails = emails
+	j.Entry.Emails = mergeEmails(docEmailExtractor(doc), regexEmailExtractor(resp.Body))
+	j.Entry.SocialLinks = docSocialLinks(doc)
 
 	return j.Entry, nil, nil
 }
@@ -127,6 +224,33 @@ func regexEmailExtractor(body []byte) []string {
 	return emails
 }
 
+// mergeEmails returns the unique emails of the lists lowercased, in order
+// and up to maxEmails
+func mergeEmails(lists ...[]string) []string {
+	seen := map[string]bool{}
+
+	var emails []string
+
+	for _, list := range lists {
+		for _, email := range list {
+			email = strings.ToLower(email)
+			if seen[email] {
+				continue
+			}
+
+			if len(emails) == maxEmails {
+				return emails
+			}
+
+			seen[email] = true
+
+			emails = append(emails, email)
+		}
+	}
+
+	return emails
+}
+
 func getValidEmail(s string) (string, error) {
 	email, err := emailaddress.Parse(strings.TrimSpace(s))
 	if err != nil {
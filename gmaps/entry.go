@@ -3,6 +3,7 @@ package gmaps
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"runtime/debug"
 	"strconv"
 	"strings"
@@ -57,6 +58,8 @@ type Entry struct {
 	ID         string              `json:"input_id"`
 	Link       string              `json:"link"`
 	Cid        string              `json:"cid"`
+	PlaceID    string              `json:"place_id"`
+	MapsURL    string              `json:"maps_url"`
 	Title      string              `json:"title"`
 	Categories []string            `json:"categories"`
 	Category   string              `json:"category"`
@@ -67,6 +70,7 @@ type Entry struct {
 	PopularTimes     map[string]map[int]int `json:"popular_times"`
 	WebSite          string                 `json:"web_site"`
 	Phone            string                 `json:"phone"`
+	PhoneE164        string                 `json:"phone_e164"`
 	PlusCode         string                 `json:"plus_code"`
 	ReviewCount      int                    `json:"review_count"`
 	ReviewRating     float64                `json:"review_rating"`
@@ -77,6 +81,7 @@ type Entry struct {
 	Description      string                 `json:"description"`
 	ReviewsLink      string                 `json:"reviews_link"`
 	Thumbnail        string                 `json:"thumbnail"`
+	Photos           []string               `json:"photos"`
 	Timezone         string                 `json:"timezone"`
 	PriceRange       string                 `json:"price_range"`
 	DataID           string                 `json:"data_id"`
@@ -89,6 +94,38 @@ type Entry struct {
 	About            []About                `json:"about"`
 	UserReviews      []Review               `json:"user_reviews"`
 	Emails           []string               `json:"emails"`
+	OpenedYear       int                    `json:"opened_year"`
+	CustomFields     map[string]string      `json:"custom_fields"`
+	BookingAvailable bool                   `json:"booking_available"`
+	BookingProvider  string                 `json:"booking_provider"`
+	RequestID        string                 `json:"request_id"`
+	DietaryOptions   []string               `json:"dietary_options"`
+	Derived          map[string]any         `json:"derived"`
+	TicketLinks      map[string]string      `json:"ticket_links"`
+	Sparse           bool                   `json:"sparse"`
+	// Charging is set only for EV charging stations
+	Charging *Charging `json:"charging"`
+	// Fuel is set only for gas stations
+	Fuel *Fuel `json:"fuel"`
+	// PricePerPerson is set when the price range is shown as an amount per person
+	PricePerPerson *PricePerPerson `json:"price_per_person"`
+	// ThirdPartyRatings are the ratings of other review sites keyed by source, on the 0-5 scale
+	ThirdPartyRatings map[string]float64 `json:"third_party_ratings"`
+	// SpamScore is a heuristic from 0 to 1 of how likely the listing is spam
+	SpamScore float64 `json:"spam_score"`
+	// MenuHighlights are the menu items shown with a photo, filled only when enabled
+	MenuHighlights []MenuHighlight `json:"menu_highlights"`
+	// ContactValidation is the outcome of the validation of the emails and the website, set only when enabled
+	ContactValidation *ContactValidation `json:"contact_validation"`
+	// ClaimURL is the link to claim the listing, set only for the unclaimed places
+	ClaimURL string `json:"claim_url"`
+	// WeeklyHours are the opening hours by weekday, nil when the place shows no hours
+	WeeklyHours *WeeklyHours `json:"weekly_hours"`
+	// SocialLinks are the social profiles linked from the website, set only with the email extraction
+	SocialLinks *SocialLinks `json:"social_links"`
+	// Tenant is the API tenant the place was scraped for. It's used
+	// to count the results towards the tenant's quota and is not exported.
+	Tenant string `json:"-"`
 }
 
 func (e *Entry) IsWebsiteValidForEmail() bool {
@@ -111,6 +148,24 @@ func (e *Entry) IsWebsiteValidForEmail() bool {
 	return true
 }
 
+// SetBooking marks the place as bookable online.
+// When source is empty the provider is derived from the host of link.
+func (e *Entry) SetBooking(link, source string) {
+	if link == "" && source == "" {
+		return
+	}
+
+	e.BookingAvailable = true
+
+	if source == "" {
+		if u, err := url.Parse(link); err == nil {
+			source = strings.TrimPrefix(u.Hostname(), "www.")
+		}
+	}
+
+	e.BookingProvider = source
+}
+
 func (e *Entry) Validate() error {
 	if e.Title == "" {
 		return fmt.Errorf("title is empty")
@@ -134,6 +189,7 @@ func (e *Entry) CsvHeaders() []string {
 		"popular_times",
 		"website",
 		"phone",
+		"phone_e164",
 		"plus_code",
 		"review_count",
 		"review_rating",
@@ -145,6 +201,7 @@ func (e *Entry) CsvHeaders() []string {
 		"descriptions",
 		"reviews_link",
 		"thumbnail",
+		"photos",
 		"timezone",
 		"price_range",
 		"data_id",
@@ -157,6 +214,27 @@ func (e *Entry) CsvHeaders() []string {
 		"about",
 		"user_reviews",
 		"emails",
+		"opened_year",
+		"custom_fields",
+		"booking_available",
+		"booking_provider",
+		"request_id",
+		"dietary_options",
+		"derived",
+		"ticket_links",
+		"sparse",
+		"charging",
+		"fuel",
+		"price_per_person",
+		"third_party_ratings",
+		"spam_score",
+		"menu_highlights",
+		"contact_validation",
+		"claim_url",
+		"weekly_hours",
+		"social_links",
+		"place_id",
+		"maps_url",
 	}
 }
 
@@ -171,6 +249,7 @@ func (e *Entry) CsvRow() []string {
 		stringify(e.PopularTimes),
 		e.WebSite,
 		e.Phone,
+		e.PhoneE164,
 		e.PlusCode,
 		stringify(e.ReviewCount),
 		stringify(e.ReviewRating),
@@ -182,6 +261,7 @@ func (e *Entry) CsvRow() []string {
 		e.Description,
 		e.ReviewsLink,
 		e.Thumbnail,
+		strings.Join(e.Photos, ";"),
 		e.Timezone,
 		e.PriceRange,
 		e.DataID,
@@ -194,11 +274,39 @@ func (e *Entry) CsvRow() []string {
 		stringify(e.About),
 		stringify(e.UserReviews),
 		stringSliceToString(e.Emails),
+		stringifyYear(e.OpenedYear),
+		stringify(e.CustomFields),
+		strconv.FormatBool(e.BookingAvailable),
+		e.BookingProvider,
+		e.RequestID,
+		stringSliceToString(e.DietaryOptions),
+		stringify(e.Derived),
+		stringify(e.TicketLinks),
+		strconv.FormatBool(e.Sparse),
+		stringifyOptional(e.Charging),
+		stringifyOptional(e.Fuel),
+		stringifyOptional(e.PricePerPerson),
+		stringify(e.ThirdPartyRatings),
+		stringify(e.SpamScore),
+		stringify(e.MenuHighlights),
+		stringifyOptional(e.ContactValidation),
+		e.ClaimURL,
+		stringifyOptional(e.WeeklyHours),
+		stringifyOptional(e.SocialLinks),
+		e.PlaceID,
+		e.MapsURL,
 	}
 }
 
+func EntryFromJSON(raw []byte) (Entry, error) {
+	return EntryFromJSONWithLang(raw, "")
+}
+
+// EntryFromJSONWithLang parses the place json like EntryFromJSON, using
+// langCode to pick the locale specific phrasings for free text fields.
+//
 //nolint:gomnd // it's ok, I need the indexes
-func EntryFromJSON(raw []byte) (entry Entry, err error) {
+func EntryFromJSONWithLang(raw []byte, langCode string) (entry Entry, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("recovered from panic: %v stack: %s", r, debug.Stack())
@@ -249,12 +357,22 @@ func EntryFromJSON(raw []byte) (entry Entry, err error) {
 	entry.Longtitude = getNthElementAndCast[float64](darray, 9, 3)
 	entry.Cid = getNthElementAndCast[string](jd, 25, 3, 0, 13, 0, 0, 1)
 	entry.Status = getNthElementAndCast[string](darray, 34, 4, 4)
+	entry.WeeklyHours = getWeeklyHours(darray, entry.Status)
 	entry.Description = getNthElementAndCast[string](darray, 32, 1, 1)
 	entry.ReviewsLink = getNthElementAndCast[string](darray, 4, 3, 0)
 	entry.Thumbnail = getNthElementAndCast[string](darray, 72, 0, 1, 6, 0)
+	entry.Photos = mainPhotos(entry.Thumbnail)
 	entry.Timezone = getNthElementAndCast[string](darray, 30)
 	entry.PriceRange = getNthElementAndCast[string](darray, 4, 2)
+	entry.PricePerPerson = getPricePerPerson(entry.PriceRange)
 	entry.DataID = getNthElementAndCast[string](darray, 10)
+	entry.PlaceID = getNthElementAndCast[string](darray, 78)
+
+	if entry.Cid == "" {
+		entry.Cid = cidFromDataID(entry.DataID)
+	}
+
+	entry.MapsURL = canonicalURL(entry.Cid, entry.PlaceID, entry.DataID)
 
 	items := getLinkSource(getLinkSourceParams{
 		arr:    getNthElementAndCast[[]any](darray, 171, 0),
@@ -332,6 +450,13 @@ func EntryFromJSON(raw []byte) (entry Entry, err error) {
 			if opt.Name != "" {
 				about.Options = append(about.Options, opt)
 			}
+
+			if opt.Enabled {
+				attrID := getNthElementAndCast[string](optsI, j, 0)
+				if tag := dietaryTag(langCode, attrID, opt.Name); tag != "" {
+					entry.DietaryOptions = appendUnique(entry.DietaryOptions, tag)
+				}
+			}
 		}
 
 		entry.About = append(entry.About, about)
@@ -386,6 +511,19 @@ func EntryFromJSON(raw []byte) (entry Entry, err error) {
 		entry.UserReviews = append(entry.UserReviews, review)
 	}
 
+	entry.OpenedYear = getOpenedYear(langCode, darray)
+	entry.TicketLinks = getTicketLinks(darray)
+	entry.Charging = getCharging(entry.Categories, darray)
+	entry.Fuel = getFuel(entry.Categories, darray)
+	entry.ThirdPartyRatings = getThirdPartyRatings(darray)
+	entry.ClaimURL = getClaimURL(&entry, darray)
+	entry.Sparse = entry.IsSparse()
+	entry.SpamScore = entry.spamScore(&DefaultSpamWeights)
+
+	if len(entry.Reservations) > 0 {
+		entry.SetBooking(entry.Reservations[0].Link, entry.Reservations[0].Source)
+	}
+
 	return entry, nil
 }
 
@@ -545,6 +683,23 @@ func stringify(v any) string {
 	}
 }
 
+// stringifyOptional returns an empty string for nil instead of null
+func stringifyOptional[T any](v *T) string {
+	if v == nil {
+		return ""
+	}
+
+	return stringify(v)
+}
+
+func stringifyYear(year int) string {
+	if year == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(year)
+}
+
 func decodeURL(url string) (string, error) {
 	quoted := `"` + strings.ReplaceAll(url, `"`, `\"`) + `"`
 
@@ -40,6 +40,19 @@ func Test_EntryFromJSON(t *testing.T) {
 			"Saturday":  {"12:30–10 pm"},
 			"Sunday":    {"12:30–10 pm"},
 		},
+		// no website
+		SpamScore: 0.15,
+		WeeklyHours: &gmaps.WeeklyHours{
+			Days: map[string][]gmaps.TimeRange{
+				"Monday":    {{Open: "12:30", Close: "22:00"}},
+				"Tuesday":   {{Open: "12:30", Close: "22:00"}},
+				"Wednesday": {{Open: "12:30", Close: "22:00"}},
+				"Thursday":  {{Open: "12:30", Close: "22:00"}},
+				"Friday":    {{Open: "12:30", Close: "22:00"}},
+				"Saturday":  {{Open: "12:30", Close: "22:00"}},
+				"Sunday":    {{Open: "12:30", Close: "22:00"}},
+			},
+		},
 		WebSite:      "",
 		Phone:        "25 101555",
 		PlusCode:     "M2CR+6X Limassol",
@@ -51,9 +64,12 @@ func Test_EntryFromJSON(t *testing.T) {
 		Status:       "Closed ⋅ Opens 12:30\u202fpm Tue",
 		ReviewsLink:  "https://search.google.com/local/reviews?placeid=ChIJDdnwdv0y5xQRRytw1ihZQeU&q=Kipriakon&authuser=0&hl=en&gl=CY",
 		Thumbnail:    "https://lh5.googleusercontent.com/p/AF1QipP4Y7A8nYL3KKXznSl69pXSq9p2IXCYUjVvOh0F=w408-h408-k-no",
+		Photos:       []string{"https://lh5.googleusercontent.com/p/AF1QipP4Y7A8nYL3KKXznSl69pXSq9p2IXCYUjVvOh0F=w408-h408-k-no"},
 		Timezone:     "Asia/Nicosia",
 		PriceRange:   "€€",
 		DataID:       "0x14e732fd76f0d90d:0xe5415928d6702b47",
+		PlaceID:      "ChIJDdnwdv0y5xQRRytw1ihZQeU",
+		MapsURL:      "https://maps.google.com/?cid=16519582940102929223",
 		Images: []gmaps.Image{
 			{
 				Title: "All",
@@ -199,4 +215,50 @@ func Test_EntryFromJSONRaw2(t *testing.T) {
 
 	require.NoError(t, err)
 	require.Greater(t, len(entry.About), 0)
+	require.Equal(t, []string{"vegan", "vegetarian"}, entry.DietaryOptions)
+}
+
+func Test_EntryFromJSONWeeklyHours(t *testing.T) {
+	// the days are in Greek, with the week starting on Saturday
+	raw, err := os.ReadFile("../testdata/raw2.json")
+	require.NoError(t, err)
+
+	entry, err := gmaps.EntryFromJSON(raw)
+	require.NoError(t, err)
+	require.NotNil(t, entry.WeeklyHours)
+	require.Len(t, entry.WeeklyHours.Days, 7)
+	require.Equal(t, []gmaps.TimeRange{{Open: "09:00", Close: "23:30"}}, entry.WeeklyHours.Days["Saturday"])
+	require.Equal(t, []gmaps.TimeRange{{Open: "09:00", Close: "23:30"}}, entry.WeeklyHours.Days["Monday"])
+	require.False(t, entry.WeeklyHours.Open24Hours)
+	require.False(t, entry.WeeklyHours.TemporarilyClosed)
+
+	raw, err = os.ReadFile("../testdata/panic.json")
+	require.NoError(t, err)
+
+	entry, err = gmaps.EntryFromJSON(raw)
+	require.NoError(t, err)
+	require.NotNil(t, entry.WeeklyHours)
+	require.Equal(t, []gmaps.TimeRange{{Open: "00:00", Close: "22:45"}}, entry.WeeklyHours.Days["Wednesday"])
+	require.Equal(t, []gmaps.TimeRange{{Open: "12:00", Close: "23:00"}}, entry.WeeklyHours.Days["Friday"])
+}
+
+func Test_NormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone, country, want string
+		ok                   bool
+	}{
+		{"25 101555", "CY", "+35725101555", true},
+		{"030 1234567", "DE", "+49301234567", true},
+		{"(212) 555-0100", "US", "+12125550100", true},
+		{"+44 20 7946 0958", "", "+442079460958", true},
+		{"0044 20 7946 0958", "FR", "+442079460958", true},
+		{"06 12 34 56 78", "", "", false},
+		{"555-0100 ext. 12", "US", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := gmaps.NormalizePhone(tt.phone, tt.country)
+		require.Equal(t, tt.ok, ok, tt.phone)
+		require.Equal(t, tt.want, got, tt.phone)
+	}
 }
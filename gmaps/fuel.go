@@ -0,0 +1,162 @@
+package gmaps
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Charging holds the details of an EV charging station
+type Charging struct {
+	Connectors []Connector `json:"connectors"`
+}
+
+// Connector is a connector type offered by a charging station
+type Connector struct {
+	Type    string  `json:"type"`
+	PowerKW float64 `json:"power_kw"`
+	// Count is the number of charge points with this connector, 0 when not shown
+	Count int `json:"count"`
+	// Available is the number of free charge points, -1 when not shown
+	Available int `json:"available"`
+}
+
+// Fuel holds the details of a gas station
+type Fuel struct {
+	Types  []string    `json:"types"`
+	Prices []FuelPrice `json:"prices"`
+}
+
+// FuelPrice is the price of a fuel type as shown by google, including the currency
+type FuelPrice struct {
+	Type  string `json:"type"`
+	Price string `json:"price"`
+}
+
+var (
+	chargingCategory = regexp.MustCompile(`(?i)charging station|charging point|ev charg|ladestation|borne de recharge|estación de carga|stazione di ricarica|σταθμός φόρτισης`)
+	fuelCategory     = regexp.MustCompile(`(?i)gas station|petrol station|filling station|fuel|tankstelle|station-service|gasolinera|distributore di benzina|πρατήριο καυσίμων`)
+
+	// the listings show these names as separate labels, so they are matched
+	// against whole strings to skip the free text of the reviews
+	connectorName = regexp.MustCompile(`(?i)^(ccs|combo|chademo|j1772|nacs|tesla|type [12]|typ [12]|gb/t|mennekes|schuko|wall outlet)\b.{0,20}$`)
+	fuelName      = regexp.MustCompile(`(?i)^(regular|mid-?grade|premium|super( plus| e5| e10)?|diesel( plus)?|e85|e10|e5|unleaded( \d{2})?|lpg|autogas|cng|lng|adblue|hydrogen|gasoline|petrol|benzin)$`)
+
+	powerKW   = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*kw\b`)
+	chargers  = regexp.MustCompile(`^(\d+)\s*/\s*(\d+)`)
+	fuelPrice = regexp.MustCompile(`^(?:[$€£¥₹]\s?\d+[.,]\d{2,3}|\d+[.,]\d{2,3}\s?(?:[$€£¥₹]|[A-Z]{3}\b))`)
+)
+
+// getCharging returns the connectors of EV charging stations and nil for the other places.
+// The listings use a different layout from the rest of the places which also changes
+// often, so instead of fixed indexes it looks for the connector labels and reads the
+// power and counts from the array that holds each label.
+func getCharging(categories []string, darray []any) *Charging {
+	if !matchesCategory(chargingCategory, categories) {
+		return nil
+	}
+
+	ans := Charging{}
+	seen := make(map[string]bool)
+
+	walkArrays(darray, func(node []any) {
+		for i := range node {
+			name, ok := node[i].(string)
+			if !ok || !connectorName.MatchString(name) {
+				continue
+			}
+
+			c := Connector{Type: name, Available: -1}
+
+			for _, s := range collectStrings(node, nil) {
+				if m := powerKW.FindStringSubmatch(s); m != nil && c.PowerKW == 0 {
+					c.PowerKW, _ = strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+				}
+
+				if m := chargers.FindStringSubmatch(s); m != nil && c.Count == 0 {
+					c.Available, _ = strconv.Atoi(m[1])
+					c.Count, _ = strconv.Atoi(m[2])
+				}
+			}
+
+			key := strings.ToLower(c.Type) + "|" + strconv.FormatFloat(c.PowerKW, 'f', -1, 64)
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+
+			ans.Connectors = append(ans.Connectors, c)
+		}
+	})
+
+	return &ans
+}
+
+// getFuel returns the fuel types and prices of gas stations and nil for the other places
+func getFuel(categories []string, darray []any) *Fuel {
+	if !matchesCategory(fuelCategory, categories) {
+		return nil
+	}
+
+	ans := Fuel{}
+	seen := make(map[string]bool)
+	priced := make(map[string]bool)
+
+	walkArrays(darray, func(node []any) {
+		for i := range node {
+			name, ok := node[i].(string)
+			if !ok || !fuelName.MatchString(name) {
+				continue
+			}
+
+			key := strings.ToLower(name)
+
+			if !seen[key] {
+				seen[key] = true
+
+				ans.Types = append(ans.Types, name)
+			}
+
+			if priced[key] {
+				continue
+			}
+
+			for _, s := range collectStrings(node, nil) {
+				if price := fuelPrice.FindString(s); price != "" {
+					priced[key] = true
+
+					ans.Prices = append(ans.Prices, FuelPrice{Type: name, Price: strings.TrimSpace(price)})
+
+					break
+				}
+			}
+		}
+	})
+
+	return &ans
+}
+
+func matchesCategory(re *regexp.Regexp, categories []string) bool {
+	for i := range categories {
+		if re.MatchString(categories[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// walkArrays calls fn for v and every array nested in it
+func walkArrays(v any, fn func([]any)) {
+	arr, ok := v.([]any)
+	if !ok {
+		return
+	}
+
+	fn(arr)
+
+	for i := range arr {
+		walkArrays(arr[i], fn)
+	}
+}
@@ -0,0 +1,110 @@
+package gmaps
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WeeklyHours are the opening hours of a place in a structured form
+type WeeklyHours struct {
+	// Days maps the English weekday name to the ranges the place is open,
+	// the days the place is closed have no range
+	Days map[string][]TimeRange `json:"days"`
+	// Open24Hours is set when the place is open all day every day
+	Open24Hours bool `json:"open_24_hours"`
+	// TemporarilyClosed is set when the listing is marked as temporarily closed
+	TemporarilyClosed bool `json:"temporarily_closed"`
+}
+
+// TimeRange is an opening range of a day in the 24h HH:MM format. Close
+// is before Open for the ranges ending after midnight and 24:00 at midnight.
+type TimeRange struct {
+	Open  string `json:"open"`
+	Close string `json:"close"`
+}
+
+// getWeeklyHours returns the opening hours of the place. The weekdays and the
+// times are taken from the numeric fields of the place data instead of the
+// displayed text, so they are the same whatever the language, the first day
+// of the week or the clock of the locale. It returns nil when the place shows
+// no hours.
+func getWeeklyHours(darray []any, status string) *WeeklyHours {
+	ans := WeeklyHours{
+		Days: map[string][]TimeRange{},
+		// the flag isn't in the hours block, only the status text shows it
+		TemporarilyClosed: strings.Contains(strings.ToLower(status), "temporarily closed"),
+	}
+
+	items := getNthElementAndCast[[]any](darray, 34, 1)
+	allDay := len(items) == 7
+
+	for i := range items {
+		item, ok := items[i].([]any)
+		if !ok {
+			continue
+		}
+
+		day := weekday(item)
+		if day == "" {
+			continue
+		}
+
+		ranges := []TimeRange{}
+		dayAllDay := false
+
+		for _, r := range getNthElementAndCast[[]any](item, 6) {
+			values, ok := r.([]any)
+			if !ok {
+				continue
+			}
+
+			var hm [4]int
+
+			for j := 0; j < len(values) && j < len(hm); j++ {
+				v, _ := values[j].(float64)
+				hm[j] = int(v)
+			}
+
+			if hm == [4]int{0, 0, 24, 0} {
+				dayAllDay = true
+			}
+
+			ranges = append(ranges, TimeRange{
+				Open:  fmt.Sprintf("%02d:%02d", hm[0], hm[1]),
+				Close: fmt.Sprintf("%02d:%02d", hm[2], hm[3]),
+			})
+		}
+
+		allDay = allDay && dayAllDay
+		ans.Days[day] = ranges
+	}
+
+	if len(ans.Days) == 0 && !ans.TemporarilyClosed {
+		return nil
+	}
+
+	ans.Open24Hours = allDay
+
+	return &ans
+}
+
+// weekday returns the English name of the day of an hours item from its
+// date, falling back to its label when it's already in English
+func weekday(item []any) string {
+	if date := getNthElementAndCast[string](item, 4); date != "" {
+		if t, err := time.Parse(time.DateOnly, date); err == nil {
+			return t.Weekday().String()
+		}
+	}
+
+	label := getNthElementAndCast[string](item, 0)
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(label, d.String()) {
+			return d.String()
+		}
+	}
+
+	return ""
+}
@@ -6,26 +6,116 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/checkpoint"
 	"github.com/gosom/google-maps-scraper/deduper"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/polygon"
 	"github.com/gosom/scrapemate"
 	"github.com/playwright-community/playwright-go"
 )
 
 type GmapJobOptions func(*GmapJob)
 
+// priorities of the jobs in the queue of the database mode, the jobs
+// with a higher one are fetched first
+const (
+	DefaultQueuePriority = 5
+	MaxQueuePriority     = 9
+)
+
 type GmapJob struct {
 	scrapemate.Job
 
 	MaxDepth     int
 	LangCode     string
 	ExtractEmail bool
+	CustomFields map[string]string
+	RequestID    string
+	// Query, GeoCoordinates and Zoom are the search parameters the URL was built from
+	Query          string
+	GeoCoordinates string
+	Zoom           int
+	// Tenant is the API tenant the job was created for
+	Tenant string
+	// QueuePriority orders the job and its places in the queue of the
+	// database mode, from 0 to MaxQueuePriority
+	QueuePriority int
+	// ClonedFrom is the id of the job this one was cloned from
+	ClonedFrom string
+	// RestrictedRegions contains the country codes that must not be scraped
+	RestrictedRegions []string
+	// Trace enables recording playwright traces when not nil
+	Trace *TraceConfig
+	// ScrollBudget limits the time spent scrolling the results.
+	// Scrolling stops at MaxDepth operations or ScrollBudget, whichever comes first.
+	ScrollBudget time.Duration
+	// AutoDepth ignores MaxDepth and scrolls until the scrolls stop yielding new results
+	AutoDepth bool
+	// MaxResults caps the places scraped for the query, scrolling stops once
+	// as many are loaded whatever MaxDepth. 0 disables the cap.
+	MaxResults int
+	// IncludeKeywords keeps only the places mentioning at least one of them
+	IncludeKeywords []string
+	// ExcludeKeywords drops the places mentioning any of them
+	ExcludeKeywords []string
+	// SpamWeights replaces the default weights of the spam score when set
+	SpamWeights *SpamWeights
+	// MenuHighlights is the maximum number of menu highlights extracted per place, 0 disables them
+	MenuHighlights int
+	// NormalizePhones adds the phone of the places in the E.164 format
+	NormalizePhones bool
+	// BookingWidget waits for the booking button rendered asynchronously on the place pages
+	BookingWidget bool
+	// MaxPhotos is the maximum number of gallery photos added per place after its main photo
+	MaxPhotos int
+	// MaxReviews is the maximum number of reviews extracted per place from the reviews panel, 0 disables it
+	MaxReviews int
+	// PlaceTimeout bounds the scrape of every place, 0 disables it
+	PlaceTimeout time.Duration
+	// EmailTimeout bounds every fetch of the websites for the emails, 0 means 15s
+	EmailTimeout time.Duration
+	// EmailRetries is how many times a failed fetch of a website is retried
+	EmailRetries int
+	// Polygon drops the places outside of it when set
+	Polygon *polygon.Polygon
+	// ParentID is the id of the search a polygon cell belongs to, the places
+	// found by several cells of the same search are deduplicated
+	ParentID string
+	// ContactRules enables the validation of the emails and the website when set
+	ContactRules *ContactRules
 
 	Deduper     deduper.Deduper
 	ExitMonitor exiter.Exiter
+	Checkpoint  checkpoint.Checkpoint
+	// PlaceDeduper drops the places already scraped with the same cid when set
+	PlaceDeduper deduper.Deduper
+	// EmailFetcher is used by the email extraction jobs when set
+	EmailFetcher EmailFetcher
+	// Throttler is set by the job provider when the job is fetched
+	Throttler Throttler
+	// Canceller is set by the job provider when the job is fetched
+	Canceller Canceller
+	// Limiter adapts the concurrency to the block rate when set
+	Limiter Limiter
+	// ProxyMonitor sidelines the proxies google blocks when set
+	ProxyMonitor ProxyMonitor
+	// UserAgents picks the user agent of the browsers when set
+	UserAgents UserAgents
+	// Deriver adds the derived fields to the places when set
+	Deriver Deriver
+	// DeadLetter is set by the job provider to retry or dead letter the failed jobs
+	DeadLetter DeadLetter
+	// Acker is set by the job provider to release the job once it's processed
+	Acker Acker
+	// WebhookURL receives the completion of the job when set
+	WebhookURL string
+	// Tracker is set by the job provider that tracks the progress of the jobs
+	Tracker JobTracker
 }
 
 func NewGmapJob(
@@ -36,8 +126,6 @@ func NewGmapJob(
 	zoom int,
 	opts ...GmapJobOptions,
 ) *GmapJob {
-	query = url.QueryEscape(query)
-
 	const (
 		maxRetries = 3
 		prio       = scrapemate.PriorityLow
@@ -49,10 +137,10 @@ func NewGmapJob(
 
 	mapURL := ""
 	if geoCoordinates != "" && zoom > 0 {
-		mapURL = fmt.Sprintf("https://www.google.com/maps/search/%s/@%s,%dz", query, strings.ReplaceAll(geoCoordinates, " ", ""), zoom)
+		mapURL = fmt.Sprintf("https://www.google.com/maps/search/%s/@%s,%dz", url.QueryEscape(query), strings.ReplaceAll(geoCoordinates, " ", ""), zoom)
 	} else {
 		//Warning: geo and zoom MUST be both set or not
-		mapURL = fmt.Sprintf("https://www.google.com/maps/search/%s", query)
+		mapURL = fmt.Sprintf("https://www.google.com/maps/search/%s", url.QueryEscape(query))
 	}
 
 	job := GmapJob{
@@ -64,9 +152,13 @@ func NewGmapJob(
 			MaxRetries: maxRetries,
 			Priority:   prio,
 		},
-		MaxDepth:     maxDepth,
-		LangCode:     langCode,
-		ExtractEmail: extractEmail,
+		MaxDepth:       maxDepth,
+		LangCode:       langCode,
+		ExtractEmail:   extractEmail,
+		Query:          query,
+		GeoCoordinates: geoCoordinates,
+		Zoom:           zoom,
+		QueuePriority:  DefaultQueuePriority,
 	}
 
 	for _, opt := range opts {
@@ -82,64 +174,483 @@ func WithDeduper(d deduper.Deduper) GmapJobOptions {
 	}
 }
 
+// WithPlaceDeduper drops the places already scraped with the same cid,
+// the places found by different links can be the same business
+func WithPlaceDeduper(d deduper.Deduper) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.PlaceDeduper = d
+	}
+}
+
 func WithExitMonitor(e exiter.Exiter) GmapJobOptions {
 	return func(j *GmapJob) {
 		j.ExitMonitor = e
 	}
 }
 
+func WithCheckpoint(c checkpoint.Checkpoint) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Checkpoint = c
+	}
+}
+
+func WithCustomFields(fields map[string]string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.CustomFields = fields
+	}
+}
+
+// WithRequestID sets the id of the API request that created the job.
+// It is attached to every result of the job.
+func WithRequestID(requestID string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.RequestID = requestID
+	}
+}
+
+// WithWebhookURL posts the completion of the job to url
+func WithWebhookURL(url string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.WebhookURL = url
+	}
+}
+
+// WithClonedFrom records the id of the job the job was cloned from
+func WithClonedFrom(jobID string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.ClonedFrom = jobID
+	}
+}
+
+// WithQueuePriority sets the priority of the job and its places in the queue
+func WithQueuePriority(priority int) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.QueuePriority = priority
+	}
+}
+
+// WithTenant sets the API tenant of the job, its results count to the tenant's quota
+func WithTenant(tenant string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Tenant = tenant
+	}
+}
+
+func WithRestrictedRegions(regions []string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.RestrictedRegions = regions
+	}
+}
+
+func WithScrollBudget(budget time.Duration) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.ScrollBudget = budget
+	}
+}
+
+// WithMaxResults scrapes at most n places for the query
+func WithMaxResults(n int) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.MaxResults = n
+	}
+}
+
+// WithAutoDepth lets the job decide when to stop scrolling instead of using the max depth
+func WithAutoDepth() GmapJobOptions {
+	return func(j *GmapJob) {
+		j.AutoDepth = true
+	}
+}
+
+func WithIncludeKeywords(keywords []string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.IncludeKeywords = keywords
+	}
+}
+
+func WithExcludeKeywords(keywords []string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.ExcludeKeywords = keywords
+	}
+}
+
+// WithContactValidation validates the emails and the website of the places
+func WithContactValidation(r *ContactRules) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.ContactRules = r
+	}
+}
+
+// WithPolygon keeps only the places inside p
+func WithPolygon(p *polygon.Polygon) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Polygon = p
+	}
+}
+
+// WithParentID marks the job as a cell of the polygon search parentID
+func WithParentID(parentID string) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.ParentID = parentID
+	}
+}
+
+// WithSpamWeights sets the weights of the signals of the spam score of the places
+func WithSpamWeights(w *SpamWeights) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.SpamWeights = w
+	}
+}
+
+// WithMenuHighlights extracts up to limit menu highlights per place
+func WithMenuHighlights(limit int) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.MenuHighlights = limit
+	}
+}
+
+// WithBookingWidget reads the booking link of the places from the booking
+// button too, every place page waits a short time for it to be rendered
+func WithBookingWidget() GmapJobOptions {
+	return func(j *GmapJob) {
+		j.BookingWidget = true
+	}
+}
+
+// WithNormalizePhones adds the phone of the places in the E.164 format
+func WithNormalizePhones() GmapJobOptions {
+	return func(j *GmapJob) {
+		j.NormalizePhones = true
+	}
+}
+
+// WithMaxPhotos adds up to n gallery photos per place after its main photo
+func WithMaxPhotos(n int) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.MaxPhotos = n
+	}
+}
+
+// WithReviews extracts up to maxReviews reviews per place
+func WithReviews(maxReviews int) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.MaxReviews = maxReviews
+	}
+}
+
+// WithPlaceTimeout fails the scrape of a place that takes longer than d
+func WithPlaceTimeout(d time.Duration) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.PlaceTimeout = d
+	}
+}
+
+// WithEmailTimeout bounds every attempt to fetch the website of a place for its emails
+func WithEmailTimeout(d time.Duration) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.EmailTimeout = d
+	}
+}
+
+// WithEmailRetries sets how many times a failed fetch of the website of a place is retried
+func WithEmailRetries(n int) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.EmailRetries = n
+	}
+}
+
+func WithTrace(cfg *TraceConfig) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Trace = cfg
+	}
+}
+
+func WithEmailFetcher(f EmailFetcher) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.EmailFetcher = f
+	}
+}
+
+func WithLimiter(l Limiter) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Limiter = l
+	}
+}
+
+// WithUserAgents sets the user agent of the browsers picked by u
+func WithUserAgents(u UserAgents) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.UserAgents = u
+	}
+}
+
+// WithDeriver adds the derived fields of d to the places
+func WithDeriver(d Deriver) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.Deriver = d
+	}
+}
+
+func WithProxyMonitor(m ProxyMonitor) GmapJobOptions {
+	return func(j *GmapJob) {
+		j.ProxyMonitor = m
+	}
+}
+
 func (j *GmapJob) UseInResults() bool {
 	return false
 }
 
+// ProcessOnFetchError returns true so the failed jobs are counted
+// and queued again or moved to the dead letter queue by the provider
+func (j *GmapJob) ProcessOnFetchError() bool {
+	return true
+}
+
 func (j *GmapJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	defer func() {
 		resp.Document = nil
 		resp.Body = nil
 	}()
 
+	// the failed fetches are retried or dead lettered instead
+	if resp.Error == nil {
+		defer ackJob(ctx, j.Acker, j.ID)
+	}
+
 	log := scrapemate.GetLoggerFromContext(ctx)
 
+	if _, ok := resp.Meta[metaCancelled]; ok {
+		log.Info(fmt.Sprintf("job %s was cancelled", j.ID))
+
+		j.placesFound(ctx, 0)
+
+		return nil, nil, nil
+	}
+
+	if resp.Error != nil {
+		fetchFailed(ctx, j.DeadLetter, j.GetID(), resp)
+
+		return nil, nil, resp.Error
+	}
+
 	doc, ok := resp.Document.(*goquery.Document)
 	if !ok {
+		metrics.JobsFailed.WithLabelValues(metrics.ReasonParse).Inc()
+
 		return nil, nil, fmt.Errorf("could not convert to goquery document")
 	}
 
 	var next []scrapemate.IJob
 
 	if strings.Contains(resp.URL, "/maps/place/") {
-		placeJob := NewPlaceJob(j.ID, j.LangCode, resp.URL, j.ExtractEmail)
+		placeJob := NewPlaceJob(j.ID, j.LangCode, resp.URL, j.ExtractEmail, j.placeJobOptions()...)
 		next = append(next, placeJob)
 	} else {
-		doc.Find(`div[role=feed] div[jsaction]>a`).Each(func(_ int, s *goquery.Selection) {
-			if href := s.AttrOr("href", ""); href != "" {
-				jopts := []PlaceJobOptions{}
-				if j.ExitMonitor != nil {
-					jopts = append(jopts, WithPlaceJobExitMonitor(j.ExitMonitor))
-				}
+		jopts := j.placeJobOptions()
+
+		doc.Find(`div[role=feed] div[jsaction]>a`).EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			// the cap is checked before the deduper, the links past it must not be marked as seen
+			if j.MaxResults > 0 && len(next) >= j.MaxResults {
+				return false
+			}
 
+			if href := s.AttrOr("href", ""); href != "" {
 				nextJob := NewPlaceJob(j.ID, j.LangCode, href, j.ExtractEmail, jopts...)
 
-				if j.Deduper == nil || j.Deduper.AddIfNotExists(ctx, href) {
+				if j.Deduper == nil || j.Deduper.AddIfNotExists(ctx, j.dedupKey(href)) {
 					next = append(next, nextJob)
 				}
 			}
+
+			return true
 		})
 	}
 
-	if j.ExitMonitor != nil {
-		j.ExitMonitor.IncrPlacesFound(len(next))
-		j.ExitMonitor.IncrSeedCompleted(1)
+	// the places of a job cancelled while it was scrolling are not scraped
+	if len(next) > 0 && jobCancelled(ctx, j.Canceller, j.ID) {
+		log.Info(fmt.Sprintf("job %s was cancelled, skipping %d places", j.ID, len(next)))
+
+		next = nil
 	}
 
+	j.placesFound(ctx, len(next))
+
 	log.Info(fmt.Sprintf("%d places found", len(next)))
 
 	return nil, next, nil
 }
 
+// placeJobOptions returns the options the place jobs of the search inherit
+func (j *GmapJob) placeJobOptions() []PlaceJobOptions {
+	jopts := []PlaceJobOptions{}
+
+	if j.ExitMonitor != nil {
+		jopts = append(jopts, WithPlaceJobExitMonitor(j.ExitMonitor))
+	}
+
+	if j.Checkpoint != nil {
+		jopts = append(jopts, WithPlaceJobCheckpoint(j.Checkpoint))
+	}
+
+	if len(j.CustomFields) > 0 {
+		jopts = append(jopts, WithPlaceJobCustomFields(j.CustomFields))
+	}
+
+	if j.RequestID != "" {
+		jopts = append(jopts, WithPlaceJobRequestID(j.RequestID))
+	}
+
+	if j.Tenant != "" {
+		jopts = append(jopts, WithPlaceJobTenant(j.Tenant))
+	}
+
+	jopts = append(jopts, WithPlaceJobQueuePriority(j.QueuePriority))
+
+	if len(j.RestrictedRegions) > 0 {
+		jopts = append(jopts, WithPlaceJobRestrictedRegions(j.RestrictedRegions))
+	}
+
+	if len(j.IncludeKeywords) > 0 || len(j.ExcludeKeywords) > 0 {
+		jopts = append(jopts, WithPlaceJobKeywords(j.IncludeKeywords, j.ExcludeKeywords))
+	}
+
+	if j.SpamWeights != nil {
+		jopts = append(jopts, WithPlaceJobSpamWeights(j.SpamWeights))
+	}
+
+	if j.MenuHighlights > 0 {
+		jopts = append(jopts, WithPlaceJobMenuHighlights(j.MenuHighlights))
+	}
+
+	if j.NormalizePhones {
+		jopts = append(jopts, WithPlaceJobNormalizePhones())
+	}
+
+	if j.BookingWidget {
+		jopts = append(jopts, WithPlaceJobBookingWidget())
+	}
+
+	if j.MaxPhotos > 0 {
+		jopts = append(jopts, WithPlaceJobMaxPhotos(j.MaxPhotos))
+	}
+
+	if j.PlaceDeduper != nil {
+		jopts = append(jopts, WithPlaceJobDeduper(j.PlaceDeduper))
+	}
+
+	if j.MaxReviews > 0 {
+		jopts = append(jopts, WithPlaceJobReviews(j.MaxReviews))
+	}
+
+	if j.PlaceTimeout > 0 {
+		jopts = append(jopts, WithPlaceJobTimeout(j.PlaceTimeout))
+	}
+
+	if j.EmailTimeout > 0 {
+		jopts = append(jopts, WithPlaceJobEmailTimeout(j.EmailTimeout))
+	}
+
+	if j.EmailRetries > 0 {
+		jopts = append(jopts, WithPlaceJobEmailRetries(j.EmailRetries))
+	}
+
+	if j.Polygon != nil {
+		jopts = append(jopts, WithPlaceJobPolygon(j.Polygon))
+	}
+
+	if j.ContactRules != nil {
+		jopts = append(jopts, WithPlaceJobContactRules(j.ContactRules))
+	}
+
+	if j.Trace != nil {
+		jopts = append(jopts, WithPlaceJobTrace(j.Trace))
+	}
+
+	if j.EmailFetcher != nil {
+		jopts = append(jopts, WithPlaceJobEmailFetcher(j.EmailFetcher))
+	}
+
+	if j.Limiter != nil {
+		jopts = append(jopts, WithPlaceJobLimiter(j.Limiter))
+	}
+
+	if j.ProxyMonitor != nil {
+		jopts = append(jopts, WithPlaceJobProxyMonitor(j.ProxyMonitor))
+	}
+
+	if j.UserAgents != nil {
+		jopts = append(jopts, WithPlaceJobUserAgents(j.UserAgents))
+	}
+
+	if j.Deriver != nil {
+		jopts = append(jopts, WithPlaceJobDeriver(j.Deriver))
+	}
+
+	if j.Tracker != nil {
+		jopts = append(jopts, WithPlaceJobTracker(j.Tracker))
+	}
+
+	return jopts
+}
+
+// dedupKey returns the key of the place in the deduper, the places of the
+// cells are deduplicated within their polygon search only
+func (j *GmapJob) dedupKey(href string) string {
+	if j.ParentID == "" {
+		return href
+	}
+
+	return j.ParentID + "\x00" + href
+}
+
+// placesFound records the number of places of the job once it's processed
+func (j *GmapJob) placesFound(ctx context.Context, n int) {
+	if j.ExitMonitor != nil {
+		j.ExitMonitor.IncrPlacesFound(n)
+		j.ExitMonitor.IncrSeedCompleted(1)
+	}
+
+	if j.Checkpoint != nil {
+		j.Checkpoint.SetPlacesFound(j.ID, n)
+	}
+
+	if j.Tracker != nil {
+		j.Tracker.PlacesFound(ctx, j.ID, n)
+	}
+}
+
 func (j *GmapJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
 	var resp scrapemate.Response
 
+	waitThrottle(ctx, j.Throttler, j.ID)
+
+	if jobCancelled(ctx, j.Canceller, j.ID) {
+		resp.Meta = map[string]any{metaCancelled: true}
+
+		return resp
+	}
+
+	release, err := acquireSlot(ctx, j.Limiter)
+	if err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
+	defer func() { release(isBlocked(page, &resp)) }()
+
+	if j.Trace != nil {
+		stop := startTrace(page, j.Trace)
+		defer func() { stop(j.ID, resp.Error != nil) }()
+	}
+
+	if err := setBrowserIdentity(page, j.UserAgents, j.LangCode); err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
 	pageResponse, err := page.Goto(j.GetFullURL(), playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
 	})
@@ -150,6 +661,8 @@ func (j *GmapJob) BrowserActions(ctx context.Context, page playwright.Page) scra
 		return resp
 	}
 
+	reportBlockedProxy(j.ProxyMonitor, page, pageResponse)
+
 	if err = clickRejectCookiesIfRequired(page); err != nil {
 		resp.Error = err
 
@@ -193,13 +706,18 @@ func (j *GmapJob) BrowserActions(ctx context.Context, page playwright.Page) scra
 		return resp
 	}
 
-	_, err = scroll(ctx, page, j.MaxDepth)
+	ops, reason, err := scroll(ctx, page, j.MaxDepth, j.ScrollBudget, j.AutoDepth, j.MaxResults)
 	if err != nil {
 		resp.Error = err
 
 		return resp
 	}
 
+	scrapemate.GetLoggerFromContext(ctx).Info("scrolling finished",
+		"job", j.ID, "operations", ops, "stopped_by", reason, "auto_depth", j.AutoDepth)
+
+	resp.Meta = map[string]any{"scroll_stopped_by": reason, "scroll_depth": ops}
+
 	body, err := page.Content()
 	if err != nil {
 		resp.Error = err
@@ -234,7 +752,34 @@ func clickRejectCookiesIfRequired(page playwright.Page) error {
 	return el.Click()
 }
 
-func scroll(ctx context.Context, page playwright.Page, maxDepth int) (int, error) {
+// The reasons scrolling the results stopped
+const (
+	ScrollStopEndOfResults = "end_of_results"
+	ScrollStopMaxOps       = "max_operations"
+	ScrollStopTimeBudget   = "time_budget"
+	ScrollStopCanceled     = "canceled"
+	ScrollStopLowYield     = "low_yield"
+	ScrollStopMaxResults   = "max_results"
+)
+
+const (
+	// autoDepthMaxOps bounds the scrolling with auto depth,
+	// google stops loading new results long before
+	autoDepthMaxOps = 100
+	// autoDepthMinNew is the number of new results per scroll under which scrolling stops
+	autoDepthMinNew = 3
+	// autoDepthPatience is the number of low yield scrolls in a row that stop scrolling,
+	// so that a single slow load does not
+	autoDepthPatience = 2
+)
+
+// scroll scrolls the results feed at most maxDepth times and for at most
+// budget (when > 0). With autoDepth maxDepth is ignored and scrolling stops
+// once the scrolls yield less than autoDepthMinNew new results. With maxResults
+// (when > 0) it stops once as many results are loaded.
+// It returns the number of scroll operations and the reason it stopped.
+// Running out of operations or time is not an error, the results loaded so far are kept.
+func scroll(ctx context.Context, page playwright.Page, maxDepth int, budget time.Duration, autoDepth bool, maxResults int) (int, string, error) {
 	scrollSelector := `div[role='feed']`
 	expr := `async () => {
 		const el = document.querySelector("` + scrollSelector + `");
@@ -247,6 +792,30 @@ func scroll(ctx context.Context, page playwright.Page, maxDepth int) (int, error
 		});
 	}`
 
+	var deadline time.Time
+	if budget > 0 {
+		deadline = time.Now().Add(budget)
+	}
+
+	var results, lowYield int
+
+	if autoDepth {
+		maxDepth = autoDepthMaxOps
+	}
+
+	if autoDepth || maxResults > 0 {
+		n, err := countResults(page)
+		if err != nil {
+			return 0, "", err
+		}
+
+		if maxResults > 0 && n >= maxResults {
+			return 0, ScrollStopMaxResults, nil
+		}
+
+		results = n
+	}
+
 	var currentScrollHeight int
 	// Scroll to the bottom of the page.
 	waitTime := 100.
@@ -258,6 +827,10 @@ func scroll(ctx context.Context, page playwright.Page, maxDepth int) (int, error
 	)
 
 	for i := 0; i < maxDepth; i++ {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return cnt, ScrollStopTimeBudget, nil
+		}
+
 		cnt++
 		waitTime2 := timeout * cnt
 
@@ -268,35 +841,77 @@ func scroll(ctx context.Context, page playwright.Page, maxDepth int) (int, error
 		// Scroll to the bottom of the page.
 		scrollHeight, err := page.Evaluate(fmt.Sprintf(expr, waitTime2))
 		if err != nil {
-			return cnt, err
+			return cnt, "", err
 		}
 
 		height, ok := scrollHeight.(int)
 		if !ok {
-			return cnt, fmt.Errorf("scrollHeight is not an int")
+			return cnt, "", fmt.Errorf("scrollHeight is not an int")
 		}
 
 		if height == currentScrollHeight {
-			break
+			return cnt, ScrollStopEndOfResults, nil
 		}
 
 		currentScrollHeight = height
 
 		select {
 		case <-ctx.Done():
-			return currentScrollHeight, nil
+			return cnt, ScrollStopCanceled, nil
 		default:
 		}
 
+		if autoDepth || maxResults > 0 {
+			n, err := countResults(page)
+			if err != nil {
+				return cnt, "", err
+			}
+
+			if maxResults > 0 && n >= maxResults {
+				return cnt, ScrollStopMaxResults, nil
+			}
+
+			if n-results < autoDepthMinNew {
+				lowYield++
+			} else {
+				lowYield = 0
+			}
+
+			results = n
+
+			if autoDepth && lowYield >= autoDepthPatience {
+				return cnt, ScrollStopLowYield, nil
+			}
+		}
+
 		waitTime *= 1.5
 
 		if waitTime > maxWait2 {
 			waitTime = maxWait2
 		}
 
+		if !deadline.IsZero() {
+			waitTime = min(waitTime, float64(max(0, time.Until(deadline).Milliseconds())))
+		}
+
 		//nolint:staticcheck // TODO replace with the new playwright API
 		page.WaitForTimeout(waitTime)
 	}
 
-	return cnt, nil
+	return cnt, ScrollStopMaxOps, nil
+}
+
+// countResults returns the number of places loaded in the results feed
+func countResults(page playwright.Page) (int, error) {
+	v, err := page.Evaluate(`() => document.querySelectorAll("div[role='feed'] a[href*='/maps/place/']").length`)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := v.(int)
+	if !ok {
+		return 0, fmt.Errorf("results count is not an int")
+	}
+
+	return n, nil
 }
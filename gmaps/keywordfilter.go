@@ -0,0 +1,51 @@
+package gmaps
+
+import "strings"
+
+// ParseKeywords parses a comma separated list of keywords
+func ParseKeywords(s string) []string {
+	var ans []string
+
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if k != "" {
+			ans = append(ans, k)
+		}
+	}
+
+	return ans
+}
+
+// keywordFilterReason returns why the place is dropped by the include and exclude
+// keywords or an empty string when it's kept. The keywords are matched case insensitive
+// against the title, the categories and the description.
+func keywordFilterReason(entry *Entry, include, exclude []string) string {
+	if len(include) == 0 && len(exclude) == 0 {
+		return ""
+	}
+
+	text := strings.ToLower(strings.Join([]string{
+		entry.Title,
+		entry.Category,
+		strings.Join(entry.Categories, " "),
+		entry.Description,
+	}, " "))
+
+	for _, k := range exclude {
+		if strings.Contains(text, strings.ToLower(k)) {
+			return "excluded"
+		}
+	}
+
+	if len(include) == 0 {
+		return ""
+	}
+
+	for _, k := range include {
+		if strings.Contains(text, strings.ToLower(k)) {
+			return ""
+		}
+	}
+
+	return "not_included"
+}
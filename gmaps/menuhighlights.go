@@ -0,0 +1,141 @@
+package gmaps
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// MenuHighlight is a menu item google shows with a photo
+type MenuHighlight struct {
+	Label    string `json:"label"`
+	ImageURL string `json:"image_url"`
+}
+
+// menuHighlightsLabel matches the title of the menu highlights block
+// in the languages we support.
+var menuHighlightsLabel = regexp.MustCompile(`(?i)^(menu highlights|popular dishes|highlights|beliebte gerichte|highlights der speisekarte|plats populaires|points forts du menu|platos populares|destacados del menú|piatti popolari|piatti in evidenza|δημοφιλή πιάτα)$`)
+
+// maxMenuLabelLength skips the reviews and descriptions next to the photos
+const maxMenuLabelLength = 80
+
+// menuHighlightsFromJSON returns up to limit menu highlights of the place json,
+// or nil when google shows none. It's parsed separately from EntryFromJSON
+// since it's only done when enabled.
+func menuHighlightsFromJSON(raw []byte, limit int) []MenuHighlight {
+	var jd []any
+	if err := json.Unmarshal(raw, &jd); err != nil {
+		return nil
+	}
+
+	darray := getNthElementAndCast[[]any](jd, 6)
+
+	return getMenuHighlights(darray, limit)
+}
+
+// getMenuHighlights looks for the block titled like "Menu highlights", its position
+// changes between the place types, and pairs the item names with their photos.
+func getMenuHighlights(darray []any, limit int) []MenuHighlight {
+	if limit <= 0 {
+		return nil
+	}
+
+	var ans []MenuHighlight
+
+	seen := make(map[string]bool)
+
+	walkArrays(darray, func(node []any) {
+		if len(ans) >= limit || !hasMenuHighlightsLabel(node) {
+			return
+		}
+
+		for i := range node {
+			walkArrays(node[i], func(item []any) {
+				if len(ans) >= limit {
+					return
+				}
+
+				h, ok := menuHighlight(item)
+				if !ok || seen[strings.ToLower(h.Label)] {
+					return
+				}
+
+				seen[strings.ToLower(h.Label)] = true
+
+				ans = append(ans, h)
+			})
+		}
+	})
+
+	return ans
+}
+
+func hasMenuHighlightsLabel(node []any) bool {
+	for i := range node {
+		if s, ok := node[i].(string); ok && menuHighlightsLabel.MatchString(strings.TrimSpace(s)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// menuHighlight returns the item of an array holding a short label, the photo
+// can be nested deeper since google wraps it together with its size
+func menuHighlight(item []any) (MenuHighlight, bool) {
+	var ans MenuHighlight
+
+	for i := range item {
+		s, ok := item[i].(string)
+		if !ok || len(s) > maxMenuLabelLength || strings.Contains(s, "://") || menuHighlightsLabel.MatchString(s) {
+			continue
+		}
+
+		if ans.Label = strings.TrimSpace(s); ans.Label != "" {
+			break
+		}
+	}
+
+	if ans.Label == "" {
+		return ans, false
+	}
+
+	for _, s := range collectStrings(item, nil) {
+		if isImageURL(s) {
+			ans.ImageURL = bestImageURL(s)
+
+			break
+		}
+	}
+
+	return ans, ans.ImageURL != ""
+}
+
+func isImageURL(s string) bool {
+	if strings.HasPrefix(s, "//") {
+		s = "https:" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return false
+	}
+
+	return strings.HasSuffix(u.Hostname(), "googleusercontent.com") || strings.HasSuffix(u.Hostname(), "ggpht.com")
+}
+
+// bestImageURL asks for the original resolution of the photos google resizes,
+// their size options follow the last "=" like in ".../p/AF1Qip...=w408-h408-k-no"
+func bestImageURL(s string) string {
+	if strings.HasPrefix(s, "//") {
+		s = "https:" + s
+	}
+
+	i := strings.LastIndex(s, "=")
+	if i == -1 || strings.ContainsAny(s[i+1:], "/?&") {
+		return s
+	}
+
+	return s[:i] + "=s0"
+}
@@ -0,0 +1,170 @@
+package gmaps
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	reviewsIndex   = 175
+	minOpenedYear  = 1000
+	yearPatternExp = `((?:1|2)\d{3})`
+)
+
+// openedYearPatterns holds per language the phrasings google uses
+// when it shows when a place was opened/founded.
+var openedYearPatterns = map[string][]*regexp.Regexp{
+	"en": compileYearPatterns(
+		`opened in `,
+		`founded in `,
+		`established in `,
+		`serving since `,
+	),
+	"de": compileYearPatterns(
+		`eröffnet (?:im jahr )?`,
+		`gegründet (?:im jahr )?`,
+	),
+	"fr": compileYearPatterns(
+		`ouvert en `,
+		`fondée? en `,
+	),
+	"es": compileYearPatterns(
+		`abierto en `,
+		`inaugurado en `,
+		`fundado en `,
+	),
+	"it": compileYearPatterns(
+		`aperto nel `,
+		`fondat[oa] nel `,
+	),
+	"pt": compileYearPatterns(
+		`aberto em `,
+		`inaugurado em `,
+		`fundad[oa] em `,
+	),
+	"nl": compileYearPatterns(
+		`geopend in `,
+		`opgericht in `,
+	),
+	"el": compileYearPatterns(
+		`άνοιξε (?:το |τον )?`,
+		`ιδρύθηκε (?:το |τον )?`,
+	),
+}
+
+// openedYearLanguages is the order the languages are tried in when the
+// language of the job is unknown, english first and then by code, so that
+// a text matching several languages always gives the same year
+var openedYearLanguages = func() []string {
+	ans := make([]string, 0, len(openedYearPatterns))
+	for lang := range openedYearPatterns {
+		if lang != "en" {
+			ans = append(ans, lang)
+		}
+	}
+
+	sort.Strings(ans)
+
+	return append([]string{"en"}, ans...)
+}()
+
+func compileYearPatterns(prefixes ...string) []*regexp.Regexp {
+	ans := make([]*regexp.Regexp, len(prefixes))
+
+	for i := range prefixes {
+		ans[i] = regexp.MustCompile(`(?i)` + prefixes[i] + yearPatternExp + `\b`)
+	}
+
+	return ans
+}
+
+// getOpenedYear looks for an "Opened in YYYY" like text in the place data.
+// The patterns of langCode are tried first and then the english ones.
+// When langCode is empty all known languages are tried, in the
+// order of openedYearLanguages.
+// It returns 0 when nothing is found.
+func getOpenedYear(langCode string, darray []any) int {
+	var texts []string
+
+	for i := range darray {
+		// skip user reviews, they mention years in many different contexts
+		if i == reviewsIndex {
+			continue
+		}
+
+		texts = collectStrings(darray[i], texts)
+	}
+
+	for _, patterns := range yearPatternsFor(langCode) {
+		for _, text := range texts {
+			if year := matchYear(patterns, text); year > 0 {
+				return year
+			}
+		}
+	}
+
+	return 0
+}
+
+func yearPatternsFor(langCode string) [][]*regexp.Regexp {
+	langCode = strings.ToLower(langCode)
+
+	if langCode == "" {
+		ans := make([][]*regexp.Regexp, 0, len(openedYearPatterns))
+		for _, lang := range openedYearLanguages {
+			ans = append(ans, openedYearPatterns[lang])
+		}
+
+		return ans
+	}
+
+	var ans [][]*regexp.Regexp
+
+	if patterns, ok := openedYearPatterns[langCode]; ok {
+		ans = append(ans, patterns)
+	}
+
+	if langCode != "en" {
+		ans = append(ans, openedYearPatterns["en"])
+	}
+
+	return ans
+}
+
+func matchYear(patterns []*regexp.Regexp, text string) int {
+	for _, re := range patterns {
+		m := re.FindStringSubmatch(text)
+		if len(m) < 2 {
+			continue
+		}
+
+		year, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+
+		if year < minOpenedYear || year > time.Now().UTC().Year() {
+			continue
+		}
+
+		return year
+	}
+
+	return 0
+}
+
+func collectStrings(v any, acc []string) []string {
+	switch val := v.(type) {
+	case string:
+		acc = append(acc, val)
+	case []any:
+		for i := range val {
+			acc = collectStrings(val[i], acc)
+		}
+	}
+
+	return acc
+}
@@ -0,0 +1,56 @@
+package gmaps
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getOpenedYear(t *testing.T) {
+	nextYear := strconv.Itoa(time.Now().UTC().Year() + 1)
+
+	// the reviews are at reviewsIndex of the place data
+	withReview := func(review string, texts ...any) []any {
+		ans := make([]any, reviewsIndex+1)
+		copy(ans, texts)
+		ans[reviewsIndex] = []any{review}
+
+		return ans
+	}
+
+	tests := []struct {
+		name   string
+		lang   string
+		darray []any
+		want   int
+	}{
+		{"english", "en", []any{"Family bakery. Opened in 1998."}, 1998},
+		{"nested strings", "en", []any{nil, []any{1, []any{"Serving since 1921"}}}, 1921},
+		{"language of the job", "de", []any{"Gegründet im Jahr 1876"}, 1876},
+		{"upper case language", "DE", []any{"Eröffnet 2005"}, 2005},
+		{"english fallback", "fr", []any{"Founded in 1950"}, 1950},
+		{"language of the job first", "de", []any{"Opened in 1990", "Gegründet 1985"}, 1985},
+		{"other language ignored", "it", []any{"Gegründet 1985"}, 0},
+		{"unknown language", "xx", []any{"Established in 1899"}, 1899},
+		{"empty language english first", "", []any{"Gegründet 1985", "Opened in 1990"}, 1990},
+		{"empty language by code", "", []any{"Fondée en 1970", "Gegründet 1985", "Fundado en 1960"}, 1985},
+		{"empty language other", "", []any{"Άνοιξε το 2010"}, 2010},
+		{"reviews are skipped", "en", withReview("opened in 2001"), 0},
+		{"text before the reviews", "en", withReview("opened in 2001", "Opened in 1999"), 1999},
+		{"future year", "en", []any{"Opened in " + nextYear}, 0},
+		{"part of a number", "en", []any{"Opened in 19999"}, 0},
+		{"absent", "en", []any{"Open 24 hours", 1998}, 0},
+		{"no data", "", nil, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// the languages used to be tried in the random order of a map
+			for range 20 {
+				require.Equal(t, tc.want, getOpenedYear(tc.lang, tc.darray))
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package gmaps
+
+import (
+	"strings"
+)
+
+// the E.164 numbers have up to 15 digits including the country calling code
+const (
+	minE164Digits = 7
+	maxE164Digits = 15
+)
+
+// dialingPlan is how the numbers of a country are dialed
+type dialingPlan struct {
+	// code is the country calling code
+	code string
+	// trunk is the prefix of the national numbers dropped in the
+	// international format, empty when the numbers keep it
+	trunk string
+	// intl is the prefix of the international numbers, "00" when empty
+	intl string
+}
+
+// dialingPlans are keyed by ISO 3166-1 alpha-2 country code
+var dialingPlans = map[string]dialingPlan{
+	"AE": {code: "971", trunk: "0"},
+	"AR": {code: "54", trunk: "0"},
+	"AT": {code: "43", trunk: "0"},
+	"AU": {code: "61", trunk: "0", intl: "0011"},
+	"BD": {code: "880", trunk: "0"},
+	"BE": {code: "32", trunk: "0"},
+	"BG": {code: "359", trunk: "0"},
+	"BR": {code: "55", trunk: "0"},
+	"CA": {code: "1", trunk: "1", intl: "011"},
+	"CH": {code: "41", trunk: "0"},
+	"CL": {code: "56"},
+	"CN": {code: "86", trunk: "0"},
+	"CO": {code: "57"},
+	"CY": {code: "357"},
+	"CZ": {code: "420"},
+	"DE": {code: "49", trunk: "0"},
+	"DK": {code: "45"},
+	"EG": {code: "20", trunk: "0"},
+	"ES": {code: "34"},
+	"FI": {code: "358", trunk: "0"},
+	"FR": {code: "33", trunk: "0"},
+	"GB": {code: "44", trunk: "0"},
+	"GR": {code: "30"},
+	"HK": {code: "852", intl: "001"},
+	"HR": {code: "385", trunk: "0"},
+	"HU": {code: "36", trunk: "06"},
+	"ID": {code: "62", trunk: "0", intl: "001"},
+	"IE": {code: "353", trunk: "0"},
+	"IL": {code: "972", trunk: "0"},
+	"IN": {code: "91", trunk: "0"},
+	"IS": {code: "354"},
+	"IT": {code: "39"},
+	"JP": {code: "81", trunk: "0", intl: "010"},
+	"KE": {code: "254", trunk: "0"},
+	"KR": {code: "82", trunk: "0", intl: "001"},
+	"LU": {code: "352"},
+	"MA": {code: "212", trunk: "0"},
+	"MT": {code: "356"},
+	"MX": {code: "52"},
+	"MY": {code: "60", trunk: "0"},
+	"NG": {code: "234", trunk: "0"},
+	"NL": {code: "31", trunk: "0"},
+	"NO": {code: "47"},
+	"NZ": {code: "64", trunk: "0"},
+	"PE": {code: "51", trunk: "0"},
+	"PH": {code: "63", trunk: "0"},
+	"PK": {code: "92", trunk: "0"},
+	"PL": {code: "48"},
+	"PR": {code: "1", trunk: "1", intl: "011"},
+	"PT": {code: "351"},
+	"RO": {code: "40", trunk: "0"},
+	"RS": {code: "381", trunk: "0"},
+	"RU": {code: "7", trunk: "8", intl: "810"},
+	"SA": {code: "966", trunk: "0"},
+	"SE": {code: "46", trunk: "0"},
+	"SG": {code: "65", intl: "000"},
+	"SI": {code: "386", trunk: "0"},
+	"SK": {code: "421", trunk: "0"},
+	"TH": {code: "66", trunk: "0", intl: "001"},
+	"TR": {code: "90", trunk: "0"},
+	"TW": {code: "886", trunk: "0", intl: "002"},
+	"UA": {code: "380", trunk: "0"},
+	"US": {code: "1", trunk: "1", intl: "011"},
+	"VN": {code: "84", trunk: "0"},
+	"ZA": {code: "27", trunk: "0"},
+}
+
+// NormalizePhone returns phone in the E.164 format (e.g. "+35725101555").
+// The national numbers are prefixed with the calling code of country, an
+// ISO 3166-1 alpha-2 code. It returns false when phone can't be parsed, it
+// has an extension or it's national and the country is unknown.
+func NormalizePhone(phone, country string) (string, bool) {
+	var (
+		digits strings.Builder
+		plus   bool
+	)
+
+	for _, r := range strings.TrimSpace(phone) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && digits.Len() == 0 && !plus:
+			plus = true
+		case strings.ContainsRune(" -./()\u00a0", r):
+		default:
+			return "", false
+		}
+	}
+
+	n := digits.String()
+
+	if !plus {
+		plan, ok := dialingPlans[strings.ToUpper(country)]
+
+		intl := plan.intl
+		if intl == "" {
+			intl = "00"
+		}
+
+		switch {
+		case strings.HasPrefix(n, "00") && (!ok || intl == "00"):
+			n = n[2:]
+		case ok && strings.HasPrefix(n, intl):
+			n = n[len(intl):]
+		case ok:
+			n = plan.code + strings.TrimPrefix(n, plan.trunk)
+		default:
+			return "", false
+		}
+	}
+
+	if len(n) < minE164Digits || len(n) > maxE164Digits || n[0] == '0' {
+		return "", false
+	}
+
+	return "+" + n, true
+}
+
+// setPhoneE164 sets PhoneE164 to the phone in the E.164 format, using the
+// country of the place or else the region of langCode (e.g. "en-GB") for the
+// national numbers. It keeps the raw phone when it can't be parsed.
+func (e *Entry) setPhoneE164(langCode string) {
+	if e.Phone == "" {
+		return
+	}
+
+	country := e.CompleteAddress.Country
+	if country == "" {
+		if _, region, ok := strings.Cut(langCode, "-"); ok {
+			country = region
+		}
+	}
+
+	if phone, ok := NormalizePhone(e.Phone, country); ok {
+		e.PhoneE164 = phone
+
+		return
+	}
+
+	e.PhoneE164 = e.Phone
+}
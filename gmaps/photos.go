@@ -0,0 +1,84 @@
+package gmaps
+
+import (
+	"net/url"
+	"strings"
+)
+
+// placeholderPhotoMarkers are found in the urls of the generic images
+// google shows for the places without a photo
+var placeholderPhotoMarkers = []string{
+	"default_geocode",
+	"no_photo",
+	"nophoto",
+	"placeholder",
+	"/tactile/",
+	"/images/icons/",
+}
+
+// isPlaceholderPhoto reports if u is not a photo of the place
+func isPlaceholderPhoto(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return true
+	}
+
+	// the photos are served from googleusercontent, gstatic only has static assets
+	if strings.HasSuffix(parsed.Hostname(), "gstatic.com") {
+		return true
+	}
+
+	path := strings.ToLower(parsed.Path)
+
+	for _, marker := range placeholderPhotoMarkers {
+		if strings.Contains(path, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// photoKey identifies a photo whatever its size, the size options
+// follow the "=" of the url (e.g. "=w408-h408-k-no")
+func photoKey(u string) string {
+	key, _, _ := strings.Cut(u, "=")
+
+	return key
+}
+
+// mainPhotos returns the photos of a place holding only its main photo,
+// nil when it has none
+func mainPhotos(thumbnail string) []string {
+	if thumbnail == "" || isPlaceholderPhoto(thumbnail) {
+		return nil
+	}
+
+	return []string{thumbnail}
+}
+
+// addGalleryPhotos appends up to n photos of the gallery of the place
+// after its main photo, skipping the placeholders and the duplicates
+func (e *Entry) addGalleryPhotos(n int) {
+	seen := make(map[string]bool, len(e.Photos))
+	for _, u := range e.Photos {
+		seen[photoKey(u)] = true
+	}
+
+	added := 0
+
+	for i := range e.Images {
+		if added >= n {
+			break
+		}
+
+		u := e.Images[i].Image
+		if u == "" || isPlaceholderPhoto(u) || seen[photoKey(u)] {
+			continue
+		}
+
+		seen[photoKey(u)] = true
+		e.Photos = append(e.Photos, u)
+		added++
+	}
+}
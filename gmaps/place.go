@@ -2,12 +2,18 @@ package gmaps
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/checkpoint"
+	"github.com/gosom/google-maps-scraper/deduper"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/polygon"
 	"github.com/gosom/scrapemate"
 	"github.com/playwright-community/playwright-go"
 )
@@ -20,6 +26,50 @@ type PlaceJob struct {
 	UsageInResultststs bool
 	ExtractEmail       bool
 	ExitMonitor        exiter.Exiter
+	Checkpoint         checkpoint.Checkpoint
+	CustomFields       map[string]string
+	RequestID          string
+	Tenant             string
+	QueuePriority      int
+	RestrictedRegions  []string
+	IncludeKeywords    []string
+	ExcludeKeywords    []string
+	SpamWeights        *SpamWeights
+	MenuHighlights     int
+	NormalizePhones    bool
+	BookingWidget      bool
+	MaxPhotos          int
+	MaxReviews         int
+	PlaceTimeout       time.Duration
+	EmailTimeout       time.Duration
+	EmailRetries       int
+	Polygon            *polygon.Polygon
+	ContactRules       *ContactRules
+	Trace              *TraceConfig
+	EmailFetcher       EmailFetcher
+	// PlaceDeduper drops the place when one with the same cid was already scraped
+	PlaceDeduper deduper.Deduper
+	// Throttler is set by the job provider when the job is fetched
+	Throttler Throttler
+	// Canceller is set by the job provider when the job is fetched,
+	// the place is skipped once its search job is cancelled
+	Canceller Canceller
+	// Limiter adapts the concurrency to the block rate when set
+	Limiter Limiter
+	// ProxyMonitor sidelines the proxies google blocks when set
+	ProxyMonitor ProxyMonitor
+	// UserAgents picks the user agent of the browsers when set
+	UserAgents UserAgents
+	// Deriver adds the derived fields to the place when set
+	Deriver Deriver
+	// DeadLetter is set by the job provider to retry or dead letter the failed jobs
+	DeadLetter DeadLetter
+	// Acker is set by the job provider to release the job once it's processed
+	Acker Acker
+	// Tracked is set for the places of the tracked search jobs,
+	// the job provider then sets Tracker when the job is fetched
+	Tracked bool
+	Tracker JobTracker
 }
 
 func NewPlaceJob(parentID, langCode, u string, extractEmail bool, opts ...PlaceJobOptions) *PlaceJob {
@@ -42,6 +92,7 @@ func NewPlaceJob(parentID, langCode, u string, extractEmail bool, opts ...PlaceJ
 
 	job.UsageInResultststs = true
 	job.ExtractEmail = extractEmail
+	job.QueuePriority = DefaultQueuePriority
 
 	for _, opt := range opts {
 		opt(&job)
@@ -56,50 +107,451 @@ func WithPlaceJobExitMonitor(exitMonitor exiter.Exiter) PlaceJobOptions {
 	}
 }
 
-func (j *PlaceJob) Process(_ context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
+func WithPlaceJobCheckpoint(c checkpoint.Checkpoint) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Checkpoint = c
+	}
+}
+
+func WithPlaceJobCustomFields(fields map[string]string) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.CustomFields = fields
+	}
+}
+
+func WithPlaceJobRequestID(requestID string) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.RequestID = requestID
+	}
+}
+
+func WithPlaceJobTenant(tenant string) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Tenant = tenant
+	}
+}
+
+// WithPlaceJobQueuePriority sets the priority of the job in the queue, the one of its search job
+func WithPlaceJobQueuePriority(priority int) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.QueuePriority = priority
+	}
+}
+
+func WithPlaceJobRestrictedRegions(regions []string) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.RestrictedRegions = regions
+	}
+}
+
+func WithPlaceJobKeywords(include, exclude []string) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.IncludeKeywords = include
+		j.ExcludeKeywords = exclude
+	}
+}
+
+func WithPlaceJobSpamWeights(w *SpamWeights) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.SpamWeights = w
+	}
+}
+
+func WithPlaceJobPolygon(p *polygon.Polygon) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Polygon = p
+	}
+}
+
+func WithPlaceJobContactRules(r *ContactRules) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.ContactRules = r
+	}
+}
+
+func WithPlaceJobMenuHighlights(limit int) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.MenuHighlights = limit
+	}
+}
+
+// WithPlaceJobBookingWidget reads the booking link from the booking button too
+func WithPlaceJobBookingWidget() PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.BookingWidget = true
+	}
+}
+
+// WithPlaceJobNormalizePhones adds the phone of the place in the E.164 format
+func WithPlaceJobNormalizePhones() PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.NormalizePhones = true
+	}
+}
+
+// WithPlaceJobMaxPhotos adds up to n gallery photos after the main photo of the place
+func WithPlaceJobMaxPhotos(n int) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.MaxPhotos = n
+	}
+}
+
+func WithPlaceJobDeduper(d deduper.Deduper) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.PlaceDeduper = d
+	}
+}
+
+// WithPlaceJobReviews scrolls the reviews panel to extract up to maxReviews reviews
+func WithPlaceJobReviews(maxReviews int) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.MaxReviews = maxReviews
+	}
+}
+
+// WithPlaceJobTimeout fails the scrape of the place when it takes longer than d
+func WithPlaceJobTimeout(d time.Duration) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.PlaceTimeout = d
+	}
+}
+
+// WithPlaceJobEmailTimeout bounds every attempt to fetch the website for the emails
+func WithPlaceJobEmailTimeout(d time.Duration) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.EmailTimeout = d
+	}
+}
+
+// WithPlaceJobEmailRetries sets how many times a failed fetch of the website is retried
+func WithPlaceJobEmailRetries(n int) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.EmailRetries = n
+	}
+}
+
+func WithPlaceJobTrace(cfg *TraceConfig) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Trace = cfg
+	}
+}
+
+func WithPlaceJobEmailFetcher(f EmailFetcher) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.EmailFetcher = f
+	}
+}
+
+func WithPlaceJobLimiter(l Limiter) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Limiter = l
+	}
+}
+
+func WithPlaceJobProxyMonitor(m ProxyMonitor) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.ProxyMonitor = m
+	}
+}
+
+// WithPlaceJobUserAgents sets the user agent of the browsers picked by u
+func WithPlaceJobUserAgents(u UserAgents) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.UserAgents = u
+	}
+}
+
+// WithPlaceJobDeriver adds the derived fields of d to the place
+func WithPlaceJobDeriver(d Deriver) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Deriver = d
+	}
+}
+
+func WithPlaceJobTracker(t JobTracker) PlaceJobOptions {
+	return func(j *PlaceJob) {
+		j.Tracked = true
+		j.Tracker = t
+	}
+}
+
+func (j *PlaceJob) Process(ctx context.Context, resp *scrapemate.Response) (any, []scrapemate.IJob, error) {
 	defer func() {
 		resp.Document = nil
 		resp.Body = nil
 		resp.Meta = nil
 	}()
 
+	// the failed fetches are retried or dead lettered instead
+	if resp.Error == nil {
+		defer ackJob(ctx, j.Acker, j.ID)
+	}
+
+	if _, ok := resp.Meta[metaCancelled]; ok {
+		j.UsageInResultststs = false
+
+		j.markCompleted(ctx, false)
+
+		return nil, nil, nil
+	}
+
+	if resp.Error != nil {
+		fetchFailed(ctx, j.DeadLetter, j.GetID(), resp)
+
+		return nil, nil, resp.Error
+	}
+
 	raw, ok := resp.Meta["json"].([]byte)
 	if !ok {
+		metrics.JobsFailed.WithLabelValues(metrics.ReasonParse).Inc()
+		j.trackDone(ctx, false)
+
 		return nil, nil, fmt.Errorf("could not convert to []byte")
 	}
 
-	entry, err := EntryFromJSON(raw)
+	entry, err := EntryFromJSONWithLang(raw, j.URLParams["hl"])
 	if err != nil {
+		metrics.JobsFailed.WithLabelValues(metrics.ReasonParse).Inc()
+		j.trackDone(ctx, false)
+
 		return nil, nil, err
 	}
 
 	entry.ID = j.ParentID
+	entry.RequestID = j.RequestID
+	entry.Tenant = j.Tenant
+
+	if j.SpamWeights != nil {
+		entry.SpamScore = entry.spamScore(j.SpamWeights)
+	}
+
+	if j.MenuHighlights > 0 {
+		entry.MenuHighlights = menuHighlightsFromJSON(raw, j.MenuHighlights)
+	}
+
+	if j.NormalizePhones {
+		entry.setPhoneE164(j.URLParams["hl"])
+	}
+
+	if j.MaxPhotos > 0 {
+		entry.addGalleryPhotos(j.MaxPhotos)
+	}
+
+	// the scraped reviews replace the few ones of the place data
+	if reviews, ok := resp.Meta["reviews"].([]Review); ok && len(reviews) > 0 {
+		entry.UserReviews = reviews
+	}
+
+	if customFields, ok := resp.Meta["custom_fields"].(map[string]string); ok {
+		entry.CustomFields = customFields
+	}
+
+	if bookingLink, ok := resp.Meta["booking_link"].(string); ok && !entry.BookingAvailable {
+		entry.SetBooking(bookingLink, "")
+	}
 
 	if entry.Link == "" {
 		entry.Link = j.GetURL()
 	}
 
+	// the places without any id still get a url to fetch them again
+	if entry.MapsURL == "" {
+		entry.MapsURL = entry.Link
+	}
+
+	// the searches are checked when they are created, the places of the ones
+	// whose region could not be resolved are checked here
+	if IsRestrictedRegion(entry.CompleteAddress.Country, j.RestrictedRegions) {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Warn(fmt.Sprintf("%v: skipping %s (%s)", ErrRestrictedRegion, entry.Title, entry.CompleteAddress.Country))
+
+		j.UsageInResultststs = false
+
+		j.markCompleted(ctx, false)
+
+		return nil, nil, nil
+	}
+
+	if j.Polygon != nil && !j.Polygon.Contains(entry.Latitude, entry.Longtitude) {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Info(fmt.Sprintf("polygon: skipping %s (%f,%f)", entry.Title, entry.Latitude, entry.Longtitude))
+
+		j.UsageInResultststs = false
+
+		j.markCompleted(ctx, false)
+
+		return nil, nil, nil
+	}
+
+	if reason := keywordFilterReason(&entry, j.IncludeKeywords, j.ExcludeKeywords); reason != "" {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Info(fmt.Sprintf("keyword filter: skipping %s (%s)", entry.Title, reason))
+
+		metrics.PlacesFiltered.WithLabelValues(reason).Inc()
+
+		j.UsageInResultststs = false
+
+		j.markCompleted(ctx, false)
+
+		return nil, nil, nil
+	}
+
+	// the places without a valid cid have no stable id and are always kept
+	if j.PlaceDeduper != nil && cidPattern.MatchString(entry.Cid) && !j.PlaceDeduper.AddIfNotExists(ctx, entry.Cid) {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Info(fmt.Sprintf("dedup: skipping %s (cid %s)", entry.Title, entry.Cid))
+
+		j.UsageInResultststs = false
+
+		j.markCompleted(ctx, false)
+
+		return nil, nil, nil
+	}
+
 	if j.ExtractEmail && entry.IsWebsiteValidForEmail() {
 		opts := []EmailExtractJobOptions{}
 		if j.ExitMonitor != nil {
 			opts = append(opts, WithEmailJobExitMonitor(j.ExitMonitor))
 		}
 
+		if j.EmailFetcher != nil {
+			opts = append(opts, WithEmailJobFetcher(j.EmailFetcher))
+		}
+
+		if j.ContactRules != nil {
+			opts = append(opts, WithEmailJobContactRules(j.ContactRules))
+		}
+
+		if j.EmailTimeout > 0 {
+			opts = append(opts, WithEmailJobTimeout(j.EmailTimeout))
+		}
+
+		if j.EmailRetries > 0 {
+			opts = append(opts, WithEmailJobRetries(j.EmailRetries))
+		}
+
+		if j.Tracker != nil {
+			opts = append(opts, WithEmailJobTracker(j.Tracker))
+		}
+
+		if j.Deriver != nil {
+			opts = append(opts, WithEmailJobDeriver(j.Deriver))
+		}
+
 		emailJob := NewEmailJob(j.ID, &entry, opts...)
 
 		j.UsageInResultststs = false
 
 		return nil, []scrapemate.IJob{emailJob}, nil
-	} else if j.ExitMonitor != nil {
-		j.ExitMonitor.IncrPlacesCompleted(1)
 	}
 
+	if j.ContactRules != nil {
+		j.ContactRules.validateContacts(ctx, &entry)
+	}
+
+	if j.Deriver != nil {
+		j.Deriver.Derive(&entry)
+	}
+
+	j.markCompleted(ctx, true)
+
+	metrics.PlacesScraped.Inc()
+
 	return &entry, nil, err
 }
 
-func (j *PlaceJob) BrowserActions(_ context.Context, page playwright.Page) scrapemate.Response {
+func (j *PlaceJob) markCompleted(ctx context.Context, saved bool) {
+	if j.ExitMonitor != nil {
+		j.ExitMonitor.IncrPlacesCompleted(1)
+	}
+
+	// the saved places are completed in the checkpoint by the writer,
+	// once their rows are on disk
+	if j.Checkpoint != nil && !saved {
+		j.Checkpoint.IncrPlacesCompleted(j.ParentID, 1)
+	}
+
+	j.trackDone(ctx, saved)
+}
+
+func (j *PlaceJob) trackDone(ctx context.Context, saved bool) {
+	if j.Tracker != nil {
+		j.Tracker.PlaceDone(ctx, j.ParentID, saved)
+	}
+}
+
+func (j *PlaceJob) BrowserActions(ctx context.Context, page playwright.Page) scrapemate.Response {
 	var resp scrapemate.Response
 
+	waitThrottle(ctx, j.Throttler, j.ParentID)
+
+	if jobCancelled(ctx, j.Canceller, j.ParentID) {
+		resp.Meta = map[string]any{metaCancelled: true}
+
+		return resp
+	}
+
+	release, err := acquireSlot(ctx, j.Limiter)
+	if err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
+	defer func() { release(isBlocked(page, &resp)) }()
+
+	if j.Trace != nil {
+		stop := startTrace(page, j.Trace)
+		defer func() { stop(j.ID, resp.Error != nil) }()
+	}
+
+	if j.PlaceTimeout <= 0 {
+		resp = j.scrape(ctx, page)
+
+		return resp
+	}
+
+	resp = scrapeWithTimeout(ctx, page, j.PlaceTimeout, ErrPlaceTimeout, j.scrape)
+	if errors.Is(resp.Error, ErrPlaceTimeout) {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Info(fmt.Sprintf("%v: %s", resp.Error, j.GetURL()))
+	}
+
+	return resp
+}
+
+// scrapeWithTimeout runs scrape on page with a deadline of d, the error of
+// the response wraps timeoutErr when it's reached. Playwright doesn't take a
+// context, the page is closed on the deadline to abort the pending
+// navigation instead. Scrapemate opens a new page for the next job.
+func scrapeWithTimeout(ctx context.Context, page playwright.Page, d time.Duration, timeoutErr error,
+	scrape func(context.Context, playwright.Page) scrapemate.Response,
+) scrapemate.Response {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() { _ = page.Close() })
+	defer stop()
+
+	resp := scrape(ctx, page)
+
+	if resp.Error != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		resp.Error = fmt.Errorf("%w: not done within %s", timeoutErr, d)
+	}
+
+	return resp
+}
+
+// scrape extracts the data of the place from page
+func (j *PlaceJob) scrape(ctx context.Context, page playwright.Page) scrapemate.Response {
+	var resp scrapemate.Response
+
+	if err := setBrowserIdentity(page, j.UserAgents, j.URLParams["hl"]); err != nil {
+		resp.Error = err
+
+		return resp
+	}
+
 	pageResponse, err := page.Goto(j.GetURL(), playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
 	})
@@ -110,6 +562,8 @@ func (j *PlaceJob) BrowserActions(_ context.Context, page playwright.Page) scrap
 		return resp
 	}
 
+	reportBlockedProxy(j.ProxyMonitor, page, pageResponse)
+
 	if err = clickRejectCookiesIfRequired(page); err != nil {
 		resp.Error = err
 
@@ -160,13 +614,68 @@ func (j *PlaceJob) BrowserActions(_ context.Context, page playwright.Page) scrap
 
 	resp.Meta["json"] = []byte(raw)
 
+	if len(j.CustomFields) > 0 {
+		resp.Meta["custom_fields"] = extractCustomFields(page, j.CustomFields)
+	}
+
+	// the booking links of the place data are always read, the button is
+	// only waited for when asked since it slows every page down
+	if j.BookingWidget {
+		if bookingLink := getBookingLink(page); bookingLink != "" {
+			resp.Meta["booking_link"] = bookingLink
+		}
+	}
+
+	// the reviews panel replaces the overview, so it's opened last
+	if j.MaxReviews > 0 {
+		reviews, err := scrapeReviews(ctx, page, j.MaxReviews)
+		if err != nil {
+			log := scrapemate.GetLoggerFromContext(ctx)
+			log.Info(fmt.Sprintf("reviews: skipping %s: %v", j.GetURL(), err))
+		} else {
+			resp.Meta["reviews"] = reviews
+		}
+	}
+
 	return resp
 }
 
+// getBookingLink returns the link of the online booking button of the place page.
+// The booking widget is rendered asynchronously, so we give it a short time to appear.
+func getBookingLink(page playwright.Page) string {
+	const (
+		sel     = `a[data-item-id="action:4"], a[data-item-id^="action:book"]`
+		timeout = 750
+	)
+
+	loc := page.Locator(sel).First()
+
+	err := loc.WaitFor(playwright.LocatorWaitForOptions{
+		State:   playwright.WaitForSelectorStateAttached,
+		Timeout: playwright.Float(timeout),
+	})
+	if err != nil {
+		return ""
+	}
+
+	href, err := loc.GetAttribute("href")
+	if err != nil {
+		return ""
+	}
+
+	return href
+}
+
 func (j *PlaceJob) UseInResults() bool {
 	return j.UsageInResultststs
 }
 
+// ProcessOnFetchError returns true so the failed jobs are counted
+// and queued again or moved to the dead letter queue by the provider
+func (j *PlaceJob) ProcessOnFetchError() bool {
+	return true
+}
+
 const js = `
 function parse() {
   const inputString = window.APP_INITIALIZATION_STATE[3][6]
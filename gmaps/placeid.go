@@ -0,0 +1,68 @@
+package gmaps
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// dataIDPattern matches the DataID of the places (e.g. 0x47e66e2964e34e2d:0x8ddca9ee380ef7e0)
+	dataIDPattern = regexp.MustCompile(`^0x[0-9a-f]+:0x[0-9a-f]+$`)
+	// cidPattern matches the Cid of the places
+	cidPattern = regexp.MustCompile(`^[0-9]{5,20}$`)
+	// placeIDPattern matches the place ids of the places API (e.g. ChIJLU7jZClu5kcR4PcOOO6p3I0)
+	placeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,}$`)
+)
+
+// PlaceURL returns the google maps url of the place with placeID.
+// placeID can be a place id of the places API, the DataID or the Cid of a place.
+// It returns ErrInvalidPlaceID for any other value.
+func PlaceURL(placeID string) (string, error) {
+	switch {
+	case dataIDPattern.MatchString(placeID):
+		return "https://www.google.com/maps/place/data=!4m2!3m1!1s" + placeID, nil
+	case cidPattern.MatchString(placeID):
+		return "https://maps.google.com/?cid=" + placeID, nil
+	case placeIDPattern.MatchString(placeID):
+		return "https://www.google.com/maps/place/?q=place_id:" + url.QueryEscape(placeID), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidPlaceID, placeID)
+	}
+}
+
+// cidFromDataID returns the Cid of the place with dataID, it's the second
+// half of the DataID in decimal
+func cidFromDataID(dataID string) string {
+	if !dataIDPattern.MatchString(dataID) {
+		return ""
+	}
+
+	_, half, _ := strings.Cut(dataID, ":")
+
+	cid, err := strconv.ParseUint(strings.TrimPrefix(half, "0x"), 16, 64)
+	if err != nil {
+		return ""
+	}
+
+	return strconv.FormatUint(cid, 10)
+}
+
+// canonicalURL returns the google maps url of the place built from its ids,
+// unlike the link of the place it's the same across runs. It's empty when
+// the place has no id.
+func canonicalURL(cid, placeID, dataID string) string {
+	for _, id := range []string{cid, placeID, dataID} {
+		if id == "" {
+			continue
+		}
+
+		if u, err := PlaceURL(id); err == nil {
+			return u
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,114 @@
+package gmaps
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PricePerPerson is the spend per person shown instead of the $ tier, e.g. "€20–30"
+type PricePerPerson struct {
+	Min float64 `json:"min"`
+	// Max is 0 when only a lower bound is shown, e.g. "€100+"
+	Max float64 `json:"max"`
+	// Currency is the ISO code when the symbol is unambiguous, the symbol as shown otherwise
+	Currency string `json:"currency"`
+}
+
+var (
+	// numbers with thousands separators are matched first so that
+	// "1.000" and "1 000" are not read as 1 followed by 000
+	priceNumber = regexp.MustCompile(`\d{1,3}(?:[.,\s\x{00a0}\x{202f}']\d{3})+(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?`)
+	// letter prefixed dollars (R$, US$, CA$) before the bare symbols
+	priceCurrency = regexp.MustCompile(`[A-Z]{1,3}\$|\p{Sc}|CHF|zł|Kč|kr|Ft|lei|Rp|RM`)
+
+	currencyCodes = map[string]string{
+		"€":   "EUR",
+		"£":   "GBP",
+		"₹":   "INR",
+		"₩":   "KRW",
+		"₺":   "TRY",
+		"₪":   "ILS",
+		"₫":   "VND",
+		"₱":   "PHP",
+		"₴":   "UAH",
+		"₦":   "NGN",
+		"฿":   "THB",
+		"R$":  "BRL",
+		"US$": "USD",
+		"CA$": "CAD",
+		"A$":  "AUD",
+		"AU$": "AUD",
+		"NZ$": "NZD",
+		"HK$": "HKD",
+		"MX$": "MXN",
+		"CHF": "CHF",
+		"zł":  "PLN",
+		"Kč":  "CZK",
+		"Ft":  "HUF",
+		"lei": "RON",
+		"Rp":  "IDR",
+		"RM":  "MYR",
+	}
+)
+
+// getPricePerPerson parses the explicit per person range google shows for some
+// restaurants in place of the $ tier. It returns nil for the tiers.
+func getPricePerPerson(s string) *PricePerPerson {
+	nums := priceNumber.FindAllString(s, 2)
+	if len(nums) == 0 {
+		return nil
+	}
+
+	ans := PricePerPerson{}
+
+	var ok bool
+
+	if ans.Min, ok = parseLocalizedNumber(nums[0]); !ok {
+		return nil
+	}
+
+	if len(nums) > 1 {
+		if ans.Max, ok = parseLocalizedNumber(nums[1]); !ok || ans.Max < ans.Min {
+			return nil
+		}
+	} else if !strings.Contains(s, "+") {
+		ans.Max = ans.Min
+	}
+
+	if symbol := priceCurrency.FindString(s); symbol != "" {
+		ans.Currency = symbol
+
+		if code, ok := currencyCodes[symbol]; ok {
+			ans.Currency = code
+		}
+	}
+
+	return &ans
+}
+
+// parseLocalizedNumber parses numbers like 1,000.50 1.000,50 1 000 and 12,5.
+// A separator followed by 3 digits groups thousands, otherwise it's the decimal separator.
+func parseLocalizedNumber(s string) (float64, bool) {
+	var digits strings.Builder
+
+	decimals := ""
+
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 && len(s)-i-1 != 3 {
+		s, decimals = s[:i], s[i+1:]
+	}
+
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+
+	if decimals != "" {
+		digits.WriteString("." + decimals)
+	}
+
+	v, err := strconv.ParseFloat(digits.String(), 64)
+
+	return v, err == nil
+}
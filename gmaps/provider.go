@@ -2,11 +2,290 @@ package gmaps
 
 import (
 	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gosom/scrapemate"
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/gosom/google-maps-scraper/metrics"
+)
+
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobCompleted     = errors.New("job is completed")
+	ErrJobStarted       = errors.New("job has already started")
+	ErrRestrictedRegion = errors.New("region is restricted by policy")
+	ErrInvalidPlaceID   = errors.New("invalid place id")
+	ErrPlaceNotFound    = errors.New("place not found")
+	ErrPlaceTimeout     = errors.New("place timeout")
+	ErrJobNotFinished   = errors.New("job is not finished")
 )
 
 // Provider defines the interface for job queue operations
 type Provider interface {
 	// Push adds a new job to the queue
 	Push(ctx context.Context, job scrapemate.IJob) error
+	// PushBatch adds the jobs to the queue at once, either all or none
+	PushBatch(ctx context.Context, jobs []scrapemate.IJob) error
+	// UpdateThrottle sets the delay applied before each fetch of the job
+	UpdateThrottle(ctx context.Context, jobID string, throttle time.Duration) error
+	// Get returns a job pushed before, whatever its status.
+	// It returns ErrJobNotFound for unknown jobs.
+	Get(ctx context.Context, jobID string) (scrapemate.IJob, error)
+	// Info returns the status and the result count of a job.
+	// It returns ErrJobNotFound for unknown jobs.
+	Info(ctx context.Context, jobID string) (JobInfo, error)
+	// List returns a page of the jobs matching filter, the most recent
+	// first, together with the number of matching jobs
+	List(ctx context.Context, filter JobFilter) ([]JobInfo, int, error)
+	// Delete removes a job that no worker picked yet. It returns ErrJobNotFound
+	// for unknown jobs and ErrJobStarted for the running or completed ones.
+	Delete(ctx context.Context, jobID string) error
+	// Cancel marks a new or running job as cancelled, the workers stop scraping
+	// it on its next place. It returns ErrJobNotFound for unknown jobs and
+	// ErrJobCompleted for the ones that can't be cancelled anymore.
+	Cancel(ctx context.Context, jobID string) error
+}
+
+// JobFilter selects the jobs returned by Provider.List
+type JobFilter struct {
+	// Status keeps only the jobs with this queue status when set
+	Status string
+	// State keeps only the jobs at this step of their lifecycle when set
+	State string
+	// Tenant keeps only the jobs of this API tenant when set
+	Tenant string
+	Limit  int
+	Offset int
+}
+
+// JobInfo is the status of a job pushed to a Provider
+type JobInfo struct {
+	ID string
+	// Type is search or place
+	Type string
+	// Query and Language are set only for the search jobs
+	Query     string
+	Language  string
+	Status    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// State is the step of the lifecycle of the job (pending, running,
+	// completed, failed or cancelled) when the provider tracks it
+	State string
+	// StartedAt is the first time the job was fetched, FinishedAt the time
+	// it ended. They are nil until then or when the provider doesn't track them.
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	// ResultCount is the number of places saved for the job
+	ResultCount int
+	// Tenant is the API tenant the job was created for
+	Tenant string
+}
+
+// Throttler returns the delay that a job should wait before each fetch
+type Throttler interface {
+	Throttle(ctx context.Context, jobID string) time.Duration
+}
+
+func waitThrottle(ctx context.Context, throttler Throttler, jobID string) {
+	if throttler == nil {
+		return
+	}
+
+	delay := throttler.Throttle(ctx, jobID)
+	if delay <= 0 {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+}
+
+// Canceller reports the jobs cancelled while they run
+type Canceller interface {
+	Cancelled(ctx context.Context, jobID string) bool
+}
+
+// metaCancelled is set in the meta of the responses of the jobs
+// that were not fetched because they were cancelled
+const metaCancelled = "cancelled"
+
+func jobCancelled(ctx context.Context, canceller Canceller, jobID string) bool {
+	return canceller != nil && canceller.Cancelled(ctx, jobID)
+}
+
+// Limiter limits the number of google maps pages loaded concurrently
+type Limiter interface {
+	Acquire(ctx context.Context) error
+	Release(blocked bool)
+}
+
+// acquireSlot waits for a slot of limiter. The returned function
+// must be called with the outcome of the request to free the slot.
+func acquireSlot(ctx context.Context, limiter Limiter) (func(blocked bool), error) {
+	if limiter == nil {
+		return func(bool) {}, nil
+	}
+
+	if err := limiter.Acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	return limiter.Release, nil
+}
+
+// isBlocked reports if google blocked the request.
+// Failed page loads count too, since overloaded or rate limited
+// sessions usually show up as timeouts.
+func isBlocked(page playwright.Page, resp *scrapemate.Response) bool {
+	return blockedByGoogle(page, resp.StatusCode) || resp.Error != nil
+}
+
+// blockedByGoogle reports if google answered with a block, a rate limit
+// or the captcha page
+func blockedByGoogle(page playwright.Page, status int) bool {
+	if status == http.StatusTooManyRequests || status == http.StatusForbidden {
+		return true
+	}
+
+	return strings.Contains(page.URL(), "/sorry/")
+}
+
+// Deriver adds the derived fields to the scraped places, they are computed
+// once per place whatever the number of writers
+type Deriver interface {
+	Derive(entry *Entry)
+}
+
+// ProxyMonitor is told about the proxies google blocked
+type ProxyMonitor interface {
+	// Blocked receives the address (ip:port) of the blocked proxy
+	Blocked(addr string)
+}
+
+// reportBlockedProxy tells monitor the proxy of the page when google blocked it.
+// The server address of a response loaded through a proxy is the one of the proxy.
+func reportBlockedProxy(monitor ProxyMonitor, page playwright.Page, pageResponse playwright.Response) {
+	if monitor == nil || pageResponse == nil || !blockedByGoogle(page, pageResponse.Status()) {
+		return
+	}
+
+	addr, err := pageResponse.ServerAddr()
+	if err != nil || addr == nil || addr.IpAddress == "" {
+		return
+	}
+
+	monitor.Blocked(net.JoinHostPort(addr.IpAddress, strconv.Itoa(addr.Port)))
+}
+
+// JobTracker follows the progress of the search jobs, to notify their
+// completion to a webhook and to tell the finished ones apart. It's set by
+// the job provider when the job is fetched.
+type JobTracker interface {
+	// PlacesFound records the number of places the search job found
+	PlacesFound(ctx context.Context, jobID string, n int)
+	// PlaceDone records that a place of the search job is done, saved is
+	// false when it was skipped or could not be parsed
+	PlaceDone(ctx context.Context, jobID string, saved bool)
+}
+
+// DeadLetter receives the jobs that failed after all their retries.
+// The provider queues them again while they have attempts left.
+type DeadLetter interface {
+	DeadLetter(ctx context.Context, jobID string, reason error) error
+}
+
+// Acker is told when a fetched job is done. The provider queues again the
+// jobs that were fetched but not acked when the scraper stops or crashes.
+type Acker interface {
+	Ack(ctx context.Context, jobID string) error
+}
+
+// ackJob tells acker that the job is done, errors are only logged
+func ackJob(ctx context.Context, acker Acker, jobID string) {
+	if acker == nil {
+		return
+	}
+
+	if err := acker.Ack(ctx, jobID); err != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error("failed to ack job", "job", jobID, "error", err)
+	}
+}
+
+// DeadLetterJob is a job kept in the dead letter queue
+type DeadLetterJob struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+// DeadLetterQueue keeps the failed jobs out of the main queue
+// until they are inspected and requeued
+type DeadLetterQueue interface {
+	DeadLetter
+	// ListDeadLetters returns the most recently failed jobs, only the
+	// ones of tenant when it's not empty
+	ListDeadLetters(ctx context.Context, limit int, tenant string) ([]DeadLetterJob, error)
+	// Requeue moves a job from the dead letter queue back to the main queue.
+	// It returns ErrJobNotFound if the job is not in the dead letter queue.
+	Requeue(ctx context.Context, jobID string) error
+}
+
+// ResultReader reads back the places saved for the jobs
+type ResultReader interface {
+	// Results calls fn with the json of every place of the finished job, in
+	// the order they were saved. It returns ErrJobNotFound for unknown jobs
+	// and ErrJobNotFinished, before calling fn, for the ones still running
+	// unless partial is set: the places saved so far are read then.
+	Results(ctx context.Context, jobID string, partial bool, fn func(data []byte) error) error
+}
+
+// fetchFailed counts the failed fetch of a job and reports it to dl
+func fetchFailed(ctx context.Context, dl DeadLetter, jobID string, resp *scrapemate.Response) {
+	// the fetch was interrupted by the shutdown, the provider queues the job again
+	if ctx.Err() != nil {
+		return
+	}
+
+	metrics.JobsFailed.WithLabelValues(failureReason(resp)).Inc()
+
+	deadLetter(ctx, dl, jobID, resp.Error)
+}
+
+// failureReason classifies the fetch error of resp for the metrics
+func failureReason(resp *scrapemate.Response) string {
+	switch {
+	case errors.Is(resp.Error, context.DeadlineExceeded) || errors.Is(resp.Error, ErrPlaceTimeout) ||
+		strings.Contains(strings.ToLower(resp.Error.Error()), "timeout"):
+		return metrics.ReasonTimeout
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden:
+		return metrics.ReasonBlocked
+	default:
+		return metrics.ReasonFetch
+	}
+}
+
+// deadLetter reports the fetch error of a job to dl.
+// The job is dropped by scrapemate anyway, so errors are only logged.
+func deadLetter(ctx context.Context, dl DeadLetter, jobID string, reason error) {
+	if dl == nil {
+		return
+	}
+
+	if err := dl.DeadLetter(ctx, jobID, reason); err != nil {
+		log := scrapemate.GetLoggerFromContext(ctx)
+		log.Error("failed to move job to the dead letter queue", "job", jobID, "error", err)
+	}
 }
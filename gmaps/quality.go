@@ -0,0 +1,40 @@
+package gmaps
+
+// sparseThreshold is the completeness under which a listing is
+// considered sparse. Such listings are usually stubs that google
+// prompts the visitors to complete ("Suggest an edit").
+const sparseThreshold = 0.4
+
+// Completeness returns the fraction of the main listing sections
+// that are filled, from 0 to 1.
+func (e *Entry) Completeness() float64 {
+	sections := []bool{
+		e.Category != "",
+		e.Address != "",
+		e.Phone != "",
+		e.WebSite != "",
+		len(e.OpenHours) > 0,
+		e.Description != "",
+		e.PriceRange != "",
+		e.ReviewCount > 0,
+		len(e.Images) > 0,
+		len(e.About) > 0,
+		e.PlusCode != "",
+		e.Owner.ID != "",
+	}
+
+	filled := 0
+
+	for _, ok := range sections {
+		if ok {
+			filled++
+		}
+	}
+
+	return float64(filled) / float64(len(sections))
+}
+
+// IsSparse reports if the listing has few filled sections
+func (e *Entry) IsSparse() bool {
+	return e.Completeness() < sparseThreshold
+}
@@ -0,0 +1,93 @@
+package gmaps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gosom/google-maps-scraper/geocode"
+)
+
+// ParseRegions parses a comma separated list of country codes
+// (ISO 3166-1 alpha-2, e.g. "CN,RU") into upper case codes.
+func ParseRegions(s string) []string {
+	var regions []string
+
+	for _, r := range strings.Split(s, ",") {
+		r = strings.ToUpper(strings.TrimSpace(r))
+		if r != "" {
+			regions = append(regions, r)
+		}
+	}
+
+	return regions
+}
+
+// IsRestrictedRegion reports whether country is one of the restricted regions
+func IsRestrictedRegion(country string, restricted []string) bool {
+	if country == "" {
+		return false
+	}
+
+	for _, r := range restricted {
+		if strings.EqualFold(country, r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckRegion returns ErrRestrictedRegion when the coordinates of the search
+// resolve to one of the restricted regions, so that its pages are never opened.
+// Only the searches with explicit coordinates (-geo, -location or a polygon)
+// are checked, guessing the country of a free text query is unreliable. The
+// searches whose country can't be resolved are allowed, their places are
+// still checked once scraped.
+func CheckRegion(ctx context.Context, g geocode.Geocoder, restricted []string, query, geoCoordinates string) error {
+	if len(restricted) == 0 || g == nil || geoCoordinates == "" {
+		return nil
+	}
+
+	lat, lon, ok := parseCoordinates(geoCoordinates)
+	if !ok {
+		return fmt.Errorf("invalid coordinates %q", geoCoordinates)
+	}
+
+	res, err := g.Reverse(ctx, lat, lon)
+
+	switch {
+	case errors.Is(err, geocode.ErrNotFound):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to resolve the region of %q: %w", query, err)
+	}
+
+	if IsRestrictedRegion(res.Country, restricted) {
+		return fmt.Errorf("%w: %q resolves to %s", ErrRestrictedRegion, query, res.Country)
+	}
+
+	return nil
+}
+
+// parseCoordinates parses coordinates in the format of the -geo flag
+func parseCoordinates(s string) (lat, lon float64, ok bool) {
+	latS, lonS, found := strings.Cut(strings.ReplaceAll(s, " ", ""), ",")
+	if !found {
+		return 0, 0, false
+	}
+
+	lat, err := strconv.ParseFloat(latS, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	lon, err = strconv.ParseFloat(lonS, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return lat, lon, true
+}
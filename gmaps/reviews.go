@@ -0,0 +1,150 @@
+package gmaps
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const (
+	// reviewSelector matches the reviews of the reviews panel
+	reviewSelector = `div[data-review-id].jftiEf`
+	// reviewsTabSelector matches the button opening the reviews panel,
+	// by its action first since the label depends on the language
+	reviewsTabSelector = `button[jsaction*="moreReviews"], button[role="tab"][aria-label*="Reviews" i]`
+	// reviewsScrollPause is the time given to the next reviews to load after a scroll
+	reviewsScrollPause = 1000
+	// reviewsMaxStaleScrolls stops the scrolling after this many scrolls in a row
+	// that load no new review, the end of the reviews or a panel that stopped loading
+	reviewsMaxStaleScrolls = 3
+)
+
+// reviewsScrollJS scrolls the panel of the reviews to its end and returns the number of reviews loaded
+const reviewsScrollJS = `(sel) => {
+  const items = document.querySelectorAll(sel);
+  if (items.length === 0) {
+    return 0;
+  }
+
+  let el = items[items.length - 1];
+  while (el && el.scrollHeight <= el.clientHeight) {
+    el = el.parentElement;
+  }
+
+  if (el) {
+    el.scrollTop = el.scrollHeight;
+  }
+
+  return items.length;
+}`
+
+// reviewsExtractJS expands the truncated texts and returns up to max reviews
+const reviewsExtractJS = `([sel, max]) => {
+  document.querySelectorAll(sel + ' button.w8nwRe').forEach((b) => b.click());
+
+  return Array.from(document.querySelectorAll(sel)).slice(0, max).map((el) => {
+    const text = (s) => {
+      const n = el.querySelector(s);
+      return n ? n.textContent.trim() : '';
+    };
+    const stars = el.querySelector('span.kvMYJc, span[role="img"][aria-label]');
+
+    return {
+      name: text('.d4r55'),
+      rating: stars ? stars.getAttribute('aria-label') : '',
+      when: text('.rsqaWe'),
+      text: text('.wiI7pd'),
+    };
+  });
+}`
+
+// scrapeReviews opens the reviews panel of the place page and scrolls it until
+// max reviews are loaded or the scrolls stop loading new ones. It returns the
+// reviews loaded so far when the panel can't be scrolled further.
+func scrapeReviews(ctx context.Context, page playwright.Page, maxReviews int) ([]Review, error) {
+	const timeout = 5000
+
+	tab := page.Locator(reviewsTabSelector).First()
+
+	if err := tab.Click(playwright.LocatorClickOptions{Timeout: playwright.Float(timeout)}); err != nil {
+		return nil, fmt.Errorf("reviews panel not found: %w", err)
+	}
+
+	err := page.Locator(reviewSelector).First().WaitFor(playwright.LocatorWaitForOptions{
+		State:   playwright.WaitForSelectorStateAttached,
+		Timeout: playwright.Float(timeout),
+	})
+	if err != nil {
+		// the place has no reviews
+		return nil, nil
+	}
+
+	loaded, stale := 0, 0
+
+	for loaded < maxReviews && stale < reviewsMaxStaleScrolls && ctx.Err() == nil {
+		countI, err := page.Evaluate(reviewsScrollJS, reviewSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		//nolint:staticcheck // TODO replace with the new playwright API
+		page.WaitForTimeout(reviewsScrollPause)
+
+		count, _ := countI.(int)
+		if count <= loaded {
+			stale++
+		} else {
+			stale = 0
+			loaded = count
+		}
+	}
+
+	rawI, err := page.Evaluate(reviewsExtractJS, []any{reviewSelector, maxReviews})
+	if err != nil {
+		return nil, err
+	}
+
+	items, _ := rawI.([]any)
+	ans := make([]Review, 0, len(items))
+
+	for i := range items {
+		item, ok := items[i].(map[string]any)
+		if !ok {
+			continue
+		}
+
+		review := Review{
+			Name:        stringField(item, "name"),
+			Rating:      parseStars(stringField(item, "rating")),
+			When:        stringField(item, "when"),
+			Description: stringField(item, "text"),
+		}
+
+		if review.Name == "" {
+			continue
+		}
+
+		ans = append(ans, review)
+	}
+
+	return ans, nil
+}
+
+func stringField(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+
+	return s
+}
+
+// parseStars returns the rating of a label like "4 stars" or "5 Sterne",
+// the first digit is the rating whatever the language
+func parseStars(label string) int {
+	for _, r := range label {
+		if r >= '1' && r <= '5' {
+			return int(r - '0')
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,124 @@
+package gmaps
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	// maxEmails caps the emails kept per website, pages listing thousands of
+	// addresses are directories rather than the contacts of the place
+	maxEmails = 20
+	// maxSocialLinks caps the profiles kept per network
+	maxSocialLinks = 5
+)
+
+// SocialLinks are the profiles of the place linked from its website
+type SocialLinks struct {
+	Facebook  []string `json:"facebook"`
+	Instagram []string `json:"instagram"`
+	LinkedIn  []string `json:"linkedin"`
+	Twitter   []string `json:"twitter"`
+}
+
+// socialHosts maps the hosts of the social networks to their network
+var socialHosts = map[string]string{
+	"facebook.com":  "facebook",
+	"fb.com":        "facebook",
+	"instagram.com": "instagram",
+	"linkedin.com":  "linkedin",
+	"twitter.com":   "twitter",
+	"x.com":         "twitter",
+}
+
+// socialSharePaths are the prefixes of the share and login links, they
+// point to the network rather than to a profile of the place
+var socialSharePaths = []string{
+	"/sharer", "/share", "/intent", "/dialog", "/plugins", "/login", "/home",
+}
+
+// docSocialLinks returns the social profiles linked from doc, or nil when
+// there are none
+func docSocialLinks(doc *goquery.Document) *SocialLinks {
+	var ans SocialLinks
+
+	seen := map[string]bool{}
+	found := false
+
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+
+		network, link, ok := socialProfile(href)
+		if !ok || seen[link] {
+			return
+		}
+
+		var links *[]string
+
+		switch network {
+		case "facebook":
+			links = &ans.Facebook
+		case "instagram":
+			links = &ans.Instagram
+		case "linkedin":
+			links = &ans.LinkedIn
+		case "twitter":
+			links = &ans.Twitter
+		}
+
+		if len(*links) >= maxSocialLinks {
+			return
+		}
+
+		seen[link] = true
+		found = true
+
+		*links = append(*links, link)
+	})
+
+	if !found {
+		return nil
+	}
+
+	return &ans
+}
+
+// socialProfile returns the network and the normalized link of href when
+// it's the profile of a social network
+func socialProfile(href string) (network, link string, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", "", false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+
+	network, ok = socialHosts[host]
+	if !ok {
+		return "", "", false
+	}
+
+	path := strings.TrimRight(u.Path, "/")
+	if path == "" {
+		return "", "", false
+	}
+
+	lower := strings.ToLower(path)
+	for _, prefix := range socialSharePaths {
+		if lower == prefix || strings.HasPrefix(lower, prefix+"/") || strings.HasPrefix(lower, prefix+".") {
+			return "", "", false
+		}
+	}
+
+	// facebook keeps the id of the profile.php links in the query
+	query := ""
+	if network == "facebook" && u.Query().Get("id") != "" {
+		query = "?id=" + u.Query().Get("id")
+	}
+
+	return network, "https://" + host + path + query, true
+}
@@ -0,0 +1,182 @@
+package gmaps
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SpamWeights are the weights of the signals of the spam score.
+// Only their ratio matters, the score is normalized by their sum.
+type SpamWeights struct {
+	// NoReviews is the weight of listings without any review
+	NoReviews float64
+	// GenericName is the weight of titles made only of the category and generic words
+	GenericName float64
+	// KeywordStuffedName is the weight of titles packed with keywords, cities and separators
+	KeywordStuffedName float64
+	// NoWebsite is the weight of listings without a website
+	NoWebsite float64
+	// NoPhone is the weight of listings without a phone
+	NoPhone float64
+}
+
+// DefaultSpamWeights are the weights used unless configured otherwise
+var DefaultSpamWeights = SpamWeights{
+	NoReviews:          0.3,
+	GenericName:        0.2,
+	KeywordStuffedName: 0.25,
+	NoWebsite:          0.15,
+	NoPhone:            0.1,
+}
+
+// spamWeightNames maps the names accepted by ParseSpamWeights to the weights
+var spamWeightNames = map[string]func(*SpamWeights) *float64{
+	"no_reviews":           func(w *SpamWeights) *float64 { return &w.NoReviews },
+	"generic_name":         func(w *SpamWeights) *float64 { return &w.GenericName },
+	"keyword_stuffed_name": func(w *SpamWeights) *float64 { return &w.KeywordStuffedName },
+	"no_website":           func(w *SpamWeights) *float64 { return &w.NoWebsite },
+	"no_phone":             func(w *SpamWeights) *float64 { return &w.NoPhone },
+}
+
+// genericWords are the words of titles like "Best 24/7 Emergency Plumber Near Me",
+// together with the words of the category they make a generic name
+var genericWords = map[string]bool{
+	"best": true, "top": true, "cheap": true, "affordable": true, "local": true,
+	"near": true, "me": true, "24/7": true, "24": true, "hour": true, "hours": true,
+	"emergency": true, "professional": true, "pro": true, "pros": true, "service": true,
+	"services": true, "company": true, "co": true, "experts": true, "expert": true,
+	"repair": true, "repairs": true, "the": true, "and": true, "&": true, "in": true,
+	"of": true, "a": true, "licensed": true, "certified": true, "fast": true, "same": true,
+	"day": true, "quality": true, "trusted": true, "#1": true, "no.1": true,
+}
+
+var (
+	titleSeparators = regexp.MustCompile(`\s[|\-–—•]\s|,|\|`)
+	stuffingPhrases = regexp.MustCompile(`(?i)near me|24/7|24 hours?|open now|cheap|best|#1|no\.? ?1\b`)
+)
+
+// maxTitleWords is the number of words above which a title is considered keyword stuffed
+const maxTitleWords = 8
+
+// ParseSpamWeights parses comma separated name=weight pairs, e.g.
+// "no_reviews=0.5,no_phone=0". The weights not given keep their default.
+func ParseSpamWeights(s string) (SpamWeights, error) {
+	ans := DefaultSpamWeights
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return SpamWeights{}, fmt.Errorf("invalid spam weight %q, expected name=weight", pair)
+		}
+
+		field, ok := spamWeightNames[strings.TrimSpace(name)]
+		if !ok {
+			return SpamWeights{}, fmt.Errorf("unknown spam signal %q", name)
+		}
+
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || v < 0 {
+			return SpamWeights{}, fmt.Errorf("invalid weight of %s: %q", name, value)
+		}
+
+		*field(&ans) = v
+	}
+
+	if ans.total() == 0 {
+		return SpamWeights{}, fmt.Errorf("at least one spam weight must be greater than 0")
+	}
+
+	return ans, nil
+}
+
+func (w *SpamWeights) total() float64 {
+	return w.NoReviews + w.GenericName + w.KeywordStuffedName + w.NoWebsite + w.NoPhone
+}
+
+// spamScore returns how likely the listing is spam from 0 to 1, the weighted
+// fraction of the suspicious signals it shows. It's a heuristic: legit new or
+// small businesses can score high and spam listings with fake reviews low.
+func (e *Entry) spamScore(w *SpamWeights) float64 {
+	total := w.total()
+	if total == 0 {
+		return 0
+	}
+
+	signals := []struct {
+		ok     bool
+		weight float64
+	}{
+		{e.ReviewCount == 0, w.NoReviews},
+		{isGenericName(e.Title, e.Categories), w.GenericName},
+		{isKeywordStuffed(e.Title), w.KeywordStuffedName},
+		{e.WebSite == "", w.NoWebsite},
+		{e.Phone == "", w.NoPhone},
+	}
+
+	var score float64
+
+	for _, s := range signals {
+		if s.ok {
+			score += s.weight
+		}
+	}
+
+	return math.Round(score/total*100) / 100
+}
+
+// isGenericName reports if the title is only made of the words of
+// the categories and generic words, e.g. "Plumber" or "Best Plumbers"
+func isGenericName(title string, categories []string) bool {
+	words := strings.Fields(strings.ToLower(title))
+	if len(words) == 0 {
+		return false
+	}
+
+	category := make(map[string]bool)
+
+	for _, c := range categories {
+		for _, w := range strings.Fields(strings.ToLower(c)) {
+			category[w] = true
+			// "Plumbers" for the "Plumber" category
+			category[w+"s"] = true
+		}
+	}
+
+	hasCategoryWord := false
+
+	for _, w := range words {
+		w = strings.Trim(w, ".,:;!-")
+
+		switch {
+		case category[w]:
+			hasCategoryWord = true
+		case genericWords[w], w == "":
+		default:
+			return false
+		}
+	}
+
+	return hasCategoryWord
+}
+
+// isKeywordStuffed reports if the title is packed with keywords, like
+// "Plumber Austin | Emergency Plumbing | Drain Cleaning Near Me"
+func isKeywordStuffed(title string) bool {
+	if len(strings.Fields(title)) > maxTitleWords {
+		return true
+	}
+
+	if len(titleSeparators.FindAllString(title, -1)) >= 2 {
+		return true
+	}
+
+	return len(stuffingPhrases.FindAllString(title, -1)) >= 2
+}
@@ -0,0 +1,133 @@
+package gmaps
+
+import (
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// thirdPartyScale is the scale the third party ratings are normalized to, the one of google
+const thirdPartyScale = 5
+
+// thirdPartySources maps the names and hosts of the review sites google
+// aggregates in the "Reviews from the web" block to the keys of ThirdPartyRatings
+var thirdPartySources = map[string]string{
+	"tripadvisor":  "tripadvisor",
+	"yelp":         "yelp",
+	"facebook":     "facebook",
+	"booking.com":  "booking",
+	"expedia":      "expedia",
+	"hotels.com":   "hotels.com",
+	"agoda":        "agoda",
+	"trip.com":     "trip.com",
+	"kayak":        "kayak",
+	"priceline":    "priceline",
+	"opentable":    "opentable",
+	"thefork":      "thefork",
+	"lafourchette": "thefork",
+	"zomato":       "zomato",
+	"foursquare":   "foursquare",
+	"trustpilot":   "trustpilot",
+	"holidaycheck": "holidaycheck",
+	"gayot":        "gayot",
+	"zagat":        "zagat",
+	"michelin":     "michelin",
+}
+
+// thirdPartyScore matches the scores as shown, e.g. "4.5/5" or "8,7/10"
+var thirdPartyScore = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)\s*/\s*(\d+)$`)
+
+// getThirdPartyRatings returns the ratings of the third party review sites keyed
+// by source and normalized to the 0-5 scale of google, or nil when google shows none.
+// The block has no stable index, so it looks for the arrays holding both a score
+// and the name or the link of a known review site.
+func getThirdPartyRatings(darray []any) map[string]float64 {
+	var ans map[string]float64
+
+	walkArrays(darray, func(node []any) {
+		var (
+			score  float64
+			source string
+		)
+
+		for i := range node {
+			s, ok := node[i].(string)
+			if !ok {
+				continue
+			}
+
+			if v, ok := parseThirdPartyScore(s); ok {
+				if score == 0 {
+					score = v
+				}
+
+				continue
+			}
+
+			if source == "" {
+				source = thirdPartySource(s)
+			}
+		}
+
+		if score == 0 || source == "" {
+			return
+		}
+
+		if ans == nil {
+			ans = make(map[string]float64)
+		}
+
+		if _, ok := ans[source]; !ok {
+			ans[source] = score
+		}
+	})
+
+	return ans
+}
+
+// parseThirdPartyScore parses a score like "8,7/10" into the 0-5 scale
+func parseThirdPartyScore(s string) (float64, bool) {
+	m := thirdPartyScore.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+
+	v, err := strconv.ParseFloat(strings.Replace(m[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	scale, err := strconv.ParseFloat(m[2], 64)
+	if err != nil || scale == 0 || v <= 0 || v > scale {
+		return 0, false
+	}
+
+	return math.Round(v/scale*thirdPartyScale*100) / 100, true
+}
+
+// thirdPartySource returns the key of the review site named or linked by s, if any
+func thirdPartySource(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	// the labels are short, longer strings are descriptions or reviews mentioning a site
+	if strings.HasPrefix(s, "http") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return ""
+		}
+
+		s = strings.TrimPrefix(u.Hostname(), "www.")
+	} else if len(s) > 30 {
+		return ""
+	}
+
+	for name, key := range thirdPartySources {
+		if strings.Contains(s, name) {
+			return key
+		}
+	}
+
+	return ""
+}
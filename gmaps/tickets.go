@@ -0,0 +1,97 @@
+package gmaps
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ticketsLabel matches the title of a "Tickets" actions group
+// in the languages we support.
+var ticketsLabel = regexp.MustCompile(`(?i)ticket|billet|entrad|bigliett|eintritt|ingresso|εισιτήρι`)
+
+// ticketProviders are the hosts of the ticketing providers google links to
+var ticketProviders = []string{
+	"tiqets.com",
+	"getyourguide",
+	"viator.com",
+	"klook.com",
+	"musement.com",
+	"headout.com",
+	"civitatis.com",
+	"ticketmaster",
+	"eventbrite",
+	"tripadvisor",
+	"ticketone.it",
+	"eventim",
+	"seetickets",
+	"fever",
+}
+
+// getTicketLinks returns the links of the ticket providers of the place
+// keyed by the provider name. Google shows them in the same actions block
+// as the order online links, but only for attractions, museums, venues etc.
+// so it returns nil for the other places.
+func getTicketLinks(darray []any) map[string]string {
+	groups := getNthElementAndCast[[]any](darray, 75, 0)
+
+	var ans map[string]string
+
+	for i := range groups {
+		group, ok := groups[i].([]any)
+		if !ok || len(group) < 3 {
+			continue
+		}
+
+		// the items are at index 2, the rest of the group may hold a title
+		var labels []string
+
+		for j := range group {
+			if j != 2 {
+				labels = collectStrings(group[j], labels)
+			}
+		}
+
+		ticketsGroup := ticketsLabel.MatchString(strings.Join(labels, " "))
+
+		items := getLinkSource(getLinkSourceParams{
+			arr:    getNthElementAndCast[[]any](group, 2),
+			link:   []int{1, 2, 0},
+			source: []int{0, 0},
+		})
+
+		for _, item := range items {
+			if !ticketsGroup && !isTicketProvider(item) {
+				continue
+			}
+
+			if ans == nil {
+				ans = make(map[string]string)
+			}
+
+			if _, ok := ans[item.Source]; !ok {
+				ans[item.Source] = item.Link
+			}
+		}
+	}
+
+	return ans
+}
+
+func isTicketProvider(item LinkSource) bool {
+	host := item.Source
+
+	if u, err := url.Parse(item.Link); err == nil {
+		host += " " + u.Hostname()
+	}
+
+	host = strings.ToLower(host)
+
+	for _, p := range ticketProviders {
+		if strings.Contains(host, p) {
+			return true
+		}
+	}
+
+	return false
+}
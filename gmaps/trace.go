@@ -0,0 +1,103 @@
+package gmaps
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const defaultMaxTraces = 100
+
+// TraceConfig configures the recording of playwright traces.
+// The traces can be opened with `npx playwright show-trace <file>`.
+type TraceConfig struct {
+	// Dir is the directory where the trace files are stored
+	Dir string
+	// FailedOnly keeps only the traces of the jobs that failed
+	FailedOnly bool
+	// MaxTraces is the maximum number of trace files kept in Dir.
+	// The oldest ones are removed when the limit is exceeded.
+	MaxTraces int
+}
+
+var pruneMu sync.Mutex
+
+// startTrace starts recording a trace in the browser context of the page.
+// The returned function stops the recording and saves the trace
+// if it should be kept.
+func startTrace(page playwright.Page, cfg *TraceConfig) func(id string, failed bool) {
+	tracing := page.Context().Tracing()
+
+	err := tracing.Start(playwright.TracingStartOptions{
+		Screenshots: playwright.Bool(true),
+		Snapshots:   playwright.Bool(true),
+	})
+	if err != nil {
+		return func(string, bool) {}
+	}
+
+	return func(id string, failed bool) {
+		if cfg.FailedOnly && !failed {
+			_ = tracing.Stop()
+
+			return
+		}
+
+		name := time.Now().UTC().Format("20060102T150405.000") + "-" + sanitizeTraceName(id) + ".zip"
+
+		if err := tracing.Stop(filepath.Join(cfg.Dir, name)); err != nil {
+			return
+		}
+
+		pruneTraces(cfg.Dir, cfg.MaxTraces)
+	}
+}
+
+func pruneTraces(dir string, limit int) {
+	if limit <= 0 {
+		limit = defaultMaxTraces
+	}
+
+	pruneMu.Lock()
+	defer pruneMu.Unlock()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	var traces []string
+
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".zip") {
+			traces = append(traces, e.Name())
+		}
+	}
+
+	if len(traces) <= limit {
+		return
+	}
+
+	// the names start with the timestamp so the oldest come first
+	sort.Strings(traces)
+
+	for _, name := range traces[:len(traces)-limit] {
+		_ = os.Remove(filepath.Join(dir, name))
+	}
+}
+
+func sanitizeTraceName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
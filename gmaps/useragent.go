@@ -0,0 +1,91 @@
+package gmaps
+
+import (
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// UserAgents picks the user agent of the browsers
+type UserAgents interface {
+	// UserAgent returns the user agent of the browser of page
+	UserAgent(page playwright.Page) string
+}
+
+type userAgentPool struct {
+	agents []string
+
+	mu        sync.Mutex
+	next      int
+	byBrowser map[playwright.BrowserContext]string
+}
+
+// NewUserAgentPool returns UserAgents giving the browsers the agents in
+// turn. A browser keeps its user agent until it's closed, like its proxy.
+func NewUserAgentPool(agents []string) UserAgents {
+	return &userAgentPool{
+		agents:    agents,
+		byBrowser: map[playwright.BrowserContext]string{},
+	}
+}
+
+func (p *userAgentPool) UserAgent(page playwright.Page) string {
+	if len(p.agents) == 0 {
+		return ""
+	}
+
+	bctx := page.Context()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ua, ok := p.byBrowser[bctx]; ok {
+		return ua
+	}
+
+	ua := p.agents[p.next%len(p.agents)]
+	p.next++
+
+	p.byBrowser[bctx] = ua
+
+	bctx.OnClose(func(bctx playwright.BrowserContext) {
+		p.mu.Lock()
+		delete(p.byBrowser, bctx)
+		p.mu.Unlock()
+	})
+
+	return ua
+}
+
+// setBrowserIdentity sets the user agent picked by agents and the
+// Accept-Language of langCode to the requests of page
+func setBrowserIdentity(page playwright.Page, agents UserAgents, langCode string) error {
+	var ua string
+	if agents != nil {
+		ua = agents.UserAgent(page)
+	}
+
+	if ua == "" {
+		if langCode == "" {
+			return nil
+		}
+
+		return page.SetExtraHTTPHeaders(map[string]string{"Accept-Language": langCode})
+	}
+
+	// unlike the User-Agent header the override changes navigator.userAgent
+	// too. It lasts as long as the session, so until the page is closed.
+	session, err := page.Context().NewCDPSession(page)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]any{"userAgent": ua}
+	if langCode != "" {
+		params["acceptLanguage"] = langCode
+	}
+
+	_, err = session.Send("Network.setUserAgentOverride", params)
+
+	return err
+}
@@ -0,0 +1,181 @@
+// Package jobcodec encodes the search and place jobs for the job queues,
+// with gob for the payloads stored by the providers and with JSON for the
+// messages read by other programs.
+//
+// The runtime state of the jobs (limiter, deduper, provider callbacks, ...)
+// isn't encoded, the providers set it again when the jobs are fetched.
+package jobcodec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const (
+	// TypeSearch is the payload type of the search jobs
+	TypeSearch = "search"
+	// TypePlace is the payload type of the place jobs
+	TypePlace = "place"
+)
+
+// ErrInvalidJob is returned for the jobs that are neither searches nor places
+var ErrInvalidJob = errors.New("invalid job type")
+
+// Encode returns the payload type, the gob payload and the tenant of the job
+func Encode(job scrapemate.IJob) (payloadType string, payload []byte, tenant string, err error) {
+	var buf bytes.Buffer
+
+	enc := gob.NewEncoder(&buf)
+
+	switch j := job.(type) {
+	case *gmaps.GmapJob:
+		c := stripSearch(j)
+		payloadType, tenant, err = TypeSearch, c.Tenant, enc.Encode(c)
+	case *gmaps.PlaceJob:
+		c := stripPlace(j)
+		payloadType, tenant, err = TypePlace, c.Tenant, enc.Encode(c)
+	default:
+		err = ErrInvalidJob
+	}
+
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	return payloadType, buf.Bytes(), tenant, nil
+}
+
+// Decode returns the job of the gob payload
+func Decode(payloadType string, payload []byte) (scrapemate.IJob, error) {
+	dec := gob.NewDecoder(bytes.NewReader(payload))
+
+	switch payloadType {
+	case TypeSearch:
+		j := new(gmaps.GmapJob)
+		if err := dec.Decode(j); err != nil {
+			return nil, fmt.Errorf("failed to decode search job: %w", err)
+		}
+
+		return j, nil
+	case TypePlace:
+		j := new(gmaps.PlaceJob)
+		if err := dec.Decode(j); err != nil {
+			return nil, fmt.Errorf("failed to decode place job: %w", err)
+		}
+
+		return j, nil
+	default:
+		return nil, fmt.Errorf("invalid payload type: %s", payloadType)
+	}
+}
+
+// jsonSearch and jsonPlace hide the fields of the scrapemate job that can't
+// be encoded as JSON, the response check and the response of the last fetch.
+// The shallower fields take precedence over the ones of the embedded job.
+type jsonSearch struct {
+	*gmaps.GmapJob
+	CheckResponse *struct{} `json:",omitempty"`
+	Response      *struct{} `json:",omitempty"`
+}
+
+type jsonPlace struct {
+	*gmaps.PlaceJob
+	CheckResponse *struct{} `json:",omitempty"`
+	Response      *struct{} `json:",omitempty"`
+}
+
+// EncodeJSON returns the payload type, the JSON payload and the tenant of the job
+func EncodeJSON(job scrapemate.IJob) (payloadType string, payload []byte, tenant string, err error) {
+	switch j := job.(type) {
+	case *gmaps.GmapJob:
+		c := stripSearch(j)
+		payload, err = json.Marshal(jsonSearch{GmapJob: c})
+		payloadType, tenant = TypeSearch, c.Tenant
+	case *gmaps.PlaceJob:
+		c := stripPlace(j)
+		payload, err = json.Marshal(jsonPlace{PlaceJob: c})
+		payloadType, tenant = TypePlace, c.Tenant
+	default:
+		err = ErrInvalidJob
+	}
+
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	return payloadType, payload, tenant, nil
+}
+
+// DecodeJSON returns the job of the JSON payload
+func DecodeJSON(payloadType string, payload []byte) (scrapemate.IJob, error) {
+	switch payloadType {
+	case TypeSearch:
+		j := jsonSearch{GmapJob: new(gmaps.GmapJob)}
+		if err := json.Unmarshal(payload, &j); err != nil {
+			return nil, fmt.Errorf("failed to decode search job: %w", err)
+		}
+
+		return j.GmapJob, nil
+	case TypePlace:
+		j := jsonPlace{PlaceJob: new(gmaps.PlaceJob)}
+		if err := json.Unmarshal(payload, &j); err != nil {
+			return nil, fmt.Errorf("failed to decode place job: %w", err)
+		}
+
+		return j.PlaceJob, nil
+	default:
+		return nil, fmt.Errorf("invalid payload type: %s", payloadType)
+	}
+}
+
+// stripSearch returns a copy of the job without its runtime state
+func stripSearch(j *gmaps.GmapJob) *gmaps.GmapJob {
+	c := *j
+
+	c.CheckResponse = nil
+	c.Deduper = nil
+	c.ExitMonitor = nil
+	c.Checkpoint = nil
+	c.PlaceDeduper = nil
+	c.EmailFetcher = nil
+	c.Throttler = nil
+	c.Canceller = nil
+	c.Limiter = nil
+	c.ProxyMonitor = nil
+	c.UserAgents = nil
+	c.Deriver = nil
+	c.DeadLetter = nil
+	c.Acker = nil
+	c.Tracker = nil
+
+	return &c
+}
+
+// stripPlace returns a copy of the job without its runtime state
+func stripPlace(j *gmaps.PlaceJob) *gmaps.PlaceJob {
+	c := *j
+
+	c.CheckResponse = nil
+	c.ExitMonitor = nil
+	c.Checkpoint = nil
+	c.EmailFetcher = nil
+	c.PlaceDeduper = nil
+	c.Throttler = nil
+	c.Canceller = nil
+	c.Limiter = nil
+	c.ProxyMonitor = nil
+	c.UserAgents = nil
+	c.Deriver = nil
+	c.DeadLetter = nil
+	c.Acker = nil
+	c.Tracker = nil
+
+	return &c
+}
@@ -0,0 +1,70 @@
+// Package jsonlwriter writes the places as JSON Lines (NDJSON), one JSON
+// object per line, for the pipelines ingesting the results as a stream.
+package jsonlwriter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/gosom/scrapemate"
+)
+
+// ContentType is the media type of JSON Lines documents
+const ContentType = "application/x-ndjson"
+
+// flusher is implemented by the buffered writers
+type flusher interface {
+	Flush() error
+}
+
+type jsonlWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// New returns a result writer that writes every place to w as a JSON line
+// as soon as it's scraped. Every line is written with a single call and
+// flushed when w is buffered, so nothing is accumulated in memory and the
+// output ends on a complete line unless the process dies in the middle of
+// a write.
+func New(w io.Writer) scrapemate.ResultWriter {
+	return &jsonlWriter{w: w}
+}
+
+func (j *jsonlWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		items, ok := result.Data.([]any)
+		if !ok {
+			items = []any{result.Data}
+		}
+
+		for i := range items {
+			if err := j.write(items[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (j *jsonlWriter) write(item any) error {
+	line, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	// the buffer is reused so that the line and its newline are one write
+	j.buf = append(append(j.buf[:0], line...), '\n')
+
+	if _, err := j.w.Write(j.buf); err != nil {
+		return err
+	}
+
+	if f, ok := j.w.(flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package kmlwriter writes places as a KML document that can be opened
+// in Google Earth and other GIS tools.
+package kmlwriter
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// ContentType is the media type of KML documents
+const ContentType = "application/vnd.google-earth.kml+xml"
+
+const uncategorized = "Uncategorized"
+
+type kmlDoc struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name    string      `xml:"name"`
+	Folders []kmlFolder `xml:"Folder"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string   `xml:"name"`
+	Description cdata    `xml:"description"`
+	Point       kmlPoint `xml:"Point"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+type kmlWriter struct {
+	w       io.Writer
+	name    string
+	entries []*gmaps.Entry
+}
+
+// New returns a result writer that writes all the places to w as a KML
+// document named name once the results end. Places without coordinates
+// are skipped.
+func New(w io.Writer, name string) scrapemate.ResultWriter {
+	return &kmlWriter{w: w, name: name}
+}
+
+func (k *kmlWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		switch v := result.Data.(type) {
+		case *gmaps.Entry:
+			k.entries = append(k.entries, v)
+		case []any:
+			for i := range v {
+				entry, ok := v[i].(*gmaps.Entry)
+				if !ok {
+					return fmt.Errorf("cannot cast %T to *gmaps.Entry", v[i])
+				}
+
+				k.entries = append(k.entries, entry)
+			}
+		default:
+			return fmt.Errorf("cannot cast %T to *gmaps.Entry", result.Data)
+		}
+	}
+
+	return Write(k.w, k.name, k.entries)
+}
+
+// Write writes entries to w as a KML document with one folder per category
+func Write(w io.Writer, name string, entries []*gmaps.Entry) error {
+	folders := map[string]*kmlFolder{}
+
+	for _, entry := range entries {
+		if entry.Latitude == 0 && entry.Longtitude == 0 {
+			continue
+		}
+
+		category := entry.Category
+		if category == "" {
+			category = uncategorized
+		}
+
+		folder, ok := folders[category]
+		if !ok {
+			folder = &kmlFolder{Name: category}
+			folders[category] = folder
+		}
+
+		folder.Placemarks = append(folder.Placemarks, kmlPlacemark{
+			Name:        entry.Title,
+			Description: cdata{Value: description(entry)},
+			Point: kmlPoint{
+				// KML uses longitude,latitude order
+				Coordinates: strconv.FormatFloat(entry.Longtitude, 'f', -1, 64) + "," +
+					strconv.FormatFloat(entry.Latitude, 'f', -1, 64),
+			},
+		})
+	}
+
+	doc := kmlDoc{
+		Xmlns:    "http://www.opengis.net/kml/2.2",
+		Document: kmlDocument{Name: name},
+	}
+
+	names := make([]string, 0, len(folders))
+	for k := range folders {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	for _, n := range names {
+		doc.Document.Folders = append(doc.Document.Folders, *folders[n])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func description(entry *gmaps.Entry) string {
+	var sb strings.Builder
+
+	field := func(label, value string) {
+		if value == "" {
+			return
+		}
+
+		sb.WriteString("<b>")
+		sb.WriteString(xmlEscape(label))
+		sb.WriteString(":</b> ")
+		sb.WriteString(xmlEscape(value))
+		sb.WriteString("<br/>")
+	}
+
+	field("Category", entry.Category)
+	field("Address", entry.Address)
+	field("Phone", entry.Phone)
+	field("Phone (E.164)", entry.PhoneE164)
+	field("Website", entry.WebSite)
+
+	if entry.ReviewCount > 0 {
+		field("Rating", fmt.Sprintf("%.1f (%d reviews)", entry.ReviewRating, entry.ReviewCount))
+	}
+
+	field("Price range", entry.PriceRange)
+	field("Status", entry.Status)
+	field("Hours", hours(entry.WeeklyHours))
+	field("Emails", strings.Join(entry.Emails, ", "))
+
+	if l := entry.SocialLinks; l != nil {
+		field("Facebook", strings.Join(l.Facebook, ", "))
+		field("Instagram", strings.Join(l.Instagram, ", "))
+		field("LinkedIn", strings.Join(l.LinkedIn, ", "))
+		field("Twitter", strings.Join(l.Twitter, ", "))
+	}
+
+	if entry.MapsURL != "" {
+		field("Google Maps", entry.MapsURL)
+	} else {
+		field("Google Maps", entry.Link)
+	}
+
+	field("CID", entry.Cid)
+	field("Place ID", entry.PlaceID)
+
+	return sb.String()
+}
+
+// hours returns the opening hours as one line from Monday to Sunday
+func hours(h *gmaps.WeeklyHours) string {
+	switch {
+	case h == nil:
+		return ""
+	case h.TemporarilyClosed:
+		return "Temporarily closed"
+	case h.Open24Hours:
+		return "Open 24 hours"
+	}
+
+	days := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+	parts := make([]string, 0, len(days))
+
+	for _, day := range days {
+		ranges, ok := h.Days[day]
+		if !ok {
+			continue
+		}
+
+		if len(ranges) == 0 {
+			parts = append(parts, day+" closed")
+			continue
+		}
+
+		times := make([]string, len(ranges))
+		for i, r := range ranges {
+			times[i] = r.Open + "-" + r.Close
+		}
+
+		parts = append(parts, day+" "+strings.Join(times, ", "))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+
+	_ = xml.EscapeText(&sb, []byte(s))
+
+	return sb.String()
+}
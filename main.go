@@ -9,19 +9,28 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/gosom/google-maps-scraper/gmaps"
 	"github.com/gosom/google-maps-scraper/postgres"
+	"github.com/gosom/google-maps-scraper/redisprovider"
+	"github.com/gosom/google-maps-scraper/refresh"
 	"github.com/gosom/google-maps-scraper/runner"
 	"github.com/gosom/google-maps-scraper/runner/databaserunner"
+	"github.com/gosom/google-maps-scraper/runner/dryrun"
 	"github.com/gosom/google-maps-scraper/runner/filerunner"
 	"github.com/gosom/google-maps-scraper/runner/installplaywright"
 	"github.com/gosom/google-maps-scraper/runner/lambdaaws"
+	"github.com/gosom/google-maps-scraper/runner/selftest"
 	"github.com/gosom/google-maps-scraper/runner/webrunner"
 	"github.com/gosom/google-maps-scraper/web/handlers"
 	"github.com/gosom/google-maps-scraper/web/server"
 	"go.uber.org/zap"
 )
 
+// shutdownTimeout bounds the wait for the in-flight API requests on shutdown
+const shutdownTimeout = 15 * time.Second
+
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -30,40 +39,45 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	go func() {
-		<-sigChan
+	cfg := runner.ParseConfig()
 
-		log.Println("Received signal, shutting down...")
+	// the runners log with the standard logger
+	if cfg.Logger != nil {
+		zap.ReplaceGlobals(cfg.Logger)
+		zap.RedirectStdLog(cfg.Logger)
+	}
 
-		cancel()
-	}()
+	if cfg.ProxyPool != nil {
+		go cfg.ProxyPool.Run(ctx, cfg.ProxiesRefresh)
+	}
 
-	cfg := runner.ParseConfig()
+	var srv *server.Server
 
-	// Initialize the web server first
-	logger, _ := zap.NewProduction()
-	defer logger.Sync()
+	// the API queues the jobs of the database workers, the other modes don't need postgres.
+	// The sqs workers don't serve it, SQS can't list or inspect the queued jobs.
+	if (cfg.RunMode == runner.RunModeDatabase && cfg.Provider != runner.ProviderSQS) || cfg.RunMode == runner.RunModeWeb {
+		var closeAPI func()
 
-	// Initialize database connection
-	db, err := sql.Open("pgx", cfg.Dsn)
-	if err != nil {
-		log.Fatal("failed to connect to database:", err)
+		srv, closeAPI = startAPI(ctx, cancel, cfg)
+		defer closeAPI()
 	}
-	defer db.Close()
 
-	// Initialize provider
-	provider := postgres.NewProvider(db)
+	go func() {
+		<-sigChan
 
-	// Initialize job handler
-	jobHandler := handlers.NewJobHandler(provider, logger)
+		log.Println("Received signal, shutting down...")
 
-	// Start web server in a goroutine
-	go func() {
-		srv := server.New(jobHandler, logger)
-		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
-			log.Printf("server error: %v", err)
-			cancel()
+		// let the in-flight requests finish before the runner stops
+		if srv != nil {
+			shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Printf("server shutdown: %v", err)
+			}
+
+			shutdownCancel()
 		}
+
+		cancel()
 	}()
 
 	// Start the scraper runner
@@ -89,6 +103,129 @@ func main() {
 	os.Exit(0)
 }
 
+// startAPI starts the API server in the background, cancel is called when it
+// fails. The returned function closes its connections.
+func startAPI(ctx context.Context, cancel context.CancelFunc, cfg *runner.Config) (*server.Server, func()) {
+	logger := cfg.Logger
+
+	// Initialize database connection
+	db, err := sql.Open("pgx", cfg.Dsn)
+	if err != nil {
+		log.Fatal("failed to connect to database:", err)
+	}
+
+	closers := []func(){func() { _ = db.Close() }}
+
+	// Initialize provider
+	var (
+		provider   gmaps.Provider
+		pgProvider postgres.Provider
+		pinger     handlers.Pinger = db
+	)
+
+	switch cfg.Provider {
+	case runner.ProviderRedis:
+		redisProvider, err := redisprovider.New(ctx, cfg.RedisURL, redisprovider.WithRetention(cfg.RedisJobTTL))
+		if err != nil {
+			log.Fatal("failed to connect to redis:", err)
+		}
+
+		closers = append(closers, func() { _ = redisProvider.Close() })
+
+		provider = redisProvider
+		pinger = redisProvider
+	default:
+		pgProvider = postgres.NewProvider(db)
+		provider = pgProvider
+	}
+
+	policy, err := handlers.NewQueryPolicy(cfg.QueryAllowlist, cfg.QueryBlocklist)
+	if err != nil {
+		log.Fatal("invalid query policy:", err)
+	}
+
+	// Initialize job handler
+	handlerOpts := []handlers.JobHandlerOption{
+		handlers.WithQueryPolicy(policy),
+		handlers.WithJobOptions(runner.SeedJobOptions(cfg)...),
+		handlers.WithGeocoder(cfg.Geocoder),
+		handlers.WithRestrictedRegions(cfg.RestrictedRegions),
+		handlers.WithPinger(pinger),
+		handlers.WithMaxBodySize(cfg.ServerMaxBodySize),
+	}
+
+	// the dlq and the quotas are rejected by the config with the redis provider
+	if cfg.DeadLetterQueue {
+		handlerOpts = append(handlerOpts, handlers.WithDeadLetterQueue(pgProvider))
+	}
+
+	if cfg.Quotas != nil {
+		handlerOpts = append(handlerOpts, handlers.WithQuotas(cfg.Quotas, pgProvider))
+	}
+
+	if cfg.AdminToken != "" {
+		handlerOpts = append(handlerOpts, handlers.WithAdminToken(cfg.AdminToken))
+	}
+
+	// the postgres workers track the jobs with a webhook
+	if cfg.Provider != runner.ProviderRedis {
+		handlerOpts = append(handlerOpts, handlers.WithJobWebhooks())
+	}
+
+	// the results are saved in postgres only
+	if pgProvider != nil {
+		handlerOpts = append(handlerOpts, handlers.WithResultReader(pgProvider))
+	}
+
+	if cfg.AdminToken != "" && cfg.ConfigFile != "" {
+		reloader, err := runner.NewReloader(cfg)
+		if err != nil {
+			log.Fatal("failed to create the config reloader:", err)
+		}
+
+		handlerOpts = append(handlerOpts, handlers.WithReloader(reloader))
+	}
+
+	var placeStore refresh.Store
+	if cfg.Dsn != "" {
+		placeStore = postgres.NewPlaceStore(db)
+	}
+
+	handlerOpts = append(handlerOpts, handlers.WithPlaceRefresher(refresh.New(cfg, placeStore)))
+
+	jobHandler := handlers.NewJobHandler(provider, logger, handlerOpts...)
+
+	serverOpts := []server.Option{
+		server.WithTimeouts(cfg.ServerReadTimeout, cfg.ServerWriteTimeout, cfg.ServerIdleTimeout),
+	}
+
+	if len(cfg.CORSOrigins) > 0 {
+		serverOpts = append(serverOpts, server.WithCORS(cfg.CORSOrigins))
+	}
+
+	if cfg.RateLimit > 0 {
+		serverOpts = append(serverOpts, server.WithRateLimit(cfg.RateLimit, cfg.RateBurst, cfg.TrustedProxies))
+	}
+
+	srv := server.New(jobHandler, logger, cfg.WebPort, serverOpts...)
+
+	// Start web server in a goroutine
+	go func() {
+		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
+			log.Printf("server error: %v", err)
+			cancel()
+		}
+	}()
+
+	return srv, func() {
+		_ = logger.Sync()
+
+		for _, c := range closers {
+			c()
+		}
+	}
+}
+
 func runnerFactory(cfg *runner.Config) (runner.Runner, error) {
 	switch cfg.RunMode {
 	case runner.RunModeFile:
@@ -103,6 +240,10 @@ func runnerFactory(cfg *runner.Config) (runner.Runner, error) {
 		return lambdaaws.New(cfg)
 	case runner.RunModeAwsLambdaInvoker:
 		return lambdaaws.NewInvoker(cfg)
+	case runner.RunModeSelfTest:
+		return selftest.New(cfg)
+	case runner.RunModeDryRun:
+		return dryrun.New(cfg)
 	default:
 		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
 	}
@@ -0,0 +1,132 @@
+// Package metrics holds the prometheus metrics shared by the API server and the scrapers.
+// They are registered in the default registry and served on /metrics by the API server.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The reasons of JobsFailed
+const (
+	ReasonTimeout = "timeout"
+	ReasonBlocked = "blocked"
+	ReasonFetch   = "fetch"
+	ReasonParse   = "parse"
+)
+
+var (
+	// JobsCreated counts the jobs created through the API
+	JobsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "jobs_created_total",
+		Help: "Number of jobs created through the API.",
+	})
+
+	// JobsFailed counts the scrape jobs that failed after their retries
+	JobsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "jobs_failed_total",
+		Help: "Number of scrape jobs that failed, by reason.",
+	}, []string{"reason"})
+
+	// PlacesScraped counts the places written to the results
+	PlacesScraped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "places_scraped_total",
+		Help: "Number of places scraped.",
+	})
+
+	// JobQueueWait is the time the queued jobs waited before their first start
+	JobQueueWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "job_queue_wait_seconds",
+		Help:    "Time the jobs waited in the queue before their first start, by type.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 16),
+	}, []string{"type"})
+
+	// JobDuration is the time from the first start of the queued jobs to their end
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "job_duration_seconds",
+		Help:    "Time from the first start of the jobs to their end, by type and final state.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 16),
+	}, []string{"type", "state"})
+
+	// AdaptiveConcurrency is the current limit of -adaptive-concurrency
+	AdaptiveConcurrency = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "adaptive_concurrency",
+		Help: "Current number of pages loaded at the same time with -adaptive-concurrency.",
+	})
+
+	// PlacesFiltered counts the places dropped by the include and exclude keywords
+	PlacesFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "places_filtered_total",
+		Help: "Number of places dropped by the keyword filter, by reason.",
+	}, []string{"reason"})
+
+	// WebhookQueued is the number of results waiting for their webhook batch
+	WebhookQueued = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "webhook_queued",
+		Help: "Number of results waiting to be sent to the webhook.",
+	})
+
+	// WebhookInFlight is the number of batches being delivered to the webhook
+	WebhookInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "webhook_in_flight",
+		Help: "Number of webhook batches being delivered.",
+	})
+
+	// WebhookAttempts counts the requests sent to the webhook
+	WebhookAttempts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_attempts_total",
+		Help: "Number of requests sent to the webhook, retries included.",
+	})
+
+	// WebhookRetries counts the retried webhook requests
+	WebhookRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_retries_total",
+		Help: "Number of webhook requests retried.",
+	})
+
+	// WebhookDeliveries counts the webhook batches by result, delivered or failed
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_deliveries_total",
+		Help: "Number of webhook batches, by result.",
+	}, []string{"result"})
+
+	// RequestDuration is the latency of the API requests
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of the API requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"handler", "method", "code"})
+)
+
+// Handler serves the metrics in the prometheus format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Instrument records the latency of the requests of h under the name of the handler
+func Instrument(name string, h http.HandlerFunc) http.Handler {
+	obs := RequestDuration.MustCurryWith(prometheus.Labels{"handler": name})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next := func(dw http.ResponseWriter, r *http.Request) {
+			h(&instrumentedWriter{ResponseWriter: dw, w: w}, r)
+		}
+
+		promhttp.InstrumentHandlerDuration(obs, http.HandlerFunc(next)).ServeHTTP(w, r)
+	})
+}
+
+// instrumentedWriter is the writer that records the status of the response.
+// It unwraps to the writer of the server, so that h can set the deadlines of
+// the response with http.ResponseController.
+type instrumentedWriter struct {
+	http.ResponseWriter
+	w http.ResponseWriter
+}
+
+func (iw *instrumentedWriter) Unwrap() http.ResponseWriter {
+	return iw.w
+}
@@ -0,0 +1,77 @@
+// Package partialfile finds the end of the complete records of the results
+// files that are still written, or were left half written by a crash.
+package partialfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+)
+
+// CSVEnd returns the offset after the last complete record of the CSV
+// file r of the given size. The quoted fields may hold newlines, so the
+// records are parsed instead of looking for the last newline.
+func CSVEnd(r io.ReaderAt, size int64) (int64, error) {
+	cr := csv.NewReader(io.NewSectionReader(r, 0, size))
+	cr.ReuseRecord = true
+
+	var end int64
+
+	for {
+		_, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		// a truncated record has missing fields or an unterminated quote
+		if err != nil {
+			return end, nil
+		}
+
+		off := cr.InputOffset()
+
+		// the last record is complete only when its newline was written
+		if off == size {
+			last := make([]byte, 1)
+			if _, err := r.ReadAt(last, size-1); err != nil {
+				return 0, err
+			}
+
+			if last[0] != '\n' {
+				break
+			}
+		}
+
+		end = off
+	}
+
+	return end, nil
+}
+
+// LinesEnd returns the offset after the last newline of r, the JSON
+// outputs write one place per line
+func LinesEnd(r io.Reader) (int64, error) {
+	var (
+		end int64
+		off int64
+		buf = make([]byte, 64*1024)
+	)
+
+	for {
+		n, err := r.Read(buf)
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			end = off + int64(i) + 1
+		}
+
+		off += int64(n)
+
+		if errors.Is(err, io.EOF) {
+			return end, nil
+		}
+
+		if err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package partialfile_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/partialfile"
+)
+
+func Test_CSVEnd(t *testing.T) {
+	const header = "title,address\n"
+
+	tests := []struct {
+		name string
+		data string
+		want int
+	}{
+		{"empty", "", 0},
+		{"complete", header + "a,b\n", len(header + "a,b\n")},
+		{"no newline", header + "a,b", len(header)},
+		{"missing fields", header + "a,b\nc", len(header + "a,b\n")},
+		{"newline in a quoted field", header + "a,\"street 1\nvienna\"\n", len(header + "a,\"street 1\nvienna\"\n")},
+		{"cut in a quoted field", header + "a,b\nc,\"street 1\n", len(header + "a,b\n")},
+		{"cut after a quoted newline", header + "a,b\nc,\"street 1\nvie", len(header + "a,b\n")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := strings.NewReader(tc.data)
+
+			end, err := partialfile.CSVEnd(r, r.Size())
+			require.NoError(t, err)
+			require.Equal(t, int64(tc.want), end)
+		})
+	}
+}
+
+func Test_LinesEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want int
+	}{
+		{"empty", "", 0},
+		{"complete", "{}\n{}\n", 6},
+		{"half a line", "{}\n{\"ti", 3},
+		{"no newline", "{\"ti", 0},
+		{"longer than the buffer", strings.Repeat("x", 70*1024) + "\n{", 70*1024 + 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			end, err := partialfile.LinesEnd(strings.NewReader(tc.data))
+			require.NoError(t, err)
+			require.Equal(t, int64(tc.want), end)
+		})
+	}
+}
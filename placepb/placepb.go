@@ -0,0 +1,389 @@
+// Package placepb encodes places using the protobuf schema
+// defined in proto/gmaps/v1/place.proto.
+package placepb
+
+import (
+	"io"
+	"math"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+const (
+	// SchemaVersion is the protobuf package of the Place message
+	SchemaVersion = "gmaps.v1"
+	// ContentType is the content type of a stream of length delimited places
+	ContentType = "application/x-protobuf; proto=gmaps.v1.Place; delimited=true"
+)
+
+// Marshal encodes entry as a gmaps.v1.Place message
+func Marshal(entry *gmaps.Entry) []byte {
+	var b []byte
+
+	b = appendString(b, 1, entry.ID)
+	b = appendString(b, 2, entry.Link)
+	b = appendString(b, 3, entry.Title)
+	b = appendString(b, 4, entry.Category)
+	b = appendString(b, 5, entry.Address)
+	b = appendString(b, 6, entry.WebSite)
+	b = appendString(b, 7, entry.Phone)
+	b = appendString(b, 8, entry.PlusCode)
+	b = appendInt(b, 9, entry.ReviewCount)
+	b = appendDouble(b, 10, entry.ReviewRating)
+	b = appendDouble(b, 11, entry.Latitude)
+	b = appendDouble(b, 12, entry.Longtitude)
+	b = appendString(b, 13, entry.Cid)
+	b = appendString(b, 14, entry.Status)
+	b = appendString(b, 15, entry.Description)
+	b = appendString(b, 16, entry.ReviewsLink)
+	b = appendString(b, 17, entry.Thumbnail)
+	b = appendString(b, 18, entry.Timezone)
+	b = appendString(b, 19, entry.PriceRange)
+	b = appendString(b, 20, entry.DataID)
+	b = appendStrings(b, 21, entry.Categories)
+	b = appendStrings(b, 22, entry.Emails)
+	b = appendMessage(b, 23, marshalAddress(&entry.CompleteAddress))
+
+	for _, day := range sortedKeys(entry.OpenHours) {
+		var hours []byte
+
+		hours = appendStrings(hours, 1, entry.OpenHours[day])
+
+		b = appendMapEntry(b, 24, appendString(nil, 1, day), appendMessage(nil, 2, hours))
+	}
+
+	b = appendMessage(b, 25, marshalOwner(&entry.Owner))
+
+	for i := range entry.Images {
+		var img []byte
+
+		img = appendString(img, 1, entry.Images[i].Title)
+		img = appendString(img, 2, entry.Images[i].Image)
+
+		b = appendSubmessage(b, 26, img)
+	}
+
+	for i := range entry.Reservations {
+		b = appendSubmessage(b, 27, marshalLinkSource(&entry.Reservations[i]))
+	}
+
+	for i := range entry.OrderOnline {
+		b = appendSubmessage(b, 28, marshalLinkSource(&entry.OrderOnline[i]))
+	}
+
+	b = appendMessage(b, 29, marshalLinkSource(&entry.Menu))
+
+	ratings := make([]int, 0, len(entry.ReviewsPerRating))
+	for k := range entry.ReviewsPerRating {
+		ratings = append(ratings, k)
+	}
+
+	sort.Ints(ratings)
+
+	for _, k := range ratings {
+		b = appendMapEntry(b, 30, appendInt(nil, 1, k), appendInt(nil, 2, entry.ReviewsPerRating[k]))
+	}
+
+	b = appendInt(b, 31, entry.OpenedYear)
+
+	for _, k := range sortedKeys(entry.CustomFields) {
+		b = appendMapEntry(b, 32, appendString(nil, 1, k), appendString(nil, 2, entry.CustomFields[k]))
+	}
+
+	b = appendBool(b, 33, entry.BookingAvailable)
+
+	b = appendString(b, 34, entry.BookingProvider)
+	b = appendString(b, 35, entry.RequestID)
+	b = appendStrings(b, 36, entry.DietaryOptions)
+
+	for _, k := range sortedKeys(entry.TicketLinks) {
+		b = appendMapEntry(b, 37, appendString(nil, 1, k), appendString(nil, 2, entry.TicketLinks[k]))
+	}
+
+	// the messages are written even when empty, their presence marks the place type
+	if entry.Charging != nil {
+		b = appendSubmessage(b, 38, marshalCharging(entry.Charging))
+	}
+
+	if entry.Fuel != nil {
+		b = appendSubmessage(b, 39, marshalFuel(entry.Fuel))
+	}
+
+	if entry.PricePerPerson != nil {
+		b = appendSubmessage(b, 40, marshalPricePerPerson(entry.PricePerPerson))
+	}
+
+	for _, k := range sortedKeys(entry.ThirdPartyRatings) {
+		b = appendMapEntry(b, 41, appendString(nil, 1, k), appendDouble(nil, 2, entry.ThirdPartyRatings[k]))
+	}
+
+	b = appendDouble(b, 42, entry.SpamScore)
+
+	for i := range entry.MenuHighlights {
+		var h []byte
+
+		h = appendString(h, 1, entry.MenuHighlights[i].Label)
+		h = appendString(h, 2, entry.MenuHighlights[i].ImageURL)
+
+		b = appendSubmessage(b, 43, h)
+	}
+
+	if entry.ContactValidation != nil {
+		b = appendSubmessage(b, 44, marshalContactValidation(entry.ContactValidation))
+	}
+
+	b = appendString(b, 45, entry.ClaimURL)
+
+	if entry.WeeklyHours != nil {
+		b = appendSubmessage(b, 46, marshalWeeklyHours(entry.WeeklyHours))
+	}
+
+	b = appendString(b, 47, entry.PhoneE164)
+	b = appendStrings(b, 48, entry.Photos)
+
+	if entry.SocialLinks != nil {
+		b = appendSubmessage(b, 49, marshalSocialLinks(entry.SocialLinks))
+	}
+
+	b = appendString(b, 50, entry.PlaceID)
+	b = appendString(b, 51, entry.MapsURL)
+
+	return b
+}
+
+// Writer writes a stream of length delimited places
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter returns a Writer that writes to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes entry prefixed by its varint encoded length
+func (w *Writer) Write(entry *gmaps.Entry) error {
+	msg := Marshal(entry)
+
+	w.buf = protowire.AppendVarint(w.buf[:0], uint64(len(msg)))
+	w.buf = append(w.buf, msg...)
+
+	_, err := w.w.Write(w.buf)
+
+	return err
+}
+
+func marshalAddress(a *gmaps.Address) []byte {
+	var b []byte
+
+	b = appendString(b, 1, a.Borough)
+	b = appendString(b, 2, a.Street)
+	b = appendString(b, 3, a.City)
+	b = appendString(b, 4, a.PostalCode)
+	b = appendString(b, 5, a.State)
+	b = appendString(b, 6, a.Country)
+
+	return b
+}
+
+func marshalOwner(o *gmaps.Owner) []byte {
+	var b []byte
+
+	b = appendString(b, 1, o.ID)
+	b = appendString(b, 2, o.Name)
+	b = appendString(b, 3, o.Link)
+
+	return b
+}
+
+func marshalCharging(c *gmaps.Charging) []byte {
+	var b []byte
+
+	for i := range c.Connectors {
+		var conn []byte
+
+		conn = appendString(conn, 1, c.Connectors[i].Type)
+		conn = appendDouble(conn, 2, c.Connectors[i].PowerKW)
+		conn = appendInt(conn, 3, c.Connectors[i].Count)
+		conn = appendInt(conn, 4, c.Connectors[i].Available)
+
+		b = appendSubmessage(b, 1, conn)
+	}
+
+	return b
+}
+
+func marshalFuel(f *gmaps.Fuel) []byte {
+	var b []byte
+
+	b = appendStrings(b, 1, f.Types)
+
+	for i := range f.Prices {
+		var price []byte
+
+		price = appendString(price, 1, f.Prices[i].Type)
+		price = appendString(price, 2, f.Prices[i].Price)
+
+		b = appendSubmessage(b, 2, price)
+	}
+
+	return b
+}
+
+func marshalPricePerPerson(p *gmaps.PricePerPerson) []byte {
+	var b []byte
+
+	b = appendDouble(b, 1, p.Min)
+	b = appendDouble(b, 2, p.Max)
+	b = appendString(b, 3, p.Currency)
+
+	return b
+}
+
+func marshalContactValidation(c *gmaps.ContactValidation) []byte {
+	var b []byte
+
+	b = appendStrings(b, 1, c.Emails)
+	b = appendString(b, 2, c.WebSite)
+	b = appendStrings(b, 3, c.RawEmails)
+	b = appendString(b, 4, c.RawWebSite)
+	b = appendStrings(b, 5, c.Issues)
+
+	return b
+}
+
+func marshalSocialLinks(l *gmaps.SocialLinks) []byte {
+	var b []byte
+
+	b = appendStrings(b, 1, l.Facebook)
+	b = appendStrings(b, 2, l.Instagram)
+	b = appendStrings(b, 3, l.LinkedIn)
+	b = appendStrings(b, 4, l.Twitter)
+
+	return b
+}
+
+func marshalWeeklyHours(h *gmaps.WeeklyHours) []byte {
+	var b []byte
+
+	for _, day := range sortedKeys(h.Days) {
+		var ranges []byte
+
+		for _, r := range h.Days[day] {
+			var tr []byte
+
+			tr = appendString(tr, 1, r.Open)
+			tr = appendString(tr, 2, r.Close)
+
+			ranges = appendSubmessage(ranges, 1, tr)
+		}
+
+		b = appendMapEntry(b, 1, appendString(nil, 1, day), appendMessage(nil, 2, ranges))
+	}
+
+	b = appendBool(b, 2, h.Open24Hours)
+	b = appendBool(b, 3, h.TemporarilyClosed)
+
+	return b
+}
+
+func marshalLinkSource(l *gmaps.LinkSource) []byte {
+	var b []byte
+
+	b = appendString(b, 1, l.Link)
+	b = appendString(b, 2, l.Source)
+
+	return b
+}
+
+// proto3 does not encode fields with default values
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendString(b, v)
+}
+
+func appendStrings(b []byte, num protowire.Number, v []string) []byte {
+	for i := range v {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendString(b, v[i])
+	}
+
+	return b
+}
+
+func appendInt(b []byte, num protowire.Number, v int) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+
+	return protowire.AppendVarint(b, uint64(int32(v))) //nolint:gosec // int32 in the schema
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+
+	return protowire.AppendVarint(b, 1)
+}
+
+func appendDouble(b []byte, num protowire.Number, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+// appendMessage appends a singular message field, skipping empty ones
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	if len(msg) == 0 {
+		return b
+	}
+
+	return appendSubmessage(b, num, msg)
+}
+
+// appendSubmessage appends an element of a repeated message field
+func appendSubmessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendBytes(b, msg)
+}
+
+// appendMapEntry appends an entry of a map field,
+// key and value are already encoded with field numbers 1 and 2
+func appendMapEntry(b []byte, num protowire.Number, key, value []byte) []byte {
+	entry := make([]byte, 0, len(key)+len(value))
+	entry = append(entry, key...)
+	entry = append(entry, value...)
+
+	return appendSubmessage(b, num, entry)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
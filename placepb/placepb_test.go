@@ -0,0 +1,281 @@
+package placepb_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/placepb"
+)
+
+// placeDescriptor compiles proto/gmaps/v1/place.proto so that the
+// hand written encoding is checked against the published schema
+func placeDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{ImportPaths: []string{"../proto"}},
+	}
+
+	files, err := compiler.Compile(context.Background(), "gmaps/v1/place.proto")
+	require.NoError(t, err)
+
+	desc := files[0].Messages().ByName("Place")
+	require.NotNil(t, desc)
+
+	return desc
+}
+
+func decode(t *testing.T, desc protoreflect.MessageDescriptor, raw []byte) string {
+	t.Helper()
+
+	msg := dynamicpb.NewMessage(desc)
+	require.NoError(t, proto.Unmarshal(raw, msg))
+
+	// unknown fields mean a field number that is not in the schema
+	require.Empty(t, msg.GetUnknown())
+
+	ans, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	require.NoError(t, err)
+
+	return string(ans)
+}
+
+func fullEntry() *gmaps.Entry {
+	return &gmaps.Entry{
+		ID:         "input-1",
+		Link:       "https://www.google.com/maps/place/cafe",
+		Cid:        "123456789",
+		PlaceID:    "ChIJ123",
+		MapsURL:    "https://www.google.com/maps/search/?api=1&query_place_id=ChIJ123",
+		Title:      "Cafe Central",
+		Categories: []string{"Cafe", "Bakery"},
+		Category:   "Cafe",
+		Address:    "Herrengasse 14, 1010 Wien",
+		OpenHours: map[string][]string{
+			"Monday": {"8 am-10 pm"},
+			"Sunday": {"10 am-6 pm", "7 pm-9 pm"},
+		},
+		WebSite:          "https://cafecentral.wien",
+		Phone:            "01 5333763",
+		PhoneE164:        "+4315333763",
+		PlusCode:         "8FVC+2F Wien",
+		ReviewCount:      12000,
+		ReviewRating:     4.5,
+		ReviewsPerRating: map[int]int{1: 100, 5: 9000},
+		Latitude:         48.2104,
+		Longtitude:       16.3655,
+		Status:           "Open",
+		Description:      "Traditional coffee house",
+		ReviewsLink:      "https://search.google.com/local/reviews?placeid=ChIJ123",
+		Thumbnail:        "https://lh5.googleusercontent.com/thumb",
+		Photos:           []string{"https://lh5.googleusercontent.com/1", "https://lh5.googleusercontent.com/2"},
+		Timezone:         "Europe/Vienna",
+		PriceRange:       "€€",
+		DataID:           "0x476d079:0x1",
+		Images:           []gmaps.Image{{Title: "All", Image: "https://lh5.googleusercontent.com/all"}},
+		Reservations:     []gmaps.LinkSource{{Link: "https://book.example/cafe", Source: "book.example"}},
+		OrderOnline:      []gmaps.LinkSource{{Link: "https://order.example/cafe", Source: "order.example"}},
+		Menu:             gmaps.LinkSource{Link: "https://cafecentral.wien/menu", Source: "cafecentral.wien"},
+		Owner:            gmaps.Owner{ID: "owner-1", Name: "Cafe Central", Link: "https://www.google.com/maps/contrib/1"},
+		CompleteAddress: gmaps.Address{
+			Borough:    "Innere Stadt",
+			Street:     "Herrengasse 14",
+			City:       "Wien",
+			PostalCode: "1010",
+			State:      "Wien",
+			Country:    "AT",
+		},
+		Emails:           []string{"info@cafecentral.wien"},
+		OpenedYear:       1876,
+		CustomFields:     map[string]string{"wifi": "yes"},
+		BookingAvailable: true,
+		BookingProvider:  "book.example",
+		RequestID:        "req-1",
+		DietaryOptions:   []string{"vegan"},
+		TicketLinks:      map[string]string{"tickets.example": "https://tickets.example/cafe"},
+		Charging: &gmaps.Charging{Connectors: []gmaps.Connector{
+			{Type: "CCS", PowerKW: 150, Count: 4, Available: -1},
+		}},
+		Fuel: &gmaps.Fuel{
+			Types:  []string{"Diesel"},
+			Prices: []gmaps.FuelPrice{{Type: "Diesel", Price: "€1.59"}},
+		},
+		PricePerPerson:    &gmaps.PricePerPerson{Min: 20, Max: 30, Currency: "EUR"},
+		ThirdPartyRatings: map[string]float64{"tripadvisor": 4.5},
+		SpamScore:         0.25,
+		MenuHighlights:    []gmaps.MenuHighlight{{Label: "Sachertorte", ImageURL: "https://lh5.googleusercontent.com/cake"}},
+		ContactValidation: &gmaps.ContactValidation{
+			Emails:     []string{"info@cafecentral.wien"},
+			WebSite:    "https://cafecentral.wien",
+			RawEmails:  []string{"INFO@cafecentral.wien", "a@mailinator.com"},
+			RawWebSite: "cafecentral.wien",
+			Issues:     []string{"disposable email domain: a@mailinator.com"},
+		},
+		ClaimURL: "https://business.google.com/claim",
+		WeeklyHours: &gmaps.WeeklyHours{
+			Days: map[string][]gmaps.TimeRange{
+				"Monday": {{Open: "08:00", Close: "22:00"}},
+				"Sunday": {},
+			},
+			Open24Hours:       true,
+			TemporarilyClosed: true,
+		},
+		SocialLinks: &gmaps.SocialLinks{
+			Facebook:  []string{"https://facebook.com/cafecentral"},
+			Instagram: []string{"https://instagram.com/cafecentral"},
+			LinkedIn:  []string{"https://linkedin.com/company/cafecentral"},
+			Twitter:   []string{"https://x.com/cafecentral"},
+		},
+	}
+}
+
+const fullPlace = `{
+	"input_id": "input-1",
+	"link": "https://www.google.com/maps/place/cafe",
+	"title": "Cafe Central",
+	"category": "Cafe",
+	"address": "Herrengasse 14, 1010 Wien",
+	"website": "https://cafecentral.wien",
+	"phone": "01 5333763",
+	"plus_code": "8FVC+2F Wien",
+	"review_count": 12000,
+	"review_rating": 4.5,
+	"latitude": 48.2104,
+	"longitude": 16.3655,
+	"cid": "123456789",
+	"status": "Open",
+	"description": "Traditional coffee house",
+	"reviews_link": "https://search.google.com/local/reviews?placeid=ChIJ123",
+	"thumbnail": "https://lh5.googleusercontent.com/thumb",
+	"timezone": "Europe/Vienna",
+	"price_range": "€€",
+	"data_id": "0x476d079:0x1",
+	"categories": ["Cafe", "Bakery"],
+	"emails": ["info@cafecentral.wien"],
+	"complete_address": {
+		"borough": "Innere Stadt",
+		"street": "Herrengasse 14",
+		"city": "Wien",
+		"postal_code": "1010",
+		"state": "Wien",
+		"country": "AT"
+	},
+	"open_hours": {
+		"Monday": {"hours": ["8 am-10 pm"]},
+		"Sunday": {"hours": ["10 am-6 pm", "7 pm-9 pm"]}
+	},
+	"owner": {"id": "owner-1", "name": "Cafe Central", "link": "https://www.google.com/maps/contrib/1"},
+	"images": [{"title": "All", "image": "https://lh5.googleusercontent.com/all"}],
+	"reservations": [{"link": "https://book.example/cafe", "source": "book.example"}],
+	"order_online": [{"link": "https://order.example/cafe", "source": "order.example"}],
+	"menu": {"link": "https://cafecentral.wien/menu", "source": "cafecentral.wien"},
+	"reviews_per_rating": {"1": 100, "5": 9000},
+	"opened_year": 1876,
+	"custom_fields": {"wifi": "yes"},
+	"booking_available": true,
+	"booking_provider": "book.example",
+	"request_id": "req-1",
+	"dietary_options": ["vegan"],
+	"ticket_links": {"tickets.example": "https://tickets.example/cafe"},
+	"charging": {"connectors": [{"type": "CCS", "power_kw": 150, "count": 4, "available": -1}]},
+	"fuel": {"types": ["Diesel"], "prices": [{"type": "Diesel", "price": "€1.59"}]},
+	"price_per_person": {"min": 20, "max": 30, "currency": "EUR"},
+	"third_party_ratings": {"tripadvisor": 4.5},
+	"spam_score": 0.25,
+	"menu_highlights": [{"label": "Sachertorte", "image_url": "https://lh5.googleusercontent.com/cake"}],
+	"contact_validation": {
+		"emails": ["info@cafecentral.wien"],
+		"web_site": "https://cafecentral.wien",
+		"raw_emails": ["INFO@cafecentral.wien", "a@mailinator.com"],
+		"raw_web_site": "cafecentral.wien",
+		"issues": ["disposable email domain: a@mailinator.com"]
+	},
+	"claim_url": "https://business.google.com/claim",
+	"weekly_hours": {
+		"days": {
+			"Monday": {"ranges": [{"open": "08:00", "close": "22:00"}]},
+			"Sunday": {}
+		},
+		"open_24_hours": true,
+		"temporarily_closed": true
+	},
+	"phone_e164": "+4315333763",
+	"photos": ["https://lh5.googleusercontent.com/1", "https://lh5.googleusercontent.com/2"],
+	"social_links": {
+		"facebook": ["https://facebook.com/cafecentral"],
+		"instagram": ["https://instagram.com/cafecentral"],
+		"linkedin": ["https://linkedin.com/company/cafecentral"],
+		"twitter": ["https://x.com/cafecentral"]
+	},
+	"place_id": "ChIJ123",
+	"maps_url": "https://www.google.com/maps/search/?api=1&query_place_id=ChIJ123"
+}`
+
+func Test_MarshalMatchesSchema(t *testing.T) {
+	desc := placeDescriptor(t)
+
+	require.JSONEq(t, fullPlace, decode(t, desc, placepb.Marshal(fullEntry())))
+}
+
+func Test_MarshalEmptyMessages(t *testing.T) {
+	desc := placeDescriptor(t)
+
+	// the optional messages are present even when empty, the rest is skipped
+	entry := &gmaps.Entry{
+		Title:          "Charger",
+		Charging:       &gmaps.Charging{},
+		Fuel:           &gmaps.Fuel{},
+		PricePerPerson: &gmaps.PricePerPerson{},
+	}
+
+	require.JSONEq(t, `{
+		"title": "Charger",
+		"charging": {},
+		"fuel": {},
+		"price_per_person": {}
+	}`, decode(t, desc, placepb.Marshal(entry)))
+
+	msg := dynamicpb.NewMessage(desc)
+	require.NoError(t, proto.Unmarshal(placepb.Marshal(&gmaps.Entry{}), msg))
+	require.False(t, msg.Has(desc.Fields().ByName("charging")))
+	require.False(t, msg.Has(desc.Fields().ByName("complete_address")))
+}
+
+func Test_WriterIsDelimited(t *testing.T) {
+	desc := placeDescriptor(t)
+
+	entries := []*gmaps.Entry{fullEntry(), {Title: "second"}, {}}
+
+	var buf bytes.Buffer
+
+	w := placepb.NewWriter(&buf)
+	for _, entry := range entries {
+		require.NoError(t, w.Write(entry))
+	}
+
+	r := bufio.NewReader(&buf)
+
+	for _, entry := range entries {
+		msg := dynamicpb.NewMessage(desc)
+		require.NoError(t, protodelim.UnmarshalFrom(r, msg))
+
+		want := dynamicpb.NewMessage(desc)
+		require.NoError(t, proto.Unmarshal(placepb.Marshal(entry), want))
+
+		require.True(t, proto.Equal(want, msg))
+	}
+
+	_, err := r.ReadByte()
+	require.Error(t, err)
+}
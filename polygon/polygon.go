@@ -0,0 +1,258 @@
+// Package polygon restricts the searches to an area given as a GeoJSON polygon:
+// it covers the area with a grid of search points and tells which places fall inside.
+package polygon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+const (
+	// DefaultZoom is the zoom of the grid searches when none is given
+	DefaultZoom = 15
+
+	// MaxGridPoints limits the searches of a single query, a larger
+	// area needs a lower zoom
+	MaxGridPoints = 2500
+
+	// cellTiles is the width in 256px map tiles of the area a search covers
+	cellTiles = 3
+)
+
+// Point is a search point of the grid
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// Coordinates returns the point in the format of the -geo flag
+func (p Point) Coordinates() string {
+	return strconv.FormatFloat(p.Lat, 'f', 6, 64) + "," + strconv.FormatFloat(p.Lon, 'f', 6, 64)
+}
+
+// Polygon is a GeoJSON Polygon or MultiPolygon. Every polygon is a list of
+// rings of [longitude, latitude] positions, the first ring is the outer
+// boundary and the others are holes.
+type Polygon struct {
+	Polygons [][][][2]float64
+}
+
+// Load reads a GeoJSON polygon from a file, see Parse
+func Load(path string) (*Polygon, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse parses a GeoJSON Polygon or MultiPolygon geometry, or a Feature or
+// FeatureCollection holding them. The polygons of a collection are merged.
+func Parse(data []byte) (*Polygon, error) {
+	var obj struct {
+		Type        string            `json:"type"`
+		Coordinates json.RawMessage   `json:"coordinates"`
+		Geometry    json.RawMessage   `json:"geometry"`
+		Features    []json.RawMessage `json:"features"`
+	}
+
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("invalid geojson: %w", err)
+	}
+
+	var ans Polygon
+
+	switch obj.Type {
+	case "Polygon":
+		var p [][][2]float64
+		if err := json.Unmarshal(obj.Coordinates, &p); err != nil {
+			return nil, fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+
+		ans.Polygons = append(ans.Polygons, p)
+	case "MultiPolygon":
+		if err := json.Unmarshal(obj.Coordinates, &ans.Polygons); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+	case "Feature":
+		return Parse(obj.Geometry)
+	case "FeatureCollection":
+		for _, f := range obj.Features {
+			p, err := Parse(f)
+			if err != nil {
+				return nil, err
+			}
+
+			ans.Polygons = append(ans.Polygons, p.Polygons...)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geojson type %q, expected a Polygon or MultiPolygon", obj.Type)
+	}
+
+	if err := ans.validate(); err != nil {
+		return nil, err
+	}
+
+	return &ans, nil
+}
+
+func (p *Polygon) validate() error {
+	if len(p.Polygons) == 0 {
+		return errors.New("the geojson has no polygon")
+	}
+
+	for _, rings := range p.Polygons {
+		if len(rings) == 0 {
+			return errors.New("polygon without rings")
+		}
+
+		for _, ring := range rings {
+			if len(ring) < 4 {
+				return errors.New("a polygon ring needs at least 4 positions")
+			}
+
+			for _, pos := range ring {
+				if pos[0] < -180 || pos[0] > 180 || pos[1] < -90 || pos[1] > 90 {
+					return fmt.Errorf("invalid position %v", pos)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Contains reports if the point is inside the polygon, outside of its holes
+func (p *Polygon) Contains(lat, lon float64) bool {
+	for _, rings := range p.Polygons {
+		if !inRing(rings[0], lat, lon) {
+			continue
+		}
+
+		inHole := false
+
+		for _, hole := range rings[1:] {
+			if inRing(hole, lat, lon) {
+				inHole = true
+
+				break
+			}
+		}
+
+		if !inHole {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inRing is the even-odd ray casting test
+func inRing(ring [][2]float64, lat, lon float64) bool {
+	ans := false
+
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			ans = !ans
+		}
+	}
+
+	return ans
+}
+
+// bounds returns the bounding box of the outer rings
+func (p *Polygon) bounds() (minLat, minLon, maxLat, maxLon float64) {
+	minLat, minLon = math.Inf(1), math.Inf(1)
+	maxLat, maxLon = math.Inf(-1), math.Inf(-1)
+
+	for _, rings := range p.Polygons {
+		for _, pos := range rings[0] {
+			minLon, maxLon = math.Min(minLon, pos[0]), math.Max(maxLon, pos[0])
+			minLat, maxLat = math.Min(minLat, pos[1]), math.Max(maxLat, pos[1])
+		}
+	}
+
+	return minLat, minLon, maxLat, maxLon
+}
+
+// Center returns the center of the bounding box of the polygon
+func (p *Polygon) Center() Point {
+	minLat, minLon, maxLat, maxLon := p.bounds()
+
+	return Point{Lat: (minLat + maxLat) / 2, Lon: (minLon + maxLon) / 2}
+}
+
+// Grid returns the search points covering the polygon at zoom. The bounding
+// box is split in cells about the size of the map a search shows, the cells
+// touching the polygon are kept and searched from their center.
+func (p *Polygon) Grid(zoom int) ([]Point, error) {
+	if zoom <= 0 {
+		zoom = DefaultZoom
+	}
+
+	minLat, minLon, maxLat, maxLon := p.bounds()
+
+	lonStep := 360 / math.Exp2(float64(zoom)) * cellTiles
+	// the cells are square on the map, their latitude span shrinks away from the equator
+	latStep := lonStep * math.Cos((minLat+maxLat)/2*math.Pi/180)
+
+	rows := int(math.Ceil((maxLat-minLat)/latStep)) + 1
+	cols := int(math.Ceil((maxLon-minLon)/lonStep)) + 1
+
+	var ans []Point
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			lat := minLat + float64(r)*latStep
+			lon := minLon + float64(c)*lonStep
+
+			if !p.touches(lat-latStep/2, lon-lonStep/2, lat+latStep/2, lon+lonStep/2) {
+				continue
+			}
+
+			if len(ans) == MaxGridPoints {
+				return nil, fmt.Errorf("the polygon needs more than %d searches at zoom %d, use a lower zoom", MaxGridPoints, zoom)
+			}
+
+			ans = append(ans, Point{Lat: lat, Lon: lon})
+		}
+	}
+
+	return ans, nil
+}
+
+// touches reports if the cell overlaps the polygon: a corner or the center of
+// the cell is inside the polygon or a vertex of the polygon is inside the cell
+func (p *Polygon) touches(minLat, minLon, maxLat, maxLon float64) bool {
+	points := [][2]float64{
+		{(minLat + maxLat) / 2, (minLon + maxLon) / 2},
+		{minLat, minLon},
+		{minLat, maxLon},
+		{maxLat, minLon},
+		{maxLat, maxLon},
+	}
+
+	for _, pt := range points {
+		if p.Contains(pt[0], pt[1]) {
+			return true
+		}
+	}
+
+	for _, rings := range p.Polygons {
+		for _, pos := range rings[0] {
+			if pos[1] >= minLat && pos[1] <= maxLat && pos[0] >= minLon && pos[0] <= maxLon {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// PlaceStore updates single places in the results table
+type PlaceStore struct {
+	db *sql.DB
+}
+
+func NewPlaceStore(db *sql.DB) *PlaceStore {
+	return &PlaceStore{db: db}
+}
+
+// SavePlace replaces the stored results of the place with entry, matching them by data_id.
+// The input_id of the stored results is kept. Places that are not stored yet are inserted.
+func (s *PlaceStore) SavePlace(ctx context.Context, entry *gmaps.Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	const update = `UPDATE results
+		SET data = $1::jsonb || jsonb_build_object('input_id', data->'input_id')
+		WHERE data->>'data_id' = $2`
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	res, err := tx.ExecContext(ctx, update, data, entry.DataID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO results (data) VALUES ($1)`, data); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -1,27 +1,67 @@
 package postgres
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/gob"
 	"errors"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
 	"github.com/gosom/scrapemate"
 
+	"github.com/gosom/google-maps-scraper/deduper"
 	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/jobcodec"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/quota"
+	"github.com/gosom/google-maps-scraper/webhook"
 )
 
 const (
 	statusNew    = "new"
 	statusQueued = "queued"
+	// statusCancelled is set by Cancel, the workers stop scraping the job
+	statusCancelled = "cancelled"
+	// statusDeadLetter is reported for the jobs of the dead letter queue
+	statusDeadLetter = "dead_letter"
 )
 
+// DefaultMaxAttempts is the number of times a failing job is fetched
+// before it's given up
+const DefaultMaxAttempts = 3
+
+// DefaultLease is how long a fetched job stays with its worker without news
+// before it's queued again. The lease is renewed while the worker runs, so
+// only the jobs of crashed workers expire.
+const DefaultLease = 5 * time.Minute
+
 var _ scrapemate.JobProvider = (*provider)(nil)
 var _ gmaps.Provider = (*provider)(nil)
+var _ gmaps.Throttler = (*provider)(nil)
+var _ gmaps.Canceller = (*provider)(nil)
+var _ gmaps.DeadLetterQueue = (*provider)(nil)
+var _ gmaps.Acker = (*provider)(nil)
+
+// Provider is a postgres backed job queue
+type Provider interface {
+	scrapemate.JobProvider
+	gmaps.Provider
+	gmaps.DeadLetterQueue
+	gmaps.ResultReader
+	gmaps.Acker
+	quota.Store
+	// Release queues again the jobs fetched by the provider that were not
+	// acked, it's called when the scraper stops
+	Release(ctx context.Context) error
+}
+
+type throttleEntry struct {
+	delay     time.Duration
+	cancelled bool
+	fetchedAt time.Time
+}
 
 type provider struct {
 	db      *sql.DB
@@ -29,14 +69,104 @@ type provider struct {
 	jobc    chan scrapemate.IJob
 	errc    chan error
 	started bool
+
+	throttleMu *sync.Mutex
+	throttles  map[string]throttleEntry
+
+	// leased are the jobs fetched by the provider and not acked yet
+	leaseMu *sync.Mutex
+	leased  map[string]struct{}
+	lease   time.Duration
+
+	limiter      gmaps.Limiter
+	proxyMonitor gmaps.ProxyMonitor
+	userAgents   gmaps.UserAgents
+	deduper      deduper.Deduper
+	deriver      gmaps.Deriver
+	deadLetter   bool
+	maxAttempts  int
+	callbacks    *webhook.Sender
+}
+
+type ProviderOption func(*provider)
+
+// WithLimiter sets the concurrency limiter of the fetched jobs
+func WithLimiter(l gmaps.Limiter) ProviderOption {
+	return func(p *provider) {
+		p.limiter = l
+	}
+}
+
+// WithUserAgents sets the picker of the user agents of the fetched jobs
+func WithUserAgents(u gmaps.UserAgents) ProviderOption {
+	return func(p *provider) {
+		p.userAgents = u
+	}
+}
+
+// WithDeduper sets the deduper of the places found by the cells of the
+// polygon searches
+func WithDeduper(d deduper.Deduper) ProviderOption {
+	return func(p *provider) {
+		p.deduper = d
+	}
+}
+
+// WithDeriver sets the derived fields added to the places of the fetched jobs
+func WithDeriver(d gmaps.Deriver) ProviderOption {
+	return func(p *provider) {
+		p.deriver = d
+	}
+}
+
+// WithProxyMonitor sets the monitor of the blocked proxies of the fetched jobs
+func WithProxyMonitor(m gmaps.ProxyMonitor) ProviderOption {
+	return func(p *provider) {
+		p.proxyMonitor = m
+	}
 }
 
-func NewProvider(db *sql.DB) scrapemate.JobProvider {
+// WithDeadLetterQueue moves the jobs that fail after all their attempts
+// to the gmaps_jobs_dlq table
+func WithDeadLetterQueue() ProviderOption {
+	return func(p *provider) {
+		p.deadLetter = true
+	}
+}
+
+// WithMaxAttempts sets the number of times a failing job is fetched,
+// the job is queued again after each failure until n is reached
+func WithMaxAttempts(n int) ProviderOption {
+	return func(p *provider) {
+		p.maxAttempts = n
+	}
+}
+
+// WithLease sets how long a fetched job is kept by its worker before it's
+// queued again when the worker stops renewing it, 0 disables the reclaim
+func WithLease(d time.Duration) ProviderOption {
+	return func(p *provider) {
+		p.lease = d
+	}
+}
+
+func NewProvider(db *sql.DB, opts ...ProviderOption) Provider {
 	prov := provider{
-		db:   db,
-		mu:   &sync.Mutex{},
-		errc: make(chan error, 1),
-		jobc: make(chan scrapemate.IJob, 100),
+		db:         db,
+		mu:         &sync.Mutex{},
+		errc:       make(chan error, 1),
+		jobc:       make(chan scrapemate.IJob, 100),
+		throttleMu: &sync.Mutex{},
+		throttles:  make(map[string]throttleEntry),
+		leaseMu:    &sync.Mutex{},
+		leased:     make(map[string]struct{}),
+
+		maxAttempts: DefaultMaxAttempts,
+		lease:       DefaultLease,
+	}
+
+	for _, opt := range opts {
+		opt(&prov)
 	}
 
 	return &prov
@@ -51,6 +181,10 @@ func (p *provider) Jobs(ctx context.Context) (<-chan scrapemate.IJob, <-chan err
 	if !p.started {
 		go p.fetchJobs(ctx)
 
+		if p.lease > 0 {
+			go p.renewLeases(ctx)
+		}
+
 		p.started = true
 	}
 	p.mu.Unlock()
@@ -81,42 +215,723 @@ func (p *provider) Jobs(ctx context.Context) (<-chan scrapemate.IJob, <-chan err
 	return outc, errc
 }
 
+const pushQuery = `INSERT INTO gmaps_jobs
+	(id, priority, payload_type, payload, created_at, status, tenant, queue_priority)
+	VALUES
+	($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT DO NOTHING`
+
+const pushProgressQuery = `INSERT INTO gmaps_job_progress (job_id, url) VALUES ($1, NULLIF($2, '')) ON CONFLICT DO NOTHING`
+
 // Push pushes a job to the job provider
 func (p *provider) Push(ctx context.Context, job scrapemate.IJob) error {
-	q := `INSERT INTO gmaps_jobs
-		(id, priority, payload_type, payload, created_at, status)
-		VALUES
-		($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`
+	if _, ok := job.(*gmaps.GmapJob); ok {
+		// the search job and its progress are saved together
+		return p.PushBatch(ctx, []scrapemate.IJob{job})
+	}
 
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
+	payloadType, payload, tenant, err := jobcodec.Encode(job)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, pushQuery,
+		job.GetID(), job.GetPriority(), payloadType, payload, time.Now().UTC(), statusNew, tenant, queuePriority(job),
+	)
+
+	return err
+}
+
+// PushBatch pushes the jobs in a single transaction, none is pushed on error
+func (p *provider) PushBatch(ctx context.Context, jobs []scrapemate.IJob) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, pushQuery)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	now := time.Now().UTC()
+
+	for _, job := range jobs {
+		payloadType, payload, tenant, err := jobcodec.Encode(job)
+		if err != nil {
+			return err
+		}
+
+		_, err = stmt.ExecContext(ctx,
+			job.GetID(), job.GetPriority(), payloadType, payload, now, statusNew, tenant, queuePriority(job),
+		)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := job.(*gmaps.GmapJob); ok {
+			if _, err := tx.ExecContext(ctx, pushProgressQuery, job.GetID(), webhookURL(job)); err != nil {
+				return err
+			}
+		}
+	}
 
-	var payloadType string
+	return tx.Commit()
+}
+
+// webhookURL returns the url receiving the completion of the search job
+func webhookURL(job scrapemate.IJob) string {
+	if j, ok := job.(*gmaps.GmapJob); ok {
+		return j.WebhookURL
+	}
+
+	return ""
+}
 
+// queuePriority returns the queue priority of the job, the jobs with a
+// higher queue priority are fetched first
+func queuePriority(job scrapemate.IJob) int {
 	switch j := job.(type) {
 	case *gmaps.GmapJob:
-		payloadType = "search"
+		return j.QueuePriority
+	case *gmaps.PlaceJob:
+		return j.QueuePriority
+	}
+
+	return gmaps.DefaultQueuePriority
+}
+
+// UpdateThrottle sets the delay that is applied before each fetch of the job
+// and its places. It returns gmaps.ErrJobCompleted for jobs that already finished.
+func (p *provider) UpdateThrottle(ctx context.Context, jobID string, throttle time.Duration) error {
+	const q = `UPDATE gmaps_jobs SET throttle_ms = $1, updated_at = NOW() WHERE id = $2 AND state IN ($3, $4)`
+
+	res, err := p.db.ExecContext(ctx, q, throttle.Milliseconds(), jobID, statePending, stateRunning)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		p.throttleMu.Lock()
+		delete(p.throttles, jobID)
+		p.throttleMu.Unlock()
+
+		return nil
+	}
+
+	var exists bool
+
+	err = p.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM gmaps_jobs WHERE id = $1)`, jobID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return gmaps.ErrJobNotFound
+	}
+
+	return gmaps.ErrJobCompleted
+}
+
+// Delete removes the job from gmaps_jobs while no worker ever fetched it. A
+// job queued again after its lease expired or a failed attempt is new too,
+// but its places may be in flight, so its attempts and start are checked.
+func (p *provider) Delete(ctx context.Context, jobID string) error {
+	const q = `DELETE FROM gmaps_jobs
+		WHERE id = $1 AND status = $2 AND attempts = 0 AND started_at IS NULL AND lease_until IS NULL`
+
+	res, err := p.db.ExecContext(ctx, q, jobID, statusNew)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return nil
+	}
+
+	const existsQ = `SELECT EXISTS(SELECT 1 FROM gmaps_jobs WHERE id = $1)
+		OR EXISTS(SELECT 1 FROM gmaps_jobs_dlq WHERE id = $1)`
+
+	var exists bool
+
+	if err := p.db.QueryRowContext(ctx, existsQ, jobID).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return gmaps.ErrJobNotFound
+	}
+
+	return gmaps.ErrJobStarted
+}
+
+// Cancel marks the new or running job as cancelled. A new job is never fetched,
+// the workers stop scraping a running one when they pick up the cancellation.
+// Cancelling a cancelled job does nothing.
+func (p *provider) Cancel(ctx context.Context, jobID string) error {
+	const q = `UPDATE gmaps_jobs
+		SET status = $1, state = $3, finished_at = COALESCE(finished_at, NOW()), updated_at = NOW()
+		WHERE id = $2 AND (state = $3 OR state = ANY($4))`
+
+	res, err := p.db.ExecContext(ctx, q, statusCancelled, jobID, stateCancelled, statesTo(stateCancelled))
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		p.throttleMu.Lock()
+		delete(p.throttles, jobID)
+		p.throttleMu.Unlock()
+
+		return nil
+	}
+
+	const existsQ = `SELECT EXISTS(SELECT 1 FROM gmaps_jobs WHERE id = $1)
+		OR EXISTS(SELECT 1 FROM gmaps_jobs_dlq WHERE id = $1)`
+
+	var exists bool
+
+	if err := p.db.QueryRowContext(ctx, existsQ, jobID).Scan(&exists); err != nil {
+		return err
+	}
+
+	if !exists {
+		return gmaps.ErrJobNotFound
+	}
+
+	return gmaps.ErrJobCompleted
+}
+
+// Get returns the job from gmaps_jobs or the dead letter queue
+func (p *provider) Get(ctx context.Context, jobID string) (scrapemate.IJob, error) {
+	const q = `
+	SELECT payload_type, payload FROM gmaps_jobs WHERE id = $1
+	UNION ALL
+	SELECT payload_type, payload FROM gmaps_jobs_dlq WHERE id = $1
+	LIMIT 1
+	`
+
+	var (
+		payloadType string
+		payload     []byte
+	)
+
+	err := p.db.QueryRowContext(ctx, q, jobID).Scan(&payloadType, &payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, gmaps.ErrJobNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return jobcodec.Decode(payloadType, payload)
+}
+
+// Info returns the status of the job and the number of its results.
+// The jobs in the dead letter queue are reported as dead_letter and failed.
+func (p *provider) Info(ctx context.Context, jobID string) (gmaps.JobInfo, error) {
+	const q = `
+	SELECT payload_type, payload, status, state, created_at, COALESCE(updated_at, created_at), started_at, finished_at
+	FROM gmaps_jobs WHERE id = $1
+	UNION ALL
+	SELECT payload_type, payload, $2, $3, created_at, failed_at, started_at, failed_at
+	FROM gmaps_jobs_dlq WHERE id = $1
+	LIMIT 1
+	`
+
+	var (
+		payloadType string
+		payload     []byte
+		ans         = gmaps.JobInfo{ID: jobID}
+	)
+
+	err := p.db.QueryRowContext(ctx, q, jobID, statusDeadLetter, stateFailed).
+		Scan(&payloadType, &payload, &ans.Status, &ans.State, &ans.CreatedAt, &ans.UpdatedAt, &ans.StartedAt, &ans.FinishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ans, gmaps.ErrJobNotFound
+	}
+
+	if err != nil {
+		return ans, err
+	}
+
+	if err := setJobParams(&ans, payloadType, payload); err != nil {
+		return ans, err
+	}
+
+	// the places keep the id of the search job they were found by
+	const countQ = `SELECT COUNT(*) FROM results WHERE data->>'input_id' = $1`
+
+	if err := p.db.QueryRowContext(ctx, countQ, jobID).Scan(&ans.ResultCount); err != nil {
+		return ans, err
+	}
+
+	return ans, nil
+}
+
+// List returns the jobs of gmaps_jobs and the dead letter queue matching
+// filter, ordered by created_at descending
+func (p *provider) List(ctx context.Context, filter gmaps.JobFilter) ([]gmaps.JobInfo, int, error) {
+	const jobs = `
+	WITH jobs AS (
+		SELECT id, payload_type, payload, status, state, tenant, created_at,
+			COALESCE(updated_at, created_at) AS updated_at, started_at, finished_at
+		FROM gmaps_jobs
+		UNION ALL
+		SELECT id, payload_type, payload, $1, '` + stateFailed + `', tenant, created_at, failed_at, started_at, failed_at
+		FROM gmaps_jobs_dlq
+	)
+	`
+
+	const where = ` WHERE ($2 = '' OR status = $2) AND ($3 = '' OR tenant = $3) AND ($4 = '' OR state = $4)`
+
+	var total int
+
+	err := p.db.QueryRowContext(ctx, jobs+`SELECT COUNT(*) FROM jobs`+where,
+		statusDeadLetter, filter.Status, filter.Tenant, filter.State,
+	).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the results of the jobs of the page are counted with a single grouped
+	// scan of results_input_id_idx instead of one count per job
+	q := jobs + `, page AS (
+		SELECT * FROM jobs` + where + ` ORDER BY created_at DESC LIMIT $5 OFFSET $6
+	), counts AS (
+		SELECT data->>'input_id' AS job_id, COUNT(*) AS n FROM results
+		WHERE data->>'input_id' IN (SELECT id::text FROM page)
+		GROUP BY 1
+	)
+	SELECT page.id, payload_type, payload, status, state, created_at, updated_at, started_at, finished_at,
+		COALESCE(counts.n, 0)
+	FROM page LEFT JOIN counts ON counts.job_id = page.id::text
+	ORDER BY created_at DESC`
+
+	rows, err := p.db.QueryContext(ctx, q, statusDeadLetter, filter.Status, filter.Tenant, filter.State, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	ans := []gmaps.JobInfo{}
+
+	for rows.Next() {
+		var (
+			item        gmaps.JobInfo
+			payloadType string
+			payload     []byte
+		)
+
+		err := rows.Scan(&item.ID, &payloadType, &payload, &item.Status, &item.State,
+			&item.CreatedAt, &item.UpdatedAt, &item.StartedAt, &item.FinishedAt, &item.ResultCount)
+		if err != nil {
+			return nil, 0, err
+		}
 
-		if err := enc.Encode(j); err != nil {
+		if err := setJobParams(&item, payloadType, payload); err != nil {
+			return nil, 0, err
+		}
+
+		ans = append(ans, item)
+	}
+
+	return ans, total, rows.Err()
+}
+
+// Results streams the places saved for the finished job, a search job is
+// completed when all its places are done. The cancelled and the failed jobs
+// are finished with the places saved before. With partial the places saved
+// so far of the running jobs are streamed too, the reads don't block the
+// workers saving more places.
+func (p *provider) Results(ctx context.Context, jobID string, partial bool, fn func(data []byte) error) error {
+	const q = `
+	SELECT state FROM gmaps_jobs WHERE id = $1
+	UNION ALL
+	SELECT $2 FROM gmaps_jobs_dlq WHERE id = $1
+	LIMIT 1
+	`
+
+	var state string
+
+	err := p.db.QueryRowContext(ctx, q, jobID, stateFailed).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return gmaps.ErrJobNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !partial && (state == statePending || state == stateRunning) {
+		return gmaps.ErrJobNotFinished
+	}
+
+	rows, err := p.db.QueryContext(ctx, `SELECT data FROM results WHERE data->>'input_id' = $1 ORDER BY id`, jobID)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+
+		if err := rows.Scan(&data); err != nil {
 			return err
 		}
-	case *gmaps.PlaceJob:
-		payloadType = "place"
 
-		if err := enc.Encode(j); err != nil {
+		if err := fn(data); err != nil {
 			return err
 		}
-	default:
-		return errors.New("invalid job type")
 	}
 
-	_, err := p.db.ExecContext(ctx, q,
-		job.GetID(), job.GetPriority(), payloadType, buf.Bytes(), time.Now().UTC(), statusNew,
+	return rows.Err()
+}
+
+// setJobParams sets the type and the search parameters of the job from its payload
+func setJobParams(info *gmaps.JobInfo, payloadType string, payload []byte) error {
+	job, err := jobcodec.Decode(payloadType, payload)
+	if err != nil {
+		return err
+	}
+
+	info.Type = payloadType
+
+	switch j := job.(type) {
+	case *gmaps.GmapJob:
+		info.Query = j.Query
+		info.Language = j.LangCode
+		info.Tenant = j.Tenant
+	case *gmaps.PlaceJob:
+		info.Tenant = j.Tenant
+	}
+
+	return nil
+}
+
+// Throttle returns the current throttle of the job.
+// The value is cached for a few seconds, so updates are picked up on
+// one of the next fetches.
+func (p *provider) Throttle(ctx context.Context, jobID string) time.Duration {
+	return p.jobState(ctx, jobID).delay
+}
+
+// Cancelled reports whether the job was cancelled. Like the throttle
+// the value is cached, so a cancellation is picked up within a few seconds.
+func (p *provider) Cancelled(ctx context.Context, jobID string) bool {
+	return p.jobState(ctx, jobID).cancelled
+}
+
+// jobState returns the throttle and the cancellation of the job,
+// cached for a few seconds
+func (p *provider) jobState(ctx context.Context, jobID string) throttleEntry {
+	const cacheTTL = 5 * time.Second
+
+	p.throttleMu.Lock()
+	entry, ok := p.throttles[jobID]
+	p.throttleMu.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < cacheTTL {
+		return entry
+	}
+
+	var (
+		ms     int64
+		status string
+	)
+
+	err := p.db.QueryRowContext(ctx, `SELECT throttle_ms, status FROM gmaps_jobs WHERE id = $1`, jobID).Scan(&ms, &status)
+	if err != nil {
+		ms, status = 0, ""
+	}
+
+	entry = throttleEntry{
+		delay:     time.Duration(ms) * time.Millisecond,
+		cancelled: status == statusCancelled,
+		fetchedAt: time.Now(),
+	}
+
+	p.throttleMu.Lock()
+	p.throttles[jobID] = entry
+	p.throttleMu.Unlock()
+
+	return entry
+}
+
+// DeadLetter queues the failed job again while it has attempts left. After its
+// last attempt the job is moved to the dead letter queue together with the
+// reason it failed when the queue is enabled, otherwise it's dropped.
+func (p *provider) DeadLetter(ctx context.Context, jobID string, reason error) error {
+	p.unlease(jobID)
+
+	// a cancelled job is neither retried nor dead lettered
+	if p.cancelledNow(ctx, jobID) {
+		p.PlacesFound(ctx, jobID, 0)
+
+		return nil
+	}
+
+	const retryQ = `UPDATE gmaps_jobs SET status = $1, state = $4, lease_until = NULL, updated_at = NOW()
+		WHERE id = $2 AND attempts < $3 AND state = ANY($5)`
+
+	res, err := p.db.ExecContext(ctx, retryQ, statusNew, jobID, p.maxAttempts, statePending, statesTo(statePending))
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		return nil
+	}
+
+	const failQ = `UPDATE gmaps_jobs SET state = $2, lease_until = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND state = ANY($3)
+		RETURNING payload_type, EXTRACT(EPOCH FROM finished_at - started_at)::float8`
+
+	rows, err := p.db.QueryContext(ctx, failQ, jobID, stateFailed, statesTo(stateFailed))
+	if err != nil {
+		return err
+	}
+
+	if n := observeDurations(rows, stateFailed); n == 0 {
+		return fmt.Errorf("job %s can't fail: %w", jobID, ErrIllegalTransition)
+	}
+
+	p.jobFailed(ctx, jobID, reason)
+
+	if !p.deadLetter {
+		return nil
+	}
+
+	const q = `
+	WITH moved AS (
+		DELETE FROM gmaps_jobs WHERE id = $1
+		RETURNING id, priority, payload_type, payload, created_at, tenant, attempts, queue_priority, started_at, finished_at
 	)
+	INSERT INTO gmaps_jobs_dlq
+		(id, priority, payload_type, payload, created_at, failed_at, reason, tenant, attempts, queue_priority, started_at)
+	SELECT id, priority, payload_type, payload, created_at, finished_at, $2, tenant, attempts, queue_priority, started_at FROM moved
+	ON CONFLICT (id) DO UPDATE SET failed_at = EXCLUDED.failed_at, reason = EXCLUDED.reason,
+		attempts = EXCLUDED.attempts, started_at = EXCLUDED.started_at
+	`
+
+	_, err = p.db.ExecContext(ctx, q, jobID, reason.Error())
 
 	return err
 }
 
+// Ack releases the lease of the processed job, it's not queued again.
+// A place job is completed, a search job once all its places are done.
+func (p *provider) Ack(ctx context.Context, jobID string) error {
+	p.unlease(jobID)
+
+	const q = `UPDATE gmaps_jobs SET lease_until = NULL,
+			state = CASE WHEN payload_type = 'place' AND state = ANY($3) THEN $2 ELSE state END,
+			finished_at = CASE WHEN payload_type = 'place' AND state = ANY($3) THEN NOW() ELSE finished_at END
+		WHERE id = $1
+		RETURNING payload_type, CASE WHEN state = $2 AND finished_at = NOW()
+			THEN EXTRACT(EPOCH FROM finished_at - started_at)::float8 END`
+
+	rows, err := p.db.QueryContext(ctx, q, jobID, stateCompleted, statesTo(stateCompleted))
+	if err != nil {
+		return err
+	}
+
+	observeDurations(rows, stateCompleted)
+
+	return nil
+}
+
+// Release queues again the jobs fetched by the provider that were not acked,
+// without counting the interrupted attempt. The other workers pick them up
+// without waiting for their lease to expire.
+func (p *provider) Release(ctx context.Context) error {
+	ids := p.leasedIDs()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	const q = `UPDATE gmaps_jobs
+		SET status = $1, state = $4, lease_until = NULL, attempts = GREATEST(attempts - 1, 0), updated_at = NOW()
+		WHERE id = ANY($2) AND status = $3 AND state = ANY($5)`
+
+	res, err := p.db.ExecContext(ctx, q, statusNew, ids, statusQueued, statePending, statesTo(statePending))
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	p.leaseMu.Lock()
+	clear(p.leased)
+	p.leaseMu.Unlock()
+
+	if n > 0 {
+		log.Printf("released %d unfinished jobs", n)
+	}
+
+	return nil
+}
+
+// renewLeases extends the lease of the jobs of the provider while it runs
+func (p *provider) renewLeases(ctx context.Context) {
+	const q = `UPDATE gmaps_jobs SET lease_until = NOW() + make_interval(secs => $1::float8)
+		WHERE id = ANY($2) AND status = $3`
+
+	ticker := time.NewTicker(p.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ids := p.leasedIDs()
+		if len(ids) == 0 {
+			continue
+		}
+
+		if _, err := p.db.ExecContext(ctx, q, p.lease.Seconds(), ids, statusQueued); err != nil && ctx.Err() == nil {
+			log.Printf("failed to renew the job leases: %v", err)
+		}
+	}
+}
+
+// reclaimExpired queues again the jobs whose lease expired, their worker
+// crashed or was killed before releasing them
+func (p *provider) reclaimExpired(ctx context.Context) error {
+	const q = `UPDATE gmaps_jobs SET status = $1, state = $4, lease_until = NULL, updated_at = NOW()
+		WHERE status = $2 AND lease_until < NOW() AND attempts < $3 AND state = ANY($5)`
+
+	res, err := p.db.ExecContext(ctx, q, statusNew, statusQueued, p.maxAttempts, statePending, statesTo(statePending))
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		log.Printf("reclaimed %d jobs with an expired lease", n)
+	}
+
+	return nil
+}
+
+func (p *provider) unlease(jobID string) {
+	p.leaseMu.Lock()
+	delete(p.leased, jobID)
+	p.leaseMu.Unlock()
+}
+
+func (p *provider) leasedIDs() []string {
+	p.leaseMu.Lock()
+	defer p.leaseMu.Unlock()
+
+	ids := make([]string, 0, len(p.leased))
+	for id := range p.leased {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// cancelledNow reports whether the job is cancelled, bypassing the cache
+func (p *provider) cancelledNow(ctx context.Context, jobID string) bool {
+	var cancelled bool
+
+	err := p.db.QueryRowContext(ctx, `SELECT status = $2 FROM gmaps_jobs WHERE id = $1`, jobID, statusCancelled).Scan(&cancelled)
+
+	return err == nil && cancelled
+}
+
+// ListDeadLetters returns the most recently failed jobs, of tenant when it's set
+func (p *provider) ListDeadLetters(ctx context.Context, limit int, tenant string) ([]gmaps.DeadLetterJob, error) {
+	const q = `SELECT id, payload_type, reason, attempts, created_at, failed_at
+		FROM gmaps_jobs_dlq WHERE ($2 = '' OR tenant = $2) ORDER BY failed_at DESC LIMIT $1`
+
+	rows, err := p.db.QueryContext(ctx, q, limit, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ans := []gmaps.DeadLetterJob{}
+
+	for rows.Next() {
+		var item gmaps.DeadLetterJob
+
+		if err := rows.Scan(&item.ID, &item.Type, &item.Reason, &item.Attempts, &item.CreatedAt, &item.FailedAt); err != nil {
+			return nil, err
+		}
+
+		ans = append(ans, item)
+	}
+
+	return ans, rows.Err()
+}
+
+// Requeue moves the job from the dead letter queue back to gmaps_jobs as a new job
+// with all its attempts
+func (p *provider) Requeue(ctx context.Context, jobID string) error {
+	const q = `
+	WITH moved AS (
+		DELETE FROM gmaps_jobs_dlq WHERE id = $1
+		RETURNING id, priority, payload_type, payload, created_at, tenant, queue_priority
+	)
+	INSERT INTO gmaps_jobs
+		(id, priority, payload_type, payload, created_at, status, state, updated_at, tenant, queue_priority)
+	SELECT id, priority, payload_type, payload, created_at, $2, $3, NOW(), tenant, queue_priority FROM moved
+	`
+
+	// the failed job is pending again, started and finished from scratch
+	res, err := p.db.ExecContext(ctx, q, jobID, statusNew, statePending)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return gmaps.ErrJobNotFound
+	}
+
+	return p.resetProgress(ctx, jobID)
+}
+
 func (p *provider) fetchJobs(ctx context.Context) {
 	defer close(p.jobc)
 	defer close(p.errc)
@@ -124,16 +939,20 @@ func (p *provider) fetchJobs(ctx context.Context) {
 	q := `
 	WITH updated AS (
 		UPDATE gmaps_jobs
-		SET status = $1
+		SET status = $1, state = $4, updated_at = NOW(), attempts = attempts + 1,
+			lease_until = CASE WHEN $3::float8 > 0 THEN NOW() + make_interval(secs => $3::float8) END,
+			started_at = COALESCE(started_at, NOW())
 		WHERE id IN (
 			SELECT id from gmaps_jobs
-			WHERE status = $2
-			ORDER BY priority ASC, created_at ASC FOR UPDATE SKIP LOCKED 
+			WHERE status = $2 AND state = ANY($5)
+			ORDER BY queue_priority DESC, priority ASC, created_at ASC, seq ASC FOR UPDATE SKIP LOCKED 
 		LIMIT 50
 		)
 		RETURNING *
 	)
-	SELECT payload_type, payload from updated ORDER by priority ASC, created_at ASC
+	SELECT payload_type, payload,
+		CASE WHEN started_at = NOW() THEN EXTRACT(EPOCH FROM started_at - created_at)::float8 END
+	FROM updated ORDER by queue_priority DESC, priority ASC, created_at ASC, seq ASC
 	`
 
 	baseDelay := time.Second
@@ -150,7 +969,15 @@ func (p *provider) fetchJobs(ctx context.Context) {
 		default:
 		}
 
-		rows, err := p.db.QueryContext(ctx, q, statusQueued, statusNew)
+		if p.lease > 0 {
+			if err := p.reclaimExpired(ctx); err != nil {
+				p.errc <- err
+
+				return
+			}
+		}
+
+		rows, err := p.db.QueryContext(ctx, q, statusQueued, statusNew, p.lease.Seconds(), stateRunning, statesTo(stateRunning))
 		if err != nil {
 			p.errc <- err
 
@@ -161,21 +988,62 @@ func (p *provider) fetchJobs(ctx context.Context) {
 			var (
 				payloadType string
 				payload     []byte
+				queueWait   *float64
 			)
 
-			if err := rows.Scan(&payloadType, &payload); err != nil {
+			if err := rows.Scan(&payloadType, &payload, &queueWait); err != nil {
 				p.errc <- err
 
 				return
 			}
 
-			job, err := decodeJob(payloadType, payload)
+			// the wait is only known on the first start of the job
+			if queueWait != nil {
+				metrics.JobQueueWait.WithLabelValues(payloadType).Observe(*queueWait)
+			}
+
+			job, err := jobcodec.Decode(payloadType, payload)
 			if err != nil {
 				p.errc <- err
 
 				return
 			}
 
+			switch j := job.(type) {
+			case *gmaps.GmapJob:
+				j.Throttler = p
+				j.Canceller = p
+				j.Limiter = p.limiter
+				j.ProxyMonitor = p.proxyMonitor
+				j.UserAgents = p.userAgents
+				j.Deriver = p.deriver
+				j.DeadLetter = p
+				j.Acker = p
+				j.Tracker = p
+
+				// the places of a polygon search are deduplicated across its cells
+				if j.ParentID != "" {
+					j.Deduper = p.deduper
+				}
+			case *gmaps.PlaceJob:
+				j.Throttler = p
+				j.Canceller = p
+				j.Limiter = p.limiter
+				j.ProxyMonitor = p.proxyMonitor
+				j.UserAgents = p.userAgents
+				j.Deriver = p.deriver
+				j.DeadLetter = p
+				j.Acker = p
+
+				if j.Tracked {
+					j.Tracker = p
+				}
+			}
+
+			p.leaseMu.Lock()
+			p.leased[job.GetID()] = struct{}{}
+			p.leaseMu.Unlock()
+
 			jobs = append(jobs, job)
 		}
 
@@ -219,27 +1087,3 @@ type encjob struct {
 	Type string
 	Data scrapemate.IJob
 }
-
-func decodeJob(payloadType string, payload []byte) (scrapemate.IJob, error) {
-	buf := bytes.NewBuffer(payload)
-	dec := gob.NewDecoder(buf)
-
-	switch payloadType {
-	case "search":
-		j := new(gmaps.GmapJob)
-		if err := dec.Decode(j); err != nil {
-			return nil, fmt.Errorf("failed to decode search job: %w", err)
-		}
-
-		return j, nil
-	case "place":
-		j := new(gmaps.PlaceJob)
-		if err := dec.Decode(j); err != nil {
-			return nil, fmt.Errorf("failed to decode place job: %w", err)
-		}
-
-		return j, nil
-	default:
-		return nil, fmt.Errorf("invalid payload type: %s", payloadType)
-	}
-}
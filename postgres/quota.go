@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/quota"
+)
+
+// Usage returns the usage of tenant, it's zero when the stored usage is of a previous period
+func (p *provider) Usage(ctx context.Context, tenant string, periodStart time.Time) (quota.Usage, error) {
+	const q = `SELECT period_start, jobs, results FROM gmaps_tenant_usage WHERE tenant = $1`
+
+	var (
+		start time.Time
+		ans   quota.Usage
+	)
+
+	err := p.db.QueryRowContext(ctx, q, tenant).Scan(&start, &ans.Jobs, &ans.Results)
+	if errors.Is(err, sql.ErrNoRows) {
+		return quota.Usage{}, nil
+	}
+
+	if err != nil {
+		return quota.Usage{}, err
+	}
+
+	if start.Before(periodStart) {
+		return quota.Usage{}, nil
+	}
+
+	return ans, nil
+}
+
+// ReserveJob counts a job for the tenant in a single statement, so concurrent
+// requests cannot exceed the quota. The usage is reset when a new period started.
+func (p *provider) ReserveJob(ctx context.Context, tenant *quota.Tenant, periodStart time.Time) (quota.Usage, bool, error) {
+	const q = `
+	INSERT INTO gmaps_tenant_usage AS u (tenant, period_start, jobs, results)
+	VALUES ($1, $2, 1, 0)
+	ON CONFLICT (tenant) DO UPDATE SET
+		jobs = CASE WHEN u.period_start < EXCLUDED.period_start THEN 1 ELSE u.jobs + 1 END,
+		results = CASE WHEN u.period_start < EXCLUDED.period_start THEN 0 ELSE u.results END,
+		period_start = GREATEST(u.period_start, EXCLUDED.period_start)
+	WHERE u.period_start < EXCLUDED.period_start
+		OR (($3 = 0 OR u.jobs < $3) AND ($4 = 0 OR u.results < $4))
+	RETURNING jobs, results
+	`
+
+	var ans quota.Usage
+
+	err := p.db.QueryRowContext(ctx, q, tenant.Name, periodStart, tenant.MaxJobs, tenant.MaxResults).
+		Scan(&ans.Jobs, &ans.Results)
+	if errors.Is(err, sql.ErrNoRows) {
+		usage, err := p.Usage(ctx, tenant.Name, periodStart)
+
+		return usage, false, err
+	}
+
+	if err != nil {
+		return quota.Usage{}, false, err
+	}
+
+	return ans, true, nil
+}
+
+// ReleaseJob uncounts a reserved job, unless the usage was reset by a new
+// period since the reservation
+func (p *provider) ReleaseJob(ctx context.Context, tenant string, periodStart time.Time) error {
+	const q = `
+	UPDATE gmaps_tenant_usage SET jobs = jobs - 1
+	WHERE tenant = $1 AND period_start = $2 AND jobs > 0
+	`
+
+	_, err := p.db.ExecContext(ctx, q, tenant, periodStart)
+
+	return err
+}
@@ -96,7 +96,32 @@ func (r *resultWriter) batchSave(ctx context.Context, entries []*gmaps.Entry) er
 		return err
 	}
 
+	if err := countTenantResults(ctx, tx, entries); err != nil {
+		return err
+	}
+
 	err = tx.Commit()
 
 	return err
 }
+
+// countTenantResults adds the results of the entries to the usage of their tenants
+func countTenantResults(ctx context.Context, tx *sql.Tx, entries []*gmaps.Entry) error {
+	counts := make(map[string]int)
+
+	for _, entry := range entries {
+		if entry.Tenant != "" {
+			counts[entry.Tenant]++
+		}
+	}
+
+	const q = `UPDATE gmaps_tenant_usage SET results = results + $2 WHERE tenant = $1`
+
+	for tenant, n := range counts {
+		if _, err := tx.ExecContext(ctx, q, tenant, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
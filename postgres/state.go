@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"slices"
+
+	"github.com/gosom/google-maps-scraper/metrics"
+)
+
+// The states of the lifecycle of a job. Unlike the status, which drives the
+// queue, a search job stays running until all its places are done.
+const (
+	statePending   = "pending"
+	stateRunning   = "running"
+	stateCompleted = "completed"
+	stateFailed    = "failed"
+	stateCancelled = "cancelled"
+)
+
+// ErrIllegalTransition is returned when the state of a job does not allow the change
+var ErrIllegalTransition = errors.New("illegal job state transition")
+
+// jobTransitions are the states a job can move to from each state. A running
+// job is pending again when it's retried or released, a failed one when it's
+// requeued from the dead letter queue. Completed and cancelled are final.
+var jobTransitions = map[string][]string{
+	statePending: {stateRunning, stateCancelled},
+	stateRunning: {statePending, stateCompleted, stateFailed, stateCancelled},
+	stateFailed:  {statePending},
+}
+
+// statesTo returns the states a job can move to state from, the queries
+// changing the state only match the jobs in one of them
+func statesTo(state string) []string {
+	var ans []string
+
+	for from, to := range jobTransitions {
+		if slices.Contains(to, state) {
+			ans = append(ans, from)
+		}
+	}
+
+	slices.Sort(ans)
+
+	return ans
+}
+
+// completeSearch marks the running search job completed once all its places
+// are done
+func (p *provider) completeSearch(ctx context.Context, jobID string) {
+	const q = `UPDATE gmaps_jobs SET state = $2, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND state = ANY($3) AND EXISTS (
+			SELECT 1 FROM gmaps_job_progress
+			WHERE job_id = $1 AND places_found IS NOT NULL AND places_done >= places_found
+		)
+		RETURNING payload_type, EXTRACT(EPOCH FROM finished_at - started_at)::float8`
+
+	rows, err := p.db.QueryContext(ctx, q, jobID, stateCompleted, statesTo(stateCompleted))
+	if err != nil {
+		log.Printf("failed to complete job %s: %v", jobID, err)
+
+		return
+	}
+
+	observeDurations(rows, stateCompleted)
+}
+
+// observeDurations records the durations of the jobs that ended in state,
+// rows are the payload type and the duration in seconds of each job.
+// It returns the number of rows.
+func observeDurations(rows *sql.Rows, state string) int {
+	defer rows.Close()
+
+	n := 0
+
+	for rows.Next() {
+		var (
+			payloadType string
+			seconds     *float64
+		)
+
+		if err := rows.Scan(&payloadType, &seconds); err != nil {
+			return n
+		}
+
+		n++
+
+		// the jobs started before the migration have no start time
+		if seconds != nil {
+			metrics.JobDuration.WithLabelValues(payloadType, state).Observe(*seconds)
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"log"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/webhook"
+)
+
+var _ gmaps.JobTracker = (*provider)(nil)
+
+// WithCallbacks posts the completion or failure of the search jobs with a
+// webhook url with s
+func WithCallbacks(s *webhook.Sender) ProviderOption {
+	return func(p *provider) {
+		p.callbacks = s
+	}
+}
+
+// PlacesFound records the number of places of the search job, a job
+// without places is completed right away
+func (p *provider) PlacesFound(ctx context.Context, jobID string, n int) {
+	const q = `UPDATE gmaps_job_progress SET places_found = $2 WHERE job_id = $1`
+
+	if _, err := p.db.ExecContext(ctx, q, jobID, n); err != nil {
+		log.Printf("failed to track the places of job %s: %v", jobID, err)
+
+		return
+	}
+
+	p.completeSearch(ctx, jobID)
+	p.notifyCompleted(ctx, jobID)
+}
+
+// PlaceDone counts a place of the search job, the job is completed
+// when all its places are done
+func (p *provider) PlaceDone(ctx context.Context, jobID string, saved bool) {
+	const q = `UPDATE gmaps_job_progress
+		SET places_done = places_done + 1, results = results + CASE WHEN $2 THEN 1 ELSE 0 END
+		WHERE job_id = $1`
+
+	if _, err := p.db.ExecContext(ctx, q, jobID, saved); err != nil {
+		log.Printf("failed to track a place of job %s: %v", jobID, err)
+
+		return
+	}
+
+	p.completeSearch(ctx, jobID)
+	p.notifyCompleted(ctx, jobID)
+}
+
+// notifyCompleted sends the completion of the job once all its places are done,
+// or its cancellation with the results saved before it. Setting notified_at
+// makes a single worker send it.
+func (p *provider) notifyCompleted(ctx context.Context, jobID string) {
+	if p.callbacks == nil {
+		return
+	}
+
+	const q = `UPDATE gmaps_job_progress SET notified_at = NOW()
+		WHERE job_id = $1 AND url IS NOT NULL AND notified_at IS NULL
+			AND places_found IS NOT NULL AND places_done >= places_found
+		RETURNING url, results, (SELECT status = $2 FROM gmaps_jobs WHERE id = $1) IS TRUE`
+
+	var (
+		url       string
+		results   int
+		cancelled bool
+	)
+
+	if err := p.db.QueryRowContext(ctx, q, jobID, statusCancelled).Scan(&url, &results, &cancelled); err != nil {
+		return
+	}
+
+	status := webhook.CallbackCompleted
+	if cancelled {
+		status = webhook.CallbackCancelled
+	}
+
+	p.callbacks.Send(url, webhook.Callback{
+		JobID:       jobID,
+		Status:      status,
+		ResultCount: results,
+	})
+}
+
+// jobFailed records the job that failed after all its attempts: the failure
+// of a search job is sent to its webhook and a failed place counts as done
+func (p *provider) jobFailed(ctx context.Context, jobID string, reason error) {
+	job, err := p.Get(ctx, jobID)
+	if err != nil {
+		return
+	}
+
+	switch j := job.(type) {
+	case *gmaps.GmapJob:
+		if j.WebhookURL == "" || p.callbacks == nil {
+			return
+		}
+
+		const q = `UPDATE gmaps_job_progress SET notified_at = NOW()
+			WHERE job_id = $1 AND notified_at IS NULL
+			RETURNING url, results`
+
+		var (
+			url     string
+			results int
+		)
+
+		if err := p.db.QueryRowContext(ctx, q, jobID).Scan(&url, &results); err != nil {
+			return
+		}
+
+		p.callbacks.Send(url, webhook.Callback{
+			JobID:       jobID,
+			Status:      webhook.CallbackFailed,
+			ResultCount: results,
+			Error:       reason.Error(),
+		})
+	case *gmaps.PlaceJob:
+		if j.Tracked {
+			p.PlaceDone(ctx, j.ParentID, false)
+		}
+	}
+}
+
+// resetProgress tracks a requeued search job from the start, its webhook
+// is notified again
+func (p *provider) resetProgress(ctx context.Context, jobID string) error {
+	job, err := p.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := job.(*gmaps.GmapJob); !ok {
+		return nil
+	}
+
+	const q = `UPDATE gmaps_job_progress
+		SET places_found = NULL, places_done = 0, results = 0, notified_at = NULL
+		WHERE job_id = $1`
+
+	_, err = p.db.ExecContext(ctx, q, jobID)
+
+	return err
+}
@@ -0,0 +1,123 @@
+// Package quota enforces per tenant quotas on the jobs created through the API
+// and the results they produce.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Tenant holds the quotas of an API key. A zero quota is unlimited.
+type Tenant struct {
+	Name       string `json:"name"`
+	MaxJobs    int    `json:"max_jobs"`
+	MaxResults int    `json:"max_results"`
+}
+
+// Usage is the consumption of a tenant in the current period
+type Usage struct {
+	Jobs    int
+	Results int
+}
+
+// Store keeps the usage of the tenants
+type Store interface {
+	// Usage returns the usage of tenant in the period starting at periodStart
+	Usage(ctx context.Context, tenant string, periodStart time.Time) (Usage, error)
+	// ReserveJob counts a new job for tenant when its usage in the period starting
+	// at periodStart is under its quotas. It returns false when a quota is exceeded.
+	ReserveJob(ctx context.Context, tenant *Tenant, periodStart time.Time) (Usage, bool, error)
+	// ReleaseJob gives back a job reserved in the period starting at periodStart
+	// that could not be created
+	ReleaseJob(ctx context.Context, tenant string, periodStart time.Time) error
+}
+
+// Config maps the API keys to their tenants
+type Config struct {
+	// Period is the length of the quota periods, zero means calendar months
+	Period  time.Duration
+	tenants map[string]Tenant
+}
+
+// Load reads the tenants from a json file keyed by API key like:
+//
+//	{
+//	  "3f6c1d0e-key-of-acme": {"name": "acme", "max_jobs": 1000, "max_results": 100000}
+//	}
+//
+// The usage is reset every period, or every calendar month when period is zero.
+func Load(fname string, period time.Duration) (*Config, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	c := Config{Period: period}
+
+	if err := json.Unmarshal(data, &c.tenants); err != nil {
+		return nil, err
+	}
+
+	if period < 0 {
+		return nil, fmt.Errorf("period must not be negative")
+	}
+
+	names := make(map[string]bool, len(c.tenants))
+
+	for key, t := range c.tenants {
+		if key == "" || t.Name == "" {
+			return nil, fmt.Errorf("every tenant needs an api key and a name")
+		}
+
+		if names[t.Name] {
+			return nil, fmt.Errorf("tenant %q is defined twice", t.Name)
+		}
+
+		names[t.Name] = true
+
+		if t.MaxJobs < 0 || t.MaxResults < 0 {
+			return nil, fmt.Errorf("tenant %q: quotas must not be negative", t.Name)
+		}
+	}
+
+	return &c, nil
+}
+
+// Tenant returns the tenant of apiKey
+func (c *Config) Tenant(apiKey string) (Tenant, bool) {
+	t, ok := c.tenants[apiKey]
+
+	return t, ok
+}
+
+// PeriodStart returns the start of the period containing t
+func (c *Config) PeriodStart(t time.Time) time.Time {
+	t = t.UTC()
+
+	if c.Period == 0 {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return t.Truncate(c.Period)
+}
+
+// PeriodEnd returns when the period starting at start ends and the usage is reset
+func (c *Config) PeriodEnd(start time.Time) time.Time {
+	if c.Period == 0 {
+		return start.AddDate(0, 1, 0)
+	}
+
+	return start.Add(c.Period)
+}
+
+// Remaining returns what is left of limit after used and false for unlimited quotas
+func Remaining(limit, used int) (int, bool) {
+	if limit == 0 {
+		return 0, false
+	}
+
+	return max(limit-used, 0), true
+}
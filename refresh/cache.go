@@ -0,0 +1,87 @@
+package refresh
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// maxCacheEntries bounds the memory of the cache, the expired
+// places are dropped first when it's full
+const maxCacheEntries = 10_000
+
+type cacheEntry struct {
+	entry   *gmaps.Entry
+	expires time.Time
+}
+
+// cache keeps the recently scraped places for ttl
+type cache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+func (c *cache) get(key string) (*gmaps.Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(item.expires) {
+		delete(c.entries, key)
+
+		return nil, false
+	}
+
+	return item.entry, true
+}
+
+func (c *cache) put(key string, entry *gmaps.Entry) {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxCacheEntries {
+		c.evict(now)
+	}
+
+	c.entries[key] = cacheEntry{entry: entry, expires: now.Add(c.ttl)}
+}
+
+// evict drops the expired places or, when none expired, the one expiring first
+func (c *cache) evict(now time.Time) {
+	var (
+		oldest    string
+		oldestExp time.Time
+	)
+
+	for k, item := range c.entries {
+		if now.After(item.expires) {
+			delete(c.entries, k)
+
+			continue
+		}
+
+		if oldest == "" || item.expires.Before(oldestExp) {
+			oldest, oldestExp = k, item.expires
+		}
+	}
+
+	if len(c.entries) >= maxCacheEntries {
+		delete(c.entries, oldest)
+	}
+}
@@ -0,0 +1,156 @@
+// Package refresh scrapes single places on demand.
+package refresh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/scrapemateapp"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+const (
+	scrapeTimeout = 2 * time.Minute
+	// every refresh starts its own browser, so only a few run at the same time
+	maxConcurrent = 2
+)
+
+// Store saves the refreshed places
+type Store interface {
+	SavePlace(ctx context.Context, entry *gmaps.Entry) error
+}
+
+// Refresher scrapes a single place and saves it to the store
+type Refresher struct {
+	cfg   *runner.Config
+	store Store
+	sem   chan struct{}
+	cache *cache
+}
+
+// New returns a Refresher. When store is nil the places are only scraped.
+// With cfg.PlaceCacheTTL the scraped places are served from memory for that duration.
+func New(cfg *runner.Config, store Store) *Refresher {
+	ans := Refresher{
+		cfg:   cfg,
+		store: store,
+		sem:   make(chan struct{}, maxConcurrent),
+	}
+
+	if cfg.PlaceCacheTTL > 0 {
+		ans.cache = newCache(cfg.PlaceCacheTTL)
+	}
+
+	return &ans
+}
+
+// Refresh scrapes the place with placeID (see gmaps.PlaceURL) and saves it.
+// It returns gmaps.ErrPlaceNotFound when the place does not resolve on google maps,
+// e.g. because it was removed. Cached places are returned without scraping or saving them.
+func (r *Refresher) Refresh(ctx context.Context, placeID string) (*gmaps.Entry, error) {
+	u, err := gmaps.PlaceURL(placeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry, ok := r.cached(u); ok {
+		return entry, nil
+	}
+
+	select {
+	case r.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	defer func() { <-r.sem }()
+
+	// a concurrent refresh of the same place may have finished while waiting
+	if entry, ok := r.cached(u); ok {
+		return entry, nil
+	}
+
+	entry, err := r.scrape(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if entry == nil || entry.Title == "" {
+		return nil, fmt.Errorf("%w: %s", gmaps.ErrPlaceNotFound, placeID)
+	}
+
+	if r.store != nil {
+		if err := r.store.SavePlace(ctx, entry); err != nil {
+			return nil, fmt.Errorf("save place: %w", err)
+		}
+	}
+
+	if r.cache != nil {
+		r.cache.put(u, entry)
+	}
+
+	return entry, nil
+}
+
+func (r *Refresher) cached(u string) (*gmaps.Entry, bool) {
+	if r.cache == nil {
+		return nil, false
+	}
+
+	return r.cache.get(u)
+}
+
+func (r *Refresher) scrape(ctx context.Context, u string) (*gmaps.Entry, error) {
+	ctx, cancel := context.WithTimeout(ctx, scrapeTimeout)
+	defer cancel()
+
+	writer := runner.NewFirstEntryWriter(cancel)
+
+	opts := []func(*scrapemateapp.Config) error{
+		scrapemateapp.WithConcurrency(1),
+		scrapemateapp.WithExitOnInactivity(time.Minute),
+		scrapemateapp.WithJS(scrapemateapp.DisableImages()),
+	}
+
+	if proxies := r.cfg.ActiveProxies(); len(proxies) > 0 {
+		opts = append(opts, scrapemateapp.WithProxies(proxies))
+	}
+
+	matecfg, err := scrapemateapp.NewConfig([]scrapemate.ResultWriter{writer}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := scrapemateapp.NewScrapeMateApp(matecfg)
+	if err != nil {
+		return nil, err
+	}
+
+	defer app.Close()
+
+	job := gmaps.NewPlaceJob("", r.cfg.LangCode, u, false,
+		gmaps.WithPlaceJobTimeout(r.cfg.PlaceTimeout),
+		gmaps.WithPlaceJobUserAgents(r.cfg.UserAgents),
+	)
+
+	err = app.Start(ctx, job)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+
+	entry := writer.First()
+	if entry == nil && ctx.Err() != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		return nil, fmt.Errorf("scrape place: %w", ctx.Err())
+	}
+
+	return entry, nil
+}
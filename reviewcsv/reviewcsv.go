@@ -0,0 +1,107 @@
+// Package reviewcsv writes the reviews of the places as a CSV file, one
+// row per review, next to the CSV of the places.
+package reviewcsv
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// Headers are the columns of the file, in order. cid, data_id and place_id
+// are the columns of the same name in the CSV of the places.
+var Headers = []string{
+	"cid",
+	"data_id",
+	"title",
+	"reviewer",
+	"rating",
+	"when",
+	"text",
+	"place_id",
+}
+
+type writer struct {
+	w *csv.Writer
+}
+
+// New returns a result writer that writes the reviews of every place to w,
+// the header is written before the first place
+func New(w io.Writer) scrapemate.ResultWriter {
+	return &writer{w: csv.NewWriter(w)}
+}
+
+// Path returns the path of the reviews file of the results file path,
+// e.g. results_reviews.csv for results.csv
+func Path(resultsPath string) string {
+	return strings.TrimSuffix(resultsPath, filepath.Ext(resultsPath)) + "_reviews.csv"
+}
+
+func (c *writer) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	headerWritten := false
+
+	for result := range in {
+		var entries []*gmaps.Entry
+
+		switch v := result.Data.(type) {
+		case *gmaps.Entry:
+			entries = append(entries, v)
+		case []any:
+			for i := range v {
+				entry, ok := v[i].(*gmaps.Entry)
+				if !ok {
+					return fmt.Errorf("cannot cast %T to *gmaps.Entry", v[i])
+				}
+
+				entries = append(entries, entry)
+			}
+		default:
+			return fmt.Errorf("cannot cast %T to *gmaps.Entry", result.Data)
+		}
+
+		if !headerWritten {
+			if err := c.w.Write(Headers); err != nil {
+				return err
+			}
+
+			headerWritten = true
+		}
+
+		for _, entry := range entries {
+			for i := range entry.UserReviews {
+				review := &entry.UserReviews[i]
+
+				row := []string{
+					entry.Cid,
+					entry.DataID,
+					entry.Title,
+					review.Name,
+					strconv.Itoa(review.Rating),
+					review.When,
+					review.Description,
+					entry.PlaceID,
+				}
+
+				if err := c.w.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+
+		c.w.Flush()
+
+		if err := c.w.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
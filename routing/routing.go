@@ -0,0 +1,186 @@
+// Package routing selects where the results of a job are delivered
+// based on the tags of the job.
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Uploader uploads a file to an S3 bucket
+type Uploader interface {
+	Upload(ctx context.Context, bucketName, key string, body io.Reader) error
+}
+
+// Rule routes the results of the jobs that have all the Match tags to Sink
+type Rule struct {
+	Match map[string]string `json:"match"`
+	Sink  string            `json:"sink"`
+}
+
+// Router holds the routing rules. The first matching rule wins and
+// Default is used when no rule matches. An empty Default keeps the
+// results only in the data folder.
+type Router struct {
+	Rules   []Rule `json:"rules"`
+	Default string `json:"default"`
+}
+
+// Load reads the routing rules from a json file like:
+//
+//	{
+//	  "rules": [
+//	    {"match": {"tenant": "acme"}, "sink": "s3://acme-results/gmaps"}
+//	  ],
+//	  "default": "/data/results"
+//	}
+//
+// A sink is either a local directory or s3://bucket/prefix.
+func Load(fname string) (*Router, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	var r Router
+
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func (r *Router) Validate() error {
+	for i := range r.Rules {
+		if len(r.Rules[i].Match) == 0 {
+			return fmt.Errorf("rule %d: match is empty", i)
+		}
+
+		if err := validateSink(r.Rules[i].Sink); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+
+	if r.Default != "" {
+		if err := validateSink(r.Default); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UsesS3 reports if any of the sinks is an s3 location
+func (r *Router) UsesS3() bool {
+	if strings.HasPrefix(r.Default, "s3://") {
+		return true
+	}
+
+	for i := range r.Rules {
+		if strings.HasPrefix(r.Rules[i].Sink, "s3://") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Select returns the sink for a job with tags
+func (r *Router) Select(tags map[string]string) string {
+	for i := range r.Rules {
+		if matches(r.Rules[i].Match, tags) {
+			return r.Rules[i].Sink
+		}
+	}
+
+	return r.Default
+}
+
+// Deliver copies the file fpath to the sink selected for tags
+// and returns the location it was written to.
+// It returns an empty location when there is no sink for tags.
+func (r *Router) Deliver(ctx context.Context, uploader Uploader, tags map[string]string, fpath string) (string, error) {
+	sink := r.Select(tags)
+	if sink == "" {
+		return "", nil
+	}
+
+	f, err := os.Open(fpath)
+	if err != nil {
+		return "", err
+	}
+
+	defer f.Close()
+
+	name := filepath.Base(fpath)
+
+	if rest, ok := strings.CutPrefix(sink, "s3://"); ok {
+		if uploader == nil {
+			return "", errors.New("s3 sink requires aws credentials")
+		}
+
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		key := path.Join(strings.Trim(prefix, "/"), name)
+
+		if err := uploader.Upload(ctx, bucket, key, f); err != nil {
+			return "", err
+		}
+
+		return "s3://" + bucket + "/" + key, nil
+	}
+
+	if err := os.MkdirAll(sink, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(sink, name)
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(out, f); err != nil {
+		_ = out.Close()
+
+		return "", err
+	}
+
+	return dst, out.Close()
+}
+
+func matches(match, tags map[string]string) bool {
+	for k, v := range match {
+		if tags[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validateSink(sink string) error {
+	if sink == "" {
+		return errors.New("sink is empty")
+	}
+
+	if rest, ok := strings.CutPrefix(sink, "s3://"); ok {
+		if bucket, _, _ := strings.Cut(rest, "/"); bucket == "" {
+			return fmt.Errorf("invalid s3 location: %s", sink)
+		}
+	}
+
+	return nil
+}
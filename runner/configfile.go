@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// configFlag is the flag of the config file path
+const configFlag = "config"
+
+// flagAliases maps the long names of the flags to their canonical name,
+// the config file values and the reloads use the canonical one
+var flagAliases = map[string]string{
+	"concurrency": "c",
+	"resume":      "checkpoint",
+}
+
+func canonicalFlag(name string) string {
+	if canonical, ok := flagAliases[name]; ok {
+		return canonical
+	}
+
+	return name
+}
+
+// readConfigFile reads a JSON object of flag names to values, e.g.
+// {"c": 8, "proxies": ["socks5://localhost:9050"], "log-level": "warn"}.
+// The values are returned as they would be given on the command line,
+// lists are joined with commas.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+
+	ans := make(map[string]string, len(raw))
+
+	for name, v := range raw {
+		if flag.Lookup(name) == nil || name == configFlag {
+			return nil, fmt.Errorf("invalid config file: unknown flag %q", name)
+		}
+
+		s, err := configValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config file: %s: %w", name, err)
+		}
+
+		ans[canonicalFlag(name)] = s
+	}
+
+	return ans, nil
+}
+
+func configValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, 0, len(v))
+
+		for _, item := range v {
+			s, err := configValue(item)
+			if err != nil {
+				return "", err
+			}
+
+			items = append(items, s)
+		}
+
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// applyConfigFile sets the flags of the config file that were not given on
+// the command line, the command line takes precedence. It returns the values
+// of the file and the flags given on the command line.
+func applyConfigFile(path string) (values map[string]string, cmdline map[string]bool, err error) {
+	values, err = readConfigFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmdline = make(map[string]bool)
+
+	flag.Visit(func(f *flag.Flag) {
+		cmdline[canonicalFlag(f.Name)] = true
+	})
+
+	for name, value := range values {
+		if cmdline[name] {
+			continue
+		}
+
+		if err := flag.Set(name, value); err != nil {
+			return nil, nil, fmt.Errorf("invalid config file: %s: %w", name, err)
+		}
+	}
+
+	return values, cmdline, nil
+}
@@ -5,14 +5,21 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
+	"log"
 	"os"
+	"time"
 
 	// postgres driver
 	_ "github.com/jackc/pgx/v5/stdlib"
 
+	"github.com/gosom/google-maps-scraper/gmaps"
 	"github.com/gosom/google-maps-scraper/postgres"
+	"github.com/gosom/google-maps-scraper/redisprovider"
 	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/sqsprovider"
+	"github.com/gosom/google-maps-scraper/streamwriter"
 	"github.com/gosom/google-maps-scraper/tlmt"
+	"github.com/gosom/google-maps-scraper/webhook"
 	"github.com/gosom/scrapemate"
 	"github.com/gosom/scrapemate/scrapemateapp"
 )
@@ -23,6 +30,10 @@ type dbrunner struct {
 	produce  bool
 	app      *scrapemateapp.ScrapemateApp
 	conn     *sql.DB
+	// closer closes the connection of the provider when it has its own
+	closer io.Closer
+	// callbacks posts the completion of the API jobs to their webhook
+	callbacks *webhook.Sender
 }
 
 func New(cfg *runner.Config) (runner.Runner, error) {
@@ -30,42 +41,137 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
 	}
 
-	conn, err := openPsqlConn(cfg.Dsn)
-	if err != nil {
-		return nil, err
+	ans := dbrunner{
+		cfg:     cfg,
+		produce: cfg.ProduceOnly,
 	}
 
-	ans := dbrunner{
-		cfg:      cfg,
-		provider: postgres.NewProvider(conn),
-		produce:  cfg.ProduceOnly,
-		conn:     conn,
+	// with the redis and sqs providers postgres only stores the results, when set
+	if cfg.Dsn != "" {
+		conn, err := openPsqlConn(cfg.Dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		ans.conn = conn
+	}
+
+	// the cells of the polygon searches fetched by this worker share the deduper
+	dedup := runner.NewDeduper(cfg)
+
+	var deriver gmaps.Deriver
+	if len(cfg.DerivedFields) > 0 {
+		deriver = cfg.DerivedFields
+	}
+
+	switch cfg.Provider {
+	case runner.ProviderRedis:
+		prov, err := redisprovider.New(context.Background(), cfg.RedisURL,
+			redisprovider.WithLimiter(cfg.Limiter),
+			redisprovider.WithProxyMonitor(cfg.ProxyMonitor()),
+			redisprovider.WithUserAgents(cfg.UserAgents),
+			redisprovider.WithDeduper(dedup),
+			redisprovider.WithDeriver(deriver),
+			redisprovider.WithLease(cfg.JobLease),
+			redisprovider.WithRetention(cfg.RedisJobTTL),
+		)
+		if err != nil {
+			_ = ans.closeConn()
+
+			return nil, err
+		}
+
+		ans.provider = prov
+		ans.closer = prov
+	case runner.ProviderSQS:
+		sqsOpts := []sqsprovider.Option{
+			sqsprovider.WithLimiter(cfg.Limiter),
+			sqsprovider.WithProxyMonitor(cfg.ProxyMonitor()),
+			sqsprovider.WithUserAgents(cfg.UserAgents),
+			sqsprovider.WithDeduper(dedup),
+			sqsprovider.WithDeriver(deriver),
+			sqsprovider.WithVisibilityTimeout(cfg.JobLease),
+			sqsprovider.WithRegion(cfg.AwsRegion),
+		}
+
+		if cfg.AwsAccessKey != "" && cfg.AwsSecretKey != "" {
+			sqsOpts = append(sqsOpts, sqsprovider.WithCredentials(cfg.AwsAccessKey, cfg.AwsSecretKey))
+		}
+
+		prov, err := sqsprovider.New(context.Background(), cfg.SQSQueueURL, sqsOpts...)
+		if err != nil {
+			_ = ans.closeConn()
+
+			return nil, err
+		}
+
+		ans.provider = prov
+	default:
+		provOpts := []postgres.ProviderOption{
+			postgres.WithLimiter(cfg.Limiter),
+			postgres.WithProxyMonitor(cfg.ProxyMonitor()),
+			postgres.WithUserAgents(cfg.UserAgents),
+			postgres.WithDeduper(dedup),
+			postgres.WithDeriver(deriver),
+			postgres.WithMaxAttempts(cfg.JobMaxAttempts),
+			postgres.WithLease(cfg.JobLease),
+		}
+
+		if !ans.produce {
+			ans.callbacks = webhook.NewSender(cfg.WebhookRetries, cfg.WebhookRetryBackoff)
+			provOpts = append(provOpts, postgres.WithCallbacks(ans.callbacks))
+		}
+
+		if cfg.DeadLetterQueue {
+			provOpts = append(provOpts, postgres.WithDeadLetterQueue())
+		}
+
+		ans.provider = postgres.NewProvider(ans.conn, provOpts...)
 	}
 
 	if ans.produce {
 		return &ans, nil
 	}
 
-	psqlWriter := postgres.NewResultWriter(conn)
+	if ans.recycle() {
+		return &ans, nil
+	}
+
+	app, err := ans.newApp(ans.provider, cfg.ExitOnInactivityDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	ans.app = app
+
+	return &ans, nil
+}
+
+func (d *dbrunner) newApp(provider scrapemate.JobProvider, exitOnInactivity time.Duration) (*scrapemateapp.ScrapemateApp, error) {
+	var writers []scrapemate.ResultWriter
+
+	if d.conn != nil {
+		writers = append(writers, postgres.NewResultWriter(d.conn))
+	}
 
-	writers := []scrapemate.ResultWriter{
-		psqlWriter,
+	if d.cfg.StreamURL != "" {
+		writers = append(writers, streamwriter.New(d.cfg.StreamURL))
 	}
 
 	opts := []func(*scrapemateapp.Config) error{
 		// scrapemateapp.WithCache("leveldb", "cache"),
-		scrapemateapp.WithConcurrency(cfg.Concurrency),
-		scrapemateapp.WithProvider(ans.provider),
-		scrapemateapp.WithExitOnInactivity(cfg.ExitOnInactivityDuration),
+		scrapemateapp.WithConcurrency(d.cfg.Concurrency),
+		scrapemateapp.WithProvider(provider),
+		scrapemateapp.WithExitOnInactivity(exitOnInactivity),
 	}
 
-	if len(cfg.Proxies) > 0 {
+	if proxies := d.cfg.ActiveProxies(); len(proxies) > 0 {
 		opts = append(opts,
-			scrapemateapp.WithProxies(cfg.Proxies),
+			scrapemateapp.WithProxies(proxies),
 		)
 	}
 
-	if cfg.Debug {
+	if d.cfg.Debug {
 		opts = append(opts, scrapemateapp.WithJS(
 			scrapemateapp.Headfull(),
 			scrapemateapp.DisableImages(),
@@ -82,12 +188,7 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 		return nil, err
 	}
 
-	ans.app, err = scrapemateapp.NewScrapeMateApp(matecfg)
-	if err != nil {
-		return nil, err
-	}
-
-	return &ans, nil
+	return scrapemateapp.NewScrapeMateApp(matecfg)
 }
 
 func (d *dbrunner) Run(ctx context.Context) error {
@@ -97,14 +198,135 @@ func (d *dbrunner) Run(ctx context.Context) error {
 		return d.produceSeedJobs(ctx)
 	}
 
+	defer d.release(ctx)
+
+	if d.recycle() {
+		return d.runRecycling(ctx)
+	}
+
 	return d.app.Start(ctx)
 }
 
+// releaser is implemented by the providers that queue again the jobs
+// fetched but not finished when the scraper stops
+type releaser interface {
+	Release(ctx context.Context) error
+}
+
+// release hands the unfinished jobs back to the queue so that another
+// worker picks them up after a restart
+func (d *dbrunner) release(ctx context.Context) {
+	r, ok := d.provider.(releaser)
+	if !ok {
+		return
+	}
+
+	// the context is usually cancelled by then
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if err := r.Release(ctx); err != nil {
+		log.Printf("failed to release the unfinished jobs: %v", err)
+	}
+}
+
+func (d *dbrunner) recycle() bool {
+	return d.cfg.RecycleAfterJobs > 0 || d.cfg.RecycleAfter > 0
+}
+
+// runRecycling runs the scraper in cycles. Every cycle uses a new scrapemate app
+// (and new browsers) that stops receiving jobs when the recycle limits are reached.
+// The app exits when its in-flight jobs are done and the next cycle starts.
+func (d *dbrunner) runRecycling(ctx context.Context) error {
+	const (
+		cycleInactivity    = time.Minute
+		providerRetryDelay = 5 * time.Second
+	)
+
+	inactivity := d.cfg.ExitOnInactivityDuration
+	if inactivity <= 0 || inactivity > cycleInactivity {
+		inactivity = cycleInactivity
+	}
+
+	rec := newRecycler(d.provider, d.cfg.RecycleAfterJobs, d.cfg.RecycleAfter)
+
+	if d.cfg.ProxyPool != nil {
+		// the browsers keep the proxies they were started with
+		rec.changed = d.cfg.ProxyPool.Changed
+	}
+
+	defer rec.stop()
+
+	for {
+		rec.start(ctx)
+
+		cycle := rec.cycle()
+
+		app, err := d.newApp(cycle, inactivity)
+		if err != nil {
+			return err
+		}
+
+		d.app = app
+
+		err = app.Start(ctx)
+
+		_ = app.Close()
+		d.app = nil
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if perr := cycle.Err(); perr != nil {
+			// the subscription is gone, the next cycle subscribes again
+			rec.stop()
+
+			if d.cfg.ExitOnInactivityDuration > 0 {
+				return perr
+			}
+
+			log.Printf("the job provider failed, subscribing again in %s: %v", providerRetryDelay, perr)
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(providerRetryDelay):
+			}
+
+			continue
+		}
+
+		if !cycle.Limited() {
+			if d.cfg.ExitOnInactivityDuration > 0 {
+				return err
+			}
+
+			// no jobs during the cycle, keep waiting for new ones with fresh browsers
+			continue
+		}
+
+		log.Printf("recycling browsers after %d jobs and %s", cycle.Count(), time.Since(cycle.startedAt).Round(time.Second))
+	}
+}
+
 func (d *dbrunner) Close(context.Context) error {
+	if d.closer != nil {
+		_ = d.closer.Close()
+	}
+
+	if d.callbacks != nil {
+		d.callbacks.Close()
+	}
+
 	if d.app != nil {
 		return d.app.Close()
 	}
 
+	return d.closeConn()
+}
+
+func (d *dbrunner) closeConn() error {
 	if d.conn != nil {
 		return d.conn.Close()
 	}
@@ -129,20 +351,45 @@ func (d *dbrunner) produceSeedJobs(ctx context.Context) error {
 		input = f
 	}
 
+	coords, zoom, err := runner.ResolveCoordinates(ctx, d.cfg.Geocoder, d.cfg.Location, d.cfg.GeoCoordinates, d.cfg.Zoom)
+	if err != nil {
+		return err
+	}
+
+	if !d.cfg.NoDedupeInput {
+		var dropped int
+
+		input, dropped, err = runner.DedupeInput(input, d.cfg.LangCode, coords, zoom)
+		if err != nil {
+			return err
+		}
+
+		if dropped > 0 {
+			log.Printf("dedup: collapsed %d duplicate queries of the input", dropped)
+		}
+	}
+
 	jobs, err := runner.CreateSeedJobs(
 		d.cfg.LangCode,
 		input,
 		d.cfg.MaxDepth,
 		d.cfg.Email,
-		d.cfg.GeoCoordinates,
-		d.cfg.Zoom,
+		coords,
+		zoom,
 		nil,
 		nil,
+		nil,
+		runner.SeedJobOptions(d.cfg)...,
 	)
 	if err != nil {
 		return err
 	}
 
+	jobs, err = runner.DropRestricted(ctx, d.cfg, jobs)
+	if err != nil {
+		return err
+	}
+
 	for i := range jobs {
 		if err := d.provider.Push(ctx, jobs[i]); err != nil {
 			return err
@@ -0,0 +1,258 @@
+package databaserunner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gosom/scrapemate"
+)
+
+var _ scrapemate.JobProvider = (*cycleProvider)(nil)
+
+var errProviderStopped = errors.New("the job provider stopped")
+
+// recycler hands out the jobs of a provider in cycles.
+// A cycle stops handing out jobs after maxJobs jobs or maxAge has passed
+// so the scrapemate app of the cycle finishes its in-flight jobs and
+// exits on inactivity. A fresh app (and browsers) is then started for the next cycle.
+type recycler struct {
+	provider scrapemate.JobProvider
+	maxJobs  int
+	maxAge   time.Duration
+	// changed returns a channel closed when the cycle must end early, e.g.
+	// because the proxies changed and the browsers must be started with the new ones
+	changed func() <-chan struct{}
+
+	// the subscription to the provider, set by start and dropped by stop
+	cancel context.CancelFunc
+	jobc   <-chan scrapemate.IJob
+	errc   <-chan error
+
+	mu      *sync.Mutex
+	pending []scrapemate.IJob
+}
+
+func newRecycler(provider scrapemate.JobProvider, maxJobs int, maxAge time.Duration) *recycler {
+	return &recycler{
+		provider: provider,
+		maxJobs:  maxJobs,
+		maxAge:   maxAge,
+		mu:       &sync.Mutex{},
+	}
+}
+
+// keep stores a job that was taken from the provider but not processed
+// so it is handed out first in the next cycle
+func (r *recycler) keep(job scrapemate.IJob) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pending = append(r.pending, job)
+}
+
+func (r *recycler) popPending() (scrapemate.IJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.pending) == 0 {
+		return nil, false
+	}
+
+	job := r.pending[0]
+	r.pending = r.pending[1:]
+
+	return job, true
+}
+
+// start subscribes to the provider jobs using the long lived ctx,
+// so the provider keeps running across cycles. It does nothing while
+// subscribed.
+func (r *recycler) start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.jobc != nil {
+		return
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.jobc, r.errc = r.provider.Jobs(ctx)
+}
+
+// stop drops the subscription, e.g. after the provider failed,
+// so that the next start subscribes again
+func (r *recycler) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cancel != nil {
+		r.cancel()
+	}
+
+	r.cancel, r.jobc, r.errc = nil, nil, nil
+}
+
+func (r *recycler) cycle() *cycleProvider {
+	r.mu.Lock()
+	jobc, errc := r.jobc, r.errc
+	r.mu.Unlock()
+
+	c := cycleProvider{
+		r:         r,
+		jobc:      jobc,
+		errc:      errc,
+		startedAt: time.Now().UTC(),
+		mu:        &sync.Mutex{},
+	}
+
+	if r.changed != nil {
+		c.changed = r.changed()
+	}
+
+	return &c
+}
+
+type cycleProvider struct {
+	r         *recycler
+	jobc      <-chan scrapemate.IJob
+	errc      <-chan error
+	startedAt time.Time
+	changed   <-chan struct{}
+
+	mu      *sync.Mutex
+	count   int
+	limited bool
+	err     error
+}
+
+//nolint:gocritic // it contains about unnamed results
+func (c *cycleProvider) Jobs(ctx context.Context) (<-chan scrapemate.IJob, <-chan error) {
+	outc := make(chan scrapemate.IJob)
+	errc := make(chan error, 1)
+
+	go func() {
+		var deadline <-chan time.Time
+
+		if c.r.maxAge > 0 {
+			timer := time.NewTimer(time.Until(c.startedAt.Add(c.r.maxAge)))
+			defer timer.Stop()
+
+			deadline = timer.C
+		}
+
+		for {
+			if c.r.maxJobs > 0 && c.Count() >= c.r.maxJobs {
+				c.setLimited()
+
+				return
+			}
+
+			if job, ok := c.r.popPending(); ok {
+				if !c.send(ctx, outc, job) {
+					return
+				}
+
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-deadline:
+				c.setLimited()
+
+				return
+			case <-c.changed:
+				c.setLimited()
+
+				return
+			case err, ok := <-c.errc:
+				if !ok || err == nil {
+					err = errProviderStopped
+				}
+
+				c.setErr(err)
+				errc <- err
+
+				return
+			case job, ok := <-c.jobc:
+				if !ok {
+					c.setErr(errProviderStopped)
+
+					return
+				}
+
+				if !c.send(ctx, outc, job) {
+					return
+				}
+			}
+		}
+	}()
+
+	return outc, errc
+}
+
+func (c *cycleProvider) send(ctx context.Context, outc chan<- scrapemate.IJob, job scrapemate.IJob) bool {
+	select {
+	case outc <- job:
+		c.incr()
+
+		return true
+	case <-ctx.Done():
+		// the job was already taken from the queue, hand it to the next cycle
+		c.r.keep(job)
+
+		return false
+	}
+}
+
+func (c *cycleProvider) Push(ctx context.Context, job scrapemate.IJob) error {
+	return c.r.provider.Push(ctx, job)
+}
+
+func (c *cycleProvider) Count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.count
+}
+
+// Limited reports whether the cycle stopped because it reached its limits
+func (c *cycleProvider) Limited() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.limited
+}
+
+// Err returns the error of the provider that ended the cycle, the
+// subscription can't be used anymore then
+func (c *cycleProvider) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *cycleProvider) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err = err
+}
+
+func (c *cycleProvider) incr() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count++
+}
+
+func (c *cycleProvider) setLimited() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.limited = true
+}
@@ -0,0 +1,127 @@
+// Package dryrun checks the queries of an input file without scraping or
+// queueing them, to catch the malformed lines before a large run.
+package dryrun
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/web/handlers"
+)
+
+type dryrun struct {
+	cfg *runner.Config
+}
+
+// New returns a runner that validates every query of the input file like
+// the jobs created through the API and prints the invalid lines
+func New(cfg *runner.Config) (runner.Runner, error) {
+	if cfg.RunMode != runner.RunModeDryRun {
+		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
+	}
+
+	return &dryrun{cfg: cfg}, nil
+}
+
+func (d *dryrun) Run(context.Context) error {
+	var input io.Reader
+
+	switch d.cfg.InputFile {
+	case "stdin":
+		input = os.Stdin
+	default:
+		f, err := os.Open(d.cfg.InputFile)
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+
+		input = f
+	}
+
+	var (
+		valid, invalid int
+		// ids are the lines of the job ids set with #!#
+		ids     = make(map[string]int)
+		lineNum int
+	)
+
+	scanner := bufio.NewScanner(input)
+
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		problems := d.check(line, lineNum, ids)
+		if len(problems) == 0 {
+			valid++
+
+			continue
+		}
+
+		invalid++
+
+		fmt.Fprintf(os.Stdout, "line %d: %s: %s\n", lineNum, line, strings.Join(problems, ", "))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "%d queries: %d valid, %d invalid\n", valid+invalid, valid, invalid)
+
+	if invalid > 0 {
+		return fmt.Errorf("dry run: %d invalid queries", invalid)
+	}
+
+	return nil
+}
+
+// check returns the problems of the input line, in the format read by
+// runner.CreateSeedJobs ("query" or "query #!# id")
+func (d *dryrun) check(line string, lineNum int, ids map[string]int) []string {
+	var problems []string
+
+	query, id, hasID := strings.Cut(line, "#!#")
+
+	req := handlers.CreateJobRequest{
+		Query:     strings.TrimSpace(query),
+		Language:  d.cfg.LangCode,
+		Zoom:      d.cfg.Zoom,
+		GeoCoords: d.cfg.GeoCoordinates,
+	}
+
+	if err := req.Validate(); err != nil {
+		problems = append(problems, strings.TrimPrefix(err.Error(), "validation failed: "))
+	}
+
+	id = strings.TrimSpace(id)
+
+	switch {
+	case hasID && id == "":
+		problems = append(problems, "the id after #!# is empty")
+	case hasID:
+		// the database queue keeps a single job per id
+		if first, ok := ids[id]; ok {
+			problems = append(problems, fmt.Sprintf("id %q is already used on line %d", id, first))
+		} else {
+			ids[id] = lineNum
+		}
+	}
+
+	return problems
+}
+
+func (d *dryrun) Close(context.Context) error {
+	return nil
+}
@@ -0,0 +1,48 @@
+package runner
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// FirstEntryWriter keeps the first place and calls done to stop the scraping
+type FirstEntryWriter struct {
+	done func()
+
+	mu    sync.Mutex
+	entry *gmaps.Entry
+}
+
+func NewFirstEntryWriter(done func()) *FirstEntryWriter {
+	return &FirstEntryWriter{done: done}
+}
+
+func (w *FirstEntryWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		entry, ok := result.Data.(*gmaps.Entry)
+		if !ok {
+			continue
+		}
+
+		w.mu.Lock()
+		if w.entry == nil {
+			w.entry = entry
+			w.done()
+		}
+		w.mu.Unlock()
+	}
+
+	return nil
+}
+
+// First returns the first place or nil when none was scraped
+func (w *FirstEntryWriter) First() *gmaps.Entry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.entry
+}
@@ -0,0 +1,131 @@
+package filerunner
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/checkpoint"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// checkpointWriter counts the places of the results as completed in the
+// checkpoint only once the inner writer wrote them and the results file is
+// synced, so that a crash never marks a query done before its rows are on disk.
+//
+// The inner writer must write every result before it takes the next one,
+// like the CSV and the JSON writers of the checkpointed outputs do.
+type checkpointWriter struct {
+	inner scrapemate.ResultWriter
+	cp    checkpoint.Checkpoint
+	f     syncer
+	// unsynced counts per job the places written since the last sync
+	unsynced map[string]int
+}
+
+// syncer is the results file, nil when the writer doesn't write to a file
+type syncer interface {
+	Sync() error
+}
+
+func newCheckpointWriter(inner scrapemate.ResultWriter, cp checkpoint.Checkpoint, f *os.File) scrapemate.ResultWriter {
+	ans := checkpointWriter{inner: inner, cp: cp, unsynced: map[string]int{}}
+
+	if f != nil {
+		ans.f = f
+	}
+
+	return &ans
+}
+
+func (w *checkpointWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	out := make(chan scrapemate.Result)
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- w.inner.Run(ctx, out)
+	}()
+
+	var (
+		prev    scrapemate.Result
+		hasPrev bool
+	)
+
+	for result := range in {
+		select {
+		case out <- result:
+		case err := <-errc:
+			return err
+		}
+
+		// the inner writer takes a result once it's done with the previous one
+		if hasPrev {
+			w.complete(prev)
+		}
+
+		prev, hasPrev = result, true
+	}
+
+	close(out)
+
+	if err := <-errc; err != nil {
+		return err
+	}
+
+	if hasPrev {
+		w.complete(prev)
+	}
+
+	w.sync()
+
+	return nil
+}
+
+func (w *checkpointWriter) complete(result scrapemate.Result) {
+	var jobID string
+
+	switch job := result.Job.(type) {
+	case *gmaps.PlaceJob:
+		jobID = job.ParentID
+	case *gmaps.EmailExtractJob:
+		jobID = job.Entry.ID
+	default:
+		return
+	}
+
+	pending, ok := w.cp.PlacesPending(jobID)
+	if !ok {
+		return
+	}
+
+	w.unsynced[jobID]++
+
+	// the file is synced once per query, when its last place is written
+	if w.unsynced[jobID] >= pending {
+		w.sync()
+	}
+}
+
+// sync syncs the results file and counts the places written before as
+// completed, the queries are not marked done when the sync fails
+func (w *checkpointWriter) sync() {
+	if len(w.unsynced) == 0 {
+		return
+	}
+
+	if w.f != nil {
+		if err := w.f.Sync(); err != nil {
+			log.Printf("checkpoint: failed to sync the results: %v", err)
+
+			return
+		}
+	}
+
+	for jobID, n := range w.unsynced {
+		w.cp.IncrPlacesCompleted(jobID, n)
+	}
+
+	clear(w.unsynced)
+}
@@ -0,0 +1,304 @@
+package filerunner
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
+	"github.com/gosom/scrapemate/adapters/writers/jsonwriter"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/checkpoint"
+	"github.com/gosom/google-maps-scraper/compactcsv"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/jsonlwriter"
+)
+
+type query struct {
+	id     string
+	text   string
+	places int
+}
+
+func place(q query, i int) scrapemate.Result {
+	return scrapemate.Result{
+		Job:  &gmaps.PlaceJob{Job: scrapemate.Job{ParentID: q.id}},
+		Data: &gmaps.Entry{ID: q.id, Title: fmt.Sprintf("%s-%d", q.id, i)},
+	}
+}
+
+// places returns the places of the queries interleaved, like the workers write them
+func places(queries []query) []scrapemate.Result {
+	var ans []scrapemate.Result
+
+	for i := 0; ; i++ {
+		n := len(ans)
+
+		for _, q := range queries {
+			if i < q.places {
+				ans = append(ans, place(q, i))
+			}
+		}
+
+		if len(ans) == n {
+			return ans
+		}
+	}
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// the checkpoint writer relies on the writers of the checkpointed
+// outputs writing every result before they take the next one
+func Test_WritersWriteEveryResultBeforeTheNext(t *testing.T) {
+	writers := map[string]func(io.Writer) scrapemate.ResultWriter{
+		"csv": func(w io.Writer) scrapemate.ResultWriter {
+			return csvwriter.NewCsvWriter(csv.NewWriter(w))
+		},
+		"compact csv": compactcsv.New,
+		"json":        jsonwriter.NewJSONWriter,
+		"jsonl":       jsonlwriter.New,
+	}
+
+	q := query{id: "q", places: 3}
+
+	for name, newWriter := range writers {
+		t.Run(name, func(t *testing.T) {
+			var buf lockedBuffer
+
+			in := make(chan scrapemate.Result)
+			errc := make(chan error, 1)
+
+			go func() {
+				errc <- newWriter(&buf).Run(context.Background(), in)
+			}()
+
+			for i := range q.places {
+				in <- place(q, i)
+
+				if i > 0 {
+					require.Contains(t, buf.String(), fmt.Sprintf("q-%d", i-1))
+				}
+			}
+
+			close(in)
+			require.NoError(t, <-errc)
+			require.Contains(t, buf.String(), fmt.Sprintf("q-%d", q.places-1))
+		})
+	}
+}
+
+// recordingSyncer records which queries were done at every sync
+type recordingSyncer struct {
+	cp      checkpoint.Checkpoint
+	queries []query
+	syncs   [][]string
+}
+
+func (s *recordingSyncer) Sync() error {
+	done := []string{}
+
+	for _, q := range s.queries {
+		if s.cp.IsDone(q.text) {
+			done = append(done, q.id)
+		}
+	}
+
+	s.syncs = append(s.syncs, done)
+
+	return nil
+}
+
+func Test_CheckpointWriterSyncsOncePerQuery(t *testing.T) {
+	cp, err := checkpoint.New(filepath.Join(t.TempDir(), "results.csv.checkpoint"))
+	require.NoError(t, err)
+
+	defer cp.Close()
+
+	queries := []query{
+		{id: "a", text: "cafes in athens", places: 3},
+		{id: "b", text: "bars in athens", places: 2},
+		{id: "c", text: "shops in athens", places: 0},
+	}
+
+	for _, q := range queries {
+		cp.Track(q.id, q.text)
+		cp.SetPlacesFound(q.id, q.places)
+	}
+
+	var buf lockedBuffer
+
+	s := &recordingSyncer{cp: cp, queries: queries}
+	w := &checkpointWriter{
+		inner:    csvwriter.NewCsvWriter(csv.NewWriter(&buf)),
+		cp:       cp,
+		f:        s,
+		unsynced: map[string]int{},
+	}
+
+	in := make(chan scrapemate.Result)
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- w.Run(context.Background(), in)
+	}()
+
+	// a0 b0 a1 b1 a2
+	for _, result := range places(queries) {
+		in <- result
+	}
+
+	close(in)
+	require.NoError(t, <-errc)
+
+	// c found no places and was done right away, b is synced once its last
+	// place is written and a at the end, the queries are done after their sync
+	require.Equal(t, [][]string{{"c"}, {"b", "c"}}, s.syncs)
+
+	for _, q := range queries {
+		require.True(t, cp.IsDone(q.text), q.text)
+	}
+}
+
+// scrape writes the places of the queries not done yet to the results file
+// at path like a checkpointed run does. The run is killed after kill places
+// when kill is not negative: the results and the checkpoint are left with a
+// half written last record.
+func scrape(t *testing.T, path string, queries []query, kill int) {
+	t.Helper()
+
+	cp, err := checkpoint.New(checkpoint.Path(path))
+	require.NoError(t, err)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+
+	resumed, err := resumeFile(f, true)
+	require.NoError(t, err)
+
+	var out io.Writer = f
+	if resumed {
+		out = &headerSkipper{w: f}
+	}
+
+	var todo []query
+
+	for _, q := range queries {
+		if cp.IsDone(q.text) {
+			continue
+		}
+
+		cp.Track(q.id, q.text)
+		cp.SetPlacesFound(q.id, q.places)
+
+		todo = append(todo, q)
+	}
+
+	w := newCheckpointWriter(csvwriter.NewCsvWriter(csv.NewWriter(out)), cp, f)
+
+	in := make(chan scrapemate.Result)
+	errc := make(chan error, 1)
+
+	go func() {
+		errc <- w.Run(context.Background(), in)
+	}()
+
+	for i, result := range places(todo) {
+		if i == kill {
+			break
+		}
+
+		in <- result
+	}
+
+	close(in)
+	require.NoError(t, <-errc)
+
+	if kill >= 0 {
+		_, err = f.WriteString("\"x\",\"half a \nrecord")
+		require.NoError(t, err)
+
+		cpf, err := os.OpenFile(checkpoint.Path(path), os.O_WRONLY|os.O_APPEND, 0o600)
+		require.NoError(t, err)
+
+		_, err = cpf.WriteString("half a qu")
+		require.NoError(t, err)
+		require.NoError(t, cpf.Close())
+	}
+
+	require.NoError(t, cp.Close())
+	require.NoError(t, f.Close())
+}
+
+func Test_KillAndResume(t *testing.T) {
+	queries := []query{
+		{id: "a", text: "cafes in athens", places: 2},
+		{id: "b", text: "bars in athens", places: 4},
+		{id: "c", text: "shops in athens", places: 1},
+		{id: "d", text: "parks in athens", places: 0},
+	}
+
+	path := filepath.Join(t.TempDir(), "results.csv")
+
+	// a0 b0 c0 a1 b1 | b2 b3: a, c and d are done, b is killed after two places
+	scrape(t, path, queries, 5)
+	scrape(t, path, queries, -1)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	require.NoError(t, err)
+
+	require.Equal(t, (&gmaps.Entry{}).CsvHeaders(), rows[0])
+
+	titles := map[string]int{}
+
+	for _, row := range rows[1:] {
+		titles[row[2]]++
+	}
+
+	// the completed queries are not scraped again, the killed one is
+	// scraped again from the start so its first two places are written twice
+	require.Equal(t, map[string]int{
+		"a-0": 1, "a-1": 1,
+		"b-0": 2, "b-1": 2, "b-2": 1, "b-3": 1,
+		"c-0": 1,
+	}, titles)
+
+	cp, err := checkpoint.New(checkpoint.Path(path))
+	require.NoError(t, err)
+
+	defer cp.Close()
+
+	for _, q := range queries {
+		require.True(t, cp.IsDone(q.text), q.text)
+	}
+}
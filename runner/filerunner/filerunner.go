@@ -3,20 +3,36 @@ package filerunner
 import (
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"strings"
 	"time"
 
-	"github.com/gosom/google-maps-scraper/deduper"
+	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/checkpoint"
+	"github.com/gosom/google-maps-scraper/compactcsv"
+	"github.com/gosom/google-maps-scraper/dirwriter"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/fieldalias"
+	"github.com/gosom/google-maps-scraper/filelock"
+	"github.com/gosom/google-maps-scraper/geojsonwriter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/jsonlwriter"
+	"github.com/gosom/google-maps-scraper/kmlwriter"
+	"github.com/gosom/google-maps-scraper/reviewcsv"
 	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/s3uploader"
+	"github.com/gosom/google-maps-scraper/streamwriter"
 	"github.com/gosom/google-maps-scraper/tlmt"
+	"github.com/gosom/google-maps-scraper/xlsxwriter"
 	"github.com/gosom/scrapemate"
 	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
 	"github.com/gosom/scrapemate/adapters/writers/jsonwriter"
 	"github.com/gosom/scrapemate/scrapemateapp"
+	"github.com/playwright-community/playwright-go"
 )
 
 type fileRunner struct {
@@ -25,6 +41,8 @@ type fileRunner struct {
 	writers []scrapemate.ResultWriter
 	app     *scrapemateapp.ScrapemateApp
 	outfile *os.File
+	reviews *os.File
+	cp      checkpoint.Checkpoint
 }
 
 func New(cfg *runner.Config) (runner.Runner, error) {
@@ -40,6 +58,10 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 		return nil, err
 	}
 
+	if err := ans.setCheckpoint(); err != nil {
+		return nil, err
+	}
+
 	if err := ans.setWriters(); err != nil {
 		return nil, err
 	}
@@ -72,23 +94,57 @@ func (r *fileRunner) Run(ctx context.Context) (err error) {
 		_ = runner.Telemetry().Send(ctx, evt)
 	}()
 
-	dedup := deduper.New()
+	coords, zoom, err := runner.ResolveCoordinates(ctx, r.cfg.Geocoder, r.cfg.Location, r.cfg.GeoCoordinates, r.cfg.Zoom)
+	if err != nil {
+		return err
+	}
+
+	dedup := runner.NewDeduper(r.cfg)
 	exitMonitor := exiter.New()
 
+	jobOpts := runner.InProcessJobOptions(r.cfg)
+
+	placeDedup := runner.NewPlaceDeduper(r.cfg)
+	if placeDedup != nil {
+		jobOpts = append(jobOpts, gmaps.WithPlaceDeduper(placeDedup))
+	}
+
+	input := r.input
+
+	if !r.cfg.NoDedupeInput {
+		var dropped int
+
+		input, dropped, err = runner.DedupeInput(input, r.cfg.LangCode, coords, zoom)
+		if err != nil {
+			return err
+		}
+
+		if dropped > 0 {
+			log.Printf("dedup: collapsed %d duplicate queries of the input", dropped)
+		}
+	}
+
 	seedJobs, err = runner.CreateSeedJobs(
 		r.cfg.LangCode,
-		r.input,
+		input,
 		r.cfg.MaxDepth,
 		r.cfg.Email,
-		r.cfg.GeoCoordinates,
-		r.cfg.Zoom,
+		coords,
+		zoom,
 		dedup,
 		exitMonitor,
+		r.cp,
+		jobOpts...,
 	)
 	if err != nil {
 		return err
 	}
 
+	seedJobs, err = runner.DropRestricted(ctx, r.cfg, seedJobs)
+	if err != nil {
+		return err
+	}
+
 	exitMonitor.SetSeedCount(len(seedJobs))
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -99,11 +155,73 @@ func (r *fileRunner) Run(ctx context.Context) (err error) {
 	go exitMonitor.Run(ctx)
 
 	err = r.app.Start(ctx, seedJobs...)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	if placeDedup != nil {
+		log.Printf("dedup: dropped %d duplicate places", placeDedup.Duplicates())
+	}
+
+	if r.cfg.Output != "" {
+		return r.upload(context.WithoutCancel(ctx))
+	}
 
 	return err
 }
 
+// upload sends the results file to the s3 output and removes it, the
+// file is kept when the upload fails so that the results are not lost
+func (r *fileRunner) upload(ctx context.Context) error {
+	if _, err := r.outfile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s%s", uuid.New().String(), time.Now().UTC().Format("20060102T150405Z"), r.extension())
+	key := s3uploader.Key(r.cfg.OutputPrefix, name)
+
+	if err := r.cfg.S3Uploader.Upload(ctx, r.cfg.OutputBucket, key, r.outfile); err != nil {
+		return fmt.Errorf("failed to upload the results to s3://%s/%s, they are kept in %s: %w",
+			r.cfg.OutputBucket, key, r.outfile.Name(), err)
+	}
+
+	log.Printf("results uploaded to s3://%s/%s\n", r.cfg.OutputBucket, key)
+
+	_ = r.outfile.Close()
+	_ = os.Remove(r.outfile.Name())
+
+	r.outfile = nil
+
+	return nil
+}
+
+// extension returns the file extension of the configured output format
+func (r *fileRunner) extension() string {
+	switch {
+	case r.cfg.JSON:
+		return ".json"
+	case r.cfg.JSONL:
+		return ".jsonl"
+	case r.cfg.KML:
+		return ".kml"
+	case r.cfg.GeoJSON:
+		return ".geojson"
+	case r.cfg.XLSX:
+		return ".xlsx"
+	default:
+		return ".csv"
+	}
+}
+
 func (r *fileRunner) Close(context.Context) error {
+	if r.cp != nil {
+		_ = r.cp.Close()
+	}
+
+	if r.reviews != nil {
+		_ = r.reviews.Close()
+	}
+
 	if r.app != nil {
 		return r.app.Close()
 	}
@@ -137,6 +255,21 @@ func (r *fileRunner) setInput() error {
 	return nil
 }
 
+func (r *fileRunner) setCheckpoint() error {
+	if !r.cfg.Checkpoint {
+		return nil
+	}
+
+	cp, err := checkpoint.New(checkpoint.Path(r.cfg.ResultsFile))
+	if err != nil {
+		return err
+	}
+
+	r.cp = cp
+
+	return nil
+}
+
 func (r *fileRunner) setWriters() error {
 	if r.cfg.CustomWriter != "" {
 		parts := strings.Split(r.cfg.CustomWriter, ":")
@@ -151,15 +284,39 @@ func (r *fileRunner) setWriters() error {
 			return err
 		}
 
-		r.writers = append(r.writers, customWriter)
+		r.writers = append(r.writers, r.withCheckpoint(customWriter, nil))
+	} else if r.cfg.ResultsDir != "" {
+		dirWriter, err := dirwriter.New(r.cfg.ResultsDir, r.cfg.S3Uploader)
+		if err != nil {
+			return err
+		}
+
+		r.writers = append(r.writers, r.withCheckpoint(dirWriter, nil))
 	} else {
 		var resultsWriter io.Writer
 
-		switch r.cfg.ResultsFile {
-		case "stdout":
+		switch {
+		case r.cfg.Output != "":
+			// the results are uploaded once the scrape ends
+			f, err := os.CreateTemp("", "gmaps-results-*"+r.extension())
+			if err != nil {
+				return err
+			}
+
+			r.outfile = f
+
+			resultsWriter = r.outfile
+		case r.cfg.ResultsFile == "stdout":
 			resultsWriter = os.Stdout
 		default:
-			f, err := os.Create(r.cfg.ResultsFile)
+			flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+			if r.cp != nil {
+				// keep the results of the previous runs when resuming
+				flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+			}
+
+			// a second run writing to the same file would interleave or wipe the results
+			f, err := filelock.OpenFile(r.cfg.ResultsFile, flags, 0o666)
 			if err != nil {
 				return err
 			}
@@ -167,30 +324,147 @@ func (r *fileRunner) setWriters() error {
 			r.outfile = f
 
 			resultsWriter = r.outfile
+
+			if r.cp != nil {
+				resumed, err := resumeFile(f, r.csvOutput())
+				if err != nil {
+					return err
+				}
+
+				if resumed && r.csvOutput() {
+					resultsWriter = &headerSkipper{w: f}
+				}
+			}
 		}
 
 		csvWriter := csvwriter.NewCsvWriter(csv.NewWriter(resultsWriter))
 
-		if r.cfg.JSON {
-			r.writers = append(r.writers, jsonwriter.NewJSONWriter(resultsWriter))
-		} else {
-			r.writers = append(r.writers, csvWriter)
+		var (
+			w       scrapemate.ResultWriter
+			aliased bool
+		)
+
+		switch {
+		case r.cfg.JSON:
+			w, aliased = jsonwriter.NewJSONWriter(resultsWriter), true
+		case r.cfg.JSONL:
+			w, aliased = jsonlwriter.New(resultsWriter), true
+		case r.cfg.KML:
+			w = kmlwriter.New(resultsWriter, "Google Maps results")
+		case r.cfg.CompactCSV:
+			w = compactcsv.New(resultsWriter)
+		case r.cfg.GeoJSON:
+			w = geojsonwriter.New(resultsWriter)
+		case r.cfg.XLSX:
+			w = xlsxwriter.New(resultsWriter)
+		default:
+			w, aliased = csvWriter, true
+		}
+
+		// wraps the writer itself, it has to know when the rows are written
+		w = r.withCheckpoint(w, r.outfile)
+
+		if aliased {
+			w = fieldalias.WrapWriter(w, r.cfg.FieldAliases)
+		}
+
+		r.writers = append(r.writers, w)
+
+		if err := r.setReviewsWriter(); err != nil {
+			return err
 		}
 	}
 
+	if r.cfg.StreamURL != "" {
+		r.writers = append(r.writers, streamwriter.New(r.cfg.StreamURL))
+	}
+
 	return nil
 }
 
+// withCheckpoint counts the places written by w in the checkpoint, f is
+// the file w writes to, synced before the queries are marked done
+func (r *fileRunner) withCheckpoint(w scrapemate.ResultWriter, f *os.File) scrapemate.ResultWriter {
+	if r.cp == nil {
+		return w
+	}
+
+	return newCheckpointWriter(w, r.cp, f)
+}
+
+// setReviewsWriter writes the extracted reviews of the CSV outputs to a file
+// next to the results file, the JSON outputs hold them in the places
+func (r *fileRunner) setReviewsWriter() error {
+	if !r.cfg.ExtractReviews || r.cfg.JSON || r.cfg.JSONL || r.cfg.KML || r.cfg.GeoJSON {
+		return nil
+	}
+
+	if r.cfg.ResultsFile == "stdout" || r.cfg.Output != "" {
+		return nil
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if r.cp != nil {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	f, err := os.OpenFile(reviewcsv.Path(r.cfg.ResultsFile), flags, 0o666)
+	if err != nil {
+		return err
+	}
+
+	r.reviews = f
+
+	var w io.Writer = f
+
+	if r.cp != nil {
+		resumed, err := resumeFile(f, true)
+		if err != nil {
+			return err
+		}
+
+		if resumed {
+			w = &headerSkipper{w: f}
+		}
+	}
+
+	r.writers = append(r.writers, reviewcsv.New(w))
+
+	return nil
+}
+
+// csvOutput reports whether the results are written as CSV
+func (r *fileRunner) csvOutput() bool {
+	return !r.cfg.JSON && !r.cfg.JSONL && !r.cfg.KML && !r.cfg.GeoJSON && !r.cfg.XLSX
+}
+
+// resultsOnStdout reports whether the results are written to stdout
+func (r *fileRunner) resultsOnStdout() bool {
+	return r.cfg.ResultsFile == "stdout" && r.cfg.Output == "" && r.cfg.ResultsDir == "" && r.cfg.CustomWriter == ""
+}
+
 func (r *fileRunner) setApp() error {
+	if r.resultsOnStdout() {
+		// install the browsers before scrapemate does, it prints the download
+		// progress to stdout where it would end up in the results
+		err := playwright.Install(&playwright.RunOptions{
+			Browsers: []string{"chromium"},
+			Stdout:   os.Stderr,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	opts := []func(*scrapemateapp.Config) error{
 		// scrapemateapp.WithCache("leveldb", "cache"),
 		scrapemateapp.WithConcurrency(r.cfg.Concurrency),
 		scrapemateapp.WithExitOnInactivity(r.cfg.ExitOnInactivityDuration),
 	}
 
-	if len(r.cfg.Proxies) > 0 {
+	if proxies := r.cfg.ActiveProxies(); len(proxies) > 0 {
 		opts = append(opts,
-			scrapemateapp.WithProxies(r.cfg.Proxies),
+			scrapemateapp.WithProxies(proxies),
 		)
 	}
 
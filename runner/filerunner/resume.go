@@ -0,0 +1,82 @@
+package filerunner
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+
+	"github.com/gosom/google-maps-scraper/partialfile"
+)
+
+// resumeFile prepares the results file f of an interrupted run for the
+// resumed one. The half written last record of a crash is cut, so that the
+// new records don't get appended to it. It reports whether f still holds
+// records, the CSV header must not be written again then.
+func resumeFile(f *os.File, csvRecords bool) (bool, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if info.Size() == 0 {
+		return false, nil
+	}
+
+	// f is opened for appending only
+	rf, err := os.Open(f.Name())
+	if err != nil {
+		return false, err
+	}
+
+	defer rf.Close()
+
+	var end int64
+
+	if csvRecords {
+		end, err = partialfile.CSVEnd(rf, info.Size())
+	} else {
+		end, err = partialfile.LinesEnd(rf)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if end < info.Size() {
+		log.Printf("dropping the incomplete last record of %s (%d bytes)", f.Name(), info.Size()-end)
+
+		if err := f.Truncate(end); err != nil {
+			return false, err
+		}
+	}
+
+	return end > 0, nil
+}
+
+// headerSkipper drops the header line that the CSV writers write before
+// their first record, the resumed results file already starts with one
+type headerSkipper struct {
+	w       io.Writer
+	skipped bool
+}
+
+func (h *headerSkipper) Write(p []byte) (int, error) {
+	if h.skipped {
+		return h.w.Write(p)
+	}
+
+	i := bytes.IndexByte(p, '\n')
+	if i < 0 {
+		// the rest of the header comes with the next write
+		return len(p), nil
+	}
+
+	h.skipped = true
+
+	if _, err := h.w.Write(p[i+1:]); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
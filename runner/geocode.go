@@ -0,0 +1,31 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gosom/google-maps-scraper/geocode"
+)
+
+// ResolveCoordinates returns the coordinates and the zoom of the searches.
+// When only a location is given it's geocoded, an explicit zoom is kept.
+func ResolveCoordinates(ctx context.Context, g geocode.Geocoder, location, geoCoordinates string, zoom int) (string, int, error) {
+	if location == "" || geoCoordinates != "" {
+		return geoCoordinates, zoom, nil
+	}
+
+	if g == nil {
+		return "", 0, fmt.Errorf("no geocoder configured to resolve %q", location)
+	}
+
+	res, err := g.Geocode(ctx, location)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to geocode %q: %w", location, err)
+	}
+
+	if zoom == 0 {
+		zoom = res.Zoom
+	}
+
+	return res.Coordinates(), zoom, nil
+}
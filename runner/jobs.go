@@ -2,16 +2,23 @@ package runner
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"plugin"
+	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/checkpoint"
 	"github.com/gosom/google-maps-scraper/deduper"
 	"github.com/gosom/google-maps-scraper/exiter"
 	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/polygon"
 	"github.com/gosom/scrapemate"
 )
 
@@ -24,6 +31,8 @@ func CreateSeedJobs(
 	zoom int,
 	dedup deduper.Deduper,
 	exitMonitor exiter.Exiter,
+	cp checkpoint.Checkpoint,
+	extraOpts ...gmaps.GmapJobOptions,
 ) (jobs []scrapemate.IJob, err error) {
 	scanner := bufio.NewScanner(r)
 
@@ -33,6 +42,12 @@ func CreateSeedJobs(
 			continue
 		}
 
+		line := query
+
+		if cp != nil && cp.IsDone(line) {
+			continue
+		}
+
 		var id string
 
 		if before, after, ok := strings.Cut(query, "#!#"); ok {
@@ -40,7 +55,7 @@ func CreateSeedJobs(
 			id = strings.TrimSpace(after)
 		}
 
-		opts := []gmaps.GmapJobOptions{}
+		opts := append([]gmaps.GmapJobOptions{}, extraOpts...)
 
 		if dedup != nil {
 			opts = append(opts, gmaps.WithDeduper(dedup))
@@ -50,14 +65,314 @@ func CreateSeedJobs(
 			opts = append(opts, gmaps.WithExitMonitor(exitMonitor))
 		}
 
+		if cp != nil {
+			opts = append(opts, gmaps.WithCheckpoint(cp))
+		}
+
 		job := gmaps.NewGmapJob(id, langCode, query, maxDepth, email, geoCoordinates, zoom, opts...)
 
+		if job.Polygon != nil {
+			cells, err := polygonSeedJobs(job, line, cp, opts...)
+			if err != nil {
+				return nil, err
+			}
+
+			jobs = append(jobs, cells...)
+
+			continue
+		}
+
+		if cp != nil {
+			cp.Track(job.GetID(), line)
+		}
+
 		jobs = append(jobs, job)
 	}
 
 	return jobs, scanner.Err()
 }
 
+// DropRestricted drops the seed jobs whose search resolves to one of the
+// restricted regions of cfg before any page is opened, the policy error of
+// every dropped job is logged. The cells of a polygon search are checked
+// once, from the center of the polygon. The jobs whose region can't be
+// resolved, e.g. when the geocoder is rate limited, are kept and the error
+// is logged. It returns an error wrapping gmaps.ErrRestrictedRegion when
+// every job is dropped.
+func DropRestricted(ctx context.Context, cfg *Config, jobs []scrapemate.IJob) ([]scrapemate.IJob, error) {
+	if len(cfg.RestrictedRegions) == 0 || len(jobs) == 0 {
+		return jobs, nil
+	}
+
+	var (
+		kept    []scrapemate.IJob
+		lastErr error
+	)
+
+	// the result of the check of every polygon search, by parent id
+	checked := map[string]error{}
+
+	for _, job := range jobs {
+		j, ok := job.(*gmaps.GmapJob)
+		if !ok {
+			kept = append(kept, job)
+
+			continue
+		}
+
+		var err error
+
+		switch {
+		case j.ParentID != "" && j.Polygon != nil:
+			var done bool
+
+			if err, done = checked[j.ParentID]; !done {
+				err = gmaps.CheckRegion(ctx, cfg.Geocoder, cfg.RestrictedRegions, j.Query, j.Polygon.Center().Coordinates())
+				checked[j.ParentID] = err
+			}
+		default:
+			err = gmaps.CheckRegion(ctx, cfg.Geocoder, cfg.RestrictedRegions, j.Query, j.GeoCoordinates)
+		}
+
+		switch {
+		case errors.Is(err, gmaps.ErrRestrictedRegion):
+			log.Printf("skipping job %s: %v", j.GetID(), err)
+
+			lastErr = err
+		case err != nil:
+			log.Printf("keeping job %s: %v", j.GetID(), err)
+
+			kept = append(kept, job)
+		default:
+			kept = append(kept, job)
+		}
+	}
+
+	if len(kept) == 0 {
+		return nil, lastErr
+	}
+
+	return kept, nil
+}
+
+// DedupeInput drops the lines of the input that search the same query as an
+// earlier line with the same language, coordinates and zoom. The queries are
+// compared without the case and the extra whitespace, and whatever their id.
+// It returns the remaining lines and the number of dropped ones.
+func DedupeInput(r io.Reader, langCode, geoCoordinates string, zoom int) (io.Reader, int, error) {
+	var (
+		sb      strings.Builder
+		dropped int
+	)
+
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		query, _, _ := strings.Cut(line, "#!#")
+
+		key := strings.Join([]string{
+			strings.ToLower(strings.Join(strings.Fields(query), " ")),
+			strings.ToLower(langCode),
+			geoCoordinates,
+			strconv.Itoa(zoom),
+		}, "\x00")
+
+		if seen[key] {
+			dropped++
+
+			continue
+		}
+
+		seen[key] = true
+
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return strings.NewReader(sb.String()), dropped, nil
+}
+
+// polygonSeedJobs replaces the search of job with a search per cell of the
+// grid covering its polygon. The places found by several cells are dropped by
+// the deduper, the ones outside the polygon by the place jobs. Every cell is
+// a job of its own with a new id, it's checkpointed on its own.
+func polygonSeedJobs(
+	job *gmaps.GmapJob,
+	line string,
+	cp checkpoint.Checkpoint,
+	opts ...gmaps.GmapJobOptions,
+) ([]scrapemate.IJob, error) {
+	zoom := job.Zoom
+	if zoom <= 0 {
+		zoom = polygon.DefaultZoom
+	}
+
+	points, err := job.Polygon.Grid(zoom)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", job.Query, err)
+	}
+
+	var jobs []scrapemate.IJob
+
+	cellOpts := append([]gmaps.GmapJobOptions{}, opts...)
+	cellOpts = append(cellOpts, gmaps.WithParentID(job.GetID()))
+
+	for _, p := range points {
+		cellLine := line + "@" + p.Coordinates()
+
+		if cp != nil && cp.IsDone(cellLine) {
+			continue
+		}
+
+		cell := gmaps.NewGmapJob(uuid.New().String(), job.LangCode, job.Query, job.MaxDepth, job.ExtractEmail, p.Coordinates(), zoom, cellOpts...)
+
+		if cp != nil {
+			cp.Track(cell.GetID(), cellLine)
+		}
+
+		jobs = append(jobs, cell)
+	}
+
+	return jobs, nil
+}
+
+// SeedJobOptions returns the job options that apply to every seed job
+// created with cfg
+func SeedJobOptions(cfg *Config) []gmaps.GmapJobOptions {
+	var opts []gmaps.GmapJobOptions
+
+	if len(cfg.RestrictedRegions) > 0 {
+		opts = append(opts, gmaps.WithRestrictedRegions(cfg.RestrictedRegions))
+	}
+
+	if cfg.ScrollBudget > 0 {
+		opts = append(opts, gmaps.WithScrollBudget(cfg.ScrollBudget))
+	}
+
+	if cfg.AutoDepth {
+		opts = append(opts, gmaps.WithAutoDepth())
+	}
+
+	if cfg.MaxResults > 0 {
+		opts = append(opts, gmaps.WithMaxResults(cfg.MaxResults))
+	}
+
+	if len(cfg.IncludeKeywords) > 0 {
+		opts = append(opts, gmaps.WithIncludeKeywords(cfg.IncludeKeywords))
+	}
+
+	if len(cfg.ExcludeKeywords) > 0 {
+		opts = append(opts, gmaps.WithExcludeKeywords(cfg.ExcludeKeywords))
+	}
+
+	if cfg.SpamWeights != nil {
+		opts = append(opts, gmaps.WithSpamWeights(cfg.SpamWeights))
+	}
+
+	if cfg.MenuHighlights > 0 {
+		opts = append(opts, gmaps.WithMenuHighlights(cfg.MenuHighlights))
+	}
+
+	if cfg.MaxPhotos > 0 {
+		opts = append(opts, gmaps.WithMaxPhotos(cfg.MaxPhotos))
+	}
+
+	if cfg.NormalizePhones {
+		opts = append(opts, gmaps.WithNormalizePhones())
+	}
+
+	if cfg.BookingWidget {
+		opts = append(opts, gmaps.WithBookingWidget())
+	}
+
+	if cfg.ExtractReviews {
+		opts = append(opts, gmaps.WithReviews(cfg.MaxReviews))
+	}
+
+	if cfg.PlaceTimeout > 0 {
+		opts = append(opts, gmaps.WithPlaceTimeout(cfg.PlaceTimeout))
+	}
+
+	// the web jobs can extract the emails without -email
+	opts = append(opts, gmaps.WithEmailTimeout(cfg.EmailTimeout), gmaps.WithEmailRetries(cfg.EmailRetries))
+
+	if cfg.Polygon != nil {
+		opts = append(opts, gmaps.WithPolygon(cfg.Polygon))
+	}
+
+	if cfg.ContactRules != nil {
+		opts = append(opts, gmaps.WithContactValidation(cfg.ContactRules))
+	}
+
+	if cfg.CaptureTrace {
+		opts = append(opts, gmaps.WithTrace(&gmaps.TraceConfig{
+			Dir:        cfg.TraceDir,
+			FailedOnly: cfg.TraceFailedOnly,
+			MaxTraces:  cfg.MaxTraces,
+		}))
+	}
+
+	return opts
+}
+
+// InProcessJobOptions returns the SeedJobOptions together with the options
+// that hold runtime state and therefore cannot be stored in the database
+func InProcessJobOptions(cfg *Config) []gmaps.GmapJobOptions {
+	opts := SeedJobOptions(cfg)
+
+	if cfg.EmailFetcher != nil {
+		opts = append(opts, gmaps.WithEmailFetcher(cfg.EmailFetcher))
+	}
+
+	if cfg.Limiter != nil {
+		opts = append(opts, gmaps.WithLimiter(cfg.Limiter))
+	}
+
+	if monitor := cfg.ProxyMonitor(); monitor != nil {
+		opts = append(opts, gmaps.WithProxyMonitor(monitor))
+	}
+
+	if cfg.UserAgents != nil {
+		opts = append(opts, gmaps.WithUserAgents(cfg.UserAgents))
+	}
+
+	if len(cfg.DerivedFields) > 0 {
+		opts = append(opts, gmaps.WithDeriver(cfg.DerivedFields))
+	}
+
+	return opts
+}
+
+// NewDeduper returns the place deduper configured in cfg
+func NewDeduper(cfg *Config) deduper.Deduper {
+	if cfg.DedupBloom {
+		return deduper.NewBloom(cfg.DedupExpected, cfg.DedupFalsePositiveRate)
+	}
+
+	return deduper.New()
+}
+
+// NewPlaceDeduper returns the deduper dropping the places already scraped with
+// the same cid, nil when it's not enabled in cfg. The deduplication is exact
+// so that no place is dropped by a false positive.
+func NewPlaceDeduper(cfg *Config) *deduper.Counter {
+	if !cfg.Dedup {
+		return nil
+	}
+
+	return deduper.NewCounter(deduper.New())
+}
+
 func LoadCustomWriter(pluginDir, pluginName string) (scrapemate.ResultWriter, error) {
 	files, err := os.ReadDir(pluginDir)
 	if err != nil {
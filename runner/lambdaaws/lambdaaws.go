@@ -17,6 +17,7 @@ import (
 
 	"github.com/gosom/google-maps-scraper/exiter"
 	"github.com/gosom/google-maps-scraper/runner"
+	"github.com/gosom/google-maps-scraper/s3uploader"
 	"github.com/gosom/scrapemate"
 	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
 	"github.com/gosom/scrapemate/scrapemateapp"
@@ -26,6 +27,8 @@ var _ runner.Runner = (*lambdaAwsRunner)(nil)
 
 type lambdaAwsRunner struct {
 	uploader runner.S3Uploader
+	bucket   string
+	prefix   string
 }
 
 func New(cfg *runner.Config) (runner.Runner, error) {
@@ -35,6 +38,8 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 
 	ans := lambdaAwsRunner{
 		uploader: cfg.S3Uploader,
+		bucket:   cfg.OutputBucket,
+		prefix:   cfg.OutputPrefix,
 	}
 
 	return &ans, nil
@@ -87,6 +92,7 @@ func (l *lambdaAwsRunner) handler(ctx context.Context, input lInput) error {
 		0,
 		nil,
 		exitMonitor,
+		nil,
 	)
 	if err != nil {
 		return err
@@ -109,14 +115,22 @@ func (l *lambdaAwsRunner) handler(ctx context.Context, input lInput) error {
 	out.Close()
 
 	if l.uploader != nil {
+		bucket := input.BucketName
 		key := fmt.Sprintf("%s-%d.csv", input.JobID, input.Part)
 
+		// -output takes precedence over the bucket of the invoker
+		if l.bucket != "" {
+			bucket = l.bucket
+			key = s3uploader.Key(l.prefix, fmt.Sprintf("%s-%d-%s.csv",
+				input.JobID, input.Part, time.Now().UTC().Format("20060102T150405Z")))
+		}
+
 		fd, err := os.Open(out.Name())
 		if err != nil {
 			return err
 		}
 
-		err = l.uploader.Upload(ctx, input.BucketName, key, fd)
+		err = l.uploader.Upload(ctx, bucket, key, fd)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// formats of the logs
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+// NewLogger returns a logger writing to stderr in the given format, its
+// level follows level so that it can be changed while running
+func NewLogger(format string, level zap.AtomicLevel) (*zap.Logger, error) {
+	var logConfig zap.Config
+
+	switch format {
+	case LogFormatJSON:
+		logConfig = zap.NewProductionConfig()
+	case LogFormatConsole:
+		logConfig = zap.NewDevelopmentConfig()
+		// the development mode panics on DPanic and adds the stack traces of the warnings
+		logConfig.Development = false
+		logConfig.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected json or console", format)
+	}
+
+	logConfig.Level = level
+
+	return logConfig.Build()
+}
@@ -0,0 +1,272 @@
+package runner
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/gosom/google-maps-scraper/adaptive"
+	"github.com/gosom/google-maps-scraper/proxypool"
+)
+
+// Reloader re-reads the config file and applies the settings that can
+// change at runtime: the log level, the proxies and the bounds of the
+// adaptive concurrency. The other settings need a restart.
+type Reloader struct {
+	cfg *Config
+
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// NewReloader returns the reloader of the config file of cfg
+func NewReloader(cfg *Config) (*Reloader, error) {
+	if cfg.ConfigFile == "" {
+		return nil, fmt.Errorf("no config file to reload, start with -%s", configFlag)
+	}
+
+	return &Reloader{
+		cfg:    cfg,
+		values: cfg.configValues,
+	}, nil
+}
+
+// reloadFunc validates the new value of a setting, values holds the
+// settings after the reload. It returns the function applying it, which
+// can return a warning, or nil when the setting can't change at runtime.
+type reloadFunc func(r *Reloader, values map[string]string, value string) (func(ctx context.Context) string, error)
+
+var reloadable = map[string]reloadFunc{
+	"log-level":       reloadLogLevel,
+	"proxies":         reloadProxies,
+	"proxies-url":     reloadProxiesURL,
+	"c":               reloadConcurrency,
+	"min-concurrency": reloadConcurrency,
+}
+
+// Reload applies the settings changed since the last reload. It returns the
+// names of the applied settings and warnings for the ones left unchanged.
+// Nothing is applied when a new value is invalid.
+func (r *Reloader) Reload(ctx context.Context) (changed, ignored []string, err error) {
+	values, err := readConfigFile(r.cfg.ConfigFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(values)+len(r.values))
+
+	for name := range values {
+		names = append(names, name)
+	}
+
+	for name := range r.values {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	// next holds the stored values with the reloadable changes, a removed
+	// setting is back to its default
+	next := make(map[string]string, len(names))
+
+	for _, name := range names {
+		oldValue, newValue := value(r.values, name), value(values, name)
+
+		switch _, ok := reloadable[name]; {
+		case oldValue == newValue:
+		case r.cfg.cmdline[name]:
+			ignored = append(ignored, fmt.Sprintf("%s is set on the command line", name))
+		case !ok:
+			ignored = append(ignored, fmt.Sprintf("%s requires a restart", name))
+		default:
+			next[name] = newValue
+			changed = append(changed, name)
+
+			continue
+		}
+
+		if v, ok := r.values[name]; ok {
+			next[name] = v
+		}
+	}
+
+	var (
+		applied []func(ctx context.Context) string
+		kept    []string
+	)
+
+	for _, name := range changed {
+		apply, err := reloadable[name](r, next, next[name])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+
+		if apply == nil {
+			ignored = append(ignored, fmt.Sprintf("%s can't change at runtime with the current settings", name))
+
+			delete(next, name)
+
+			if v, ok := r.values[name]; ok {
+				next[name] = v
+			}
+
+			continue
+		}
+
+		// the concurrency bounds are applied together
+		if name != "min-concurrency" || !slices.Contains(changed, "c") {
+			applied = append(applied, apply)
+		}
+
+		kept = append(kept, name)
+	}
+
+	changed = kept
+
+	r.values = next
+
+	for _, apply := range applied {
+		if warning := apply(ctx); warning != "" {
+			ignored = append(ignored, warning)
+		}
+	}
+
+	if len(changed) > 0 {
+		log.Printf("config reloaded, changed: %s", strings.Join(changed, ", "))
+	}
+
+	return changed, ignored, nil
+}
+
+// value returns the value of the setting in values or its default
+func value(values map[string]string, name string) string {
+	if v, ok := values[name]; ok {
+		return v
+	}
+
+	if f := flag.Lookup(name); f != nil {
+		return f.DefValue
+	}
+
+	return ""
+}
+
+func reloadLogLevel(r *Reloader, _ map[string]string, value string) (func(ctx context.Context) string, error) {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(value)); err != nil {
+		return nil, err
+	}
+
+	return func(context.Context) string {
+		r.cfg.LogLevel.SetLevel(lvl)
+
+		return ""
+	}, nil
+}
+
+func reloadProxies(r *Reloader, _ map[string]string, value string) (func(ctx context.Context) string, error) {
+	if r.cfg.ProxyPool == nil {
+		return nil, nil
+	}
+
+	var proxies []string
+
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+
+	if err := proxypool.Validate(proxies); err != nil {
+		return nil, err
+	}
+
+	return func(context.Context) string {
+		if err := r.cfg.ProxyPool.Set(proxies); err != nil {
+			return fmt.Sprintf("proxies were not changed: %v", err)
+		}
+
+		return ""
+	}, nil
+}
+
+func reloadProxiesURL(r *Reloader, _ map[string]string, value string) (func(ctx context.Context) string, error) {
+	if r.cfg.ProxyPool == nil {
+		return nil, nil
+	}
+
+	return func(ctx context.Context) string {
+		r.cfg.ProxyPool.SetURL(value)
+
+		if value == "" {
+			return ""
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+
+		if err := r.cfg.ProxyPool.Refresh(ctx); err != nil {
+			return fmt.Sprintf("failed to fetch the proxies from the new proxies-url, keeping the active ones: %v", err)
+		}
+
+		return ""
+	}, nil
+}
+
+// reloadConcurrency changes the bounds of the adaptive concurrency, the
+// number of workers is fixed at startup so -c can only be lowered
+func reloadConcurrency(r *Reloader, values map[string]string, _ string) (func(ctx context.Context) string, error) {
+	limiter, ok := r.cfg.Limiter.(*adaptive.Limiter)
+	if !ok {
+		return nil, nil
+	}
+
+	maxLimit, minLimit := r.cfg.Concurrency, r.cfg.MinConcurrency
+
+	if !r.cfg.cmdline["c"] {
+		n, err := strconv.Atoi(value(values, "c"))
+		if err != nil {
+			return nil, err
+		}
+
+		maxLimit = n
+	}
+
+	if !r.cfg.cmdline["min-concurrency"] {
+		n, err := strconv.Atoi(value(values, "min-concurrency"))
+		if err != nil {
+			return nil, err
+		}
+
+		minLimit = n
+	}
+
+	if minLimit < 1 || minLimit > maxLimit {
+		return nil, fmt.Errorf("min-concurrency must be between 1 and c")
+	}
+
+	return func(context.Context) string {
+		var warning string
+
+		if maxLimit > r.cfg.Concurrency {
+			warning = fmt.Sprintf("c can't be raised above %d at runtime", r.cfg.Concurrency)
+			maxLimit = r.cfg.Concurrency
+		}
+
+		limiter.SetBounds(min(minLimit, maxLimit), maxLimit)
+
+		return warning
+	}, nil
+}
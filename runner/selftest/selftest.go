@@ -0,0 +1,188 @@
+package selftest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	// postgres driver
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/gosom/scrapemate"
+	"github.com/gosom/scrapemate/scrapemateapp"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/runner"
+)
+
+const (
+	scrapeTimeout = 3 * time.Minute
+	dbTimeout     = 10 * time.Second
+)
+
+var errSkipped = errors.New("skipped")
+
+type check struct {
+	name string
+	fn   func(context.Context) error
+}
+
+type selftest struct {
+	cfg *runner.Config
+}
+
+// New returns a runner that checks that the scraper works end to end:
+// it scrapes a well known place, validates the parsed fields and checks
+// the database connectivity when a dsn is configured.
+func New(cfg *runner.Config) (runner.Runner, error) {
+	if cfg.RunMode != runner.RunModeSelfTest {
+		return nil, fmt.Errorf("%w: %d", runner.ErrInvalidRunMode, cfg.RunMode)
+	}
+
+	return &selftest{cfg: cfg}, nil
+}
+
+func (s *selftest) Run(ctx context.Context) error {
+	checks := []check{
+		{name: "database", fn: s.checkDatabase},
+		{name: "scrape", fn: s.checkScrape},
+	}
+
+	var failed []string
+
+	for _, c := range checks {
+		t0 := time.Now()
+		err := c.fn(ctx)
+		elapsed := time.Since(t0).Round(time.Millisecond)
+
+		switch {
+		case errors.Is(err, errSkipped):
+			fmt.Fprintf(os.Stdout, "SKIP %-10s %v\n", c.name, err)
+		case err != nil:
+			fmt.Fprintf(os.Stdout, "FAIL %-10s (%s) %v\n", c.name, elapsed, err)
+
+			failed = append(failed, c.name)
+		default:
+			fmt.Fprintf(os.Stdout, "PASS %-10s (%s)\n", c.name, elapsed)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("selftest failed: %s", strings.Join(failed, ", "))
+	}
+
+	fmt.Fprintln(os.Stdout, "selftest passed")
+
+	return nil
+}
+
+func (s *selftest) Close(context.Context) error {
+	return nil
+}
+
+func (s *selftest) checkDatabase(ctx context.Context) error {
+	if s.cfg.Dsn == "" {
+		return fmt.Errorf("%w: no dsn configured", errSkipped)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	db, err := sql.Open("pgx", s.cfg.Dsn)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+
+	for _, table := range []string{"gmaps_jobs", "results"} {
+		//nolint:gosec // the table names are constants
+		if _, err := db.ExecContext(ctx, "SELECT 1 FROM "+table+" LIMIT 1"); err != nil {
+			return fmt.Errorf("table %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *selftest) checkScrape(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, scrapeTimeout)
+	defer cancel()
+
+	writer := runner.NewFirstEntryWriter(cancel)
+
+	opts := []func(*scrapemateapp.Config) error{
+		scrapemateapp.WithConcurrency(1),
+		scrapemateapp.WithExitOnInactivity(time.Minute),
+		scrapemateapp.WithJS(scrapemateapp.DisableImages()),
+	}
+
+	if proxies := s.cfg.ActiveProxies(); len(proxies) > 0 {
+		opts = append(opts, scrapemateapp.WithProxies(proxies))
+	}
+
+	matecfg, err := scrapemateapp.NewConfig([]scrapemate.ResultWriter{writer}, opts...)
+	if err != nil {
+		return err
+	}
+
+	app, err := scrapemateapp.NewScrapeMateApp(matecfg)
+	if err != nil {
+		return err
+	}
+
+	defer app.Close()
+
+	job := gmaps.NewGmapJob("selftest", "en", s.cfg.SelfTestQuery, 1, false, "", 0)
+
+	err = app.Start(ctx, job)
+	if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	entry := writer.First()
+	if entry == nil {
+		return fmt.Errorf("no place found for %q", s.cfg.SelfTestQuery)
+	}
+
+	return validate(entry)
+}
+
+// validate checks the fields that every well known place has
+func validate(entry *gmaps.Entry) error {
+	var missing []string
+
+	if entry.Title == "" {
+		missing = append(missing, "title")
+	}
+
+	if entry.Category == "" {
+		missing = append(missing, "category")
+	}
+
+	if entry.Address == "" {
+		missing = append(missing, "address")
+	}
+
+	if entry.Latitude == 0 || entry.Longtitude == 0 {
+		missing = append(missing, "coordinates")
+	}
+
+	if entry.ReviewCount == 0 {
+		missing = append(missing, "review_count")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("place %q has empty fields: %s", entry.Title, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
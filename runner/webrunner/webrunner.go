@@ -7,17 +7,21 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/gosom/google-maps-scraper/deduper"
 	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/polygon"
+	"github.com/gosom/google-maps-scraper/routing"
 	"github.com/gosom/google-maps-scraper/runner"
 	"github.com/gosom/google-maps-scraper/tlmt"
 	"github.com/gosom/google-maps-scraper/web"
 	"github.com/gosom/google-maps-scraper/web/sqlite"
+	"github.com/gosom/google-maps-scraper/webhook"
 	"github.com/gosom/scrapemate"
 	"github.com/gosom/scrapemate/adapters/writers/csvwriter"
 	"github.com/gosom/scrapemate/scrapemateapp"
@@ -25,9 +29,10 @@ import (
 )
 
 type webrunner struct {
-	srv *web.Server
-	svc *web.Service
-	cfg *runner.Config
+	srv      *web.Server
+	svc      *web.Service
+	cfg      *runner.Config
+	notifier *webhook.Notifier
 }
 
 func New(cfg *runner.Config) (runner.Runner, error) {
@@ -43,24 +48,37 @@ func New(cfg *runner.Config) (runner.Runner, error) {
 
 	dbpath := filepath.Join(cfg.DataFolder, dbfname)
 
-	repo, err := sqlite.New(dbpath)
+	db, err := sqlite.Open(dbpath)
 	if err != nil {
 		return nil, err
 	}
 
-	svc := web.NewService(repo, cfg.DataFolder)
-
-	srv, err := web.New(svc)
-	if err != nil {
-		return nil, err
-	}
+	svc := web.NewService(sqlite.NewWithDB(db), cfg.DataFolder, web.WithDedupWindow(cfg.JobDedupWindow))
 
 	ans := webrunner{
-		srv: srv,
 		svc: svc,
 		cfg: cfg,
 	}
 
+	var srvOpts []web.ServerOption
+
+	if cfg.WebhookURL != "" {
+		deliveries := sqlite.NewDeliveryLog(db)
+
+		ans.notifier = webhook.New(cfg.WebhookURL, cfg.WebhookBatchSize, cfg.WebhookBatchInterval,
+			webhook.WithConcurrency(cfg.WebhookConcurrency),
+			webhook.WithRetries(cfg.WebhookRetries, cfg.WebhookRetryBackoff),
+			webhook.WithDeliveryLog(deliveries),
+		)
+
+		srvOpts = append(srvOpts, web.WithDeliveryLog(deliveries))
+	}
+
+	ans.srv, err = web.New(svc, srvOpts...)
+	if err != nil {
+		return nil, err
+	}
+
 	return &ans, nil
 }
 
@@ -75,6 +93,12 @@ func (w *webrunner) Run(ctx context.Context) error {
 		return w.srv.Start(ctx)
 	})
 
+	if w.notifier != nil {
+		egroup.Go(func() error {
+			return w.notifier.Run(ctx)
+		})
+	}
+
 	return egroup.Wait()
 }
 
@@ -124,12 +148,28 @@ func (w *webrunner) work(ctx context.Context) error {
 
 						log.Printf("job %s scraped successfully", jobs[i].ID)
 					}
+
+					w.notify(&jobs[i])
 				}
 			}
 		}
 	}
 }
 
+func (w *webrunner) notify(job *web.Job) {
+	if w.notifier == nil {
+		return
+	}
+
+	w.notifier.Notify(webhook.Notification{
+		JobID:      job.ID,
+		Name:       job.Name,
+		Status:     job.Status,
+		ResultsURL: w.cfg.PublicURL + "/download?id=" + url.QueryEscape(job.ID),
+		FinishedAt: time.Now().UTC(),
+	})
+}
+
 func (w *webrunner) scrapeJob(ctx context.Context, job *web.Job) error {
 	job.Status = web.StatusWorking
 
@@ -174,20 +214,78 @@ func (w *webrunner) scrapeJob(ctx context.Context, job *web.Job) error {
 		coords = job.Data.Lat + "," + job.Data.Lon
 	}
 
-	dedup := deduper.New()
+	coords, zoom, err := runner.ResolveCoordinates(ctx, w.cfg.Geocoder, job.Data.Location, coords, job.Data.Zoom)
+	if err != nil {
+		job.Status = web.StatusFailed
+
+		err2 := w.svc.Update(ctx, job)
+		if err2 != nil {
+			log.Printf("failed to update job status: %v", err2)
+		}
+
+		return err
+	}
+
+	dedup := runner.NewDeduper(w.cfg)
 	exitMonitor := exiter.New()
 
+	jobOpts := runner.InProcessJobOptions(w.cfg)
+
+	placeDedup := runner.NewPlaceDeduper(w.cfg)
+	if placeDedup != nil {
+		jobOpts = append(jobOpts, gmaps.WithPlaceDeduper(placeDedup))
+	}
+
+	if job.Data.ScrollBudget > 0 {
+		jobOpts = append(jobOpts, gmaps.WithScrollBudget(job.Data.ScrollBudget))
+	}
+
+	if job.Data.AutoDepth {
+		jobOpts = append(jobOpts, gmaps.WithAutoDepth())
+	}
+
+	if len(job.Data.IncludeKeywords) > 0 {
+		jobOpts = append(jobOpts, gmaps.WithIncludeKeywords(job.Data.IncludeKeywords))
+	}
+
+	if len(job.Data.ExcludeKeywords) > 0 {
+		jobOpts = append(jobOpts, gmaps.WithExcludeKeywords(job.Data.ExcludeKeywords))
+	}
+
+	if len(job.Data.Polygon) > 0 {
+		area, err := polygon.Parse(job.Data.Polygon)
+		if err != nil {
+			job.Status = web.StatusFailed
+
+			if err2 := w.svc.Update(ctx, job); err2 != nil {
+				log.Printf("failed to update job status: %v", err2)
+			}
+
+			return err
+		}
+
+		jobOpts = append(jobOpts, gmaps.WithPolygon(area))
+	}
+
 	seedJobs, err := runner.CreateSeedJobs(
 		job.Data.Lang,
 		strings.NewReader(strings.Join(job.Data.Keywords, "\n")),
 		job.Data.Depth,
 		job.Data.Email,
 		coords,
-		job.Data.Zoom,
+		zoom,
 		dedup,
 		exitMonitor,
+		nil,
+		jobOpts...,
 	)
+	if err == nil {
+		seedJobs, err = runner.DropRestricted(ctx, w.cfg, seedJobs)
+	}
+
 	if err != nil {
+		job.Status = web.StatusFailed
+
 		err2 := w.svc.Update(ctx, job)
 		if err2 != nil {
 			log.Printf("failed to update job status: %v", err2)
@@ -218,6 +316,20 @@ func (w *webrunner) scrapeJob(ctx context.Context, job *web.Job) error {
 
 		go exitMonitor.Run(mateCtx)
 
+		progressDone := make(chan struct{})
+
+		go func() {
+			defer close(progressDone)
+
+			w.reportProgress(mateCtx, job.ID, exitMonitor)
+		}()
+
+		defer func() {
+			cancel()
+			<-progressDone
+			w.svc.ClearProgress(job.ID)
+		}()
+
 		err = mate.Start(mateCtx, seedJobs...)
 		if err != nil && !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
 			cancel()
@@ -235,9 +347,58 @@ func (w *webrunner) scrapeJob(ctx context.Context, job *web.Job) error {
 
 	mate.Close()
 
+	if placeDedup != nil {
+		log.Printf("dedup: dropped %d duplicate places of job %s", placeDedup.Duplicates(), job.ID)
+	}
+
 	job.Status = web.StatusOK
 
-	return w.svc.Update(ctx, job)
+	if err := w.svc.Update(ctx, job); err != nil {
+		return err
+	}
+
+	w.route(ctx, job, outpath)
+
+	return nil
+}
+
+// reportProgress records the progress of the job on every heartbeat until ctx is done
+func (w *webrunner) reportProgress(ctx context.Context, jobID string, exitMonitor exiter.Exiter) {
+	ticker := time.NewTicker(web.ProgressHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		w.svc.SetProgress(jobID, exitMonitor.Progress())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// route delivers the results of job to the sink selected by its tags
+func (w *webrunner) route(ctx context.Context, job *web.Job, fpath string) {
+	if w.cfg.OutputRoutes == nil {
+		return
+	}
+
+	var uploader routing.Uploader
+	if w.cfg.S3Uploader != nil {
+		uploader = w.cfg.S3Uploader
+	}
+
+	location, err := w.cfg.OutputRoutes.Deliver(ctx, uploader, job.Data.Tags, fpath)
+	if err != nil {
+		log.Printf("failed to route results of job %s: %v", job.ID, err)
+
+		return
+	}
+
+	if location != "" {
+		log.Printf("results of job %s delivered to %s", job.ID, location)
+	}
 }
 
 func (w *webrunner) setupMate(_ context.Context, writer io.Writer, job *web.Job) (*scrapemateapp.ScrapemateApp, error) {
@@ -249,8 +410,8 @@ func (w *webrunner) setupMate(_ context.Context, writer io.Writer, job *web.Job)
 
 	hasProxy := false
 
-	if len(w.cfg.Proxies) > 0 {
-		opts = append(opts, scrapemateapp.WithProxies(w.cfg.Proxies))
+	if proxies := w.cfg.ActiveProxies(); len(proxies) > 0 {
+		opts = append(opts, scrapemateapp.WithProxies(proxies))
 		hasProxy = true
 	} else if len(job.Data.Proxies) > 0 {
 		opts = append(opts,
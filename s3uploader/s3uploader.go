@@ -2,7 +2,9 @@ package s3uploader
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -32,6 +34,49 @@ func New(accessKey, secretKey, region string) *Uploader {
 	}
 }
 
+// NewFromEnv returns an uploader using the standard AWS credential chain
+// (environment, shared config files, instance and task roles). An empty
+// region is resolved the same way.
+func NewFromEnv(ctx context.Context, region string) (*Uploader, error) {
+	var opts []func(*config.LoadOptions) error
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uploader{
+		client: s3.NewFromConfig(cfg),
+	}, nil
+}
+
+// ParseURL splits an s3://bucket/prefix location, the prefix may be empty
+func ParseURL(s string) (bucket, prefix string, err error) {
+	rest, ok := strings.CutPrefix(s, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid s3 location %q, expected s3://bucket/prefix", s)
+	}
+
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid s3 location %q, the bucket is missing", s)
+	}
+
+	return bucket, strings.Trim(prefix, "/"), nil
+}
+
+// Key joins prefix and name into an object key
+func Key(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "/" + name
+}
+
 func (u *Uploader) Upload(ctx context.Context, bucketName, key string, body io.Reader) error {
 	input := &s3.PutObjectInput{
 		Bucket: aws.String(bucketName),
@@ -0,0 +1,3 @@
+BEGIN;
+    ALTER TABLE gmaps_jobs DROP COLUMN throttle_ms;
+COMMIT;
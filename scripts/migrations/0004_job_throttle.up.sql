@@ -0,0 +1,4 @@
+BEGIN;
+    ALTER TABLE gmaps_jobs
+        ADD COLUMN throttle_ms INT NOT NULL DEFAULT 0;
+COMMIT;
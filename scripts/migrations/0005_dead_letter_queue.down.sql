@@ -0,0 +1,3 @@
+BEGIN;
+    DROP TABLE gmaps_jobs_dlq;
+COMMIT;
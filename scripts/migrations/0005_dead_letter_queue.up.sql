@@ -0,0 +1,13 @@
+BEGIN;
+    CREATE TABLE gmaps_jobs_dlq(
+        id UUID PRIMARY KEY,
+        priority SMALLINT NOT NULL,
+        payload_type TEXT NOT NULL,
+        payload BYTEA NOT NULL,
+        created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+        failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+        reason TEXT NOT NULL
+    );
+
+    CREATE INDEX gmaps_jobs_dlq_failed_at_idx ON gmaps_jobs_dlq(failed_at);
+COMMIT;
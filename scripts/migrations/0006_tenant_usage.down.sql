@@ -0,0 +1,3 @@
+BEGIN;
+    DROP TABLE gmaps_tenant_usage;
+COMMIT;
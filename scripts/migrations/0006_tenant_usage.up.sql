@@ -0,0 +1,8 @@
+BEGIN;
+    CREATE TABLE gmaps_tenant_usage(
+        tenant TEXT PRIMARY KEY,
+        period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+        jobs INT NOT NULL DEFAULT 0,
+        results INT NOT NULL DEFAULT 0
+    );
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+    DROP INDEX results_input_id_idx;
+
+    ALTER TABLE gmaps_jobs DROP COLUMN updated_at;
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+    ALTER TABLE gmaps_jobs
+        ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE;
+
+    CREATE INDEX results_input_id_idx ON results ((data->>'input_id'));
+COMMIT;
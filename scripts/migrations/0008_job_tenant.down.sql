@@ -0,0 +1,7 @@
+BEGIN;
+    DROP INDEX gmaps_jobs_created_at_idx;
+
+    ALTER TABLE gmaps_jobs_dlq DROP COLUMN tenant;
+
+    ALTER TABLE gmaps_jobs DROP COLUMN tenant;
+COMMIT;
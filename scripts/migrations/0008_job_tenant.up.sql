@@ -0,0 +1,9 @@
+BEGIN;
+    ALTER TABLE gmaps_jobs
+        ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+    ALTER TABLE gmaps_jobs_dlq
+        ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+
+    CREATE INDEX gmaps_jobs_created_at_idx ON gmaps_jobs(created_at);
+COMMIT;
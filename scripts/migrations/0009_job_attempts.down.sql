@@ -0,0 +1,5 @@
+BEGIN;
+    ALTER TABLE gmaps_jobs_dlq DROP COLUMN attempts;
+
+    ALTER TABLE gmaps_jobs DROP COLUMN attempts;
+COMMIT;
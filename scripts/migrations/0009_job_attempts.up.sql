@@ -0,0 +1,7 @@
+BEGIN;
+    ALTER TABLE gmaps_jobs
+        ADD COLUMN attempts INT NOT NULL DEFAULT 0;
+
+    ALTER TABLE gmaps_jobs_dlq
+        ADD COLUMN attempts INT NOT NULL DEFAULT 0;
+COMMIT;
@@ -0,0 +1,3 @@
+BEGIN;
+    DROP TABLE gmaps_job_webhooks;
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+    CREATE TABLE gmaps_job_webhooks(
+        job_id UUID PRIMARY KEY,
+        url TEXT NOT NULL,
+        places_found INT,
+        places_done INT NOT NULL DEFAULT 0,
+        results INT NOT NULL DEFAULT 0,
+        notified_at TIMESTAMP WITH TIME ZONE
+    );
+COMMIT;
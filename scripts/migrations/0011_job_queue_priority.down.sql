@@ -0,0 +1,9 @@
+BEGIN;
+    DROP INDEX gmaps_jobs_queue_idx;
+
+    ALTER TABLE gmaps_jobs_dlq DROP COLUMN queue_priority;
+
+    ALTER TABLE gmaps_jobs
+        DROP COLUMN seq,
+        DROP COLUMN queue_priority;
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+    ALTER TABLE gmaps_jobs
+        ADD COLUMN queue_priority SMALLINT NOT NULL DEFAULT 5,
+        ADD COLUMN seq BIGINT GENERATED ALWAYS AS IDENTITY;
+
+    ALTER TABLE gmaps_jobs_dlq
+        ADD COLUMN queue_priority SMALLINT NOT NULL DEFAULT 5;
+
+    CREATE INDEX gmaps_jobs_queue_idx ON gmaps_jobs(queue_priority DESC, priority, created_at, seq)
+        WHERE status = 'new';
+COMMIT;
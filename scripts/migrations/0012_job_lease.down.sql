@@ -0,0 +1,5 @@
+BEGIN;
+    DROP INDEX gmaps_jobs_lease_idx;
+
+    ALTER TABLE gmaps_jobs DROP COLUMN lease_until;
+COMMIT;
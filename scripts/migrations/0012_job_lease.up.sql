@@ -0,0 +1,6 @@
+BEGIN;
+    ALTER TABLE gmaps_jobs ADD COLUMN lease_until TIMESTAMPTZ;
+
+    CREATE INDEX gmaps_jobs_lease_idx ON gmaps_jobs(lease_until)
+        WHERE status = 'queued' AND lease_until IS NOT NULL;
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+    DELETE FROM gmaps_job_progress WHERE url IS NULL;
+
+    ALTER TABLE gmaps_job_progress ALTER COLUMN url SET NOT NULL;
+
+    ALTER TABLE gmaps_job_progress RENAME TO gmaps_job_webhooks;
+COMMIT;
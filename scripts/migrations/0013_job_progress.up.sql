@@ -0,0 +1,6 @@
+BEGIN;
+    ALTER TABLE gmaps_job_webhooks RENAME TO gmaps_job_progress;
+
+    -- the progress of every search job is tracked, with or without a webhook
+    ALTER TABLE gmaps_job_progress ALTER COLUMN url DROP NOT NULL;
+COMMIT;
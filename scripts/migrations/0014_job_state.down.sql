@@ -0,0 +1,10 @@
+BEGIN;
+    DROP INDEX gmaps_jobs_state_idx;
+
+    ALTER TABLE gmaps_jobs_dlq DROP COLUMN started_at;
+
+    ALTER TABLE gmaps_jobs
+        DROP COLUMN finished_at,
+        DROP COLUMN started_at,
+        DROP COLUMN state;
+COMMIT;
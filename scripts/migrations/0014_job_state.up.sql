@@ -0,0 +1,27 @@
+BEGIN;
+    -- the lifecycle of the jobs, status keeps driving the queue
+    ALTER TABLE gmaps_jobs
+        ADD COLUMN state TEXT NOT NULL DEFAULT 'pending'
+            CHECK (state IN ('pending', 'running', 'completed', 'failed', 'cancelled')),
+        ADD COLUMN started_at TIMESTAMP WITH TIME ZONE,
+        ADD COLUMN finished_at TIMESTAMP WITH TIME ZONE;
+
+    ALTER TABLE gmaps_jobs_dlq ADD COLUMN started_at TIMESTAMP WITH TIME ZONE;
+
+    -- the existing jobs get the state of their status, a fetched job is done
+    -- once released and all the places of a search job are done
+    UPDATE gmaps_jobs SET state = 'running', started_at = COALESCE(updated_at, created_at)
+        WHERE status = 'queued';
+
+    UPDATE gmaps_jobs j SET state = 'completed', finished_at = started_at
+        WHERE status = 'queued' AND lease_until IS NULL
+            AND NOT EXISTS (
+                SELECT 1 FROM gmaps_job_progress pr
+                WHERE pr.job_id = j.id AND (pr.places_found IS NULL OR pr.places_done < pr.places_found)
+            );
+
+    UPDATE gmaps_jobs SET state = 'cancelled', finished_at = COALESCE(updated_at, created_at)
+        WHERE status = 'cancelled';
+
+    CREATE INDEX gmaps_jobs_state_idx ON gmaps_jobs(state);
+COMMIT;
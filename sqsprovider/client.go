@@ -0,0 +1,178 @@
+package sqsprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+)
+
+// client calls the SQS actions of a queue
+type client struct {
+	queueURL string
+	sqs      *sqs.Client
+}
+
+type message struct {
+	MessageID     string
+	ReceiptHandle string
+	Body          string
+}
+
+type batchEntry struct {
+	ID                     string
+	MessageBody            string
+	MessageGroupID         string
+	MessageDeduplicationID string
+}
+
+// newClient returns a client of the queue at queueURL. The queues that are
+// not hosted by AWS are reached at the host of their url, so that the local
+// SQS emulators work too.
+func newClient(queueURL string, awscfg aws.Config) (*client, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("invalid sqs queue url %q", queueURL)
+	}
+
+	var opts []func(*sqs.Options)
+
+	if !strings.HasSuffix(u.Hostname(), ".amazonaws.com") && !strings.HasSuffix(u.Hostname(), ".amazonaws.com.cn") {
+		opts = append(opts, func(o *sqs.Options) {
+			o.BaseEndpoint = aws.String(u.Scheme + "://" + u.Host)
+		})
+	}
+
+	return &client{
+		queueURL: queueURL,
+		sqs:      sqs.NewFromConfig(awscfg, opts...),
+	}, nil
+}
+
+// regionFromURL returns the region of the queue urls of the form
+// https://sqs.<region>.amazonaws.com/<account>/<queue>
+func regionFromURL(queueURL string) string {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+
+	parts := strings.Split(u.Hostname(), ".")
+	if len(parts) >= 4 && parts[0] == "sqs" && parts[2] == "amazonaws" {
+		return parts[1]
+	}
+
+	return ""
+}
+
+func (c *client) sendMessage(ctx context.Context, entry batchEntry) error {
+	_, err := c.sqs.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:               aws.String(c.queueURL),
+		MessageBody:            aws.String(entry.MessageBody),
+		MessageGroupId:         optional(entry.MessageGroupID),
+		MessageDeduplicationId: optional(entry.MessageDeduplicationID),
+	})
+
+	return err
+}
+
+// sendMessageBatch sends up to 10 messages, the entries that SQS didn't
+// accept are returned as an error
+func (c *client) sendMessageBatch(ctx context.Context, entries []batchEntry) error {
+	in := sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(c.queueURL),
+		Entries:  make([]types.SendMessageBatchRequestEntry, len(entries)),
+	}
+
+	for i := range entries {
+		in.Entries[i] = types.SendMessageBatchRequestEntry{
+			Id:                     aws.String(entries[i].ID),
+			MessageBody:            aws.String(entries[i].MessageBody),
+			MessageGroupId:         optional(entries[i].MessageGroupID),
+			MessageDeduplicationId: optional(entries[i].MessageDeduplicationID),
+		}
+	}
+
+	out, err := c.sqs.SendMessageBatch(ctx, &in)
+	if err != nil {
+		return err
+	}
+
+	if len(out.Failed) > 0 {
+		f := out.Failed[0]
+
+		return fmt.Errorf("sqs: %d of %d messages were not sent, the first failed with %s: %s",
+			len(out.Failed), len(entries), aws.ToString(f.Code), aws.ToString(f.Message))
+	}
+
+	return nil
+}
+
+// receiveMessages waits up to receiveWait for up to 10 messages. A zero
+// visibility uses the visibility timeout of the queue.
+func (c *client) receiveMessages(ctx context.Context, visibility time.Duration) ([]message, error) {
+	out, err := c.sqs.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(c.queueURL),
+		MaxNumberOfMessages: maxBatch,
+		WaitTimeSeconds:     int32(receiveWait.Seconds()),
+		VisibilityTimeout:   int32(visibility.Seconds()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ans := make([]message, len(out.Messages))
+
+	for i, m := range out.Messages {
+		ans[i] = message{
+			MessageID:     aws.ToString(m.MessageId),
+			ReceiptHandle: aws.ToString(m.ReceiptHandle),
+			Body:          aws.ToString(m.Body),
+		}
+	}
+
+	return ans, nil
+}
+
+func (c *client) deleteMessage(ctx context.Context, receiptHandle string) error {
+	_, err := c.sqs.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(c.queueURL),
+		ReceiptHandle: aws.String(receiptHandle),
+	})
+
+	return err
+}
+
+// changeVisibility hides the message for d more, 0 makes it visible again
+func (c *client) changeVisibility(ctx context.Context, receiptHandle string, d time.Duration) error {
+	_, err := c.sqs.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(c.queueURL),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: int32(d.Seconds()),
+	})
+
+	return err
+}
+
+// isClientError reports whether SQS refused the request, retrying it won't help
+func isClientError(err error) bool {
+	var apiErr smithy.APIError
+
+	return errors.As(err, &apiErr) && apiErr.ErrorFault() == smithy.FaultClient
+}
+
+// optional returns nil for the empty strings, for the optional parameters
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return aws.String(s)
+}
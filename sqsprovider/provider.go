@@ -0,0 +1,535 @@
+// Package sqsprovider is a job queue backed by Amazon SQS, for the workers
+// running on AWS that don't want to run a database just for the queue.
+//
+// Every job is a JSON message of the queue. A worker hides the messages it
+// receives for the visibility timeout and deletes them once the job is
+// processed, the messages of the failed or interrupted jobs become visible
+// again for the other workers. SQS keeps no index of the jobs, so they can't
+// be listed, inspected or cancelled once pushed.
+package sqsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/deduper"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/jobcodec"
+)
+
+const (
+	// MaxMessageSize is the largest message body that SQS accepts
+	MaxMessageSize = 256 * 1024
+	// maxBatch is the largest number of messages of a batch request
+	maxBatch = 10
+	// receiveWait is how long a receive request waits for messages before
+	// the worker checks its context again
+	receiveWait = 20 * time.Second
+	// maxVisibility is the longest visibility timeout of SQS
+	maxVisibility = 12 * time.Hour
+)
+
+var (
+	// ErrNotSupported is returned for the operations that need an index
+	// of the jobs, SQS only hands out the queued messages
+	ErrNotSupported = errors.New("not supported by the sqs provider")
+	// ErrJobTooLarge is returned for the jobs whose message is larger than
+	// MaxMessageSize
+	ErrJobTooLarge = errors.New("job is too large for an sqs message")
+)
+
+var _ scrapemate.JobProvider = (*Provider)(nil)
+var _ gmaps.Provider = (*Provider)(nil)
+var _ gmaps.DeadLetter = (*Provider)(nil)
+var _ gmaps.Acker = (*Provider)(nil)
+
+// envelope is the body of the messages, the job is encoded as JSON
+type envelope struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Tenant string          `json:"tenant,omitempty"`
+	Job    json.RawMessage `json:"job"`
+}
+
+// Provider is an SQS backed job queue. The jobs are handed out in the order
+// of the queue, their priority is ignored.
+type Provider struct {
+	client  *client
+	fifo    bool
+	mu      *sync.Mutex
+	jobc    chan scrapemate.IJob
+	errc    chan error
+	started bool
+
+	// inflight are the receipt handles of the received messages that are
+	// not processed yet, by job id
+	inflightMu sync.Mutex
+	inflight   map[string]string
+
+	accessKey  string
+	secretKey  string
+	region     string
+	visibility time.Duration
+
+	limiter      gmaps.Limiter
+	proxyMonitor gmaps.ProxyMonitor
+	userAgents   gmaps.UserAgents
+	deduper      deduper.Deduper
+	deriver      gmaps.Deriver
+}
+
+type Option func(*Provider)
+
+// WithLimiter sets the concurrency limiter of the fetched jobs
+func WithLimiter(l gmaps.Limiter) Option {
+	return func(p *Provider) {
+		p.limiter = l
+	}
+}
+
+// WithUserAgents sets the picker of the user agents of the fetched jobs
+func WithUserAgents(u gmaps.UserAgents) Option {
+	return func(p *Provider) {
+		p.userAgents = u
+	}
+}
+
+// WithDeduper sets the deduper of the places found by the cells of the
+// polygon searches
+func WithDeduper(d deduper.Deduper) Option {
+	return func(p *Provider) {
+		p.deduper = d
+	}
+}
+
+// WithDeriver sets the derived fields added to the places of the fetched jobs
+func WithDeriver(d gmaps.Deriver) Option {
+	return func(p *Provider) {
+		p.deriver = d
+	}
+}
+
+// WithProxyMonitor sets the monitor of the blocked proxies of the fetched jobs
+func WithProxyMonitor(m gmaps.ProxyMonitor) Option {
+	return func(p *Provider) {
+		p.proxyMonitor = m
+	}
+}
+
+// WithCredentials uses the static credentials instead of the standard
+// AWS credential chain
+func WithCredentials(accessKey, secretKey string) Option {
+	return func(p *Provider) {
+		p.accessKey = accessKey
+		p.secretKey = secretKey
+	}
+}
+
+// WithRegion sets the region of the queue, by default it's read from the
+// queue url or else resolved by the AWS config
+func WithRegion(region string) Option {
+	return func(p *Provider) {
+		p.region = region
+	}
+}
+
+// WithVisibilityTimeout sets how long the received messages stay hidden from
+// the other workers, it's extended while their job runs. Zero uses the
+// visibility timeout of the queue without extending it.
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(p *Provider) {
+		p.visibility = min(d, maxVisibility)
+	}
+}
+
+// New returns a provider of the queue at queueURL
+// (https://sqs.<region>.amazonaws.com/<account>/<queue>)
+func New(ctx context.Context, queueURL string, opts ...Option) (*Provider, error) {
+	ans := Provider{
+		fifo:     strings.HasSuffix(queueURL, ".fifo"),
+		mu:       &sync.Mutex{},
+		errc:     make(chan error, 1),
+		jobc:     make(chan scrapemate.IJob, 100),
+		inflight: make(map[string]string),
+	}
+
+	for _, opt := range opts {
+		opt(&ans)
+	}
+
+	region := ans.region
+	if region == "" {
+		region = regionFromURL(queueURL)
+	}
+
+	var loadOpts []func(*config.LoadOptions) error
+	if region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(region))
+	}
+
+	if ans.accessKey != "" && ans.secretKey != "" {
+		loadOpts = append(loadOpts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(ans.accessKey, ans.secretKey, ""),
+		))
+	}
+
+	awscfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the aws config: %w", err)
+	}
+
+	if awscfg.Region == "" {
+		return nil, errors.New("the region of the sqs queue is unknown, set -aws-region")
+	}
+
+	c, err := newClient(queueURL, awscfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ans.client = c
+
+	return &ans, nil
+}
+
+//nolint:gocritic // it contains about unnamed results
+func (p *Provider) Jobs(ctx context.Context) (<-chan scrapemate.IJob, <-chan error) {
+	outc := make(chan scrapemate.IJob)
+	errc := make(chan error, 1)
+
+	p.mu.Lock()
+	if !p.started {
+		go p.fetchJobs(ctx)
+
+		if p.visibility > 0 {
+			go p.extendVisibility(ctx)
+		}
+
+		p.started = true
+	}
+	p.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-p.errc:
+				errc <- err
+
+				return
+			case job, ok := <-p.jobc:
+				if !ok {
+					return
+				}
+
+				select {
+				case outc <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return outc, errc
+}
+
+// Push sends the job as a message of the queue. On a FIFO queue the id of
+// the job deduplicates the messages, a standard queue may hold the same
+// job twice when it's pushed twice.
+func (p *Provider) Push(ctx context.Context, job scrapemate.IJob) error {
+	entry, err := p.entry(job)
+	if err != nil {
+		return err
+	}
+
+	return p.client.sendMessage(ctx, entry)
+}
+
+// PushBatch sends the jobs in batches of 10 messages. All the jobs are
+// encoded before the first batch is sent, so a job too large for a message
+// rejects the whole batch. SQS has no transactions though, the batches sent
+// before a failed one stay in the queue.
+func (p *Provider) PushBatch(ctx context.Context, jobs []scrapemate.IJob) error {
+	entries := make([]batchEntry, len(jobs))
+
+	for i := range jobs {
+		entry, err := p.entry(jobs[i])
+		if err != nil {
+			return err
+		}
+
+		entry.ID = fmt.Sprintf("m%d", i)
+		entries[i] = entry
+	}
+
+	for len(entries) > 0 {
+		// the size limit of a message applies to the whole batch too
+		n, size := 0, 0
+		for n < len(entries) && n < maxBatch && size+len(entries[n].MessageBody) <= MaxMessageSize {
+			size += len(entries[n].MessageBody)
+			n++
+		}
+
+		if err := p.client.sendMessageBatch(ctx, entries[:n]); err != nil {
+			return err
+		}
+
+		entries = entries[n:]
+	}
+
+	return nil
+}
+
+// entry returns the message of job, or ErrJobTooLarge when its body is
+// larger than MaxMessageSize
+func (p *Provider) entry(job scrapemate.IJob) (batchEntry, error) {
+	body, err := encodeMessage(job)
+	if err != nil {
+		return batchEntry{}, err
+	}
+
+	if len(body) > MaxMessageSize {
+		return batchEntry{}, fmt.Errorf("%w: job %s is %d bytes, the limit is %d bytes",
+			ErrJobTooLarge, job.GetID(), len(body), MaxMessageSize)
+	}
+
+	entry := batchEntry{MessageBody: string(body)}
+
+	if p.fifo {
+		// every job is its own group, the workers take them in parallel
+		entry.MessageGroupID = job.GetID()
+		entry.MessageDeduplicationID = job.GetID()
+	}
+
+	return entry, nil
+}
+
+// Ack deletes the message of the processed job
+func (p *Provider) Ack(ctx context.Context, jobID string) error {
+	handle, ok := p.untrack(jobID)
+	if !ok {
+		return nil
+	}
+
+	return p.client.deleteMessage(ctx, handle)
+}
+
+// DeadLetter makes the message of the failed job visible again, so that it's
+// retried. The redrive policy of the queue moves it to its dead letter queue
+// after the maximum number of receives.
+func (p *Provider) DeadLetter(ctx context.Context, jobID string, _ error) error {
+	handle, ok := p.untrack(jobID)
+	if !ok {
+		return nil
+	}
+
+	return p.client.changeVisibility(ctx, handle, 0)
+}
+
+// Release makes the messages of the jobs received but not processed visible
+// again, the other workers pick them up without waiting for their visibility
+// timeout
+func (p *Provider) Release(ctx context.Context) error {
+	p.inflightMu.Lock()
+	handles := make([]string, 0, len(p.inflight))
+
+	for _, handle := range p.inflight {
+		handles = append(handles, handle)
+	}
+
+	clear(p.inflight)
+	p.inflightMu.Unlock()
+
+	var errs []error
+
+	for _, handle := range handles {
+		if err := p.client.changeVisibility(ctx, handle, 0); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if n := len(handles) - len(errs); n > 0 {
+		log.Printf("released %d unfinished jobs", n)
+	}
+
+	return errors.Join(errs...)
+}
+
+// UpdateThrottle is not supported, the jobs can't be changed once pushed
+func (p *Provider) UpdateThrottle(context.Context, string, time.Duration) error {
+	return ErrNotSupported
+}
+
+// Get is not supported, the messages can only be received
+func (p *Provider) Get(context.Context, string) (scrapemate.IJob, error) {
+	return nil, ErrNotSupported
+}
+
+// Info is not supported, the messages can only be received
+func (p *Provider) Info(context.Context, string) (gmaps.JobInfo, error) {
+	return gmaps.JobInfo{}, ErrNotSupported
+}
+
+// List is not supported, the messages can only be received
+func (p *Provider) List(context.Context, gmaps.JobFilter) ([]gmaps.JobInfo, int, error) {
+	return nil, 0, ErrNotSupported
+}
+
+// Delete is not supported, a message can only be deleted by its receiver
+func (p *Provider) Delete(context.Context, string) error {
+	return ErrNotSupported
+}
+
+// Cancel is not supported, a message can only be deleted by its receiver
+func (p *Provider) Cancel(context.Context, string) error {
+	return ErrNotSupported
+}
+
+func (p *Provider) fetchJobs(ctx context.Context) {
+	defer close(p.jobc)
+	defer close(p.errc)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		msgs, err := p.client.receiveMessages(ctx, p.visibility)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			p.errc <- err
+
+			return
+		}
+
+		for i := range msgs {
+			job, err := decodeMessage([]byte(msgs[i].Body))
+			if err != nil {
+				// the message is retried until the redrive policy moves it
+				// to the dead letter queue, where it can be inspected
+				log.Printf("skipping sqs message %s: %v", msgs[i].MessageID, err)
+
+				continue
+			}
+
+			p.track(job.GetID(), msgs[i].ReceiptHandle)
+
+			switch j := job.(type) {
+			case *gmaps.GmapJob:
+				j.Limiter = p.limiter
+				j.ProxyMonitor = p.proxyMonitor
+				j.UserAgents = p.userAgents
+				j.Deriver = p.deriver
+				j.DeadLetter = p
+				j.Acker = p
+
+				// the places of a polygon search are deduplicated across its cells
+				if j.ParentID != "" {
+					j.Deduper = p.deduper
+				}
+			case *gmaps.PlaceJob:
+				j.Limiter = p.limiter
+				j.ProxyMonitor = p.proxyMonitor
+				j.UserAgents = p.userAgents
+				j.Deriver = p.deriver
+				j.DeadLetter = p
+				j.Acker = p
+			}
+
+			select {
+			case p.jobc <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// extendVisibility keeps the messages of the running jobs hidden from the
+// other workers. SQS refuses to extend a message past 12 hours after its
+// receive, such a message is dropped and handed to another worker.
+func (p *Provider) extendVisibility(ctx context.Context) {
+	ticker := time.NewTicker(p.visibility / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p.inflightMu.Lock()
+		inflight := make(map[string]string, len(p.inflight))
+
+		for id, handle := range p.inflight {
+			inflight[id] = handle
+		}
+		p.inflightMu.Unlock()
+
+		for id, handle := range inflight {
+			err := p.client.changeVisibility(ctx, handle, p.visibility)
+			if err == nil || ctx.Err() != nil {
+				continue
+			}
+
+			log.Printf("failed to extend the visibility of job %s: %v", id, err)
+
+			if isClientError(err) {
+				p.untrack(id)
+			}
+		}
+	}
+}
+
+func (p *Provider) track(jobID, receiptHandle string) {
+	p.inflightMu.Lock()
+	p.inflight[jobID] = receiptHandle
+	p.inflightMu.Unlock()
+}
+
+func (p *Provider) untrack(jobID string) (string, bool) {
+	p.inflightMu.Lock()
+	defer p.inflightMu.Unlock()
+
+	handle, ok := p.inflight[jobID]
+	delete(p.inflight, jobID)
+
+	return handle, ok
+}
+
+// encodeMessage returns the message body of the job
+func encodeMessage(job scrapemate.IJob) ([]byte, error) {
+	payloadType, payload, tenant, err := jobcodec.EncodeJSON(job)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(envelope{ID: job.GetID(), Type: payloadType, Tenant: tenant, Job: payload})
+}
+
+func decodeMessage(body []byte) (scrapemate.IJob, error) {
+	var msg envelope
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	return jobcodec.DecodeJSON(msg.Type, msg.Job)
+}
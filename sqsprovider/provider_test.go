@@ -0,0 +1,94 @@
+package sqsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/polygon"
+)
+
+type nopAcker struct{}
+
+func (nopAcker) Ack(_ context.Context, _ string) error { return nil }
+
+func Test_MessageRoundTrip(t *testing.T) {
+	search := gmaps.NewGmapJob("", "en", "cafe in Limassol", 10, true, "34.68,33.04", 15,
+		gmaps.WithTenant("acme"),
+		gmaps.WithCustomFields(map[string]string{"campaign": "spring"}),
+		gmaps.WithQueuePriority(7),
+		gmaps.WithPolygon(&polygon.Polygon{Polygons: [][][][2]float64{{{{33, 34}, {33.1, 34}, {33.1, 34.1}, {33, 34}}}}}),
+	)
+	search.ScrollBudget = 2 * time.Minute
+	search.Acker = nopAcker{}
+	search.CheckResponse = func(*scrapemate.Response) bool { return true }
+
+	place := gmaps.NewPlaceJob(search.GetID(), "en", "https://www.google.com/maps/place/x", true,
+		gmaps.WithPlaceJobTenant("acme"),
+		gmaps.WithPlaceJobMaxPhotos(5),
+	)
+
+	for _, job := range []scrapemate.IJob{search, place} {
+		body, err := encodeMessage(job)
+		require.NoError(t, err)
+
+		var msg map[string]any
+
+		// the message is plain JSON, readable by the other consumers of the queue
+		require.NoError(t, json.Unmarshal(body, &msg))
+		require.Equal(t, job.GetID(), msg["id"])
+		require.Equal(t, "acme", msg["tenant"])
+		require.IsType(t, map[string]any{}, msg["job"])
+
+		got, err := decodeMessage(body)
+		require.NoError(t, err)
+		require.IsType(t, job, got)
+
+		switch want := job.(type) {
+		case *gmaps.GmapJob:
+			g := got.(*gmaps.GmapJob)
+
+			require.Equal(t, want.Job.URL, g.Job.URL)
+			require.Equal(t, want.Job.URLParams, g.Job.URLParams)
+			require.Equal(t, want.Query, g.Query)
+			require.Equal(t, want.GeoCoordinates, g.GeoCoordinates)
+			require.Equal(t, want.CustomFields, g.CustomFields)
+			require.Equal(t, want.QueuePriority, g.QueuePriority)
+			require.Equal(t, want.ScrollBudget, g.ScrollBudget)
+			require.Equal(t, want.Polygon, g.Polygon)
+			require.Nil(t, g.Acker)
+			require.Nil(t, g.CheckResponse)
+
+			// the runtime state of the pushed job is kept
+			require.NotNil(t, want.Acker)
+		case *gmaps.PlaceJob:
+			p := got.(*gmaps.PlaceJob)
+
+			require.Equal(t, want.Job.ParentID, p.Job.ParentID)
+			require.Equal(t, want.Job.URL, p.Job.URL)
+			require.Equal(t, want.ExtractEmail, p.ExtractEmail)
+			require.Equal(t, want.MaxPhotos, p.MaxPhotos)
+		}
+	}
+}
+
+func Test_MessageInvalid(t *testing.T) {
+	_, err := decodeMessage([]byte(`{"id": "1", "type": "unknown", "job": {}}`))
+	require.Error(t, err)
+
+	_, err = decodeMessage([]byte(`not json`))
+	require.Error(t, err)
+}
+
+func Test_EntryTooLarge(t *testing.T) {
+	job := gmaps.NewGmapJob("", "en", strings.Repeat("x", MaxMessageSize), 10, false, "", 0)
+
+	_, err := (&Provider{}).entry(job)
+	require.ErrorIs(t, err, ErrJobTooLarge)
+}
@@ -0,0 +1,273 @@
+// Package streamwriter streams the places as NDJSON to an HTTP endpoint
+// in a single long lived chunked POST.
+package streamwriter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// ContentType is the media type of the streamed records
+const ContentType = "application/x-ndjson"
+
+// OffsetHeader holds the sequence number of the first record of a request,
+// receivers use it to drop the records they already got before a reconnect
+const OffsetHeader = "X-Stream-Offset"
+
+const (
+	// maxUnconfirmed is the number of records after which the request is
+	// completed to have them confirmed, it bounds the memory used for resending
+	maxUnconfirmed = 10_000
+	maxAttempts    = 10
+	minBackoff     = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+var errStreamClosed = errors.New("stream closed")
+
+type streamWriter struct {
+	url    string
+	client *http.Client
+
+	// offset is the sequence number of pending[0], the records before it are confirmed
+	offset  int64
+	pending [][]byte
+
+	conn *conn
+}
+
+// conn is an open request, the records written to w are sent as its body
+type conn struct {
+	w    *io.PipeWriter
+	sent int
+	done chan error
+}
+
+// New returns a result writer that streams every place as a JSON line to url
+// in one chunked POST kept open while the job runs.
+//
+// The records of a request are confirmed when it completes with a 2xx status.
+// When the connection drops the unconfirmed records are sent again in a new
+// request, its OffsetHeader tells the receiver the sequence number of the first one.
+func New(url string) scrapemate.ResultWriter {
+	return &streamWriter{
+		url:    url,
+		client: &http.Client{},
+	}
+}
+
+func (s *streamWriter) Run(ctx context.Context, in <-chan scrapemate.Result) error {
+	for result := range in {
+		entries, err := entriesOf(result.Data)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			line, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+
+			s.pending = append(s.pending, append(line, '\n'))
+
+			if err := s.send(ctx, len(s.pending)-1); err != nil {
+				return err
+			}
+		}
+
+		if len(s.pending) >= maxUnconfirmed {
+			if err := s.confirm(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(s.pending) == 0 && s.conn == nil {
+		return nil
+	}
+
+	return s.confirm(ctx)
+}
+
+// send writes the pending record i, reconnecting when the connection dropped
+func (s *streamWriter) send(ctx context.Context, i int) error {
+	for attempt := 0; ; attempt++ {
+		err := s.write(i)
+		if err == nil {
+			return nil
+		}
+
+		if err := s.reconnect(ctx, attempt, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *streamWriter) write(i int) error {
+	if i < 0 {
+		return nil
+	}
+
+	if s.conn == nil {
+		s.conn = s.open()
+	}
+
+	// after a reconnect the unconfirmed records are sent first
+	for s.conn.sent <= i {
+		if _, err := s.conn.w.Write(s.pending[s.conn.sent]); err != nil {
+			return err
+		}
+
+		s.conn.sent++
+	}
+
+	return nil
+}
+
+// confirm completes the open request and waits for the receiver to confirm the pending records
+func (s *streamWriter) confirm(ctx context.Context) error {
+	for attempt := 0; ; attempt++ {
+		err := s.write(len(s.pending) - 1)
+		if err == nil {
+			err = s.closeConn()
+		}
+
+		if err == nil {
+			s.offset += int64(len(s.pending))
+			s.pending = nil
+
+			return nil
+		}
+
+		if err := s.reconnect(ctx, attempt, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *streamWriter) open() *conn {
+	pr, pw := io.Pipe()
+
+	c := conn{
+		w:    pw,
+		done: make(chan error, 1),
+	}
+
+	offset := s.offset
+
+	go func() {
+		c.done <- s.post(pr, offset)
+	}()
+
+	return &c
+}
+
+func (s *streamWriter) post(body io.ReadCloser, offset int64) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, body)
+	if err != nil {
+		_ = body.Close()
+
+		return err
+	}
+
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set(OffsetHeader, strconv.FormatInt(offset, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// unblock the writer when the request failed before reading the body
+		_ = body.Close()
+
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		_ = body.Close()
+
+		return fmt.Errorf("stream rejected with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// closeConn ends the body of the open request and returns its outcome
+func (s *streamWriter) closeConn() error {
+	if s.conn == nil {
+		return nil
+	}
+
+	c := s.conn
+	s.conn = nil
+
+	_ = c.w.Close()
+
+	return <-c.done
+}
+
+// reconnect drops the open request after err and waits before the next attempt
+func (s *streamWriter) reconnect(ctx context.Context, attempt int, err error) error {
+	if s.conn != nil {
+		c := s.conn
+		s.conn = nil
+
+		_ = c.w.CloseWithError(errStreamClosed)
+
+		if reqErr := <-c.done; reqErr != nil {
+			err = reqErr
+		}
+	}
+
+	if attempt+1 >= maxAttempts {
+		return fmt.Errorf("stream to %s failed after %d attempts: %w", s.url, maxAttempts, err)
+	}
+
+	backoff := min(minBackoff<<attempt, maxBackoff)
+
+	log.Printf("stream to %s dropped, resending %d records from %d in %s: %v",
+		s.url, len(s.pending), s.offset, backoff, err)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(backoff):
+		return nil
+	}
+}
+
+func entriesOf(data any) ([]*gmaps.Entry, error) {
+	switch v := data.(type) {
+	case *gmaps.Entry:
+		return []*gmaps.Entry{v}, nil
+	case []any:
+		ans := make([]*gmaps.Entry, 0, len(v))
+
+		for i := range v {
+			entry, ok := v[i].(*gmaps.Entry)
+			if !ok {
+				return nil, fmt.Errorf("cannot cast %T to *gmaps.Entry", v[i])
+			}
+
+			ans = append(ans, entry)
+		}
+
+		return ans, nil
+	default:
+		return nil, fmt.Errorf("unexpected result type %T", data)
+	}
+}
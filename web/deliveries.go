@@ -0,0 +1,83 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/gosom/google-maps-scraper/webhook"
+)
+
+type ServerOption func(*Server)
+
+// WithDeliveryLog serves the webhook delivery attempts of l
+func WithDeliveryLog(l webhook.DeliveryLog) ServerOption {
+	return func(s *Server) {
+		s.deliveries = l
+	}
+}
+
+// webhookDeliveries returns the latest webhook delivery attempts as JSON,
+// optionally of a single job (job_id) or only the failed ones (failed=true)
+func (s *Server) webhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if s.deliveries == nil {
+		http.Error(w, "webhooks are not enabled", http.StatusNotFound)
+
+		return
+	}
+
+	var params webhook.AttemptParams
+
+	query := r.URL.Query()
+
+	if id := query.Get("job_id"); id != "" {
+		if _, err := uuid.Parse(id); err != nil {
+			http.Error(w, "Invalid ID", http.StatusUnprocessableEntity)
+
+			return
+		}
+
+		params.JobID = id
+	}
+
+	if v := query.Get("failed"); v != "" {
+		failed, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid failed", http.StatusUnprocessableEntity)
+
+			return
+		}
+
+		params.FailedOnly = failed
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			http.Error(w, "invalid limit", http.StatusUnprocessableEntity)
+
+			return
+		}
+
+		params.Limit = limit
+	}
+
+	attempts, err := s.deliveries.Attempts(r.Context(), params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(attempts)
+}
@@ -5,4 +5,5 @@ import "errors"
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrAlreadyExists = errors.New("already exists")
+	ErrInvalidJob    = errors.New("invalid job")
 )
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gosom/google-maps-scraper/exiter"
+)
+
+// ProgressHeartbeat is how often the progress of the running jobs is updated
+const ProgressHeartbeat = 2 * time.Second
+
+// statusEvent is the data of the status events of the stream
+type statusEvent struct {
+	ID       string           `json:"id"`
+	Status   string           `json:"status"`
+	Progress *exiter.Progress `json:"progress"`
+}
+
+// events streams the status and the progress of a job as server sent events.
+// An event is sent on every heartbeat the job changed, the stream ends when the job finished.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "Invalid ID", http.StatusUnprocessableEntity)
+
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+
+		return
+	}
+
+	job, err := s.svc.Get(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "job not found", http.StatusNotFound)
+
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ticker := time.NewTicker(ProgressHeartbeat)
+	defer ticker.Stop()
+
+	var last *statusEvent
+
+	for {
+		evt := statusEvent{ID: job.ID, Status: job.Status, Progress: job.Progress}
+
+		if last == nil || !reflect.DeepEqual(*last, evt) {
+			data, err := json.Marshal(evt)
+			if err != nil {
+				return
+			}
+
+			if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+				return
+			}
+
+			flusher.Flush()
+
+			last = &evt
+		}
+
+		if job.Status == StatusOK || job.Status == StatusFailed {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		job, err = s.svc.Get(r.Context(), id)
+		if err != nil {
+			// the job was deleted or the database is gone, the client reconnects if it cares
+			return
+		}
+	}
+}
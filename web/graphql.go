@@ -0,0 +1,336 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/web/graphql"
+)
+
+const (
+	defaultResultsLimit = 100
+	// maxGraphQLBodySize is the maximum size in bytes of a GraphQL request
+	maxGraphQLBodySize = 1 << 20
+)
+
+var errStopReading = errors.New("stop reading")
+
+// jobView is the representation of a job in the GraphQL API
+type jobView struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      JobData   `json:"data"`
+	// Progress is set while the job is running
+	Progress *exiter.Progress `json:"progress"`
+	// Partial is true while the job is running, its results so far
+	// are returned and must be fetched again once it's done
+	Partial bool `json:"partial"`
+}
+
+func newJobView(j *Job) jobView {
+	return jobView{
+		ID:        j.ID,
+		Name:      j.Name,
+		Status:    j.Status,
+		CreatedAt: j.Date,
+		Data:      j.Data,
+		Progress:  j.Progress,
+		Partial:   j.Status == StatusWorking,
+	}
+}
+
+type statsView struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Working int `json:"working"`
+	OK      int `json:"ok"`
+	Failed  int `json:"failed"`
+}
+
+// graphqlSchema exposes the operations of the web UI to GraphQL clients
+func (s *Server) graphqlSchema() *graphql.Schema {
+	return &graphql.Schema{
+		Query: map[string]graphql.Resolver{
+			"job":     s.gqlJob,
+			"jobs":    s.gqlJobs,
+			"stats":   s.gqlStats,
+			"results": s.gqlResults,
+		},
+		Mutation: map[string]graphql.Resolver{
+			"createJob": s.gqlCreateJob,
+			"deleteJob": s.gqlDeleteJob,
+			"retryJob":  s.gqlRetryJob,
+		},
+	}
+}
+
+func (s *Server) graphqlQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	var req graphql.Request
+
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBodySize)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+
+			return
+		}
+
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+
+		return
+	}
+
+	resp := s.gql.Execute(r.Context(), &req)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) gqlJob(ctx context.Context, args map[string]any) (any, error) {
+	id, err := idArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.svc.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newJobView(&job), nil
+}
+
+func (s *Server) gqlJobs(ctx context.Context, args map[string]any) (any, error) {
+	var params SelectParams
+
+	if v, ok := args["status"].(string); ok {
+		params.Status = v
+	}
+
+	limit, err := intArg(args, "limit", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	params.Limit = limit
+
+	jobs, err := s.svc.Select(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := make([]jobView, 0, len(jobs))
+	for i := range jobs {
+		ans = append(ans, newJobView(&jobs[i]))
+	}
+
+	return ans, nil
+}
+
+func (s *Server) gqlStats(ctx context.Context, _ map[string]any) (any, error) {
+	jobs, err := s.svc.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ans := statsView{Total: len(jobs)}
+
+	for i := range jobs {
+		switch jobs[i].Status {
+		case StatusPending:
+			ans.Pending++
+		case StatusWorking:
+			ans.Working++
+		case StatusOK:
+			ans.OK++
+		case StatusFailed:
+			ans.Failed++
+		}
+	}
+
+	return ans, nil
+}
+
+func (s *Server) gqlResults(ctx context.Context, args map[string]any) (any, error) {
+	id, err := idArg(args, "jobId")
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := intArg(args, "limit", defaultResultsLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := intArg(args, "offset", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.openResults(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Close()
+
+	ans := []*gmaps.Entry{}
+	n := 0
+
+	err = readCsvEntries(res, res.Path, func(entry *gmaps.Entry) error {
+		n++
+
+		if n <= offset {
+			return nil
+		}
+
+		if limit > 0 && len(ans) >= limit {
+			return errStopReading
+		}
+
+		ans = append(ans, entry)
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopReading) {
+		return nil, err
+	}
+
+	return ans, nil
+}
+
+func (s *Server) gqlCreateJob(ctx context.Context, args map[string]any) (any, error) {
+	input, ok := args["input"].(map[string]any)
+	if !ok {
+		return nil, errors.New("input is required")
+	}
+
+	// the input uses the names of the JobData fields, max_time and
+	// scroll_budget are given as durations e.g. "10m"
+	var in struct {
+		Name string `json:"name"`
+		JobData
+		MaxTime      string `json:"max_time"`
+		ScrollBudget string `json:"scroll_budget"`
+	}
+
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	job := Job{
+		ID:     uuid.New().String(),
+		Name:   in.Name,
+		Date:   time.Now().UTC(),
+		Status: StatusPending,
+		Data:   in.JobData,
+	}
+
+	if in.MaxTime != "" {
+		job.Data.MaxTime, err = time.ParseDuration(in.MaxTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_time: %w", err)
+		}
+	}
+
+	if in.ScrollBudget != "" {
+		job.Data.ScrollBudget, err = time.ParseDuration(in.ScrollBudget)
+		if err != nil || job.Data.ScrollBudget < 0 {
+			return nil, fmt.Errorf("invalid scroll_budget")
+		}
+	}
+
+	created, _, err := s.svc.Submit(ctx, &job)
+	if err != nil {
+		return nil, err
+	}
+
+	return newJobView(&created), nil
+}
+
+func (s *Server) gqlDeleteJob(ctx context.Context, args map[string]any) (any, error) {
+	id, err := idArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.svc.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.svc.Delete(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return true, nil
+}
+
+func (s *Server) gqlRetryJob(ctx context.Context, args map[string]any) (any, error) {
+	id, err := idArg(args, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.svc.Retry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newJobView(&job), nil
+}
+
+// idArg returns the job id argument key
+func idArg(args map[string]any, key string) (string, error) {
+	id, _ := args[key].(string)
+
+	if _, err := uuid.Parse(id); err != nil {
+		return "", fmt.Errorf("invalid %s", key)
+	}
+
+	return id, nil
+}
+
+// intArg returns the int argument key or def when it's not set.
+// Literals are parsed as int64 and variables as float64.
+func intArg(args map[string]any, key string, def int) (int, error) {
+	switch v := args[key].(type) {
+	case nil:
+		return def, nil
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("%s must not be negative", key)
+		}
+
+		return int(v), nil
+	case float64:
+		if v < 0 || v != float64(int(v)) {
+			return 0, fmt.Errorf("%s must be a non negative integer", key)
+		}
+
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+}
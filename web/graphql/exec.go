@@ -0,0 +1,419 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Resolver resolves a top level field. The selection set of the field
+// is applied to the result, the fields of the objects are named after
+// their JSON keys.
+type Resolver func(ctx context.Context, args map[string]any) (any, error)
+
+// Schema holds the resolvers of the query and mutation fields
+type Schema struct {
+	Query    map[string]Resolver
+	Mutation map[string]Resolver
+}
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Error is an error of the request or of a field
+type Error struct {
+	Message string   `json:"message"`
+	Path    []string `json:"path,omitempty"`
+}
+
+// Response is the result of a request
+type Response struct {
+	Data   *Object `json:"data"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Object is a JSON object that keeps the order of the selected fields
+type Object struct {
+	keys   []string
+	values map[string]any
+}
+
+func newObject() *Object {
+	return &Object{values: make(map[string]any)}
+}
+
+func (o *Object) set(key string, v any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+
+	o.values[key] = v
+}
+
+// Get returns the value of key
+func (o *Object) Get(key string) any {
+	return o.values[key]
+}
+
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// Execute runs the operation of req. Fields are resolved in order, so
+// mutations run serially as the spec requires. A failing field is set to null
+// and its error is reported next to the data of the other fields.
+func (s *Schema) Execute(ctx context.Context, req *Request) Response {
+	op, err := Parse(req.Query, req.OperationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	resolvers, typename := s.Query, "Query"
+	if op.Type == "mutation" {
+		resolvers, typename = s.Mutation, "Mutation"
+	}
+
+	vars := make(map[string]any, len(op.Variables))
+
+	for k, v := range op.Variables {
+		if given, ok := req.Variables[k]; ok {
+			v = given
+		}
+
+		vars[k] = v
+	}
+
+	var resp Response
+
+	data := newObject()
+
+	for i := range op.Selections {
+		field := &op.Selections[i]
+		key := field.Key()
+
+		if field.Name == "__typename" {
+			data.set(key, typename)
+
+			continue
+		}
+
+		value, err := s.resolve(ctx, resolvers, field, vars)
+		if err != nil {
+			data.set(key, nil)
+
+			resp.Errors = append(resp.Errors, Error{Message: err.Error(), Path: []string{key}})
+
+			continue
+		}
+
+		data.set(key, value)
+	}
+
+	resp.Data = data
+
+	return resp
+}
+
+func (s *Schema) resolve(ctx context.Context, resolvers map[string]Resolver, field *Field, vars map[string]any) (any, error) {
+	resolver, ok := resolvers[field.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q", field.Name)
+	}
+
+	args := make(map[string]any, len(field.Arguments))
+
+	for k, v := range field.Arguments {
+		val, err := substitute(v, vars)
+		if err != nil {
+			return nil, err
+		}
+
+		args[k] = val
+	}
+
+	result, err := resolver(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return project(reflect.ValueOf(result), field.Selections)
+}
+
+// substitute replaces the variable references in v with their values
+func substitute(v any, vars map[string]any) (any, error) {
+	switch val := v.(type) {
+	case variable:
+		ans, ok := vars[string(val)]
+		if !ok {
+			return nil, fmt.Errorf("undefined variable $%s", string(val))
+		}
+
+		return ans, nil
+	case []any:
+		ans := make([]any, len(val))
+
+		for i := range val {
+			item, err := substitute(val[i], vars)
+			if err != nil {
+				return nil, err
+			}
+
+			ans[i] = item
+		}
+
+		return ans, nil
+	case map[string]any:
+		ans := make(map[string]any, len(val))
+
+		for k := range val {
+			item, err := substitute(val[k], vars)
+			if err != nil {
+				return nil, err
+			}
+
+			ans[k] = item
+		}
+
+		return ans, nil
+	default:
+		return v, nil
+	}
+}
+
+// project keeps the selected fields of v. The fields of a struct are
+// named after their JSON keys and looked up in its type, so that a field
+// left out of the JSON by omitempty is still selectable. The values that
+// marshal themselves and the maps are selected from their JSON.
+// Without a selection set the value is returned as is.
+func project(v reflect.Value, selections []Field) (any, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		if len(selections) > 0 && v.Type().Implements(marshalerType) {
+			return projectJSON(v.Interface(), selections)
+		}
+
+		v = v.Elem()
+	}
+
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if len(selections) == 0 {
+		return v.Interface(), nil
+	}
+
+	if v.Type().Implements(marshalerType) || reflect.PointerTo(v.Type()).Implements(marshalerType) {
+		return projectJSON(v.Interface(), selections)
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+
+		ans := make([]any, v.Len())
+
+		for i := range ans {
+			item, err := project(v.Index(i), selections)
+			if err != nil {
+				return nil, err
+			}
+
+			ans[i] = item
+		}
+
+		return ans, nil
+	case reflect.Map:
+		return projectJSON(v.Interface(), selections)
+	case reflect.Struct:
+		fields := jsonFields(v.Type())
+		ans := newObject()
+
+		for i := range selections {
+			field := &selections[i]
+
+			if field.Name == "__typename" {
+				continue
+			}
+
+			index, ok := fields[field.Name]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q", field.Name)
+			}
+
+			fv, err := v.FieldByIndexErr(index)
+			if err != nil {
+				// a field of a nil embedded struct
+				ans.set(field.Key(), nil)
+
+				continue
+			}
+
+			item, err := project(fv, field.Selections)
+			if err != nil {
+				return nil, err
+			}
+
+			ans.set(field.Key(), item)
+		}
+
+		return ans, nil
+	default:
+		return nil, fmt.Errorf("field of type %s has no fields to select", v.Type())
+	}
+}
+
+var marshalerType = reflect.TypeFor[json.Marshaler]()
+
+// projectJSON selects the fields of the JSON of v, the keys missing
+// from it are null
+func projectJSON(v any, selections []Field) (any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+
+	return projectGeneric(generic, selections)
+}
+
+func projectGeneric(v any, selections []Field) (any, error) {
+	if len(selections) == 0 || v == nil {
+		return v, nil
+	}
+
+	switch val := v.(type) {
+	case []any:
+		ans := make([]any, len(val))
+
+		for i := range val {
+			item, err := projectGeneric(val[i], selections)
+			if err != nil {
+				return nil, err
+			}
+
+			ans[i] = item
+		}
+
+		return ans, nil
+	case map[string]any:
+		ans := newObject()
+
+		for i := range selections {
+			field := &selections[i]
+
+			if field.Name == "__typename" {
+				continue
+			}
+
+			item, err := projectGeneric(val[field.Name], field.Selections)
+			if err != nil {
+				return nil, err
+			}
+
+			ans.set(field.Key(), item)
+		}
+
+		return ans, nil
+	default:
+		return nil, fmt.Errorf("field of type %T has no fields to select", v)
+	}
+}
+
+// jsonFields returns the index of the fields of the struct type t by their
+// JSON key, following the rules of encoding/json for the embedded structs
+func jsonFields(t reflect.Type) map[string][]int {
+	ans := make(map[string][]int)
+
+	var embedded [][]int
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct && f.IsExported() {
+			embedded = append(embedded, []int{i})
+
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		ans[name] = []int{i}
+	}
+
+	// the fields of the outer struct win over the promoted ones
+	for _, index := range embedded {
+		ft := t.Field(index[0]).Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		for name, inner := range jsonFields(ft) {
+			if _, ok := ans[name]; !ok {
+				ans[name] = append(append([]int{}, index...), inner...)
+			}
+		}
+	}
+
+	return ans
+}
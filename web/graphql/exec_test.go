@@ -0,0 +1,154 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testData struct {
+	Keywords []string          `json:"keywords"`
+	Location string            `json:"location,omitempty"`
+	Polygon  json.RawMessage   `json:"polygon,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Secret   string            `json:"-"`
+}
+
+type testProgress struct {
+	Percent int `json:"percent"`
+}
+
+type testJob struct {
+	ID        string        `json:"id"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Data      testData      `json:"data"`
+	Progress  *testProgress `json:"progress"`
+}
+
+func testSchema() *Schema {
+	job := testJob{
+		ID:        "1",
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data: testData{
+			Keywords: []string{"cafe"},
+			Polygon:  json.RawMessage(`{"type":"Polygon"}`),
+			Tags:     map[string]string{"team": "a"},
+		},
+	}
+
+	return &Schema{
+		Query: map[string]Resolver{
+			"job": func(context.Context, map[string]any) (any, error) {
+				return &job, nil
+			},
+			"jobs": func(_ context.Context, args map[string]any) (any, error) {
+				limit, _ := args["limit"].(float64)
+
+				ans := []testJob{job, job}
+
+				return ans[:int(limit)], nil
+			},
+			"fail": func(context.Context, map[string]any) (any, error) {
+				return nil, errors.New("boom")
+			},
+		},
+		Mutation: map[string]Resolver{
+			"deleteJob": func(context.Context, map[string]any) (any, error) {
+				return true, nil
+			},
+		},
+	}
+}
+
+func execute(t *testing.T, req *Request) string {
+	t.Helper()
+
+	resp := testSchema().Execute(context.Background(), req)
+
+	raw, err := json.Marshal(resp)
+	require.NoError(t, err)
+
+	return string(raw)
+}
+
+func Test_ExecuteSelectsFields(t *testing.T) {
+	got := execute(t, &Request{Query: `{
+		job { createdAt id data { keywords tags { team } polygon { type } } progress { percent } }
+		__typename
+	}`})
+
+	require.JSONEq(t, `{"data": {
+		"job": {
+			"createdAt": "2024-01-02T03:04:05Z",
+			"id": "1",
+			"data": {"keywords": ["cafe"], "tags": {"team": "a"}, "polygon": {"type": "Polygon"}},
+			"progress": null
+		},
+		"__typename": "Query"
+	}}`, got)
+}
+
+func Test_ExecuteOmitemptyFields(t *testing.T) {
+	got := execute(t, &Request{Query: `{ job { data { location tags { missing } } } }`})
+
+	require.JSONEq(t, `{"data": {"job": {"data": {"location": "", "tags": {"missing": null}}}}}`, got)
+}
+
+func Test_ExecuteVariablesAndAliases(t *testing.T) {
+	got := execute(t, &Request{
+		Query:     `query ($n: Int = 2) { first: jobs(limit: $n) { id } }`,
+		Variables: map[string]any{"n": float64(1)},
+	})
+
+	require.JSONEq(t, `{"data": {"first": [{"id": "1"}]}}`, got)
+}
+
+func Test_ExecuteMutation(t *testing.T) {
+	got := execute(t, &Request{Query: `mutation { deleteJob(id: "1") }`})
+
+	require.JSONEq(t, `{"data": {"deleteJob": true}}`, got)
+}
+
+func Test_ExecuteFieldErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"unknown top level field", `{ nope }`, `unknown field "nope"`},
+		{"unknown field", `{ job { id nope } }`, `unknown field "nope"`},
+		{"hidden field", `{ job { data { Secret } } }`, `unknown field "Secret"`},
+		{"resolver error", `{ fail }`, "boom"},
+		{"selection on a scalar", `{ job { id { x } } }`, "no fields to select"},
+		{"undefined variable", `{ jobs(limit: $n) { id } }`, "undefined variable $n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := testSchema().Execute(context.Background(), &Request{Query: tc.query})
+
+			require.Len(t, resp.Errors, 1)
+			require.Contains(t, resp.Errors[0].Message, tc.want)
+		})
+	}
+}
+
+func Test_ExecuteKeepsTheOtherFields(t *testing.T) {
+	got := execute(t, &Request{Query: `{ fail job { id } }`})
+
+	require.JSONEq(t, `{
+		"data": {"fail": null, "job": {"id": "1"}},
+		"errors": [{"message": "boom", "path": ["fail"]}]
+	}`, got)
+}
+
+func Test_ExecuteParseError(t *testing.T) {
+	resp := testSchema().Execute(context.Background(), &Request{Query: `{ job {`})
+
+	require.Nil(t, resp.Data)
+	require.Len(t, resp.Errors, 1)
+}
@@ -0,0 +1,515 @@
+// Package graphql implements the subset of GraphQL used by the web API:
+// queries and mutations with arguments, variables, aliases and nested
+// selections. Fragments, directives and introspection are not supported.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operation is a parsed query or mutation
+type Operation struct {
+	Type       string // query or mutation
+	Name       string
+	Variables  map[string]any // default values of the variables
+	Selections []Field
+}
+
+// Field is a selected field
+type Field struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]any
+	Selections []Field
+}
+
+// Key returns the key of the field in the response
+func (f *Field) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+
+	return f.Name
+}
+
+// MaxDepth is the maximum nesting of the selection sets and of the
+// argument values, the deeper documents are rejected
+const MaxDepth = 10
+
+// variable is a reference to a variable in an argument value
+type variable string
+
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind int
+	val  string
+	pos  int
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// Parse parses the document and returns the operation named operationName.
+// When operationName is empty the document must contain a single operation.
+func Parse(document, operationName string) (*Operation, error) {
+	tokens, err := lex(document)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{tokens: tokens}
+
+	var ops []*Operation
+
+	for p.peek().kind != tokEOF {
+		op, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+
+		ops = append(ops, op)
+	}
+
+	switch {
+	case len(ops) == 0:
+		return nil, fmt.Errorf("no operation found")
+	case operationName == "" && len(ops) > 1:
+		return nil, fmt.Errorf("operationName is required for documents with multiple operations")
+	case operationName == "":
+		return ops[0], nil
+	}
+
+	for _, op := range ops {
+		if op.Name == operationName {
+			return op, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown operation %q", operationName)
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+func (p *parser) skip(punct string) bool {
+	if t := p.peek(); t.kind == tokPunct && t.val == punct {
+		p.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.skip(punct) {
+		t := p.peek()
+
+		return fmt.Errorf("expected %q at %d, got %q", punct, t.pos, t.val)
+	}
+
+	return nil
+}
+
+func (p *parser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != tokName {
+		return "", fmt.Errorf("expected name at %d, got %q", t.pos, t.val)
+	}
+
+	return t.val, nil
+}
+
+func (p *parser) parseOperation() (*Operation, error) {
+	op := Operation{Type: "query"}
+
+	if t := p.peek(); t.kind == tokName {
+		switch t.val {
+		case "query", "mutation":
+			op.Type = p.next().val
+		case "fragment", "subscription":
+			return nil, fmt.Errorf("%s is not supported", t.val)
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", t.val, t.pos)
+		}
+
+		if p.peek().kind == tokName {
+			op.Name = p.next().val
+		}
+
+		if p.skip("(") {
+			vars, err := p.parseVariableDefinitions()
+			if err != nil {
+				return nil, err
+			}
+
+			op.Variables = vars
+		}
+	}
+
+	sels, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	op.Selections = sels
+
+	return &op, nil
+}
+
+// parseVariableDefinitions parses ($id: ID!, $limit: Int = 10).
+// The types are not checked, only the default values are kept.
+func (p *parser) parseVariableDefinitions() (map[string]any, error) {
+	vars := make(map[string]any)
+
+	for !p.skip(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		if err := p.parseType(); err != nil {
+			return nil, err
+		}
+
+		vars[name] = nil
+
+		if p.skip("=") {
+			v, err := p.parseValue(true)
+			if err != nil {
+				return nil, err
+			}
+
+			vars[name] = v
+		}
+	}
+
+	return vars, nil
+}
+
+func (p *parser) parseType() error {
+	if p.skip("[") {
+		if err := p.enter(); err != nil {
+			return err
+		}
+
+		defer p.leave()
+
+		if err := p.parseType(); err != nil {
+			return err
+		}
+
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+
+	p.skip("!")
+
+	return nil
+}
+
+// enter increments the nesting of the document, the caller must call
+// leave when done with the nested part
+func (p *parser) enter() error {
+	p.depth++
+
+	if p.depth > MaxDepth {
+		return fmt.Errorf("the document exceeds the maximum depth of %d at %d", MaxDepth, p.peek().pos)
+	}
+
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parseSelectionSet() ([]Field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+
+	defer p.leave()
+
+	var fields []Field
+
+	for !p.skip("}") {
+		if t := p.peek(); t.kind == tokPunct && (t.val == "..." || t.val == "@") {
+			return nil, fmt.Errorf("fragments and directives are not supported")
+		}
+
+		field, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty selection set")
+	}
+
+	return fields, nil
+}
+
+func (p *parser) parseField() (Field, error) {
+	var f Field
+
+	name, err := p.expectName()
+	if err != nil {
+		return f, err
+	}
+
+	f.Name = name
+
+	if p.skip(":") {
+		f.Alias = name
+
+		f.Name, err = p.expectName()
+		if err != nil {
+			return f, err
+		}
+	}
+
+	if p.skip("(") {
+		f.Arguments = make(map[string]any)
+
+		for !p.skip(")") {
+			argName, err := p.expectName()
+			if err != nil {
+				return f, err
+			}
+
+			if err := p.expect(":"); err != nil {
+				return f, err
+			}
+
+			v, err := p.parseValue(false)
+			if err != nil {
+				return f, err
+			}
+
+			f.Arguments[argName] = v
+		}
+	}
+
+	if t := p.peek(); t.kind == tokPunct && t.val == "{" {
+		f.Selections, err = p.parseSelectionSet()
+		if err != nil {
+			return f, err
+		}
+	}
+
+	return f, nil
+}
+
+func (p *parser) parseValue(constant bool) (any, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokInt:
+		return strconv.ParseInt(t.val, 10, 64)
+	case tokFloat:
+		return strconv.ParseFloat(t.val, 64)
+	case tokString:
+		return t.val, nil
+	case tokName:
+		switch t.val {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		default:
+			// enum values are passed as strings
+			return t.val, nil
+		}
+	case tokPunct:
+		switch t.val {
+		case "$":
+			if constant {
+				return nil, fmt.Errorf("unexpected variable at %d", t.pos)
+			}
+
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+
+			return variable(name), nil
+		case "[":
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+
+			defer p.leave()
+
+			list := []any{}
+
+			for !p.skip("]") {
+				v, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+
+				list = append(list, v)
+			}
+
+			return list, nil
+		case "{":
+			if err := p.enter(); err != nil {
+				return nil, err
+			}
+
+			defer p.leave()
+
+			obj := map[string]any{}
+
+			for !p.skip("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+
+				v, err := p.parseValue(constant)
+				if err != nil {
+					return nil, err
+				}
+
+				obj[name] = v
+			}
+
+			return obj, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q at %d", t.val, t.pos)
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+
+	i := 0
+
+	for i < len(s) {
+		c := s[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			// commas are insignificant in GraphQL
+			i++
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "..."):
+			tokens = append(tokens, token{kind: tokPunct, val: "...", pos: i})
+			i += 3
+		case strings.ContainsRune("{}()[]:!$=@", rune(c)):
+			tokens = append(tokens, token{kind: tokPunct, val: string(c), pos: i})
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(s) && (s[i] == '_' || isLetter(s[i]) || isDigit(s[i])) {
+				i++
+			}
+
+			tokens = append(tokens, token{kind: tokName, val: s[start:i], pos: start})
+		case c == '-' || isDigit(c):
+			start := i
+			kind := tokInt
+
+			i++
+
+			for i < len(s) && (isDigit(s[i]) || strings.ContainsRune(".eE+-", rune(s[i]))) {
+				if !isDigit(s[i]) {
+					kind = tokFloat
+				}
+
+				i++
+			}
+
+			tokens = append(tokens, token{kind: kind, val: s[start:i], pos: start})
+		case c == '"':
+			end := i + 1
+
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+
+				end++
+			}
+
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+
+			val, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d: %w", i, err)
+			}
+
+			tokens = append(tokens, token{kind: tokString, val: val, pos: i})
+			i = end + 1
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(s)})
+
+	return tokens, nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,100 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseQuery(t *testing.T) {
+	op, err := Parse(`
+		# the jobs of the dashboard
+		query Dashboard($limit: Int = 5, $status: String!) {
+			stats { pending, failed }
+			recent: jobs(limit: $limit, status: $status, tags: {team: "a", ids: [1, 2.5]}) {
+				id
+				data { keywords }
+			}
+		}`, "")
+	require.NoError(t, err)
+
+	require.Equal(t, "query", op.Type)
+	require.Equal(t, "Dashboard", op.Name)
+	require.Equal(t, map[string]any{"limit": int64(5), "status": nil}, op.Variables)
+
+	require.Len(t, op.Selections, 2)
+
+	stats := op.Selections[0]
+	require.Equal(t, "stats", stats.Key())
+	require.Equal(t, []Field{{Name: "pending"}, {Name: "failed"}}, stats.Selections)
+
+	jobs := op.Selections[1]
+	require.Equal(t, "jobs", jobs.Name)
+	require.Equal(t, "recent", jobs.Key())
+	require.Equal(t, map[string]any{
+		"limit":  variable("limit"),
+		"status": variable("status"),
+		"tags":   map[string]any{"team": "a", "ids": []any{int64(1), 2.5}},
+	}, jobs.Arguments)
+	require.Equal(t, []Field{
+		{Name: "id"},
+		{Name: "data", Selections: []Field{{Name: "keywords"}}},
+	}, jobs.Selections)
+}
+
+func Test_ParseOperationName(t *testing.T) {
+	doc := `query A { stats { total } } mutation B { deleteJob(id: "x") }`
+
+	_, err := Parse(doc, "")
+	require.Error(t, err)
+
+	op, err := Parse(doc, "B")
+	require.NoError(t, err)
+	require.Equal(t, "mutation", op.Type)
+	require.Equal(t, "deleteJob", op.Selections[0].Name)
+
+	_, err = Parse(doc, "C")
+	require.Error(t, err)
+}
+
+func Test_ParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"empty", ""},
+		{"empty selection set", "{ }"},
+		{"unterminated selection set", "{ stats { total }"},
+		{"unterminated string", `{ job(id: "x) { id } }`},
+		{"fragment", "{ jobs { ...F } }"},
+		{"directive", "{ jobs @skip(if: true) { id } }"},
+		{"subscription", "subscription { jobs { id } }"},
+		{"variable in default value", "query ($a: Int = $b) { jobs(limit: $a) { id } }"},
+		{"unexpected character", "{ jobs { id % } }"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(tc.doc, "")
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_ParseMaxDepth(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("{ a ", depth) + strings.Repeat("}", depth)
+	}
+
+	_, err := Parse(nested(MaxDepth), "")
+	require.NoError(t, err)
+
+	_, err = Parse(nested(MaxDepth+1), "")
+	require.ErrorContains(t, err, "maximum depth")
+
+	list := "{ jobs(ids: " + strings.Repeat("[", MaxDepth) + strings.Repeat("]", MaxDepth) + ") { id } }"
+
+	_, err = Parse(list, "")
+	require.ErrorContains(t, err, "maximum depth")
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/gosom/google-maps-scraper/metrics"
+)
+
+// ConfigReloader re-reads the configuration and applies the settings that
+// can change at runtime
+type ConfigReloader interface {
+	Reload(ctx context.Context) (changed, ignored []string, err error)
+}
+
+// WithAdminToken protects the admin endpoints and /metrics, the requests
+// must carry token as a bearer token
+func WithAdminToken(token string) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.adminToken = token
+	}
+}
+
+// WithReloader enables the reload endpoint, it requires WithAdminToken
+func WithReloader(r ConfigReloader) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.reloader = r
+	}
+}
+
+// ReloadResponse lists the applied settings and the warnings about the
+// settings left unchanged
+type ReloadResponse struct {
+	Changed   []string `json:"changed"`
+	Ignored   []string `json:"ignored"`
+	RequestID string   `json:"request_id"`
+}
+
+// authorizeAdmin checks the bearer token of r or writes the error response
+func (h *JobHandler) authorizeAdmin(w http.ResponseWriter, r *http.Request, requestID string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		h.respondWithError(w, http.StatusUnauthorized, "Bearer token is required", requestID)
+		return false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		h.respondWithError(w, http.StatusForbidden, "Invalid token", requestID)
+		return false
+	}
+
+	return true
+}
+
+// Reload re-reads the config file and applies the changed settings
+func (h *JobHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "Reload"),
+	)
+
+	if h.reloader == nil || h.adminToken == "" {
+		h.respondWithError(w, http.StatusNotFound, "Reload is disabled", requestID)
+		return
+	}
+
+	if !h.authorizeAdmin(w, r, requestID) {
+		return
+	}
+
+	changed, ignored, err := h.reloader.Reload(r.Context())
+	if err != nil {
+		logger.Warn("config reload rejected", zap.Error(err))
+		h.respondWithError(w, http.StatusBadRequest, err.Error(), requestID)
+		return
+	}
+
+	logger.Info("config reloaded", zap.Strings("changed", changed), zap.Strings("ignored", ignored))
+
+	if changed == nil {
+		changed = []string{}
+	}
+
+	if ignored == nil {
+		ignored = []string{}
+	}
+
+	h.respondWithJSON(w, http.StatusOK, ReloadResponse{
+		Changed:   changed,
+		Ignored:   ignored,
+		RequestID: requestID,
+	})
+}
+
+// Metrics serves the prometheus metrics, it requires the admin token when one is set
+func (h *JobHandler) Metrics(w http.ResponseWriter, r *http.Request) {
+	if h.adminToken != "" && !h.authorizeAdmin(w, r, uuid.New().String()) {
+		return
+	}
+
+	metrics.Handler().ServeHTTP(w, r)
+}
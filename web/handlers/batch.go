@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gosom/scrapemate"
+	"go.uber.org/zap"
+
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/quota"
+)
+
+// maxBatchSize is the maximum number of jobs of a batch request
+const maxBatchSize = 500
+
+// CreateJobsBatchRequest is the body of a batch request, the jobs can
+// also be sent as a bare array
+type CreateJobsBatchRequest struct {
+	Jobs []CreateJobRequest `json:"jobs"`
+}
+
+// BatchJobResult is the outcome of one job of a batch, in the order of the request
+type BatchJobResult struct {
+	Index  int    `json:"index"`
+	JobID  string `json:"job_id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Code identifies the error of the rejected jobs, like in the error responses
+	Code string `json:"code,omitempty"`
+}
+
+// CreateJobsBatchResponse lists the outcome of every job of a batch
+type CreateJobsBatchResponse struct {
+	Count     int              `json:"count"`
+	Created   int              `json:"created"`
+	Rejected  int              `json:"rejected"`
+	Jobs      []BatchJobResult `json:"jobs"`
+	RequestID string           `json:"request_id"`
+}
+
+// decodeBatch accepts either an array of jobs or a {"jobs": [...]} object,
+// the fields unknown to CreateJobRequest are rejected
+func decodeBatch(body []byte) ([]CreateJobRequest, error) {
+	body = bytes.TrimSpace(body)
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+
+	if len(body) > 0 && body[0] == '[' {
+		var jobs []CreateJobRequest
+		err := dec.Decode(&jobs)
+
+		return jobs, err
+	}
+
+	var req CreateJobsBatchRequest
+	err := dec.Decode(&req)
+
+	return req.Jobs, err
+}
+
+// CreateJobsBatch validates every job of the batch on its own and pushes
+// the valid ones together. The rejected jobs are reported in the response
+// and don't prevent the others from being created.
+func (h *JobHandler) CreateJobsBatch(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "CreateJobsBatch"),
+	)
+
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(h.limitBody(w, r)); err != nil {
+		logger.Error("failed to read request body", zap.Error(err))
+		h.respondWithBodyError(w, err, requestID)
+		return
+	}
+
+	reqs, err := decodeBatch(body.Bytes())
+	if err != nil {
+		logger.Error("failed to decode request body", zap.Error(err))
+		h.respondWithBodyError(w, err, requestID)
+		return
+	}
+
+	switch {
+	case len(reqs) == 0:
+		h.respondWithError(w, http.StatusBadRequest, "the batch has no jobs", requestID)
+		return
+	case len(reqs) > maxBatchSize:
+		h.respondWithError(w, http.StatusBadRequest, fmt.Sprintf("a batch holds at most %d jobs", maxBatchSize), requestID)
+		return
+	}
+
+	var tenant quota.Tenant
+
+	if h.quotas != nil {
+		var ok bool
+
+		if tenant, ok = h.tenant(w, r, requestID); !ok {
+			return
+		}
+	}
+
+	results := make([]BatchJobResult, len(reqs))
+	jobs := make([]scrapemate.IJob, 0, len(reqs))
+	created := make([]int, 0, len(reqs))
+	// the periods the created jobs are counted in
+	reserved := make([]time.Time, 0, len(reqs))
+
+	for i := range reqs {
+		results[i] = BatchJobResult{Index: i, Status: "rejected"}
+
+		job, jerr := h.newJob(r.Context(), &reqs[i], nil, tenant.Name, requestID, logger)
+		if jerr == nil && h.quotas != nil {
+			var periodStart time.Time
+
+			if periodStart, jerr = h.reserveJob(r.Context(), w, &tenant, logger); jerr == nil {
+				reserved = append(reserved, periodStart)
+			}
+		}
+
+		if jerr != nil {
+			results[i].Error = jerr.message
+			results[i].Code = errorCode(jerr.code)
+
+			continue
+		}
+
+		jobs = append(jobs, job)
+		created = append(created, i)
+		results[i].JobID = job.ID
+	}
+
+	if len(jobs) > 0 {
+		if err := h.provider.PushBatch(r.Context(), jobs); err != nil {
+			logger.Error("failed to push jobs", zap.Error(err), zap.Int("jobs", len(jobs)))
+
+			for _, periodStart := range reserved {
+				h.releaseJob(r.Context(), tenant.Name, periodStart, logger)
+			}
+
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to create jobs", requestID)
+			return
+		}
+	}
+
+	for _, i := range created {
+		results[i].Status = "created"
+	}
+
+	metrics.JobsCreated.Add(float64(len(created)))
+
+	logger.Info("jobs batch created",
+		zap.Int("count", len(reqs)),
+		zap.Int("created", len(created)),
+	)
+
+	h.respondWithJSON(w, http.StatusOK, CreateJobsBatchResponse{
+		Count:     len(reqs),
+		Created:   len(created),
+		Rejected:  len(reqs) - len(created),
+		Jobs:      results,
+		RequestID: requestID,
+	})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gosom/google-maps-scraper/apilimits"
+)
+
+// DefaultMaxBodySize is the default maximum size in bytes of the request bodies
+const DefaultMaxBodySize = apilimits.MaxBodySize
+
+// WithMaxBodySize sets the maximum size in bytes of the request bodies,
+// the larger ones are rejected with 413
+func WithMaxBodySize(n int64) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.maxBodySize = n
+	}
+}
+
+// decodeBody decodes the JSON body of r into v. The body is capped at the
+// maximum body size and the fields unknown to v are rejected.
+func (h *JobHandler) decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(h.limitBody(w, r))
+	dec.DisallowUnknownFields()
+
+	return dec.Decode(v)
+}
+
+// limitBody returns the body of r capped at the maximum body size
+func (h *JobHandler) limitBody(w http.ResponseWriter, r *http.Request) io.Reader {
+	limit := h.maxBodySize
+	if limit <= 0 {
+		limit = DefaultMaxBodySize
+	}
+
+	return http.MaxBytesReader(w, r.Body, limit)
+}
+
+// respondWithBodyError answers a body that can't be read or decoded with 413
+// when it's too large and 400 otherwise, naming the unknown field if any
+func (h *JobHandler) respondWithBodyError(w http.ResponseWriter, err error, requestID string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.respondWithError(w, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit), requestID)
+
+		return
+	}
+
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid request body: unknown field "+field, requestID)
+
+		return
+	}
+
+	h.respondWithError(w, http.StatusBadRequest, "Invalid request body", requestID)
+}
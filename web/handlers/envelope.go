@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Envelope wraps the results of the GET endpoints together with their metadata
+type Envelope struct {
+	Data any  `json:"data"`
+	Meta Meta `json:"meta"`
+}
+
+// TotalCountHeader is the number of items matching a paginated GET endpoint,
+// it's set with the envelope too so the bare responses can be paginated
+const TotalCountHeader = "X-Total-Count"
+
+// Meta describes the results of a GET endpoint
+type Meta struct {
+	RequestID string `json:"request_id"`
+	Count     int    `json:"count"`
+	Limit     int    `json:"limit,omitempty"`
+	Offset    int    `json:"offset,omitempty"`
+	// Total is set by the paginated endpoints
+	Total *int `json:"total,omitempty"`
+}
+
+// wantsEnvelope reports if the results must be wrapped.
+// The envelope is the default, clients opt out with ?envelope=false.
+func wantsEnvelope(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("envelope")
+	if v == "" {
+		return true, nil
+	}
+
+	return strconv.ParseBool(v)
+}
+
+// respondWithList writes items either bare or wrapped in an Envelope
+func (h *JobHandler) respondWithList(w http.ResponseWriter, r *http.Request, items any, meta Meta) {
+	envelope, err := wantsEnvelope(r)
+	if err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "envelope must be true or false", meta.RequestID)
+		return
+	}
+
+	if !envelope {
+		h.respondWithJSON(w, http.StatusOK, items)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, Envelope{
+		Data: items,
+		Meta: meta,
+	})
+}
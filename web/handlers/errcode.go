@@ -0,0 +1,50 @@
+package handlers
+
+import "net/http"
+
+// The codes of the error responses, they don't change with the wording of
+// the messages so the clients can branch on them
+const (
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeConflict           = "conflict"
+	CodePayloadTooLarge    = "payload_too_large"
+	CodeUnprocessable      = "unprocessable"
+	CodeQuotaExceeded      = "quota_exceeded"
+	CodeRateLimited        = "rate_limited"
+	CodeInternalError      = "internal_error"
+	CodeServiceUnavailable = "service_unavailable"
+)
+
+// errorCodes are the codes of the http statuses of the handlers
+var errorCodes = map[int]string{
+	http.StatusBadRequest:            CodeValidationFailed,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusConflict:              CodeConflict,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnprocessableEntity:   CodeUnprocessable,
+	// the handlers answer 429 only for the tenant quotas, the rate limit
+	// of the server sets CodeRateLimited
+	http.StatusTooManyRequests:     CodeQuotaExceeded,
+	http.StatusInternalServerError: CodeInternalError,
+	http.StatusServiceUnavailable:  CodeServiceUnavailable,
+}
+
+// errorCode returns the code of the error response with the http status
+func errorCode(status int) string {
+	if code, ok := errorCodes[status]; ok {
+		return code
+	}
+
+	if status >= http.StatusInternalServerError {
+		return CodeInternalError
+	}
+
+	return CodeValidationFailed
+}
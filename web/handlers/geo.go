@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// metersPerPixel is the size of a pixel at the equator at zoom 0
+	metersPerPixel = 156543.03392
+	// viewportHalfWidth is half the width in pixels of the browser viewport,
+	// the radius is fitted in it
+	viewportHalfWidth = 960
+	maxZoom           = 21
+)
+
+// parseGeoCoords parses "lat,lng" or "lat,lng,radius" where the radius is in
+// meters with an optional "m" suffix. It returns the coordinates normalized
+// as "lat,lng" and the radius, 0 when it's not given.
+func parseGeoCoords(s string) (coords string, lat, radius float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 && len(parts) != 3 {
+		return "", 0, 0, fmt.Errorf("geo_coordinates must be \"lat,lng\" or \"lat,lng,radius\"")
+	}
+
+	lat, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return "", 0, 0, fmt.Errorf("geo_coordinates latitude must be a number between -90 and 90")
+	}
+
+	lng, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || math.IsNaN(lng) || lng < -180 || lng > 180 {
+		return "", 0, 0, fmt.Errorf("geo_coordinates longitude must be a number between -180 and 180")
+	}
+
+	if len(parts) == 3 {
+		radius, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(parts[2]), "m"), 64)
+		if err != nil || math.IsNaN(radius) || math.IsInf(radius, 0) || radius <= 0 {
+			return "", 0, 0, fmt.Errorf("geo_coordinates radius must be a positive number of meters")
+		}
+	}
+
+	coords = strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lng, 'f', -1, 64)
+
+	return coords, lat, radius, nil
+}
+
+// zoomForRadius returns the zoom level showing about radius meters
+// around the center at latitude lat
+func zoomForRadius(lat, radius float64) int {
+	z := math.Log2(viewportHalfWidth * metersPerPixel * math.Cos(lat*math.Pi/180) / radius)
+
+	return min(max(int(math.Round(z)), 1), maxZoom)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// readinessTimeout bounds the ping of the database, a hung database must
+// not block the probe
+const readinessTimeout = 2 * time.Second
+
+// Pinger checks the connection to a backing store, *sql.DB implements it
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// WithPinger makes the readiness probe check the connection of p
+func WithPinger(p Pinger) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.pinger = p
+	}
+}
+
+// HealthResponse is the body of the liveness and readiness probes
+type HealthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Health is the liveness probe, it answers as long as the server runs
+func (h *JobHandler) Health(w http.ResponseWriter, _ *http.Request) {
+	h.respondWithJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// Readiness is the readiness probe, it fails with 503 when the database is unreachable
+func (h *JobHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if h.pinger != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		if err := h.pinger.PingContext(ctx); err != nil {
+			h.logger.Warn("readiness check failed", zap.Error(err))
+			h.respondWithJSON(w, http.StatusServiceUnavailable, HealthResponse{
+				Status: "unavailable",
+				Error:  "database is unreachable",
+			})
+
+			return
+		}
+	}
+
+	h.respondWithJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
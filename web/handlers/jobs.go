@@ -1,13 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/geocode"
 	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/quota"
 	"go.uber.org/zap"
 )
 
@@ -15,23 +25,123 @@ import (
 type JobHandler struct {
 	provider gmaps.Provider
 	logger   *zap.Logger
+	policy   *QueryPolicy
+	jobOpts  []gmaps.GmapJobOptions
+	dlq      gmaps.DeadLetterQueue
+	results  gmaps.ResultReader
+	places   PlaceRefresher
+	geocoder geocode.Geocoder
+	// restricted are the country codes of the searches that are refused
+	restricted []string
+	quotas     *quota.Config
+	usage      quota.Store
+
+	reloader   ConfigReloader
+	adminToken string
+	pinger     Pinger
+	webhooks   bool
+
+	maxBodySize int64
+}
+
+// PlaceRefresher scrapes a single place on demand
+type PlaceRefresher interface {
+	Refresh(ctx context.Context, placeID string) (*gmaps.Entry, error)
 }
 
+// JobHandlerOption configures optional JobHandler behavior
+type JobHandlerOption func(*JobHandler)
+
 // NewJobHandler creates a new JobHandler instance
-func NewJobHandler(provider gmaps.Provider, logger *zap.Logger) *JobHandler {
-	return &JobHandler{
+func NewJobHandler(provider gmaps.Provider, logger *zap.Logger, opts ...JobHandlerOption) *JobHandler {
+	h := &JobHandler{
 		provider: provider,
 		logger:   logger,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// WithJobOptions adds opts to every job created by the handler
+func WithJobOptions(opts ...gmaps.GmapJobOptions) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.jobOpts = append(h.jobOpts, opts...)
+	}
+}
+
+// WithQueryPolicy restricts the accepted queries to the ones allowed by policy
+func WithQueryPolicy(policy *QueryPolicy) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.policy = policy
+	}
+}
+
+// WithDeadLetterQueue enables the endpoints that inspect and requeue failed jobs
+func WithDeadLetterQueue(dlq gmaps.DeadLetterQueue) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.dlq = dlq
+	}
+}
+
+// WithJobWebhooks accepts the webhook_url of the created jobs, the
+// provider of the workers must notify the completion of the jobs
+func WithJobWebhooks() JobHandlerOption {
+	return func(h *JobHandler) {
+		h.webhooks = true
+	}
+}
+
+// WithPlaceRefresher enables the endpoint that refreshes single places
+func WithPlaceRefresher(r PlaceRefresher) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.places = r
+	}
+}
+
+// WithGeocoder resolves the location of the created jobs into coordinates
+func WithGeocoder(g geocode.Geocoder) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.geocoder = g
+	}
+}
+
+// WithRestrictedRegions refuses the jobs searching in one of the regions,
+// they are resolved with the geocoder
+func WithRestrictedRegions(regions []string) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.restricted = regions
+	}
 }
 
 type CreateJobRequest struct {
-	Query        string `json:"query"`
-	Language     string `json:"language"`
-	MaxDepth     int    `json:"max_depth"`
-	ExtractEmail bool   `json:"extract_email"`
-	GeoCoords    string `json:"geo_coordinates"`
-	Zoom         int    `json:"zoom"`
+	Query        string            `json:"query"`
+	Language     string            `json:"language"`
+	MaxDepth     int               `json:"max_depth"`
+	ExtractEmail bool              `json:"extract_email"`
+	GeoCoords    string            `json:"geo_coordinates"`
+	Zoom         int               `json:"zoom"`
+	CustomFields map[string]string `json:"custom_fields"`
+	// Location is geocoded into the coordinates when geo_coordinates is empty
+	Location string `json:"location"`
+	// ScrollBudgetSeconds limits the time spent scrolling the results,
+	// together with MaxDepth which limits the scroll operations
+	ScrollBudgetSeconds int `json:"scroll_budget_seconds"`
+	// AutoDepth ignores MaxDepth and stops scrolling when the scrolls stop yielding new places
+	AutoDepth bool `json:"auto_depth"`
+	// MaxResults caps the places scraped, whatever MaxDepth, 0 means no cap
+	MaxResults int `json:"max_results"`
+	// IncludeKeywords keeps only the places mentioning one of them
+	IncludeKeywords []string `json:"include_keywords"`
+	// ExcludeKeywords drops the places mentioning any of them
+	ExcludeKeywords []string `json:"exclude_keywords"`
+	// WebhookURL receives a POST when the job completes or fails
+	WebhookURL string `json:"webhook_url"`
+	// Priority orders the queued jobs from 0 to 9, the higher first, 5 when it's not given
+	Priority *int `json:"priority"`
 }
 
 type CreateJobResponse struct {
@@ -39,9 +149,16 @@ type CreateJobResponse struct {
 	Status    string `json:"status"`
 	Message   string `json:"message,omitempty"`
 	RequestID string `json:"request_id"`
+	// Code identifies the error of the failed requests (e.g. "not_found"),
+	// Message is meant for humans and may change
+	Code string `json:"code,omitempty"`
+	// ClonedFrom is the id of the source job of the cloned jobs
+	ClonedFrom string `json:"cloned_from,omitempty"`
 }
 
-func (r *CreateJobRequest) validate() error {
+// Validate checks the fields of the request, the language and the
+// coordinates are normalized
+func (r *CreateJobRequest) Validate() error {
 	var errors []string
 
 	if strings.TrimSpace(r.Query) == "" {
@@ -50,6 +167,10 @@ func (r *CreateJobRequest) validate() error {
 
 	if strings.TrimSpace(r.Language) == "" {
 		errors = append(errors, "language is required")
+	} else if lang, ok := normalizeLanguage(r.Language); ok {
+		r.Language = lang
+	} else {
+		errors = append(errors, fmt.Sprintf("language %q is not supported, expected one of %s", r.Language, strings.Join(languages, ", ")))
 	}
 
 	if r.MaxDepth < 0 || r.MaxDepth > 10 {
@@ -60,6 +181,41 @@ func (r *CreateJobRequest) validate() error {
 		errors = append(errors, "zoom must be between 0 and 21")
 	}
 
+	// geo_coordinates is "lat,lng" or "lat,lng,radius", empty means no location bias
+	if r.GeoCoords != "" {
+		coords, lat, radius, err := parseGeoCoords(r.GeoCoords)
+		if err != nil {
+			errors = append(errors, err.Error())
+		} else {
+			r.GeoCoords = coords
+
+			// the radius is only used to pick the zoom
+			if radius > 0 && r.Zoom == 0 {
+				r.Zoom = zoomForRadius(lat, radius)
+			}
+		}
+	}
+
+	if r.ScrollBudgetSeconds < 0 || r.ScrollBudgetSeconds > 3600 {
+		errors = append(errors, "scroll_budget_seconds must be between 0 and 3600")
+	}
+
+	if r.MaxResults < 0 {
+		errors = append(errors, "max_results must be greater than or equal to 0")
+	}
+
+	if err := gmaps.ValidateCustomFields(r.CustomFields); err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	if r.WebhookURL != "" && !validWebhookURL(r.WebhookURL) {
+		errors = append(errors, "webhook_url must be an absolute http or https url")
+	}
+
+	if r.Priority != nil && (*r.Priority < 0 || *r.Priority > gmaps.MaxQueuePriority) {
+		errors = append(errors, fmt.Sprintf("priority must be between 0 and %d", gmaps.MaxQueuePriority))
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("validation failed: %s", strings.Join(errors, ", "))
 	}
@@ -67,6 +223,15 @@ func (r *CreateJobRequest) validate() error {
 	return nil
 }
 
+func validWebhookURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 // CreateJob handles the creation of new scraping jobs
 func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 	requestID := uuid.New().String()
@@ -83,59 +248,923 @@ func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req CreateJobRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := h.decodeBody(w, r, &req); err != nil {
 		logger.Error("failed to decode request body", zap.Error(err))
-		h.respondWithError(w, http.StatusBadRequest, "Invalid request body", requestID)
+		h.respondWithBodyError(w, err, requestID)
 		return
 	}
 
+	h.createJob(w, r, &req, nil, requestID, logger)
+}
+
+// CloneJob creates a new job with the parameters of an existing search job.
+// The request body is an optional CreateJobRequest whose fields override the copied ones.
+func (h *JobHandler) CloneJob(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "CloneJob"),
+	)
+
+	srcID := r.PathValue("id")
+	if _, err := uuid.Parse(srcID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid job id", requestID)
+		return
+	}
+
+	job, err := h.provider.Get(r.Context(), srcID)
+
+	switch {
+	case errors.Is(err, gmaps.ErrJobNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+		return
+	case err != nil:
+		logger.Error("failed to get job", zap.Error(err), zap.String("job_id", srcID))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to clone job", requestID)
+		return
+	}
+
+	src, ok := job.(*gmaps.GmapJob)
+	if !ok {
+		h.respondWithError(w, http.StatusBadRequest, "Only search jobs can be cloned", requestID)
+		return
+	}
+
+	if src.Query == "" {
+		// the jobs created by older versions don't keep their search parameters
+		h.respondWithError(w, http.StatusUnprocessableEntity, "Job has no stored parameters and cannot be cloned", requestID)
+		return
+	}
+
+	priority := src.QueuePriority
+
+	req := CreateJobRequest{
+		Query:               src.Query,
+		Language:            src.LangCode,
+		MaxDepth:            src.MaxDepth,
+		ExtractEmail:        src.ExtractEmail,
+		GeoCoords:           src.GeoCoordinates,
+		Zoom:                src.Zoom,
+		CustomFields:        src.CustomFields,
+		ScrollBudgetSeconds: int(src.ScrollBudget / time.Second),
+		AutoDepth:           src.AutoDepth,
+		MaxResults:          src.MaxResults,
+		IncludeKeywords:     src.IncludeKeywords,
+		ExcludeKeywords:     src.ExcludeKeywords,
+		WebhookURL:          src.WebhookURL,
+		Priority:            &priority,
+	}
+
+	// the overrides are decoded over the copied parameters, so only the given fields change
+	if err := h.decodeBody(w, r, &req); err != nil && !errors.Is(err, io.EOF) {
+		logger.Error("failed to decode request body", zap.Error(err))
+		h.respondWithBodyError(w, err, requestID)
+		return
+	}
+
+	// a new location replaces the copied coordinates unless they were overridden too
+	if req.Location != "" && req.GeoCoords == src.GeoCoordinates {
+		req.GeoCoords = ""
+
+		if req.Zoom == src.Zoom {
+			req.Zoom = 0
+		}
+	}
+
+	h.createJob(w, r, &req, src, requestID, logger)
+}
+
+// createJob validates req and pushes the job, src is the job it's cloned from if any
+func (h *JobHandler) createJob(w http.ResponseWriter, r *http.Request, req *CreateJobRequest, src *gmaps.GmapJob, requestID string, logger *zap.Logger) {
+	var tenant quota.Tenant
+
+	if h.quotas != nil {
+		var ok bool
+
+		if tenant, ok = h.tenant(w, r, requestID); !ok {
+			return
+		}
+
+		// the tenants can only see their own jobs
+		if src != nil && src.Tenant != tenant.Name {
+			h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+			return
+		}
+	}
+
+	job, jerr := h.newJob(r.Context(), req, src, tenant.Name, requestID, logger)
+	if jerr != nil {
+		h.respondWithError(w, jerr.code, jerr.message, requestID)
+		return
+	}
+
+	var periodStart time.Time
+
+	if h.quotas != nil {
+		var jerr *jobError
+
+		if periodStart, jerr = h.reserveJob(r.Context(), w, &tenant, logger); jerr != nil {
+			h.respondWithError(w, jerr.code, jerr.message, requestID)
+			return
+		}
+	}
+
+	// Push job to provider
+	if err := h.provider.Push(r.Context(), job); err != nil {
+		logger.Error("failed to push job",
+			zap.Error(err),
+			zap.String("job_id", job.ID),
+		)
+
+		if h.quotas != nil {
+			h.releaseJob(r.Context(), tenant.Name, periodStart, logger)
+		}
+
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to create job", requestID)
+		return
+	}
+
+	var clonedFrom string
+	if src != nil {
+		clonedFrom = src.ID
+	}
+
+	metrics.JobsCreated.Inc()
+
+	logger.Info("job created successfully",
+		zap.String("job_id", job.ID),
+		zap.String("query", req.Query),
+		zap.String("cloned_from", clonedFrom),
+	)
+
+	// Respond with success
+	h.respondWithJSON(w, http.StatusCreated, CreateJobResponse{
+		JobID:      job.ID,
+		Status:     "created",
+		Message:    "Job created successfully",
+		RequestID:  requestID,
+		ClonedFrom: clonedFrom,
+	})
+}
+
+// jobError is the rejection of a job with its http status
+type jobError struct {
+	code    int
+	message string
+}
+
+// newJob validates req and returns the job to push, src is the job it's cloned from if any
+func (h *JobHandler) newJob(ctx context.Context, req *CreateJobRequest, src *gmaps.GmapJob, tenant, requestID string, logger *zap.Logger) (*gmaps.GmapJob, *jobError) {
 	// Validate request
-	if err := req.validate(); err != nil {
+	if err := req.Validate(); err != nil {
 		logger.Error("request validation failed", zap.Error(err))
-		h.respondWithError(w, http.StatusBadRequest, err.Error(), requestID)
-		return
+		return nil, &jobError{http.StatusBadRequest, err.Error()}
+	}
+
+	if !h.policy.Allowed(req.Query) {
+		logger.Warn("query rejected by policy", zap.String("query", req.Query))
+		return nil, &jobError{http.StatusForbidden, "query is not permitted"}
+	}
+
+	geoCoords, zoom := req.GeoCoords, req.Zoom
+
+	if req.Location != "" && geoCoords == "" {
+		if h.geocoder == nil {
+			return nil, &jobError{http.StatusBadRequest, "location is not supported"}
+		}
+
+		res, err := h.geocoder.Geocode(ctx, req.Location)
+		if err != nil {
+			logger.Warn("failed to geocode location", zap.String("location", req.Location), zap.Error(err))
+			return nil, &jobError{http.StatusUnprocessableEntity, "failed to geocode location"}
+		}
+
+		geoCoords = res.Coordinates()
+
+		if zoom == 0 {
+			zoom = res.Zoom
+		}
+	}
+
+	if err := gmaps.CheckRegion(ctx, h.geocoder, h.restricted, req.Query, geoCoords); err != nil {
+		if errors.Is(err, gmaps.ErrRestrictedRegion) {
+			logger.Warn("query rejected by region policy", zap.String("query", req.Query), zap.Error(err))
+			return nil, &jobError{http.StatusForbidden, err.Error()}
+		}
+
+		logger.Warn("failed to resolve the region of the query", zap.String("query", req.Query), zap.Error(err))
 	}
 
 	// Create job
 	jobID := uuid.New().String()
+
+	opts := append([]gmaps.GmapJobOptions{
+		gmaps.WithRequestID(requestID),
+	}, h.jobOpts...)
+
+	if tenant != "" {
+		opts = append(opts, gmaps.WithTenant(tenant))
+	}
+
+	if src != nil {
+		opts = append(opts, gmaps.WithClonedFrom(src.ID))
+	}
+
+	if len(req.CustomFields) > 0 {
+		opts = append(opts, gmaps.WithCustomFields(req.CustomFields))
+	}
+
+	if req.ScrollBudgetSeconds > 0 {
+		opts = append(opts, gmaps.WithScrollBudget(time.Duration(req.ScrollBudgetSeconds)*time.Second))
+	}
+
+	if req.AutoDepth {
+		opts = append(opts, gmaps.WithAutoDepth())
+	}
+
+	if req.MaxResults > 0 {
+		opts = append(opts, gmaps.WithMaxResults(req.MaxResults))
+	}
+
+	if len(req.IncludeKeywords) > 0 {
+		opts = append(opts, gmaps.WithIncludeKeywords(req.IncludeKeywords))
+	}
+
+	if len(req.ExcludeKeywords) > 0 {
+		opts = append(opts, gmaps.WithExcludeKeywords(req.ExcludeKeywords))
+	}
+
+	if req.WebhookURL != "" {
+		if !h.webhooks {
+			return nil, &jobError{http.StatusBadRequest, "webhook_url is not supported"}
+		}
+
+		opts = append(opts, gmaps.WithWebhookURL(req.WebhookURL))
+	}
+
+	if req.Priority != nil {
+		opts = append(opts, gmaps.WithQueuePriority(*req.Priority))
+	}
+
 	job := gmaps.NewGmapJob(
 		jobID,
 		req.Language,
 		req.Query,
 		req.MaxDepth,
 		req.ExtractEmail,
-		req.GeoCoords,
-		req.Zoom,
+		geoCoords,
+		zoom,
+		opts...,
 	)
 
-	// Push job to provider
-	if err := h.provider.Push(r.Context(), job); err != nil {
-		logger.Error("failed to push job",
-			zap.Error(err),
-			zap.String("job_id", jobID),
-		)
-		h.respondWithError(w, http.StatusInternalServerError, "Failed to create job", requestID)
+	return job, nil
+}
+
+// reserveJob counts a job towards the quota of tenant and sets the quota headers.
+// It returns the start of the period the job is counted in.
+func (h *JobHandler) reserveJob(ctx context.Context, w http.ResponseWriter, tenant *quota.Tenant, logger *zap.Logger) (time.Time, *jobError) {
+	periodStart := h.quotas.PeriodStart(time.Now())
+
+	usage, ok, err := h.usage.ReserveJob(ctx, tenant, periodStart)
+	if err != nil {
+		logger.Error("failed to reserve job", zap.Error(err), zap.String("tenant", tenant.Name))
+		return periodStart, &jobError{http.StatusInternalServerError, "Failed to create job"}
+	}
+
+	h.setQuotaHeaders(w, tenant, usage, periodStart)
+
+	if !ok {
+		logger.Warn("quota exceeded", zap.String("tenant", tenant.Name))
+		return periodStart, &jobError{http.StatusTooManyRequests, "quota exceeded"}
+	}
+
+	return periodStart, nil
+}
+
+// releaseJob gives back the job reserved in the period starting at
+// periodStart when it could not be created
+func (h *JobHandler) releaseJob(ctx context.Context, tenant string, periodStart time.Time, logger *zap.Logger) {
+	// the request may be cancelled, the refund must not be lost with it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	if err := h.usage.ReleaseJob(ctx, tenant, periodStart); err != nil {
+		logger.Error("failed to release job", zap.Error(err), zap.String("tenant", tenant))
+	}
+}
+
+// JobResponse is the status of a job
+type JobResponse struct {
+	JobID       string    `json:"job_id"`
+	Type        string    `json:"type"`
+	Query       string    `json:"query,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	ResultCount int       `json:"result_count"`
+	RequestID   string    `json:"request_id,omitempty"`
+	// State is the authoritative step of the lifecycle of the job, status is
+	// the state of the job in the queue. State is reported by the postgres and
+	// the redis providers, StartedAt and FinishedAt only by postgres.
+	State      string     `json:"state,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Partial is true while the job is pending or running, result_count is
+	// then the number of places saved so far
+	Partial bool `json:"partial,omitempty"`
+}
+
+// jobRunning reports whether the job in state can still save places
+func jobRunning(state string) bool {
+	return state == "pending" || state == "running"
+}
+
+func newJobResponse(info *gmaps.JobInfo, requestID string) JobResponse {
+	return JobResponse{
+		JobID:       info.ID,
+		Type:        info.Type,
+		Query:       info.Query,
+		Language:    info.Language,
+		Status:      info.Status,
+		CreatedAt:   info.CreatedAt,
+		UpdatedAt:   info.UpdatedAt,
+		ResultCount: info.ResultCount,
+		RequestID:   requestID,
+		State:       info.State,
+		StartedAt:   info.StartedAt,
+		FinishedAt:  info.FinishedAt,
+		Partial:     jobRunning(info.State),
+	}
+}
+
+// GetJob returns the status and the result count of a job
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "GetJob"),
+	)
+
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid job id", requestID)
 		return
 	}
 
-	logger.Info("job created successfully",
+	var tenant quota.Tenant
+
+	if h.quotas != nil {
+		var ok bool
+
+		if tenant, ok = h.tenant(w, r, requestID); !ok {
+			return
+		}
+	}
+
+	info, err := h.provider.Info(r.Context(), jobID)
+
+	switch {
+	case errors.Is(err, gmaps.ErrJobNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+		return
+	case err != nil:
+		logger.Error("failed to get job", zap.Error(err), zap.String("job_id", jobID))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get job", requestID)
+		return
+	}
+
+	// the tenants can only see their own jobs
+	if h.quotas != nil && info.Tenant != tenant.Name {
+		h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+		return
+	}
+
+	h.respondWithJSON(w, http.StatusOK, newJobResponse(&info, requestID))
+}
+
+// DeleteJob removes a job before a worker picks it up
+func (h *JobHandler) DeleteJob(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "DeleteJob"),
+	)
+
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid job id", requestID)
+		return
+	}
+
+	if h.quotas != nil {
+		tenant, ok := h.tenant(w, r, requestID)
+		if !ok {
+			return
+		}
+
+		info, err := h.provider.Info(r.Context(), jobID)
+
+		switch {
+		case errors.Is(err, gmaps.ErrJobNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+			return
+		case err != nil:
+			logger.Error("failed to get job", zap.Error(err), zap.String("job_id", jobID))
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to delete job", requestID)
+			return
+		}
+
+		// the tenants can only see their own jobs
+		if info.Tenant != tenant.Name {
+			h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+			return
+		}
+	}
+
+	err := h.provider.Delete(r.Context(), jobID)
+
+	switch {
+	case errors.Is(err, gmaps.ErrJobNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+		return
+	case errors.Is(err, gmaps.ErrJobStarted):
+		h.respondWithError(w, http.StatusConflict, "Job has already started", requestID)
+		return
+	case err != nil:
+		logger.Error("failed to delete job", zap.Error(err), zap.String("job_id", jobID))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to delete job", requestID)
+		return
+	}
+
+	logger.Info("job deleted successfully", zap.String("job_id", jobID))
+
+	h.respondWithJSON(w, http.StatusOK, CreateJobResponse{
+		JobID:     jobID,
+		Status:    "deleted",
+		Message:   "Job deleted successfully",
+		RequestID: requestID,
+	})
+}
+
+// CancelJob stops a new or running job. It answers right away, the workers
+// stop scraping the job and its places when they pick up the cancellation.
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "CancelJob"),
+	)
+
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid job id", requestID)
+		return
+	}
+
+	if h.quotas != nil {
+		tenant, ok := h.tenant(w, r, requestID)
+		if !ok {
+			return
+		}
+
+		info, err := h.provider.Info(r.Context(), jobID)
+
+		switch {
+		case errors.Is(err, gmaps.ErrJobNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+			return
+		case err != nil:
+			logger.Error("failed to get job", zap.Error(err), zap.String("job_id", jobID))
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to cancel job", requestID)
+			return
+		}
+
+		// the tenants can only see their own jobs
+		if info.Tenant != tenant.Name {
+			h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+			return
+		}
+	}
+
+	err := h.provider.Cancel(r.Context(), jobID)
+
+	switch {
+	case errors.Is(err, gmaps.ErrJobNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+		return
+	case errors.Is(err, gmaps.ErrJobCompleted):
+		h.respondWithError(w, http.StatusConflict, "Job is already completed", requestID)
+		return
+	case err != nil:
+		logger.Error("failed to cancel job", zap.Error(err), zap.String("job_id", jobID))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to cancel job", requestID)
+		return
+	}
+
+	logger.Info("job cancelled", zap.String("job_id", jobID))
+
+	h.respondWithJSON(w, http.StatusAccepted, CreateJobResponse{
+		JobID:     jobID,
+		Status:    "cancelled",
+		Message:   "Job is being cancelled",
+		RequestID: requestID,
+	})
+}
+
+// jobStatuses are the values of the status filter of ListJobs
+var jobStatuses = []string{"new", "queued", "cancelled", "dead_letter"}
+
+// jobStates are the values of the state filter of ListJobs
+var jobStates = []string{"pending", "running", "completed", "failed", "cancelled"}
+
+// ListJobs returns the submitted jobs, the most recent first.
+// It's paginated with ?limit= and ?offset= and filtered with ?state=,
+// or ?status= for the queue status.
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "ListJobs"),
+	)
+
+	const (
+		defaultLimit = 20
+		maxLimit     = 100
+	)
+
+	query := r.URL.Query()
+	filter := gmaps.JobFilter{
+		Status: query.Get("status"),
+		State:  query.Get("state"),
+		Limit:  defaultLimit,
+	}
+
+	if filter.Status != "" && !slices.Contains(jobStatuses, filter.Status) {
+		h.respondWithError(w, http.StatusBadRequest, fmt.Sprintf("status must be one of %s", strings.Join(jobStatuses, ", ")), requestID)
+		return
+	}
+
+	if filter.State != "" && !slices.Contains(jobStates, filter.State) {
+		h.respondWithError(w, http.StatusBadRequest, fmt.Sprintf("state must be one of %s", strings.Join(jobStates, ", ")), requestID)
+		return
+	}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			h.respondWithError(w, http.StatusBadRequest, "limit must be a positive number", requestID)
+			return
+		}
+
+		filter.Limit = min(n, maxLimit)
+	}
+
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			h.respondWithError(w, http.StatusBadRequest, "offset must be a number greater than or equal to 0", requestID)
+			return
+		}
+
+		filter.Offset = n
+	}
+
+	if h.quotas != nil {
+		tenant, ok := h.tenant(w, r, requestID)
+		if !ok {
+			return
+		}
+
+		// the tenants can only see their own jobs
+		filter.Tenant = tenant.Name
+	}
+
+	jobs, total, err := h.provider.List(r.Context(), filter)
+	if err != nil {
+		logger.Error("failed to list jobs", zap.Error(err))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list jobs", requestID)
+		return
+	}
+
+	items := make([]JobResponse, 0, len(jobs))
+
+	for i := range jobs {
+		items = append(items, newJobResponse(&jobs[i], ""))
+	}
+
+	w.Header().Set(TotalCountHeader, strconv.Itoa(total))
+
+	h.respondWithList(w, r, items, Meta{
+		RequestID: requestID,
+		Count:     len(items),
+		Limit:     filter.Limit,
+		Offset:    filter.Offset,
+		Total:     &total,
+	})
+}
+
+type UpdateJobRequest struct {
+	ThrottleMs *int `json:"throttle_ms"`
+}
+
+func (r *UpdateJobRequest) validate() error {
+	const maxThrottleMs = 60_000
+
+	if r.ThrottleMs == nil {
+		return fmt.Errorf("validation failed: throttle_ms is required")
+	}
+
+	if *r.ThrottleMs < 0 || *r.ThrottleMs > maxThrottleMs {
+		return fmt.Errorf("validation failed: throttle_ms must be between 0 and %d", maxThrottleMs)
+	}
+
+	return nil
+}
+
+// UpdateJob updates the throttle of a job while it is running.
+// The workers pick up the new value on one of their next fetches.
+func (h *JobHandler) UpdateJob(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "UpdateJob"),
+	)
+
+	if r.Method != http.MethodPatch {
+		h.respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", requestID)
+		return
+	}
+
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid job id", requestID)
+		return
+	}
+
+	var req UpdateJobRequest
+	if err := h.decodeBody(w, r, &req); err != nil {
+		logger.Error("failed to decode request body", zap.Error(err))
+		h.respondWithBodyError(w, err, requestID)
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		logger.Error("request validation failed", zap.Error(err))
+		h.respondWithError(w, http.StatusBadRequest, err.Error(), requestID)
+		return
+	}
+
+	if h.quotas != nil {
+		tenant, ok := h.tenant(w, r, requestID)
+		if !ok {
+			return
+		}
+
+		info, err := h.provider.Info(r.Context(), jobID)
+
+		switch {
+		case errors.Is(err, gmaps.ErrJobNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+			return
+		case err != nil:
+			logger.Error("failed to get job", zap.Error(err), zap.String("job_id", jobID))
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to update job", requestID)
+			return
+		}
+
+		// the tenants can only see their own jobs
+		if info.Tenant != tenant.Name {
+			h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+			return
+		}
+	}
+
+	throttle := time.Duration(*req.ThrottleMs) * time.Millisecond
+
+	err := h.provider.UpdateThrottle(r.Context(), jobID, throttle)
+
+	switch {
+	case errors.Is(err, gmaps.ErrJobNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+		return
+	case errors.Is(err, gmaps.ErrJobCompleted):
+		h.respondWithError(w, http.StatusConflict, "Job is already completed", requestID)
+		return
+	case err != nil:
+		logger.Error("failed to update job", zap.Error(err), zap.String("job_id", jobID))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to update job", requestID)
+		return
+	}
+
+	logger.Info("job updated successfully",
 		zap.String("job_id", jobID),
-		zap.String("query", req.Query),
+		zap.Duration("throttle", throttle),
 	)
 
-	// Respond with success
-	h.respondWithJSON(w, http.StatusCreated, CreateJobResponse{
+	h.respondWithJSON(w, http.StatusOK, CreateJobResponse{
 		JobID:     jobID,
-		Status:    "created",
-		Message:   "Job created successfully",
+		Status:    "updated",
+		Message:   "Job updated successfully",
 		RequestID: requestID,
 	})
 }
 
+// ListDeadLetters returns the jobs that failed after all their retries
+func (h *JobHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "ListDeadLetters"),
+	)
+
+	if h.dlq == nil {
+		h.respondWithError(w, http.StatusNotFound, "Dead letter queue is disabled", requestID)
+		return
+	}
+
+	const (
+		defaultLimit = 100
+		maxLimit     = 1000
+	)
+
+	limit := defaultLimit
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			h.respondWithError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxLimit), requestID)
+			return
+		}
+
+		limit = n
+	}
+
+	var tenant quota.Tenant
+
+	if h.quotas != nil {
+		var ok bool
+
+		// the tenants can only see their own jobs
+		if tenant, ok = h.tenant(w, r, requestID); !ok {
+			return
+		}
+	}
+
+	items, err := h.dlq.ListDeadLetters(r.Context(), limit, tenant.Name)
+	if err != nil {
+		logger.Error("failed to list dead letters", zap.Error(err))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to list dead letters", requestID)
+		return
+	}
+
+	h.respondWithList(w, r, items, Meta{
+		RequestID: requestID,
+		Count:     len(items),
+		Limit:     limit,
+	})
+}
+
+// RequeueDeadLetter moves a failed job back to the main queue
+func (h *JobHandler) RequeueDeadLetter(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "RequeueDeadLetter"),
+	)
+
+	if h.dlq == nil {
+		h.respondWithError(w, http.StatusNotFound, "Dead letter queue is disabled", requestID)
+		return
+	}
+
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid job id", requestID)
+		return
+	}
+
+	if h.quotas != nil {
+		tenant, ok := h.tenant(w, r, requestID)
+		if !ok {
+			return
+		}
+
+		info, err := h.provider.Info(r.Context(), jobID)
+
+		switch {
+		case errors.Is(err, gmaps.ErrJobNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Job not found in the dead letter queue", requestID)
+			return
+		case err != nil:
+			logger.Error("failed to get job", zap.Error(err), zap.String("job_id", jobID))
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to requeue job", requestID)
+			return
+		}
+
+		// the tenants can only see their own jobs
+		if info.Tenant != tenant.Name {
+			h.respondWithError(w, http.StatusNotFound, "Job not found in the dead letter queue", requestID)
+			return
+		}
+	}
+
+	err := h.dlq.Requeue(r.Context(), jobID)
+
+	switch {
+	case errors.Is(err, gmaps.ErrJobNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Job not found in the dead letter queue", requestID)
+		return
+	case err != nil:
+		logger.Error("failed to requeue job", zap.Error(err), zap.String("job_id", jobID))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to requeue job", requestID)
+		return
+	}
+
+	logger.Info("job requeued successfully", zap.String("job_id", jobID))
+
+	h.respondWithJSON(w, http.StatusOK, CreateJobResponse{
+		JobID:     jobID,
+		Status:    "requeued",
+		Message:   "Job requeued successfully",
+		RequestID: requestID,
+	})
+}
+
+// RefreshPlace scrapes a single place again, saves it and returns the fresh data.
+// With quotas every refresh counts as a job of the tenant.
+func (h *JobHandler) RefreshPlace(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "RefreshPlace"),
+	)
+
+	if h.places == nil {
+		h.respondWithError(w, http.StatusNotFound, "Place refresh is disabled", requestID)
+		return
+	}
+
+	placeID := r.PathValue("placeID")
+
+	// the id is checked before any scraping is started
+	if _, err := gmaps.PlaceURL(placeID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid place id", requestID)
+		return
+	}
+
+	var (
+		tenant      quota.Tenant
+		periodStart time.Time
+	)
+
+	if h.quotas != nil {
+		var (
+			ok   bool
+			jerr *jobError
+		)
+
+		if tenant, ok = h.tenant(w, r, requestID); !ok {
+			return
+		}
+
+		if periodStart, jerr = h.reserveJob(r.Context(), w, &tenant, logger); jerr != nil {
+			h.respondWithError(w, jerr.code, jerr.message, requestID)
+			return
+		}
+	}
+
+	entry, err := h.places.Refresh(r.Context(), placeID)
+	if err != nil && h.quotas != nil {
+		h.releaseJob(r.Context(), tenant.Name, periodStart, logger)
+	}
+
+	switch {
+	case errors.Is(err, gmaps.ErrInvalidPlaceID):
+		h.respondWithError(w, http.StatusBadRequest, "Invalid place id", requestID)
+		return
+	case errors.Is(err, gmaps.ErrPlaceNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Place not found", requestID)
+		return
+	case err != nil:
+		logger.Error("failed to refresh place", zap.Error(err), zap.String("place_id", placeID))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to refresh place", requestID)
+		return
+	}
+
+	logger.Info("place refreshed successfully",
+		zap.String("place_id", placeID),
+		zap.String("title", entry.Title),
+	)
+
+	h.respondWithJSON(w, http.StatusOK, entry)
+}
+
 func (h *JobHandler) respondWithError(w http.ResponseWriter, code int, message string, requestID string) {
 	h.respondWithJSON(w, code, CreateJobResponse{
 		Status:    "error",
 		Message:   message,
+		Code:      errorCode(code),
 		RequestID: requestID,
 	})
 }
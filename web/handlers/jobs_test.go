@@ -0,0 +1,562 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/quota"
+	"github.com/gosom/google-maps-scraper/web/handlers"
+)
+
+const (
+	runningJobID   = "7c1c3a52-5f4e-4b58-9d1e-0a4f3f9f6c01"
+	completedJobID = "7c1c3a52-5f4e-4b58-9d1e-0a4f3f9f6c02"
+	unknownJobID   = "7c1c3a52-5f4e-4b58-9d1e-0a4f3f9f6c03"
+)
+
+// fakeProvider keeps the jobs in memory, the completed ones can't be changed anymore
+type fakeProvider struct {
+	mu        sync.Mutex
+	jobs      map[string]gmaps.JobInfo
+	throttles map[string]time.Duration
+	pushed    []scrapemate.IJob
+}
+
+func newFakeProvider(jobs ...gmaps.JobInfo) *fakeProvider {
+	p := &fakeProvider{
+		jobs:      map[string]gmaps.JobInfo{},
+		throttles: map[string]time.Duration{},
+	}
+
+	for _, job := range jobs {
+		p.jobs[job.ID] = job
+	}
+
+	return p
+}
+
+func (p *fakeProvider) Push(_ context.Context, job scrapemate.IJob) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pushed = append(p.pushed, job)
+
+	return nil
+}
+
+func (p *fakeProvider) PushBatch(ctx context.Context, jobs []scrapemate.IJob) error {
+	for _, job := range jobs {
+		if err := p.Push(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *fakeProvider) UpdateThrottle(_ context.Context, jobID string, throttle time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, ok := p.jobs[jobID]
+	if !ok {
+		return gmaps.ErrJobNotFound
+	}
+
+	if job.State == "completed" {
+		return gmaps.ErrJobCompleted
+	}
+
+	p.throttles[jobID] = throttle
+
+	return nil
+}
+
+func (p *fakeProvider) Get(context.Context, string) (scrapemate.IJob, error) {
+	return nil, gmaps.ErrJobNotFound
+}
+
+func (p *fakeProvider) Info(_ context.Context, jobID string) (gmaps.JobInfo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, ok := p.jobs[jobID]
+	if !ok {
+		return gmaps.JobInfo{ID: jobID}, gmaps.ErrJobNotFound
+	}
+
+	return job, nil
+}
+
+func (p *fakeProvider) List(context.Context, gmaps.JobFilter) ([]gmaps.JobInfo, int, error) {
+	return nil, 0, nil
+}
+
+func (p *fakeProvider) Delete(context.Context, string) error {
+	return nil
+}
+
+func (p *fakeProvider) Cancel(context.Context, string) error {
+	return nil
+}
+
+func (p *fakeProvider) throttle(jobID string) (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	d, ok := p.throttles[jobID]
+
+	return d, ok
+}
+
+// fakeDLQ keeps the failed jobs in memory
+type fakeDLQ struct {
+	mu   sync.Mutex
+	jobs []gmaps.DeadLetterJob
+	// tenants are the tenants of the jobs by id
+	tenants  map[string]string
+	requeued []string
+}
+
+func (q *fakeDLQ) DeadLetter(_ context.Context, jobID string, reason error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.jobs = append(q.jobs, gmaps.DeadLetterJob{ID: jobID, Reason: reason.Error()})
+
+	return nil
+}
+
+func (q *fakeDLQ) ListDeadLetters(_ context.Context, limit int, tenant string) ([]gmaps.DeadLetterJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ans := []gmaps.DeadLetterJob{}
+
+	for _, job := range q.jobs {
+		if len(ans) < limit && (tenant == "" || q.tenants[job.ID] == tenant) {
+			ans = append(ans, job)
+		}
+	}
+
+	return ans, nil
+}
+
+func (q *fakeDLQ) Requeue(_ context.Context, jobID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := range q.jobs {
+		if q.jobs[i].ID == jobID {
+			q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+			q.requeued = append(q.requeued, jobID)
+
+			return nil
+		}
+	}
+
+	return gmaps.ErrJobNotFound
+}
+
+// fakeRefresher resolves only the places it knows
+type fakeRefresher struct {
+	mu        sync.Mutex
+	places    map[string]*gmaps.Entry
+	refreshed []string
+}
+
+func (f *fakeRefresher) Refresh(_ context.Context, placeID string) (*gmaps.Entry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.refreshed = append(f.refreshed, placeID)
+
+	entry, ok := f.places[placeID]
+	if !ok {
+		return nil, gmaps.ErrPlaceNotFound
+	}
+
+	return entry, nil
+}
+
+// fakeUsage counts the reserved jobs of the tenants in memory
+type fakeUsage struct {
+	mu   sync.Mutex
+	jobs map[string]int
+}
+
+func (u *fakeUsage) Usage(_ context.Context, tenant string, _ time.Time) (quota.Usage, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return quota.Usage{Jobs: u.jobs[tenant]}, nil
+}
+
+func (u *fakeUsage) ReserveJob(_ context.Context, tenant *quota.Tenant, _ time.Time) (quota.Usage, bool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if tenant.MaxJobs > 0 && u.jobs[tenant.Name] >= tenant.MaxJobs {
+		return quota.Usage{Jobs: u.jobs[tenant.Name]}, false, nil
+	}
+
+	u.jobs[tenant.Name]++
+
+	return quota.Usage{Jobs: u.jobs[tenant.Name]}, true, nil
+}
+
+func (u *fakeUsage) ReleaseJob(_ context.Context, tenant string, _ time.Time) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.jobs[tenant]--
+
+	return nil
+}
+
+func (u *fakeUsage) reserved(tenant string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.jobs[tenant]
+}
+
+const (
+	acmeKey   = "acme-key"
+	globexKey = "globex-key"
+)
+
+// withQuotas enables the quotas of the tenants acme and globex, globex can
+// create a single job
+func withQuotas(t *testing.T, usage *fakeUsage) handlers.JobHandlerOption {
+	t.Helper()
+
+	fname := filepath.Join(t.TempDir(), "quotas.json")
+
+	err := os.WriteFile(fname, []byte(`{
+		"`+acmeKey+`": {"name": "acme"},
+		"`+globexKey+`": {"name": "globex", "max_jobs": 1}
+	}`), 0o600)
+	require.NoError(t, err)
+
+	cfg, err := quota.Load(fname, 0)
+	require.NoError(t, err)
+
+	return handlers.WithQuotas(cfg, usage)
+}
+
+func apiKey(key string) http.Header {
+	return http.Header{handlers.APIKeyHeader: []string{key}}
+}
+
+// serve routes the request like the API server does
+func serve(h *handlers.JobHandler, method, target, body string, header http.Header) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/jobs/{id}", h.GetJob)
+	mux.HandleFunc("PATCH /api/jobs/{id}", h.UpdateJob)
+	mux.HandleFunc("GET /api/jobs/{id}/results", h.JobResults)
+	mux.HandleFunc("GET /api/dlq", h.ListDeadLetters)
+	mux.HandleFunc("POST /api/dlq/{id}/requeue", h.RequeueDeadLetter)
+	mux.HandleFunc("POST /api/places/{placeID}/refresh", h.RefreshPlace)
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+
+	for k, v := range header {
+		for _, value := range v {
+			req.Header.Add(k, value)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder) handlers.CreateJobResponse {
+	t.Helper()
+
+	var resp handlers.CreateJobResponse
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+	return resp
+}
+
+func Test_UpdateJob(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobID   string
+		body    string
+		code    int
+		message string
+	}{
+		{"running job", runningJobID, `{"throttle_ms": 1500}`, http.StatusOK, "Job updated successfully"},
+		{"no throttle", runningJobID, `{"throttle_ms": 0}`, http.StatusOK, "Job updated successfully"},
+		{"completed job", completedJobID, `{"throttle_ms": 1500}`, http.StatusConflict, "Job is already completed"},
+		{"unknown job", unknownJobID, `{"throttle_ms": 1500}`, http.StatusNotFound, "Job not found"},
+		{"invalid job id", "nope", `{"throttle_ms": 1500}`, http.StatusBadRequest, "Invalid job id"},
+		{"missing throttle", runningJobID, `{}`, http.StatusBadRequest, "throttle_ms is required"},
+		{"negative throttle", runningJobID, `{"throttle_ms": -1}`, http.StatusBadRequest, "throttle_ms must be between 0 and 60000"},
+		{"throttle too large", runningJobID, `{"throttle_ms": 60001}`, http.StatusBadRequest, "throttle_ms must be between 0 and 60000"},
+		{"unknown field", runningJobID, `{"concurrency": 2}`, http.StatusBadRequest, `unknown field "concurrency"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := newFakeProvider(
+				gmaps.JobInfo{ID: runningJobID, State: "running"},
+				gmaps.JobInfo{ID: completedJobID, State: "completed"},
+			)
+
+			h := handlers.NewJobHandler(provider, zap.NewNop())
+
+			rec := serve(h, http.MethodPatch, "/api/jobs/"+tc.jobID, tc.body, nil)
+
+			require.Equal(t, tc.code, rec.Code)
+			require.Contains(t, decodeResponse(t, rec).Message, tc.message)
+
+			_, updated := provider.throttle(tc.jobID)
+			require.Equal(t, tc.code == http.StatusOK, updated)
+		})
+	}
+
+	t.Run("new throttle", func(t *testing.T) {
+		provider := newFakeProvider(gmaps.JobInfo{ID: runningJobID, State: "running"})
+		h := handlers.NewJobHandler(provider, zap.NewNop())
+
+		rec := serve(h, http.MethodPatch, "/api/jobs/"+runningJobID, `{"throttle_ms": 250}`, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		throttle, _ := provider.throttle(runningJobID)
+		require.Equal(t, 250*time.Millisecond, throttle)
+	})
+}
+
+func Test_ListDeadLetters(t *testing.T) {
+	dlq := &fakeDLQ{jobs: []gmaps.DeadLetterJob{
+		{ID: runningJobID, Type: "search", Reason: "timeout", Attempts: 3},
+		{ID: completedJobID, Type: "place", Reason: "blocked", Attempts: 3},
+	}}
+
+	h := handlers.NewJobHandler(newFakeProvider(), zap.NewNop(), handlers.WithDeadLetterQueue(dlq))
+
+	rec := serve(h, http.MethodGet, "/api/dlq?limit=1&envelope=false", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var items []gmaps.DeadLetterJob
+
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &items))
+	require.Equal(t, dlq.jobs[:1], items)
+
+	for _, limit := range []string{"0", "1001", "x"} {
+		rec := serve(h, http.MethodGet, "/api/dlq?limit="+limit, "", nil)
+		require.Equal(t, http.StatusBadRequest, rec.Code, limit)
+	}
+}
+
+func Test_RequeueDeadLetter(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobID   string
+		code    int
+		message string
+	}{
+		{"failed job", runningJobID, http.StatusOK, "Job requeued successfully"},
+		{"unknown job", unknownJobID, http.StatusNotFound, "Job not found in the dead letter queue"},
+		{"invalid job id", "nope", http.StatusBadRequest, "Invalid job id"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dlq := &fakeDLQ{jobs: []gmaps.DeadLetterJob{{ID: runningJobID}}}
+			h := handlers.NewJobHandler(newFakeProvider(), zap.NewNop(), handlers.WithDeadLetterQueue(dlq))
+
+			rec := serve(h, http.MethodPost, "/api/dlq/"+tc.jobID+"/requeue", "", nil)
+
+			require.Equal(t, tc.code, rec.Code)
+			require.Equal(t, tc.message, decodeResponse(t, rec).Message)
+
+			if tc.code == http.StatusOK {
+				require.Equal(t, []string{tc.jobID}, dlq.requeued)
+			} else {
+				require.Empty(t, dlq.requeued)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		h := handlers.NewJobHandler(newFakeProvider(), zap.NewNop())
+
+		rec := serve(h, http.MethodPost, "/api/dlq/"+runningJobID+"/requeue", "", nil)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Equal(t, "Dead letter queue is disabled", decodeResponse(t, rec).Message)
+	})
+}
+
+func Test_RefreshPlace(t *testing.T) {
+	const (
+		placeID = "ChIJLU7jZClu5kcR4PcOOO6p3I0"
+		removed = "0x47e66e2964e34e2d:0x8ddca9ee380ef7e0"
+	)
+
+	tests := []struct {
+		name      string
+		placeID   string
+		code      int
+		refreshed bool
+	}{
+		{"place id", placeID, http.StatusOK, true},
+		{"removed place", removed, http.StatusNotFound, true},
+		{"invalid place id", "not-a-place", http.StatusBadRequest, false},
+		{"too short", "ChIJ", http.StatusBadRequest, false},
+		{"escaped path", "..%2Fjobs", http.StatusBadRequest, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			refresher := &fakeRefresher{places: map[string]*gmaps.Entry{
+				placeID: {PlaceID: placeID, Title: "Eiffel Tower"},
+			}}
+
+			h := handlers.NewJobHandler(newFakeProvider(), zap.NewNop(), handlers.WithPlaceRefresher(refresher))
+
+			rec := serve(h, http.MethodPost, "/api/places/"+tc.placeID+"/refresh", "", nil)
+			require.Equal(t, tc.code, rec.Code)
+
+			if tc.refreshed {
+				require.Len(t, refresher.refreshed, 1)
+			} else {
+				require.Empty(t, refresher.refreshed)
+			}
+
+			if tc.code == http.StatusOK {
+				var entry gmaps.Entry
+
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entry))
+				require.Equal(t, "Eiffel Tower", entry.Title)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		h := handlers.NewJobHandler(newFakeProvider(), zap.NewNop())
+
+		rec := serve(h, http.MethodPost, "/api/places/"+placeID+"/refresh", "", nil)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Equal(t, "Place refresh is disabled", decodeResponse(t, rec).Message)
+	})
+}
+
+// the tenants can only see and change their own jobs
+func Test_TenantGuard(t *testing.T) {
+	acmeJob := gmaps.JobInfo{ID: runningJobID, State: "running", Tenant: "acme"}
+
+	t.Run("update job", func(t *testing.T) {
+		provider := newFakeProvider(acmeJob)
+		h := handlers.NewJobHandler(provider, zap.NewNop(), withQuotas(t, &fakeUsage{jobs: map[string]int{}}))
+
+		rec := serve(h, http.MethodPatch, "/api/jobs/"+runningJobID, `{"throttle_ms": 1500}`, apiKey(globexKey))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Equal(t, "Job not found", decodeResponse(t, rec).Message)
+
+		_, updated := provider.throttle(runningJobID)
+		require.False(t, updated)
+
+		rec = serve(h, http.MethodPatch, "/api/jobs/"+runningJobID, `{"throttle_ms": 1500}`, nil)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		rec = serve(h, http.MethodPatch, "/api/jobs/"+runningJobID, `{"throttle_ms": 1500}`, apiKey(acmeKey))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		throttle, _ := provider.throttle(runningJobID)
+		require.Equal(t, 1500*time.Millisecond, throttle)
+	})
+
+	t.Run("requeue", func(t *testing.T) {
+		dlq := &fakeDLQ{
+			jobs:    []gmaps.DeadLetterJob{{ID: runningJobID}},
+			tenants: map[string]string{runningJobID: "acme"},
+		}
+
+		provider := newFakeProvider(acmeJob)
+		h := handlers.NewJobHandler(provider, zap.NewNop(),
+			handlers.WithDeadLetterQueue(dlq),
+			withQuotas(t, &fakeUsage{jobs: map[string]int{}}),
+		)
+
+		rec := serve(h, http.MethodPost, "/api/dlq/"+runningJobID+"/requeue", "", apiKey(globexKey))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Equal(t, "Job not found in the dead letter queue", decodeResponse(t, rec).Message)
+		require.Empty(t, dlq.requeued)
+
+		rec = serve(h, http.MethodPost, "/api/dlq/"+runningJobID+"/requeue", "", apiKey(acmeKey))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, []string{runningJobID}, dlq.requeued)
+	})
+
+	t.Run("dead letters", func(t *testing.T) {
+		dlq := &fakeDLQ{
+			jobs:    []gmaps.DeadLetterJob{{ID: runningJobID}, {ID: completedJobID}},
+			tenants: map[string]string{runningJobID: "acme", completedJobID: "globex"},
+		}
+
+		h := handlers.NewJobHandler(newFakeProvider(), zap.NewNop(),
+			handlers.WithDeadLetterQueue(dlq),
+			withQuotas(t, &fakeUsage{jobs: map[string]int{}}),
+		)
+
+		for key, want := range map[string]string{acmeKey: runningJobID, globexKey: completedJobID} {
+			rec := serve(h, http.MethodGet, "/api/dlq?envelope=false", "", apiKey(key))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			var items []gmaps.DeadLetterJob
+
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &items))
+			require.Equal(t, []gmaps.DeadLetterJob{{ID: want}}, items, key)
+		}
+	})
+
+	t.Run("refresh place", func(t *testing.T) {
+		const placeID = "ChIJLU7jZClu5kcR4PcOOO6p3I0"
+
+		refresher := &fakeRefresher{places: map[string]*gmaps.Entry{
+			placeID: {PlaceID: placeID, Title: "Eiffel Tower"},
+		}}
+
+		usage := &fakeUsage{jobs: map[string]int{}}
+		h := handlers.NewJobHandler(newFakeProvider(), zap.NewNop(),
+			handlers.WithPlaceRefresher(refresher),
+			withQuotas(t, usage),
+		)
+
+		rec := serve(h, http.MethodPost, "/api/places/"+placeID+"/refresh", "", nil)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		// the failed refreshes are given back
+		rec = serve(h, http.MethodPost, "/api/places/ChIJLU7jZClu5kcR4PcOOO6p3I1/refresh", "", apiKey(globexKey))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.Equal(t, 0, usage.reserved("globex"))
+
+		rec = serve(h, http.MethodPost, "/api/places/"+placeID+"/refresh", "", apiKey(globexKey))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, 1, usage.reserved("globex"))
+
+		rec = serve(h, http.MethodPost, "/api/places/"+placeID+"/refresh", "", apiKey(globexKey))
+		require.Equal(t, http.StatusTooManyRequests, rec.Code)
+		require.Len(t, refresher.refreshed, 2)
+	})
+}
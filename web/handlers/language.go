@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+)
+
+// languages are the language tags google maps supports for hl
+var languages = []string{
+	"af", "am", "ar", "az", "be", "bg", "bn", "bs", "ca", "cs", "cy", "da", "de", "el",
+	"en", "en-AU", "en-GB", "es", "es-419", "et", "eu", "fa", "fi", "fil", "fr", "fr-CA",
+	"gl", "gu", "he", "hi", "hr", "hu", "hy", "id", "is", "it", "iw", "ja", "ka", "kk",
+	"km", "kn", "ko", "ky", "lo", "lt", "lv", "mk", "ml", "mn", "mr", "ms", "my", "ne",
+	"nl", "no", "pa", "pl", "pt", "pt-BR", "pt-PT", "ro", "ru", "si", "sk", "sl", "sq",
+	"sr", "sr-Latn", "sv", "sw", "ta", "te", "th", "tr", "uk", "ur", "uz", "vi", "zh",
+	"zh-CN", "zh-HK", "zh-TW", "zu",
+}
+
+// normalizeLanguage returns the supported language tag matching s whatever
+// its case ("EN" is "en", "pt-br" is "pt-BR"). It returns false when the
+// language is not supported.
+func normalizeLanguage(s string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(p)
+		case len(p) == 2:
+			// region
+			parts[i] = strings.ToUpper(p)
+		case len(p) == 4:
+			// script
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		}
+	}
+
+	tag := strings.Join(parts, "-")
+
+	return tag, slices.Contains(languages, tag)
+}
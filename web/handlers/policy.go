@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// QueryPolicy restricts the queries that can be submitted via the API.
+// A query must match at least one allow pattern (when any are set)
+// and none of the block patterns.
+type QueryPolicy struct {
+	allow []*regexp.Regexp
+	block []*regexp.Regexp
+}
+
+func NewQueryPolicy(allow, block []string) (*QueryPolicy, error) {
+	ans := QueryPolicy{}
+
+	for _, p := range allow {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist pattern %q: %w", p, err)
+		}
+
+		ans.allow = append(ans.allow, re)
+	}
+
+	for _, p := range block {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocklist pattern %q: %w", p, err)
+		}
+
+		ans.block = append(ans.block, re)
+	}
+
+	return &ans, nil
+}
+
+// Allowed reports whether the query may be scraped
+func (p *QueryPolicy) Allowed(query string) bool {
+	if p == nil {
+		return true
+	}
+
+	for _, re := range p.block {
+		if re.MatchString(query) {
+			return false
+		}
+	}
+
+	if len(p.allow) == 0 {
+		return true
+	}
+
+	for _, re := range p.allow {
+		if re.MatchString(query) {
+			return true
+		}
+	}
+
+	return false
+}
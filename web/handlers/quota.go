@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/gosom/google-maps-scraper/quota"
+)
+
+// APIKeyHeader identifies the tenant of a request when quotas are enabled
+const APIKeyHeader = "X-API-Key"
+
+// Headers reporting the quotas left to the tenant, the remaining ones are omitted for unlimited quotas
+const (
+	QuotaJobsRemainingHeader    = "X-Quota-Jobs-Remaining"
+	QuotaResultsRemainingHeader = "X-Quota-Results-Remaining"
+	QuotaResetHeader            = "X-Quota-Reset"
+)
+
+// WithQuotas requires an API key on the job creation and enforces the
+// quotas of its tenant, the usage is kept in store
+func WithQuotas(cfg *quota.Config, store quota.Store) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.quotas = cfg
+		h.usage = store
+	}
+}
+
+// QuotaCounter is the usage of one quota
+type QuotaCounter struct {
+	Used      int  `json:"used"`
+	Limit     int  `json:"limit"`
+	Remaining *int `json:"remaining"`
+}
+
+// QuotaResponse is the usage of a tenant in the current period,
+// a zero limit and a null remaining mean unlimited
+type QuotaResponse struct {
+	Tenant      string       `json:"tenant"`
+	PeriodStart time.Time    `json:"period_start"`
+	ResetAt     time.Time    `json:"reset_at"`
+	Jobs        QuotaCounter `json:"jobs"`
+	Results     QuotaCounter `json:"results"`
+	RequestID   string       `json:"request_id"`
+}
+
+// tenant returns the tenant of the API key of r or writes the error response
+func (h *JobHandler) tenant(w http.ResponseWriter, r *http.Request, requestID string) (quota.Tenant, bool) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		h.respondWithError(w, http.StatusUnauthorized, "API key is required", requestID)
+		return quota.Tenant{}, false
+	}
+
+	tenant, ok := h.quotas.Tenant(key)
+	if !ok {
+		h.respondWithError(w, http.StatusForbidden, "Invalid API key", requestID)
+		return quota.Tenant{}, false
+	}
+
+	return tenant, true
+}
+
+// setQuotaHeaders reports the quotas left to tenant after usage
+func (h *JobHandler) setQuotaHeaders(w http.ResponseWriter, tenant *quota.Tenant, usage quota.Usage, periodStart time.Time) {
+	if n, ok := quota.Remaining(tenant.MaxJobs, usage.Jobs); ok {
+		w.Header().Set(QuotaJobsRemainingHeader, strconv.Itoa(n))
+	}
+
+	if n, ok := quota.Remaining(tenant.MaxResults, usage.Results); ok {
+		w.Header().Set(QuotaResultsRemainingHeader, strconv.Itoa(n))
+	}
+
+	w.Header().Set(QuotaResetHeader, h.quotas.PeriodEnd(periodStart).Format(time.RFC3339))
+}
+
+// GetQuota returns the usage and the quotas of the tenant of the API key
+func (h *JobHandler) GetQuota(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "GetQuota"),
+	)
+
+	if h.quotas == nil {
+		h.respondWithError(w, http.StatusNotFound, "Quotas are disabled", requestID)
+		return
+	}
+
+	tenant, ok := h.tenant(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	periodStart := h.quotas.PeriodStart(time.Now())
+
+	usage, err := h.usage.Usage(r.Context(), tenant.Name, periodStart)
+	if err != nil {
+		logger.Error("failed to get usage", zap.Error(err), zap.String("tenant", tenant.Name))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get quota", requestID)
+		return
+	}
+
+	counter := func(limit, used int) QuotaCounter {
+		ans := QuotaCounter{Used: used, Limit: limit}
+
+		if n, ok := quota.Remaining(limit, used); ok {
+			ans.Remaining = &n
+		}
+
+		return ans
+	}
+
+	h.setQuotaHeaders(w, &tenant, usage, periodStart)
+
+	h.respondWithJSON(w, http.StatusOK, QuotaResponse{
+		Tenant:      tenant.Name,
+		PeriodStart: periodStart,
+		ResetAt:     h.quotas.PeriodEnd(periodStart),
+		Jobs:        counter(tenant.MaxJobs, usage.Jobs),
+		Results:     counter(tenant.MaxResults, usage.Results),
+		RequestID:   requestID,
+	})
+}
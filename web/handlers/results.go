@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/quota"
+	"go.uber.org/zap"
+)
+
+// formats of the results of a job
+const (
+	resultsJSON = "json"
+	resultsCSV  = "csv"
+)
+
+// resultsWriteTimeout is how long a client can stop reading the results
+// before it's cut off, the whole download may take longer
+const resultsWriteTimeout = time.Minute
+
+// PartialResultsHeader is set on the results of the jobs still running
+const PartialResultsHeader = "X-Results-Partial"
+
+// WithResultReader enables the endpoint that downloads the results of the jobs
+func WithResultReader(rr gmaps.ResultReader) JobHandlerOption {
+	return func(h *JobHandler) {
+		h.results = rr
+	}
+}
+
+// JobResults streams the places of a finished job as a json array or as csv,
+// picked with ?format= or else with the Accept header. With ?partial=true the
+// places saved so far of a running job are streamed too.
+func (h *JobHandler) JobResults(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+	logger := h.logger.With(
+		zap.String("request_id", requestID),
+		zap.String("handler", "JobResults"),
+	)
+
+	if h.results == nil {
+		h.respondWithError(w, http.StatusNotFound, "Results are not available with this provider", requestID)
+		return
+	}
+
+	jobID := r.PathValue("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		h.respondWithError(w, http.StatusBadRequest, "Invalid job id", requestID)
+		return
+	}
+
+	format, ok := resultsFormat(r)
+	if !ok {
+		h.respondWithError(w, http.StatusBadRequest, "format must be json or csv", requestID)
+		return
+	}
+
+	var partial bool
+
+	if v := r.URL.Query().Get("partial"); v != "" {
+		var err error
+
+		if partial, err = strconv.ParseBool(v); err != nil {
+			h.respondWithError(w, http.StatusBadRequest, "partial must be true or false", requestID)
+			return
+		}
+	}
+
+	if h.quotas != nil || partial {
+		var tenant quota.Tenant
+
+		if h.quotas != nil {
+			if tenant, ok = h.tenant(w, r, requestID); !ok {
+				return
+			}
+		}
+
+		info, err := h.provider.Info(r.Context(), jobID)
+
+		switch {
+		case errors.Is(err, gmaps.ErrJobNotFound):
+			h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+			return
+		case err != nil:
+			logger.Error("failed to get job", zap.Error(err), zap.String("job_id", jobID))
+			h.respondWithError(w, http.StatusInternalServerError, "Failed to get the results", requestID)
+			return
+		}
+
+		// the tenants can only see their own jobs
+		if h.quotas != nil && info.Tenant != tenant.Name {
+			h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+			return
+		}
+
+		// the clients fetch the results again once the job is done
+		if partial && jobRunning(info.State) {
+			w.Header().Set(PartialResultsHeader, "true")
+		}
+	}
+
+	// the write timeout of the server would cut the downloads of large jobs
+	dw := &deadlineWriter{ResponseWriter: w, rc: http.NewResponseController(w)}
+
+	var out resultsWriter
+	if format == resultsCSV {
+		out = &csvResults{w: dw}
+	} else {
+		out = &jsonResults{w: dw}
+	}
+
+	err := h.results.Results(r.Context(), jobID, partial, out.write)
+
+	switch {
+	case errors.Is(err, gmaps.ErrJobNotFound):
+		h.respondWithError(w, http.StatusNotFound, "Job not found", requestID)
+		return
+	case errors.Is(err, gmaps.ErrJobNotFinished):
+		h.respondWithError(w, http.StatusConflict, "Job is not finished yet", requestID)
+		return
+	case err != nil && out.started():
+		logger.Error("failed to stream the results", zap.Error(err), zap.String("job_id", jobID))
+
+		// the status is sent already, the connection is dropped without ending
+		// the body so that the client sees the results are truncated
+		panic(http.ErrAbortHandler)
+	case err != nil:
+		logger.Error("failed to get the results", zap.Error(err), zap.String("job_id", jobID))
+		h.respondWithError(w, http.StatusInternalServerError, "Failed to get the results", requestID)
+		return
+	}
+
+	if err := out.close(); err != nil {
+		logger.Error("failed to stream the results", zap.Error(err), zap.String("job_id", jobID))
+	}
+}
+
+// resultsFormat returns the format asked with ?format=, or else the first of
+// json and csv accepted by the client. It's false for an unknown ?format=.
+func resultsFormat(r *http.Request) (string, bool) {
+	switch f := r.URL.Query().Get("format"); f {
+	case resultsJSON, resultsCSV:
+		return f, true
+	case "":
+	default:
+		return "", false
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "text/csv":
+			return resultsCSV, true
+		case "application/json":
+			return resultsJSON, true
+		}
+	}
+
+	return resultsJSON, true
+}
+
+// resultsWriter writes the places as they are read, the status and the
+// headers are sent with the first one so that the errors before it can
+// still be answered
+type resultsWriter interface {
+	write(data []byte) error
+	started() bool
+	// close ends the output, also when there were no places
+	close() error
+}
+
+// deadlineWriter moves the write deadline of the response forward while the
+// results are written. A client that stops reading is cut off after
+// resultsWriteTimeout.
+type deadlineWriter struct {
+	http.ResponseWriter
+	rc    *http.ResponseController
+	until time.Time
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	// the deadline is moved once half of it is used, not on every write
+	if time.Until(d.until) < resultsWriteTimeout/2 {
+		d.until = time.Now().Add(resultsWriteTimeout)
+
+		// the writers without deadlines, like the test recorders, are not cut off
+		if err := d.rc.SetWriteDeadline(d.until); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return 0, err
+		}
+	}
+
+	return d.ResponseWriter.Write(p)
+}
+
+type jsonResults struct {
+	w http.ResponseWriter
+	n int
+}
+
+func (j *jsonResults) write(data []byte) error {
+	sep := []byte(",\n")
+
+	if j.n == 0 {
+		j.w.Header().Set("Content-Type", "application/json")
+		j.w.WriteHeader(http.StatusOK)
+
+		sep = []byte("[\n")
+	}
+
+	j.n++
+
+	if _, err := j.w.Write(sep); err != nil {
+		return err
+	}
+
+	_, err := j.w.Write(data)
+
+	return err
+}
+
+func (j *jsonResults) started() bool {
+	return j.n > 0
+}
+
+func (j *jsonResults) close() error {
+	if j.n == 0 {
+		j.w.Header().Set("Content-Type", "application/json")
+		j.w.WriteHeader(http.StatusOK)
+
+		_, err := j.w.Write([]byte("[]\n"))
+
+		return err
+	}
+
+	_, err := j.w.Write([]byte("\n]\n"))
+
+	return err
+}
+
+type csvResults struct {
+	w   http.ResponseWriter
+	enc *csv.Writer
+}
+
+func (c *csvResults) start() error {
+	c.w.Header().Set("Content-Type", "text/csv")
+	c.w.WriteHeader(http.StatusOK)
+
+	c.enc = csv.NewWriter(c.w)
+
+	return c.enc.Write((&gmaps.Entry{}).CsvHeaders())
+}
+
+func (c *csvResults) write(data []byte) error {
+	var entry gmaps.Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+
+	if c.enc == nil {
+		if err := c.start(); err != nil {
+			return err
+		}
+	}
+
+	// the csv writer flushes to the client whenever its buffer is full
+	return c.enc.Write(entry.CsvRow())
+}
+
+func (c *csvResults) started() bool {
+	return c.enc != nil
+}
+
+func (c *csvResults) close() error {
+	if c.enc == nil {
+		if err := c.start(); err != nil {
+			return err
+		}
+	}
+
+	c.enc.Flush()
+
+	return c.enc.Error()
+}
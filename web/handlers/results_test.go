@@ -0,0 +1,240 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/metrics"
+	"github.com/gosom/google-maps-scraper/web/handlers"
+)
+
+// fakeResults reads the places of the jobs of the provider, the places of
+// the running jobs only when partial is set
+type fakeResults struct {
+	provider *fakeProvider
+	places   map[string][]*gmaps.Entry
+	// delay is the time to read every place
+	delay time.Duration
+	// failAfter fails the read after this many places when it's not zero
+	failAfter int
+}
+
+func (f *fakeResults) Results(ctx context.Context, jobID string, partial bool, fn func(data []byte) error) error {
+	info, err := f.provider.Info(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	if !partial && (info.State == "pending" || info.State == "running") {
+		return gmaps.ErrJobNotFinished
+	}
+
+	for i, entry := range f.places[jobID] {
+		if f.failAfter > 0 && i == f.failAfter {
+			return errors.New("connection reset")
+		}
+
+		time.Sleep(f.delay)
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newResultsHandler(places map[string][]*gmaps.Entry) *handlers.JobHandler {
+	provider := newFakeProvider(
+		gmaps.JobInfo{ID: runningJobID, State: "running", ResultCount: len(places[runningJobID])},
+		gmaps.JobInfo{ID: completedJobID, State: "completed", ResultCount: len(places[completedJobID])},
+	)
+
+	return handlers.NewJobHandler(provider, zap.NewNop(),
+		handlers.WithResultReader(&fakeResults{provider: provider, places: places}),
+	)
+}
+
+// resultsServer serves the results like the API server does, with a write
+// timeout shorter than the download
+func resultsServer(t *testing.T, rr *fakeResults) *httptest.Server {
+	t.Helper()
+
+	h := handlers.NewJobHandler(rr.provider, zap.NewNop(), handlers.WithResultReader(rr))
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/jobs/{id}/results", metrics.Instrument("JobResults", h.JobResults))
+
+	srv := httptest.NewUnstartedServer(mux)
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func download(t *testing.T, srv *httptest.Server, format string) (*http.Response, []byte, error) {
+	t.Helper()
+
+	resp, err := srv.Client().Get(srv.URL + "/api/jobs/" + completedJobID + "/results?format=" + format)
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+
+	return resp, body, err
+}
+
+func entries(prefix string, n int) []*gmaps.Entry {
+	ans := make([]*gmaps.Entry, 0, n)
+
+	for i := range n {
+		ans = append(ans, &gmaps.Entry{Title: fmt.Sprintf("%s-%d", prefix, i)})
+	}
+
+	return ans
+}
+
+func Test_PartialResults(t *testing.T) {
+	h := newResultsHandler(map[string][]*gmaps.Entry{
+		runningJobID:   entries("running", 2),
+		completedJobID: entries("completed", 3),
+	})
+
+	tests := []struct {
+		name    string
+		target  string
+		code    int
+		titles  []string
+		partial bool
+	}{
+		{"running job", "/api/jobs/" + runningJobID + "/results", http.StatusConflict, nil, false},
+		{"running job partial", "/api/jobs/" + runningJobID + "/results?partial=true", http.StatusOK, []string{"running-0", "running-1"}, true},
+		{"completed job", "/api/jobs/" + completedJobID + "/results", http.StatusOK, []string{"completed-0", "completed-1", "completed-2"}, false},
+		{"completed job partial", "/api/jobs/" + completedJobID + "/results?partial=true", http.StatusOK, []string{"completed-0", "completed-1", "completed-2"}, false},
+		{"unknown job partial", "/api/jobs/" + unknownJobID + "/results?partial=true", http.StatusNotFound, nil, false},
+		{"invalid partial", "/api/jobs/" + runningJobID + "/results?partial=maybe", http.StatusBadRequest, nil, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(h, http.MethodGet, tc.target, "", nil)
+			require.Equal(t, tc.code, rec.Code)
+
+			if tc.partial {
+				require.Equal(t, "true", rec.Header().Get(handlers.PartialResultsHeader))
+			} else {
+				require.Empty(t, rec.Header().Get(handlers.PartialResultsHeader))
+			}
+
+			if tc.code != http.StatusOK {
+				return
+			}
+
+			var places []gmaps.Entry
+
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &places))
+
+			titles := []string{}
+			for i := range places {
+				titles = append(titles, places[i].Title)
+			}
+
+			require.Equal(t, tc.titles, titles)
+		})
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		rec := serve(h, http.MethodGet, "/api/jobs/"+runningJobID+"/results?partial=true&format=csv", "", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "true", rec.Header().Get(handlers.PartialResultsHeader))
+
+		rows, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+	})
+}
+
+func Test_GetJobPartial(t *testing.T) {
+	h := newResultsHandler(map[string][]*gmaps.Entry{runningJobID: entries("running", 2)})
+
+	for jobID, partial := range map[string]bool{runningJobID: true, completedJobID: false} {
+		rec := serve(h, http.MethodGet, "/api/jobs/"+jobID, "", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp handlers.JobResponse
+
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, partial, resp.Partial, jobID)
+	}
+}
+
+// the results are streamed for longer than the write timeout of the server
+// and are larger than the buffers of the response
+func Test_JobResultsOutlastTheWriteTimeout(t *testing.T) {
+	const n = 300
+
+	provider := newFakeProvider(gmaps.JobInfo{ID: completedJobID, State: "completed"})
+	srv := resultsServer(t, &fakeResults{
+		provider: provider,
+		places:   map[string][]*gmaps.Entry{completedJobID: entries(strings.Repeat("x", 100), n)},
+		delay:    2 * time.Millisecond,
+	})
+
+	resp, body, err := download(t, srv, "json")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Greater(t, len(body), 8*4096)
+
+	var places []gmaps.Entry
+
+	require.NoError(t, json.Unmarshal(body, &places))
+	require.Len(t, places, n)
+
+	resp, body, err = download(t, srv, "csv")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, n+1)
+}
+
+// a failed read after the status is sent doesn't end the body cleanly
+func Test_JobResultsTruncated(t *testing.T) {
+	provider := newFakeProvider(gmaps.JobInfo{ID: completedJobID, State: "completed"})
+	srv := resultsServer(t, &fakeResults{
+		provider:  provider,
+		places:    map[string][]*gmaps.Entry{completedJobID: entries(strings.Repeat("x", 100), 300)},
+		failAfter: 200,
+	})
+
+	for _, format := range []string{"json", "csv"} {
+		resp, _, err := download(t, srv, format)
+		require.Equal(t, http.StatusOK, resp.StatusCode, format)
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF, format)
+	}
+}
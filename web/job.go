@@ -1,13 +1,25 @@
 package web
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/gosom/google-maps-scraper/exiter"
+	"github.com/gosom/google-maps-scraper/polygon"
 )
 
 var jobs []Job
 
+// minMaxTime is the minimum runtime of a job
+const minMaxTime = 3 * time.Minute
+
 const (
 	StatusPending = "pending"
 	StatusWorking = "working"
@@ -34,6 +46,8 @@ type Job struct {
 	Date   time.Time
 	Status string
 	Data   JobData
+	// Progress is set while the job is running, it's not persisted
+	Progress *exiter.Progress
 }
 
 func (j *Job) Validate() error {
@@ -70,6 +84,20 @@ type JobData struct {
 	Email    bool          `json:"email"`
 	MaxTime  time.Duration `json:"max_time"`
 	Proxies  []string      `json:"proxies"`
+	// Location is geocoded into the coordinates when Lat and Lon are empty
+	Location string `json:"location,omitempty"`
+	// Polygon is a GeoJSON polygon, when set the keywords are searched from a grid
+	// covering it instead of the coordinates and only the places inside are kept
+	Polygon json.RawMessage `json:"polygon,omitempty"`
+	// ScrollBudget limits the time spent scrolling the results of each keyword
+	ScrollBudget time.Duration `json:"scroll_budget,omitempty"`
+	// AutoDepth ignores Depth and stops scrolling when the scrolls stop yielding new places
+	AutoDepth bool `json:"auto_depth,omitempty"`
+	// IncludeKeywords and ExcludeKeywords filter the places by their title, category and description
+	IncludeKeywords []string `json:"include_keywords,omitempty"`
+	ExcludeKeywords []string `json:"exclude_keywords,omitempty"`
+	// Tags are used to route the results of the job (see -output-routes)
+	Tags map[string]string `json:"tags,omitempty"`
 }
 
 func (d *JobData) Validate() error {
@@ -85,7 +113,7 @@ func (d *JobData) Validate() error {
 		return errors.New("invalid lang")
 	}
 
-	if d.Depth == 0 {
+	if d.Depth == 0 && !d.AutoDepth {
 		return errors.New("missing depth")
 	}
 
@@ -93,5 +121,75 @@ func (d *JobData) Validate() error {
 		return errors.New("missing max time")
 	}
 
+	if d.MaxTime < minMaxTime {
+		return errors.New("max time must be more than 3m")
+	}
+
+	if len(d.Polygon) > 0 {
+		if _, err := polygon.Parse(d.Polygon); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// Hash returns a hash of the normalized scrape parameters.
+// Jobs with the same hash produce the same results, the name and tags are not part of it.
+func (d *JobData) Hash() string {
+	normalize := func(items []string, lower bool) []string {
+		ans := make([]string, 0, len(items))
+
+		for _, item := range items {
+			item = strings.Join(strings.Fields(item), " ")
+			if lower {
+				item = strings.ToLower(item)
+			}
+
+			if item != "" {
+				ans = append(ans, item)
+			}
+		}
+
+		slices.Sort(ans)
+
+		return slices.Compact(ans)
+	}
+
+	normalized := JobData{
+		Keywords:        normalize(d.Keywords, true),
+		Lang:            strings.ToLower(d.Lang),
+		Zoom:            d.Zoom,
+		Lat:             strings.TrimSpace(d.Lat),
+		Lon:             strings.TrimSpace(d.Lon),
+		Location:        strings.ToLower(strings.Join(strings.Fields(d.Location), " ")),
+		Polygon:         compactJSON(d.Polygon),
+		Depth:           d.Depth,
+		Email:           d.Email,
+		MaxTime:         d.MaxTime,
+		Proxies:         normalize(d.Proxies, false),
+		ScrollBudget:    d.ScrollBudget,
+		AutoDepth:       d.AutoDepth,
+		IncludeKeywords: normalize(d.IncludeKeywords, true),
+		ExcludeKeywords: normalize(d.ExcludeKeywords, true),
+	}
+
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func compactJSON(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gosom/google-maps-scraper/web/handlers"
+)
+
+const (
+	corsMethods = "GET, POST, PATCH, DELETE, OPTIONS"
+	corsHeaders = "Content-Type, Authorization, " + handlers.APIKeyHeader
+)
+
+// corsExposedHeaders are the response headers the browsers let the scripts read
+var corsExposedHeaders = strings.Join([]string{
+	handlers.QuotaJobsRemainingHeader,
+	handlers.QuotaResultsRemainingHeader,
+	handlers.QuotaResetHeader,
+	handlers.TotalCountHeader,
+	"Retry-After",
+}, ", ")
+
+// Option configures the optional behavior of the Server
+type Option func(*Server)
+
+// WithCORS lets the browsers call the API from origins, "*" allows every origin
+func WithCORS(origins []string) Option {
+	return func(s *Server) {
+		s.corsOrigins = origins
+	}
+}
+
+// cors sets the CORS headers of the requests from the allowed origins and
+// answers the preflight requests before they reach the handlers
+func cors(origins []string, next http.Handler) http.Handler {
+	allowAll := slices.Contains(origins, "*")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+
+		if !allowAll && !slices.Contains(origins, origin) {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+			w.Header().Set("Access-Control-Allow-Headers", corsHeaders)
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+
+	"github.com/gosom/google-maps-scraper/web/handlers"
+)
+
+// rateLimitedPrefix is the path prefix of the rate limited requests
+const rateLimitedPrefix = "/api/jobs"
+
+// WithRateLimit limits the requests to the jobs endpoints to limit per second
+// per client, with bursts of up to burst requests. The client is the address
+// of the connection, or the X-Forwarded-For address when the connection comes
+// from one of the trusted proxies.
+func WithRateLimit(limit float64, burst int, trustedProxies []netip.Prefix) Option {
+	return func(s *Server) {
+		s.limiter = newRateLimiter(limit, burst, trustedProxies)
+	}
+}
+
+// client is the limiter of a client and the time it was last used
+type client struct {
+	limiter *rate.Limiter
+	last    time.Time
+}
+
+type rateLimiter struct {
+	limit   rate.Limit
+	burst   int
+	trusted []netip.Prefix
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastSweep time.Time
+}
+
+func newRateLimiter(limit float64, burst int, trusted []netip.Prefix) *rateLimiter {
+	return &rateLimiter{
+		limit:     rate.Limit(limit),
+		burst:     burst,
+		trusted:   trusted,
+		clients:   make(map[string]*client),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow takes a token of the limiter of addr. When there is none it
+// returns false and the time until the next one.
+func (l *rateLimiter) allow(addr string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	c, ok := l.clients[addr]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[addr] = c
+	}
+
+	c.last = now
+
+	r := c.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Second
+	}
+
+	if wait := r.DelayFrom(now); wait > 0 {
+		// the token is not taken when the request is refused
+		r.CancelAt(now)
+
+		return false, wait
+	}
+
+	return true, 0
+}
+
+// sweep drops the limiters that refilled, they are the same as new ones.
+// It must be called with the lock held.
+func (l *rateLimiter) sweep(now time.Time) {
+	const interval = time.Minute
+
+	if now.Sub(l.lastSweep) < interval {
+		return
+	}
+
+	l.lastSweep = now
+
+	full := time.Duration(float64(l.burst) / float64(l.limit) * float64(time.Second))
+
+	for addr, c := range l.clients {
+		if now.Sub(c.last) > full {
+			delete(l.clients, addr)
+		}
+	}
+}
+
+// clientIP returns the address of the client of r. The X-Forwarded-For
+// addresses are read from the right, skipping the trusted proxies, so that
+// the client can't pick its address by sending the header itself.
+func (l *rateLimiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !l.isTrusted(addr) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+
+		if !l.isTrusted(hop) {
+			return hop.Unmap().String()
+		}
+	}
+
+	return host
+}
+
+func (l *rateLimiter) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	for _, p := range l.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rateLimit answers 429 with the standard error response to the clients
+// that exceed the rate of the jobs endpoints
+func rateLimit(l *rateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, rateLimitedPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ok, wait := l.allow(l.clientIP(r), time.Now())
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+
+		_ = json.NewEncoder(w).Encode(handlers.CreateJobResponse{
+			Status:    "error",
+			Message:   "rate limit exceeded",
+			Code:      handlers.CodeRateLimited,
+			RequestID: uuid.New().String(),
+		})
+	})
+}
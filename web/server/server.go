@@ -3,35 +3,111 @@ package server
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/gosom/google-maps-scraper/apilimits"
+	"github.com/gosom/google-maps-scraper/metrics"
 	"github.com/gosom/google-maps-scraper/web/handlers"
 	"go.uber.org/zap"
 )
 
+// default timeouts of the server
+const (
+	DefaultReadTimeout  = apilimits.ReadTimeout
+	DefaultWriteTimeout = apilimits.WriteTimeout
+	DefaultIdleTimeout  = apilimits.IdleTimeout
+)
+
 type Server struct {
-	srv    *http.Server
-	logger *zap.Logger
+	srv         *http.Server
+	logger      *zap.Logger
+	corsOrigins []string
+	limiter     *rateLimiter
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
 }
 
-func New(handler *handlers.JobHandler, logger *zap.Logger) *Server {
+// WithTimeouts sets the timeouts of the server. read bounds reading a whole
+// request including its body, write bounds the handling of a request and
+// writing its response, idle bounds the wait for the next request of a
+// keep-alive connection. 0 keeps the default.
+func WithTimeouts(read, write, idle time.Duration) Option {
+	return func(s *Server) {
+		if read > 0 {
+			s.readTimeout = read
+		}
+
+		if write > 0 {
+			s.writeTimeout = write
+		}
+
+		if idle > 0 {
+			s.idleTimeout = idle
+		}
+	}
+}
+
+// New returns the API server listening on port
+func New(handler *handlers.JobHandler, logger *zap.Logger, port int, opts ...Option) *Server {
+	s := &Server{
+		logger:       logger,
+		readTimeout:  DefaultReadTimeout,
+		writeTimeout: DefaultWriteTimeout,
+		idleTimeout:  DefaultIdleTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
 	mux := http.NewServeMux()
 
+	// handle registers a route, the latency of its requests is recorded under name
+	handle := func(pattern, name string, h http.HandlerFunc) {
+		mux.Handle(pattern, metrics.Instrument(name, h))
+	}
+
 	// Register routes
-	mux.HandleFunc("/api/jobs", handler.CreateJob)
-
-	srv := &http.Server{
-		Addr:         ":6060",
-		Handler:      mux,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  120 * time.Second,
+	handle("/api/jobs", "CreateJob", handler.CreateJob)
+	handle("GET /api/jobs", "ListJobs", handler.ListJobs)
+	handle("POST /api/jobs/batch", "CreateJobsBatch", handler.CreateJobsBatch)
+	handle("GET /api/jobs/{id}", "GetJob", handler.GetJob)
+	handle("GET /api/jobs/{id}/results", "JobResults", handler.JobResults)
+	handle("PATCH /api/jobs/{id}", "UpdateJob", handler.UpdateJob)
+	handle("DELETE /api/jobs/{id}", "DeleteJob", handler.DeleteJob)
+	handle("POST /api/jobs/{id}/cancel", "CancelJob", handler.CancelJob)
+	handle("POST /api/jobs/{id}/clone", "CloneJob", handler.CloneJob)
+	handle("GET /api/dlq", "ListDeadLetters", handler.ListDeadLetters)
+	handle("POST /api/dlq/{id}/requeue", "RequeueDeadLetter", handler.RequeueDeadLetter)
+	handle("POST /api/places/{placeID}/refresh", "RefreshPlace", handler.RefreshPlace)
+	handle("GET /api/quota", "GetQuota", handler.GetQuota)
+	handle("POST /api/admin/reload", "Reload", handler.Reload)
+	mux.HandleFunc("GET /health", handler.Health)
+	mux.HandleFunc("GET /readiness", handler.Readiness)
+	mux.HandleFunc("GET /metrics", handler.Metrics)
+
+	var h http.Handler = mux
+	if s.limiter != nil {
+		h = rateLimit(s.limiter, h)
+	}
+
+	// the preflight requests are answered before the rate limit
+	if len(s.corsOrigins) > 0 {
+		h = cors(s.corsOrigins, h)
 	}
 
-	return &Server{
-		srv:    srv,
-		logger: logger,
+	s.srv = &http.Server{
+		Addr:         ":" + strconv.Itoa(port),
+		Handler:      h,
+		ReadTimeout:  s.readTimeout,
+		WriteTimeout: s.writeTimeout,
+		IdleTimeout:  s.idleTimeout,
 	}
+
+	return s
 }
 
 func (s *Server) Start() error {
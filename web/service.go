@@ -2,30 +2,189 @@ package web
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/exiter"
 )
 
 type Service struct {
-	repo       JobRepository
-	dataFolder string
+	repo        JobRepository
+	dataFolder  string
+	dedupWindow time.Duration
+
+	mu       sync.Mutex
+	progress map[string]exiter.Progress
+}
+
+type ServiceOption func(*Service)
+
+// WithDedupWindow makes FindDuplicate match the pending and working jobs
+// created within window. A zero window disables the deduplication.
+func WithDedupWindow(window time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.dedupWindow = window
+	}
 }
 
-func NewService(repo JobRepository, dataFolder string) *Service {
-	return &Service{
+func NewService(repo JobRepository, dataFolder string, opts ...ServiceOption) *Service {
+	s := &Service{
 		repo:       repo,
 		dataFolder: dataFolder,
+		progress:   make(map[string]exiter.Progress),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// FindDuplicate returns a pending or working job with the same parameters as job
+// that was created within the dedup window. It returns ErrNotFound when there is none.
+func (s *Service) FindDuplicate(ctx context.Context, job *Job) (Job, error) {
+	if s.dedupWindow <= 0 {
+		return Job{}, ErrNotFound
+	}
+
+	hash := job.Data.Hash()
+	since := time.Now().UTC().Add(-s.dedupWindow)
+
+	for _, status := range []string{StatusPending, StatusWorking} {
+		items, err := s.repo.Select(ctx, SelectParams{Status: status})
+		if err != nil {
+			return Job{}, err
+		}
+
+		for i := range items {
+			if items[i].Date.Before(since) {
+				continue
+			}
+
+			if items[i].Data.Hash() == hash {
+				return items[i], nil
+			}
+		}
 	}
+
+	return Job{}, ErrNotFound
 }
 
 func (s *Service) Create(ctx context.Context, job *Job) error {
 	return s.repo.Create(ctx, job)
 }
 
+// Submit validates job and creates it. When an identical job is found by FindDuplicate
+// that job is returned instead and duplicate is true.
+func (s *Service) Submit(ctx context.Context, job *Job) (ans Job, duplicate bool, err error) {
+	if err := job.Validate(); err != nil {
+		return Job{}, false, fmt.Errorf("%w: %v", ErrInvalidJob, err)
+	}
+
+	existing, err := s.FindDuplicate(ctx, job)
+
+	switch {
+	case err == nil:
+		return existing, true, nil
+	case !errors.Is(err, ErrNotFound):
+		return Job{}, false, err
+	}
+
+	if err := s.repo.Create(ctx, job); err != nil {
+		return Job{}, false, err
+	}
+
+	return *job, false, nil
+}
+
+// Get returns the job with id or ErrNotFound
+func (s *Service) Get(ctx context.Context, id string) (Job, error) {
+	job, err := s.repo.Get(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, ErrNotFound
+	}
+
+	if err != nil {
+		return Job{}, err
+	}
+
+	s.attachProgress(&job)
+
+	return job, nil
+}
+
+// Select returns the jobs matching params
+func (s *Service) Select(ctx context.Context, params SelectParams) ([]Job, error) {
+	items, err := s.repo.Select(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		s.attachProgress(&items[i])
+	}
+
+	return items, nil
+}
+
+// SetProgress records the progress of the running job with id
+func (s *Service) SetProgress(id string, p exiter.Progress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.progress[id] = p
+}
+
+// ClearProgress drops the progress of the job with id once it stopped running
+func (s *Service) ClearProgress(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.progress, id)
+}
+
+func (s *Service) attachProgress(job *Job) {
+	if job.Status != StatusWorking {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.progress[job.ID]; ok {
+		job.Progress = &p
+	}
+}
+
+// Retry sets a failed job back to pending so that it's scraped again
+func (s *Service) Retry(ctx context.Context, id string) (Job, error) {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+
+	if job.Status != StatusFailed {
+		return Job{}, fmt.Errorf("%w: only failed jobs can be retried, job is %s", ErrInvalidJob, job.Status)
+	}
+
+	job.Status = StatusPending
+
+	if err := s.repo.Update(ctx, &job); err != nil {
+		return Job{}, err
+	}
+
+	return job, nil
+}
+
 func (s *Service) All(ctx context.Context) ([]Job, error) {
-	return s.repo.Select(ctx, SelectParams{})
+	return s.Select(ctx, SelectParams{})
 }
 
 func (s *Service) Delete(ctx context.Context, id string) error {
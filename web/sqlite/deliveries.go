@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/gosom/google-maps-scraper/webhook"
+)
+
+const defaultAttemptsLimit = 100
+
+type deliveryLog struct {
+	db *sql.DB
+}
+
+// NewDeliveryLog returns the webhook delivery log of a database returned by Open
+func NewDeliveryLog(db *sql.DB) webhook.DeliveryLog {
+	return &deliveryLog{db: db}
+}
+
+func (l *deliveryLog) SaveAttempt(ctx context.Context, attempt *webhook.Attempt) error {
+	jobIDs, err := json.Marshal(attempt.JobIDs)
+	if err != nil {
+		return err
+	}
+
+	const q = `INSERT INTO webhook_deliveries
+		(delivery_id, job_ids, attempt, status_code, response, error, duration, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = l.db.ExecContext(ctx, q,
+		attempt.DeliveryID, string(jobIDs), attempt.Attempt, attempt.StatusCode,
+		attempt.Response, attempt.Error, attempt.Duration, attempt.CreatedAt.UnixMilli(),
+	)
+
+	return err
+}
+
+func (l *deliveryLog) Attempts(ctx context.Context, params webhook.AttemptParams) ([]webhook.Attempt, error) {
+	q := `SELECT delivery_id, job_ids, attempt, status_code, response, error, duration, created_at
+		FROM webhook_deliveries WHERE 1 = 1`
+
+	var args []any
+
+	if params.JobID != "" {
+		q += ` AND EXISTS (SELECT 1 FROM json_each(job_ids) WHERE json_each.value = ?)`
+
+		args = append(args, params.JobID)
+	}
+
+	if params.FailedOnly {
+		q += ` AND error != ''`
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultAttemptsLimit
+	}
+
+	q += ` ORDER BY id DESC LIMIT ?`
+
+	args = append(args, limit)
+
+	rows, err := l.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ans := []webhook.Attempt{}
+
+	for rows.Next() {
+		var (
+			a         webhook.Attempt
+			jobIDs    string
+			createdAt int64
+		)
+
+		err := rows.Scan(&a.DeliveryID, &jobIDs, &a.Attempt, &a.StatusCode, &a.Response, &a.Error, &a.Duration, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(jobIDs), &a.JobIDs); err != nil {
+			return nil, err
+		}
+
+		a.CreatedAt = time.UnixMilli(createdAt).UTC()
+
+		ans = append(ans, a)
+	}
+
+	return ans, rows.Err()
+}
@@ -16,12 +16,22 @@ type repo struct {
 }
 
 func New(path string) (web.JobRepository, error) {
-	db, err := initDatabase(path)
+	db, err := Open(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return &repo{db: db}, nil
+	return NewWithDB(db), nil
+}
+
+// Open opens the database at path and creates its tables
+func Open(path string) (*sql.DB, error) {
+	return initDatabase(path)
+}
+
+// NewWithDB returns the job repository of a database returned by Open
+func NewWithDB(db *sql.DB) web.JobRepository {
+	return &repo{db: db}
 }
 
 func (repo *repo) Get(ctx context.Context, id string) (web.Job, error) {
@@ -188,7 +198,21 @@ func createSchema(db *sql.DB) error {
 			data TEXT NOT NULL,
 			created_at INT NOT NULL,
 			updated_at INT NOT NULL
-		)
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			delivery_id TEXT NOT NULL,
+			job_ids TEXT NOT NULL,
+			attempt INT NOT NULL,
+			status_code INT NOT NULL,
+			response TEXT NOT NULL,
+			error TEXT NOT NULL,
+			duration TEXT NOT NULL,
+			created_at INT NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_created_at ON webhook_deliveries (created_at)
 	`)
 
 	return err
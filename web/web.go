@@ -3,6 +3,9 @@ package web
 import (
 	"context"
 	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -16,6 +19,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/kmlwriter"
+	"github.com/gosom/google-maps-scraper/partialfile"
+	"github.com/gosom/google-maps-scraper/placepb"
+	"github.com/gosom/google-maps-scraper/web/graphql"
+	"github.com/gosom/google-maps-scraper/webhook"
 )
 
 //go:embed static
@@ -25,9 +35,12 @@ type Server struct {
 	tmpl map[string]*template.Template
 	srv  *http.Server
 	svc  *Service
+	gql  *graphql.Schema
+
+	deliveries webhook.DeliveryLog
 }
 
-func New(svc *Service) (*Server, error) {
+func New(svc *Service, opts ...ServerOption) (*Server, error) {
 	ans := Server{
 		svc:  svc,
 		tmpl: make(map[string]*template.Template),
@@ -42,6 +55,12 @@ func New(svc *Service) (*Server, error) {
 		return nil, err
 	}
 
+	for _, opt := range opts {
+		opt(&ans)
+	}
+
+	ans.gql = ans.graphqlSchema()
+
 	fileServer := http.FileServer(http.FS(staticFS))
 	mux := http.NewServeMux()
 
@@ -50,6 +69,9 @@ func New(svc *Service) (*Server, error) {
 	mux.HandleFunc("/download", ans.download)
 	mux.HandleFunc("/delete", ans.delete)
 	mux.HandleFunc("/jobs", ans.getJobs)
+	mux.HandleFunc("/events", ans.events)
+	mux.HandleFunc("/graphql", ans.graphqlQuery)
+	mux.HandleFunc("/webhooks/deliveries", ans.webhookDeliveries)
 	mux.HandleFunc("/", ans.index)
 
 	ans.srv.Handler = mux
@@ -177,12 +199,6 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if maxTime < time.Minute*3 {
-		http.Error(w, "max time must be more than 3m", http.StatusUnprocessableEntity)
-
-		return
-	}
-
 	newJob.Data.MaxTime = maxTime
 
 	keywordsStr, ok := r.Form["keywords"]
@@ -213,6 +229,16 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 
 	newJob.Data.Lat = r.Form.Get("latitude")
 	newJob.Data.Lon = r.Form.Get("longitude")
+	newJob.Data.Location = strings.TrimSpace(r.Form.Get("location"))
+
+	if v := strings.TrimSpace(r.Form.Get("polygon")); v != "" {
+		newJob.Data.Polygon = json.RawMessage(v)
+	}
+
+	// the form defaults the coordinates to 0, they are not meant as coordinates
+	if (newJob.Data.Location != "" || len(newJob.Data.Polygon) > 0) && isZeroCoordinate(newJob.Data.Lat) && isZeroCoordinate(newJob.Data.Lon) {
+		newJob.Data.Lat, newJob.Data.Lon = "", ""
+	}
 
 	newJob.Data.Depth, err = strconv.Atoi(r.Form.Get("depth"))
 	if err != nil {
@@ -222,6 +248,19 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 	}
 
 	newJob.Data.Email = r.Form.Get("email") == "on"
+	newJob.Data.AutoDepth = r.Form.Get("autodepth") == "on"
+
+	if v := strings.TrimSpace(r.Form.Get("scrollbudget")); v != "" {
+		newJob.Data.ScrollBudget, err = time.ParseDuration(v)
+		if err != nil || newJob.Data.ScrollBudget < 0 {
+			http.Error(w, "invalid scroll budget", http.StatusUnprocessableEntity)
+
+			return
+		}
+	}
+
+	newJob.Data.IncludeKeywords = gmaps.ParseKeywords(r.Form.Get("includekeywords"))
+	newJob.Data.ExcludeKeywords = gmaps.ParseKeywords(r.Form.Get("excludekeywords"))
 
 	proxies := strings.Split(r.Form.Get("proxies"), "\n")
 	if len(proxies) > 0 {
@@ -235,19 +274,14 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err = newJob.Validate()
+	tags, err := parseTags(r.Form.Get("tags"))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 
 		return
 	}
 
-	err = s.svc.Create(r.Context(), &newJob)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-
-		return
-	}
+	newJob.Data.Tags = tags
 
 	tmpl, ok := s.tmpl["static/templates/job_row.html"]
 	if !ok {
@@ -256,7 +290,51 @@ func (s *Server) scrape(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_ = tmpl.Execute(w, newJob)
+	job, duplicate, err := s.svc.Submit(r.Context(), &newJob)
+
+	switch {
+	case errors.Is(err, ErrInvalidJob):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	case duplicate:
+		// the row of the existing job is already in the table
+		w.Header().Set("X-Existing-Job-ID", job.ID)
+		w.Header().Set("HX-Reswap", "none")
+	}
+
+	_ = tmpl.Execute(w, job)
+}
+
+// parseTags parses one key=value tag per line
+func parseTags(s string) (map[string]string, error) {
+	var tags map[string]string
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		k, v, ok := strings.Cut(line, "=")
+		k = strings.TrimSpace(k)
+
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid tag %q: expected key=value", line)
+		}
+
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+
+		tags[k] = strings.TrimSpace(v)
+	}
+
+	return tags, nil
 }
 
 func (s *Server) getJobs(w http.ResponseWriter, r *http.Request) {
@@ -299,30 +377,193 @@ func (s *Server) download(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	filePath, err := s.svc.GetCSV(ctx, id)
+	res, err := s.openResults(ctx, id)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	defer res.Close()
+
+	if res.Partial {
+		// the job is still running, clients should download again later
+		w.Header().Set(partialResultsHeader, "true")
+	}
+
+	filePath := res.Path
+
+	switch r.URL.Query().Get("format") {
+	case "protobuf":
+		s.streamProtobuf(w, res, filePath)
+
+		return
+	case "kml":
+		s.downloadKML(w, res, filePath)
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		http.Error(w, "Failed to open file", http.StatusInternalServerError)
 		return
 	}
-	defer file.Close()
 
 	fileName := filepath.Base(filePath)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
 	w.Header().Set("Content-Type", "text/csv")
 
-	_, err = io.Copy(w, file)
+	_, err = io.Copy(w, res)
 	if err != nil {
 		http.Error(w, "Failed to send file", http.StatusInternalServerError)
 		return
 	}
 }
 
+// streamProtobuf converts the csv results to a stream of length delimited
+// protobuf messages (see proto/gmaps/v1/place.proto)
+func (s *Server) streamProtobuf(w http.ResponseWriter, file io.Reader, filePath string) {
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", exportName(filePath, ".pb")))
+	w.Header().Set("Content-Type", placepb.ContentType)
+	w.Header().Set("X-Schema-Version", placepb.SchemaVersion)
+
+	pbw := placepb.NewWriter(w)
+
+	err := readCsvEntries(file, filePath, pbw.Write)
+	if err != nil {
+		log.Printf("failed to stream %s: %v", filePath, err)
+	}
+}
+
+// downloadKML converts the csv results to a KML document
+func (s *Server) downloadKML(w http.ResponseWriter, file io.Reader, filePath string) {
+	var entries []*gmaps.Entry
+
+	err := readCsvEntries(file, filePath, func(entry *gmaps.Entry) error {
+		entries = append(entries, entry)
+
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Failed to read results", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", exportName(filePath, ".kml")))
+	w.Header().Set("Content-Type", kmlwriter.ContentType)
+
+	if err := kmlwriter.Write(w, exportName(filePath, ""), entries); err != nil {
+		log.Printf("failed to write kml for %s: %v", filePath, err)
+	}
+}
+
+// readCsvEntries calls fn for every place of the csv results.
+// Invalid rows are logged and skipped.
+func readCsvEntries(file io.Reader, filePath string, fn func(*gmaps.Entry) error) error {
+	reader := csv.NewReader(file)
+
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		entry, err := gmaps.EntryFromCsvRow(headers, row)
+		if err != nil {
+			log.Printf("skipping invalid row in %s: %v", filePath, err)
+
+			continue
+		}
+
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+}
+
+// partialResultsHeader is set on the downloads of the jobs still running
+const partialResultsHeader = "X-Results-Partial"
+
+// results are the csv results of a job
+type results struct {
+	io.Reader
+	file *os.File
+	// Path is the path of the csv file
+	Path string
+	// Partial is true when the job is still running
+	Partial bool
+}
+
+func (r *results) Close() error {
+	return r.file.Close()
+}
+
+// openResults opens the csv results of the job. The results of a running job
+// are read while the job appends to the file: only the rows complete when
+// opened are read, so the reads never wait for nor see a half written row.
+func (s *Server) openResults(ctx context.Context, id string) (*results, error) {
+	job, err := s.svc.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	filePath, err := s.svc.GetCSV(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	ans := results{
+		Reader: file,
+		file:   file,
+		Path:   filePath,
+	}
+
+	if job.Status == StatusWorking {
+		ans.Partial = true
+
+		ans.Reader, err = completeRows(file)
+		if err != nil {
+			file.Close()
+
+			return nil, err
+		}
+	}
+
+	return &ans, nil
+}
+
+// completeRows returns a reader of the file up to its last complete record,
+// the quoted fields may hold newlines
+func completeRows(file *os.File) (io.Reader, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := partialfile.CSVEnd(file, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NewSectionReader(file, 0, end), nil
+}
+
+func exportName(filePath, ext string) string {
+	return strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)) + ext
+}
+
 func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -352,3 +593,9 @@ func (s *Server) delete(w http.ResponseWriter, r *http.Request) {
 func formatDate(t time.Time) string {
 	return t.Format("Jan 02, 2006 15:04:05")
 }
+
+func isZeroCoordinate(s string) bool {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+
+	return s == "" || (err == nil && v == 0)
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// statuses of the callbacks
+const (
+	CallbackCompleted = "completed"
+	CallbackFailed    = "failed"
+	CallbackCancelled = "cancelled"
+)
+
+// Callback is the payload posted to the webhook_url of an API job
+// when the job completes or fails
+type Callback struct {
+	JobID       string `json:"job_id"`
+	Status      string `json:"status"`
+	ResultCount int    `json:"result_count"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Sender posts the callbacks of the API jobs to the url of every job.
+// The callbacks are sent in the background and a failed delivery is
+// retried with an exponential backoff.
+type Sender struct {
+	client  *http.Client
+	retries int
+	backoff time.Duration
+
+	wg sync.WaitGroup
+}
+
+// NewSender returns a sender retrying a failed delivery up to retries times,
+// waiting backoff before the first retry and doubling the wait after every retry
+func NewSender(retries int, backoff time.Duration) *Sender {
+	const defaultTimeout = 30 * time.Second
+
+	return &Sender{
+		client:  &http.Client{Timeout: defaultTimeout},
+		retries: max(retries, 0),
+		backoff: backoff,
+	}
+}
+
+// Send delivers cb to url without blocking the caller
+func (s *Sender) Send(url string, cb Callback) {
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+
+		s.deliver(url, cb)
+	}()
+}
+
+// Close waits for the callbacks being delivered
+func (s *Sender) Close() {
+	s.wg.Wait()
+}
+
+func (s *Sender) deliver(url string, cb Callback) {
+	backoff := s.backoff
+
+	for attempt := 1; ; attempt++ {
+		err := s.post(url, &cb)
+		if err == nil {
+			log.Printf("delivered the %s callback of job %s after %d attempts", cb.Status, cb.JobID, attempt)
+
+			return
+		}
+
+		if attempt > s.retries {
+			log.Printf("failed to deliver the %s callback of job %s after %d attempts: %v", cb.Status, cb.JobID, attempt, err)
+
+			return
+		}
+
+		time.Sleep(backoff)
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (s *Sender) post(url string, cb *Callback) error {
+	payload, err := json.Marshal(cb)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"context"
+	"time"
+)
+
+// maxResponseSnippet is the number of bytes of the response body kept in the log
+const maxResponseSnippet = 512
+
+// Attempt is a single try to deliver a batch to the webhook
+type Attempt struct {
+	// DeliveryID is shared by the attempts to deliver the same batch
+	DeliveryID string    `json:"delivery_id"`
+	JobIDs     []string  `json:"job_ids"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Response   string    `json:"response,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Duration   string    `json:"duration"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AttemptParams selects the attempts of the delivery log
+type AttemptParams struct {
+	// JobID returns only the attempts delivering the job
+	JobID string
+	// FailedOnly returns only the failed attempts
+	FailedOnly bool
+	Limit      int
+}
+
+// DeliveryLog persists the delivery attempts
+type DeliveryLog interface {
+	SaveAttempt(ctx context.Context, attempt *Attempt) error
+	Attempts(ctx context.Context, params AttemptParams) ([]Attempt, error)
+}
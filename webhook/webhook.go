@@ -0,0 +1,347 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gosom/google-maps-scraper/metrics"
+)
+
+// maxBackoff bounds the wait between two delivery attempts
+const maxBackoff = 5 * time.Minute
+
+// Notification describes a job that reached a final state
+type Notification struct {
+	JobID      string    `json:"job_id"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	ResultsURL string    `json:"results_url,omitempty"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Batch is the payload posted to the webhook url
+type Batch struct {
+	Jobs   []Notification `json:"jobs"`
+	SentAt time.Time      `json:"sent_at"`
+}
+
+// Notifier buffers job notifications and delivers them to a webhook
+// in batches of BatchSize or every Interval, whichever comes first.
+// With a batch size of 1 every job is delivered on its own.
+// The batches are delivered by a bounded pool of workers, the failed
+// deliveries are retried with an exponential backoff.
+type Notifier struct {
+	url         string
+	batchSize   int
+	interval    time.Duration
+	client      *http.Client
+	concurrency int
+	retries     int
+	backoff     time.Duration
+	deliveries  DeliveryLog
+
+	mu     *sync.Mutex
+	buff   []Notification
+	flushc chan struct{}
+}
+
+type Option func(*Notifier)
+
+// WithConcurrency sets the number of batches delivered at the same time
+func WithConcurrency(workers int) Option {
+	return func(n *Notifier) {
+		if workers > 0 {
+			n.concurrency = workers
+		}
+	}
+}
+
+// WithRetries retries a failed delivery up to retries times, waiting backoff
+// before the first retry and doubling the wait after every retry
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(n *Notifier) {
+		n.retries = max(retries, 0)
+		n.backoff = backoff
+	}
+}
+
+// WithDeliveryLog persists every delivery attempt to l
+func WithDeliveryLog(l DeliveryLog) Option {
+	return func(n *Notifier) {
+		n.deliveries = l
+	}
+}
+
+func New(url string, batchSize int, interval time.Duration, opts ...Option) *Notifier {
+	const defaultTimeout = 30 * time.Second
+
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	ans := Notifier{
+		url:         url,
+		batchSize:   batchSize,
+		interval:    interval,
+		client:      &http.Client{Timeout: defaultTimeout},
+		concurrency: 1,
+		mu:          &sync.Mutex{},
+		flushc:      make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt(&ans)
+	}
+
+	return &ans
+}
+
+// Notify adds item to the current batch
+func (n *Notifier) Notify(item Notification) {
+	n.mu.Lock()
+	n.buff = append(n.buff, item)
+	full := len(n.buff) >= n.batchSize
+	n.mu.Unlock()
+
+	metrics.WebhookQueued.Inc()
+
+	if full {
+		select {
+		case n.flushc <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Run delivers the batches until ctx is cancelled.
+// The pending notifications are delivered before it returns.
+func (n *Notifier) Run(ctx context.Context) error {
+	// the deliveries outlive ctx to deliver the pending notifications,
+	// they are cancelled once the shutdown timeout expires
+	deliverCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	batchc := make(chan []Notification)
+
+	var wg sync.WaitGroup
+
+	for range n.concurrency {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for items := range batchc {
+				n.deliver(deliverCtx, items)
+			}
+		}()
+	}
+
+	var tickc <-chan time.Time
+
+	if n.interval > 0 {
+		ticker := time.NewTicker(n.interval)
+		defer ticker.Stop()
+
+		tickc = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			const shutdownTimeout = 10 * time.Second
+
+			timer := time.AfterFunc(shutdownTimeout, cancel)
+			defer timer.Stop()
+
+			n.flush(deliverCtx, batchc, true)
+
+			close(batchc)
+			wg.Wait()
+
+			if dropped := n.pending(); dropped > 0 {
+				log.Printf("dropped %d webhook notifications on shutdown", dropped)
+			}
+
+			return nil
+		case <-n.flushc:
+			n.flush(ctx, batchc, false)
+		case <-tickc:
+			n.flush(ctx, batchc, true)
+		}
+	}
+}
+
+// flush hands the buffered notifications in batches to the workers.
+// When all is false only full batches are delivered. It returns when ctx is
+// cancelled while the workers are busy, keeping the batch buffered.
+func (n *Notifier) flush(ctx context.Context, batchc chan<- []Notification, all bool) {
+	for {
+		n.mu.Lock()
+
+		if len(n.buff) == 0 || (!all && len(n.buff) < n.batchSize) {
+			n.mu.Unlock()
+
+			return
+		}
+
+		size := min(len(n.buff), n.batchSize)
+		items := make([]Notification, size)
+		copy(items, n.buff[:size])
+		n.buff = n.buff[size:]
+
+		n.mu.Unlock()
+
+		select {
+		case batchc <- items:
+			metrics.WebhookQueued.Sub(float64(len(items)))
+		case <-ctx.Done():
+			n.mu.Lock()
+			n.buff = append(items, n.buff...)
+			n.mu.Unlock()
+
+			return
+		}
+	}
+}
+
+func (n *Notifier) pending() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return len(n.buff)
+}
+
+// deliver sends the batch, retrying the failures that may succeed later
+func (n *Notifier) deliver(ctx context.Context, items []Notification) {
+	metrics.WebhookInFlight.Inc()
+	defer metrics.WebhookInFlight.Dec()
+
+	attempt := Attempt{
+		DeliveryID: uuid.New().String(),
+		JobIDs:     make([]string, len(items)),
+	}
+
+	for i := range items {
+		attempt.JobIDs[i] = items[i].JobID
+	}
+
+	backoff := n.backoff
+
+	for {
+		attempt.Attempt++
+
+		retry, err := n.send(ctx, items, &attempt)
+
+		n.saveAttempt(&attempt)
+
+		if err == nil {
+			metrics.WebhookDeliveries.WithLabelValues("delivered").Inc()
+
+			return
+		}
+
+		if !retry || attempt.Attempt > n.retries {
+			metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+
+			log.Printf("failed to deliver webhook batch of %d jobs after %d attempts: %v", len(items), attempt.Attempt, err)
+
+			return
+		}
+
+		metrics.WebhookRetries.Inc()
+
+		select {
+		case <-ctx.Done():
+			metrics.WebhookDeliveries.WithLabelValues("failed").Inc()
+
+			log.Printf("failed to deliver webhook batch of %d jobs: %v", len(items), err)
+
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// send posts the batch once and records the outcome in attempt.
+// retry is false when the webhook rejects the batch itself.
+func (n *Notifier) send(ctx context.Context, items []Notification, attempt *Attempt) (retry bool, err error) {
+	t0 := time.Now().UTC()
+
+	attempt.CreatedAt = t0
+	attempt.StatusCode = 0
+	attempt.Response = ""
+	attempt.Error = ""
+
+	defer func() {
+		attempt.Duration = time.Since(t0).Round(time.Millisecond).String()
+
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+	}()
+
+	metrics.WebhookAttempts.Inc()
+
+	payload, err := json.Marshal(Batch{
+		Jobs:   items,
+		SentAt: t0,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSnippet))
+
+	attempt.StatusCode = resp.StatusCode
+	attempt.Response = strings.ToValidUTF8(string(body), "")
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		retry := resp.StatusCode >= http.StatusInternalServerError ||
+			resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode == http.StatusRequestTimeout
+
+		return retry, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return false, nil
+}
+
+func (n *Notifier) saveAttempt(attempt *Attempt) {
+	if n.deliveries == nil {
+		return
+	}
+
+	// the attempt is saved even when the delivery was cancelled by the shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := n.deliveries.SaveAttempt(ctx, attempt); err != nil {
+		log.Printf("failed to save webhook delivery attempt: %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+// Package xlsxwriter writes the main fields of the places as an Excel
+// workbook, for the users opening the results in a spreadsheet.
+package xlsxwriter
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gosom/scrapemate"
+
+	"github.com/gosom/google-maps-scraper/compactcsv"
+	"github.com/gosom/google-maps-scraper/gmaps"
+)
+
+// ContentType is the media type of xlsx workbooks
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// column widths, in characters
+const (
+	minWidth = 8
+	maxWidth = 60
+)
+
+// cell is a value of the sheet, the numbers are written as numeric
+// cells so that they sort and sum in the spreadsheet
+type cell struct {
+	value  string
+	number bool
+}
+
+type xlsxWriter struct {
+	w io.Writer
+	// rows are the rendered rows of the sheet after the header,
+	// the column widths must be written before them
+	rows   bytes.Buffer
+	count  int
+	widths []int
+}
+
+// New returns a result writer that writes the places to w as a workbook with
+// one sheet, with the columns of compactcsv.Headers. The workbook is written
+// when the results end, it holds only the header when no place was found.
+func New(w io.Writer) scrapemate.ResultWriter {
+	return &xlsxWriter{w: w}
+}
+
+func (x *xlsxWriter) Run(_ context.Context, in <-chan scrapemate.Result) error {
+	header := make([]cell, len(compactcsv.Headers))
+	for i, h := range compactcsv.Headers {
+		header[i] = cell{value: h}
+	}
+
+	x.widths = make([]int, len(header))
+	x.measure(header)
+
+	for result := range in {
+		var entries []*gmaps.Entry
+
+		switch v := result.Data.(type) {
+		case *gmaps.Entry:
+			entries = append(entries, v)
+		case []any:
+			for i := range v {
+				entry, ok := v[i].(*gmaps.Entry)
+				if !ok {
+					return fmt.Errorf("cannot cast %T to *gmaps.Entry", v[i])
+				}
+
+				entries = append(entries, entry)
+			}
+		default:
+			return fmt.Errorf("cannot cast %T to *gmaps.Entry", result.Data)
+		}
+
+		for _, entry := range entries {
+			cells := row(entry)
+
+			x.measure(cells)
+			x.count++
+
+			// the header is the first row
+			writeRow(&x.rows, x.count+1, cells, 0)
+		}
+	}
+
+	return x.writeWorkbook(header)
+}
+
+// row returns the cells of entry in the order of compactcsv.Headers. Like in
+// the compact CSV the rating of the places without reviews and the coordinates
+// of the places without a location are left blank.
+func row(entry *gmaps.Entry) []cell {
+	var rating, lat, lon cell
+
+	if entry.ReviewCount > 0 {
+		rating = number(entry.ReviewRating)
+	}
+
+	if entry.Latitude != 0 || entry.Longtitude != 0 {
+		lat = number(entry.Latitude)
+		lon = number(entry.Longtitude)
+	}
+
+	return []cell{
+		{value: entry.Title},
+		{value: entry.Address},
+		{value: entry.Phone},
+		{value: entry.PhoneE164},
+		{value: entry.WebSite},
+		rating,
+		{value: strconv.Itoa(entry.ReviewCount), number: true},
+		lat,
+		lon,
+		{value: entry.Category},
+		{value: entry.Cid},
+		{value: entry.PlaceID},
+		{value: entry.MapsURL},
+	}
+}
+
+func number(f float64) cell {
+	return cell{value: strconv.FormatFloat(f, 'f', -1, 64), number: true}
+}
+
+// measure widens the columns to fit cells
+func (x *xlsxWriter) measure(cells []cell) {
+	for i, c := range cells {
+		if n := utf8.RuneCountInString(c.value) + 2; n > x.widths[i] {
+			x.widths[i] = min(n, maxWidth)
+		}
+	}
+}
+
+func (x *xlsxWriter) writeWorkbook(header []cell) error {
+	zw := zip.NewWriter(x.w)
+
+	for _, part := range []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", relsXML},
+		{"xl/workbook.xml", workbookXML},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+
+	var sheet bytes.Buffer
+
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	// the header row stays visible while scrolling
+	sheet.WriteString(`<sheetViews><sheetView workbookViewId="0">` +
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+		`</sheetView></sheetViews>`)
+
+	sheet.WriteString(`<cols>`)
+
+	for i, w := range x.widths {
+		fmt.Fprintf(&sheet, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, max(w, minWidth))
+	}
+
+	sheet.WriteString(`</cols><sheetData>`)
+
+	writeRow(&sheet, 1, header, styleHeader)
+
+	if _, err := f.Write(sheet.Bytes()); err != nil {
+		return err
+	}
+
+	if _, err := x.rows.WriteTo(f); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(f, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeRow writes the row n (from 1) of the sheet, the blank cells are omitted
+func writeRow(buf *bytes.Buffer, n int, cells []cell, style int) {
+	fmt.Fprintf(buf, `<row r="%d">`, n)
+
+	for i, c := range cells {
+		if c.value == "" {
+			continue
+		}
+
+		ref := columnName(i) + strconv.Itoa(n)
+
+		if c.number {
+			fmt.Fprintf(buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, c.value)
+
+			continue
+		}
+
+		fmt.Fprintf(buf, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+		// invalid characters are replaced, the workbook stays readable
+		_ = xml.EscapeText(buf, []byte(c.value))
+		buf.WriteString(`</t></is></c>`)
+	}
+
+	buf.WriteString(`</row>`)
+}
+
+// columnName returns the letters of the column i (from 0), e.g. A, Z, AA
+func columnName(i int) string {
+	name := ""
+
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+
+	return name
+}
+
+// styleHeader is the index of the bold cell format of styles.xml
+const styleHeader = 1
+
+const contentTypesXML = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const relsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookXML = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Places" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const workbookRelsXML = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+const stylesXML = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
@@ -0,0 +1,222 @@
+package xlsxwriter_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/gosom/scrapemate"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/gosom/google-maps-scraper/compactcsv"
+	"github.com/gosom/google-maps-scraper/gmaps"
+	"github.com/gosom/google-maps-scraper/xlsxwriter"
+)
+
+const sheet = "Places"
+
+func write(t *testing.T, results ...scrapemate.Result) []byte {
+	t.Helper()
+
+	in := make(chan scrapemate.Result, len(results))
+	for i := range results {
+		in <- results[i]
+	}
+
+	close(in)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, xlsxwriter.New(&buf).Run(context.Background(), in))
+
+	return buf.Bytes()
+}
+
+func open(t *testing.T, raw []byte) *excelize.File {
+	t.Helper()
+
+	f, err := excelize.OpenReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = f.Close() })
+
+	require.Equal(t, []string{sheet}, f.GetSheetList())
+
+	return f
+}
+
+// value returns the raw value and the type of the cell
+func value(t *testing.T, f *excelize.File, ref string) (string, excelize.CellType) {
+	t.Helper()
+
+	v, err := f.GetCellValue(sheet, ref, excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+
+	typ, err := f.GetCellType(sheet, ref)
+	require.NoError(t, err)
+
+	return v, typ
+}
+
+func Test_WritesPlaces(t *testing.T) {
+	cafe := &gmaps.Entry{
+		Title:        "Café <Central> & Co",
+		Address:      "Herrengasse 14, 1010 Wien",
+		Phone:        "01 5333763",
+		PhoneE164:    "+4315333763",
+		WebSite:      "https://cafecentral.wien",
+		ReviewRating: 4.5,
+		ReviewCount:  12000,
+		Latitude:     48.2104,
+		Longtitude:   16.3655,
+		Category:     "Cafe",
+		Cid:          "123456789",
+		PlaceID:      "ChIJ123",
+		MapsURL:      "https://www.google.com/maps/search/?api=1&query_place_id=ChIJ123",
+	}
+
+	// no reviews and no location, the rating and the coordinates are blank
+	shop := &gmaps.Entry{Title: "Shop\x01", ReviewRating: 3}
+
+	f := open(t, write(t,
+		scrapemate.Result{Data: cafe},
+		scrapemate.Result{Data: []any{shop}},
+	))
+
+	rows, err := f.GetRows(sheet)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, compactcsv.Headers, rows[0])
+
+	tests := []struct {
+		ref  string
+		want string
+		typ  excelize.CellType
+	}{
+		{"A2", "Café <Central> & Co", excelize.CellTypeInlineString},
+		{"B2", "Herrengasse 14, 1010 Wien", excelize.CellTypeInlineString},
+		{"C2", "01 5333763", excelize.CellTypeInlineString},
+		{"D2", "+4315333763", excelize.CellTypeInlineString},
+		{"E2", "https://cafecentral.wien", excelize.CellTypeInlineString},
+		{"F2", "4.5", excelize.CellTypeUnset},
+		{"G2", "12000", excelize.CellTypeUnset},
+		{"H2", "48.2104", excelize.CellTypeUnset},
+		{"I2", "16.3655", excelize.CellTypeUnset},
+		{"J2", "Cafe", excelize.CellTypeInlineString},
+		{"K2", "123456789", excelize.CellTypeInlineString},
+		{"L2", "ChIJ123", excelize.CellTypeInlineString},
+		{"M2", "https://www.google.com/maps/search/?api=1&query_place_id=ChIJ123", excelize.CellTypeInlineString},
+		// the invalid character is replaced
+		{"A3", "Shop�", excelize.CellTypeInlineString},
+		{"F3", "", excelize.CellTypeUnset},
+		{"G3", "0", excelize.CellTypeUnset},
+		{"H3", "", excelize.CellTypeUnset},
+		{"I3", "", excelize.CellTypeUnset},
+	}
+
+	for _, tc := range tests {
+		got, typ := value(t, f, tc.ref)
+		require.Equal(t, tc.want, got, tc.ref)
+		require.Equal(t, tc.typ, typ, tc.ref)
+	}
+}
+
+func Test_HeaderIsBoldAndFrozen(t *testing.T) {
+	f := open(t, write(t, scrapemate.Result{Data: &gmaps.Entry{Title: "Cafe"}}))
+
+	styleID, err := f.GetCellStyle(sheet, "A1")
+	require.NoError(t, err)
+
+	style, err := f.GetStyle(styleID)
+	require.NoError(t, err)
+	require.NotNil(t, style.Font)
+	require.True(t, style.Font.Bold)
+
+	styleID, err = f.GetCellStyle(sheet, "A2")
+	require.NoError(t, err)
+
+	style, err = f.GetStyle(styleID)
+	require.NoError(t, err)
+	require.False(t, style.Font != nil && style.Font.Bold)
+
+	panes, err := f.GetPanes(sheet)
+	require.NoError(t, err)
+	require.True(t, panes.Freeze)
+	require.Equal(t, 1, panes.YSplit)
+	require.Equal(t, "A2", panes.TopLeftCell)
+}
+
+func Test_ColumnWidths(t *testing.T) {
+	f := open(t, write(t, scrapemate.Result{Data: &gmaps.Entry{
+		Title:   "A",
+		Address: string(bytes.Repeat([]byte("x"), 100)),
+		MapsURL: "https://maps.example/1",
+	}}))
+
+	tests := []struct {
+		col  string
+		want float64
+	}{
+		// the header is wider than the value
+		{"A", 8},
+		{"B", 60},
+		{"M", float64(len("https://maps.example/1") + 2)},
+		{"E", float64(len("website") + 2)},
+	}
+
+	for _, tc := range tests {
+		got, err := f.GetColWidth(sheet, tc.col)
+		require.NoError(t, err)
+		require.InDelta(t, tc.want, got, 0, tc.col)
+	}
+}
+
+func Test_EmptyResults(t *testing.T) {
+	f := open(t, write(t))
+
+	rows, err := f.GetRows(sheet)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{compactcsv.Headers}, rows)
+}
+
+// the cells hold their strings inline, the workbook has no shared strings part
+func Test_SheetXML(t *testing.T) {
+	raw := write(t, scrapemate.Result{Data: &gmaps.Entry{Title: "Cafe", ReviewCount: 3}})
+
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	require.NoError(t, err)
+
+	parts := map[string]string{}
+
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+
+		parts[zf.Name] = string(content)
+	}
+
+	require.NotContains(t, parts, "xl/sharedStrings.xml")
+	require.NotContains(t, parts["[Content_Types].xml"], "sharedStrings")
+
+	sheetXML := parts["xl/worksheets/sheet1.xml"]
+	require.Contains(t, sheetXML, `<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">title</t></is></c>`)
+	require.Contains(t, sheetXML, `<c r="A2" s="0" t="inlineStr"><is><t xml:space="preserve">Cafe</t></is></c>`)
+	require.Contains(t, sheetXML, `<c r="G2" s="0"><v>3</v></c>`)
+	require.NotContains(t, sheetXML, `t="s"`)
+}
+
+func Test_RejectsOtherData(t *testing.T) {
+	in := make(chan scrapemate.Result, 1)
+	in <- scrapemate.Result{Data: "not a place"}
+
+	close(in)
+
+	require.Error(t, xlsxwriter.New(io.Discard).Run(context.Background(), in))
+}